@@ -0,0 +1,109 @@
+package snowflake
+
+import "sync"
+
+// maxActorBatch caps how many queued requests the actor serves from a single
+// clock read. (internal-use only)
+const maxActorBatch = 256
+
+// ActorGen is a Generator whose state is owned by a single goroutine.
+// NextID sends a request to that goroutine and waits for the answer, which
+// avoids mutex contention at very high goroutine counts and lets the actor
+// serve every queued request from a single clock read.
+//
+// Call Close to stop the goroutine. NextID keeps working after Close by
+// falling back to a mutex.
+type ActorGen struct {
+	g       generator
+	reqs    chan chan uint64
+	done    chan struct{}
+	stopped chan struct{}
+	resps   sync.Pool
+
+	closeOnce sync.Once
+}
+
+// NewActor returns a new snowflake.ActorGen (max field value: 1023)
+// A field bigger than the max is reset to 0.
+func NewActor(field uint64, opts ...Option) *ActorGen {
+	a := &ActorGen{
+		reqs:    make(chan chan uint64),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		resps: sync.Pool{New: func() interface{} {
+			return make(chan uint64, 1)
+		}},
+	}
+	if field <= maxFieldBits {
+		a.g.fieldSegment = field << sequenceBits
+	}
+	a.g.apply(opts)
+	a.g.checkField(field, maxFieldBits)
+
+	go a.run()
+
+	return a
+}
+
+// NextID returns a new snowflake ID.
+func (a *ActorGen) NextID() uint64 {
+	resp := a.resps.Get().(chan uint64)
+
+	select {
+	case a.reqs <- resp:
+		id := <-resp
+		a.resps.Put(resp)
+		return id
+	case <-a.stopped:
+		// the actor is gone, its state is ours to share now
+		a.resps.Put(resp)
+		return a.g.nextID()
+	}
+}
+
+// Close stops the actor goroutine and waits for it to exit.
+func (a *ActorGen) Close() {
+	a.closeOnce.Do(func() {
+		close(a.done)
+		<-a.stopped
+	})
+}
+
+// run serves requests until Close is called. (internal-use only)
+func (a *ActorGen) run() {
+	defer close(a.stopped)
+
+	batch := make([]chan uint64, 0, maxActorBatch)
+	for {
+		select {
+		case resp := <-a.reqs:
+			batch = append(batch[:0], resp)
+		case <-a.done:
+			return
+		}
+
+		// pick up whoever else is already waiting
+	drain:
+		for len(batch) < maxActorBatch {
+			select {
+			case resp := <-a.reqs:
+				batch = append(batch, resp)
+			default:
+				break drain
+			}
+		}
+
+		if a.g.limiter != nil {
+			// every ID has to wait for its own token
+			for _, resp := range batch {
+				resp <- a.g.nextID()
+			}
+			continue
+		}
+
+		now := a.g.now()
+		for _, resp := range batch {
+			resp <- a.g.generateAt(now)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package snowflake_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestActorGen(t *testing.T) {
+	n := 100000
+	sf := snowflake.NewActor(1)
+	defer sf.Close()
+
+	var last uint64
+	for i := 0; i < n; i++ {
+		id := sf.NextID()
+		if id <= last {
+			t.Errorf("expected to be increasing, but got %d after %d", id, last)
+			break
+		}
+		last = id
+	}
+
+	if field := snowflake.Parse(last).MachineID; field != 1 {
+		t.Errorf("expected field %d got %d", 1, field)
+	}
+}
+
+func TestActorGen_Concurrent(t *testing.T) {
+	n := 100000
+	ch := make(chan uint64, n)
+	sf := snowflake.NewActor(1)
+	defer sf.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ch <- sf.NextID()
+		}()
+	}
+	wg.Wait()
+	close(ch)
+
+	ids := make(map[uint64]bool)
+	for id := range ch {
+		if _, ok := ids[id]; ok {
+			t.Error("expected to be unique, but got a repeated ID")
+			break
+		}
+		ids[id] = true
+	}
+	if len(ids) != n {
+		t.Errorf("expected map length %d got %d", n, len(ids))
+	}
+}
+
+func TestActorGen_Close(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	sf := snowflake.NewActor(1)
+
+	// close while other goroutines are still asking for IDs
+	var wg sync.WaitGroup
+	ids := make(chan uint64, 8*1000)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				ids <- sf.NextID()
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	sf.Close()
+	sf.Close() // should be safe to call twice
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[uint64]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Fatal("expected to be unique across Close, but got a repeated ID")
+		}
+		seen[id] = true
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("expected %d goroutines after Close got %d", before, after)
+	}
+}
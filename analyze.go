@@ -0,0 +1,184 @@
+package snowflake
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// outlierFloor is the least distance from the bulk of the timestamps that
+// Analyze flags as an outlier, so that a tight burst doesn't make every ID
+// a second away suspicious. (internal-use only)
+const outlierFloor = 24 * time.Hour
+
+// Report is the forensic summary of a set of snowflake IDs returned by
+// Analyze. Unless noted, duplicates are counted once and outliers are
+// left out.
+type Report struct {
+	// Count is the number of IDs, duplicates and outliers included.
+	Count int
+	// Duplicates is the number of IDs that repeat an earlier one.
+	Duplicates int
+
+	// First is the timestamp of the oldest ID.
+	First time.Time
+	// Last is the timestamp of the newest ID.
+	Last time.Time
+
+	// RateP50, RateP90 and RateP99 are the percentiles of the number of
+	// IDs per second, over the seconds that have any.
+	RateP50, RateP90, RateP99 int
+	// RateMax is the number of IDs in the busiest second.
+	RateMax int
+
+	// Busiest is the millisecond with the most IDs, the earliest on a tie.
+	Busiest time.Time
+	// BusiestCount is the number of IDs in Busiest.
+	BusiestCount int
+
+	// Fields is the number of IDs per field, outliers included.
+	Fields map[uint64]int
+	// MaxSequence is the highest sequence number, outliers included.
+	MaxSequence uint64
+
+	// Outliers are the IDs whose timestamps are far from the others
+	// (beyond 3 interquartile ranges, and at least a day), by increasing
+	// timestamp. They usually were issued under another epoch or by a
+	// generator with a broken clock.
+	Outliers []uint64
+}
+
+// Span returns the time covered by the IDs, from First to Last.
+func (r Report) Span() time.Duration { return r.Last.Sub(r.First) }
+
+// Analyze summarizes a set of snowflake IDs parsed under the current
+// epoch, in any order.
+func Analyze(ids []uint64) Report {
+	r := Report{Count: len(ids), Fields: make(map[uint64]int)}
+
+	sorted := append([]uint64(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// IDs sort by timestamp, so deduplicating keeps the timestamps sorted
+	distinct := sorted[:0]
+	for i, id := range sorted {
+		if i > 0 && id == sorted[i-1] {
+			r.Duplicates++
+			continue
+		}
+		distinct = append(distinct, id)
+		r.Fields[getMachineID(id)]++
+		if seq := getSequence(id); seq > r.MaxSequence {
+			r.MaxSequence = seq
+		}
+	}
+	if len(distinct) == 0 {
+		return r
+	}
+
+	q1, q3 := distinct[len(distinct)/4]>>(sequenceBits+fieldBits), distinct[len(distinct)*3/4]>>(sequenceBits+fieldBits)
+	fence := 3 * (q3 - q1)
+	if floor := uint64(outlierFloor / time.Millisecond); fence < floor {
+		fence = floor
+	}
+
+	epoch := atomic.LoadInt64(&epochMillis)
+	var rates []int
+	var ms, second int64 = -1, -1
+	var msCount int
+	for _, id := range distinct {
+		elapsed := id >> (sequenceBits + fieldBits)
+		if elapsed+fence < q1 || elapsed > q3+fence {
+			r.Outliers = append(r.Outliers, id)
+			continue
+		}
+
+		ts := int64(elapsed) + epoch
+		if r.First.IsZero() {
+			r.First = time.UnixMilli(ts).UTC()
+		}
+		r.Last = time.UnixMilli(ts).UTC()
+
+		if ts != ms {
+			ms, msCount = ts, 0
+		}
+		if msCount++; msCount > r.BusiestCount {
+			r.Busiest, r.BusiestCount = time.UnixMilli(ts).UTC(), msCount
+		}
+
+		if ts/1000 != second {
+			second = ts / 1000
+			rates = append(rates, 0)
+		}
+		rates[len(rates)-1]++
+	}
+
+	sort.Ints(rates)
+	r.RateP50, r.RateP90, r.RateP99 = percentile(rates, 50), percentile(rates, 90), percentile(rates, 99)
+	r.RateMax = rates[len(rates)-1]
+	return r
+}
+
+// percentile returns the nearest-rank pth percentile of sorted.
+// (internal-use only)
+func percentile(sorted []int, p int) int {
+	rank := (len(sorted)*p + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// String returns r as a compact table, e.g.
+//
+//	ids          48 (2 duplicates)
+//	span         2024-01-01T00:00:00Z .. 2024-01-01T00:00:02.999Z (2.999s)
+//	ids/s        p50 10, p90 30, p99 30, max 30
+//	busiest ms   2024-01-01T00:00:01Z (20 IDs)
+//	max sequence 19
+//	fields       1: 20, 2: 20, 3: 5, 4: 1
+//	outliers     1: 1329923481927172096
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "ids          %d (%d duplicates)\n", r.Count, r.Duplicates)
+	if r.First.IsZero() {
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "span         %s .. %s (%s)\n", r.First.Format(time.RFC3339Nano), r.Last.Format(time.RFC3339Nano), r.Span())
+	fmt.Fprintf(&b, "ids/s        p50 %d, p90 %d, p99 %d, max %d\n", r.RateP50, r.RateP90, r.RateP99, r.RateMax)
+	fmt.Fprintf(&b, "busiest ms   %s (%d IDs)\n", r.Busiest.Format(time.RFC3339Nano), r.BusiestCount)
+	fmt.Fprintf(&b, "max sequence %d\n", r.MaxSequence)
+
+	fields := make([]uint64, 0, len(r.Fields))
+	for field := range r.Fields {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i] < fields[j] })
+	b.WriteString("fields       ")
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%d: %d", field, r.Fields[field])
+	}
+	b.WriteString("\n")
+
+	if len(r.Outliers) > 0 {
+		fmt.Fprintf(&b, "outliers     %d: ", len(r.Outliers))
+		for i, id := range r.Outliers {
+			if i == 5 {
+				b.WriteString(" ...")
+				break
+			}
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			fmt.Fprintf(&b, "%d", id)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
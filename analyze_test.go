@@ -0,0 +1,98 @@
+package snowflake_test
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// idAt returns the ID of field and seq issued ms milliseconds after the
+// epoch.
+func idAt(ms, field, seq uint64) uint64 { return ms<<22 | field<<12 | seq }
+
+func TestAnalyze(t *testing.T) {
+	var ids []uint64
+	for seq := uint64(0); seq < 10; seq++ {
+		ids = append(ids, idAt(0, 1, seq)) // second 0: 10 IDs
+	}
+	for seq := uint64(0); seq < 20; seq++ {
+		ids = append(ids, idAt(1000, 2, seq)) // second 1: 30 IDs
+	}
+	for seq := uint64(0); seq < 10; seq++ {
+		ids = append(ids, idAt(1500, 1, seq))
+	}
+	for seq := uint64(0); seq < 5; seq++ {
+		ids = append(ids, idAt(2999, 3, seq)) // second 2: 5 IDs
+	}
+	outlier := idAt(uint64(10*365*24*time.Hour/time.Millisecond), 4, 0)
+	ids = append(ids, ids[3], ids[40], outlier)
+	rand.New(rand.NewSource(1)).Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	r := snowflake.Analyze(ids)
+
+	epoch := snowflake.Epoch()
+	expected := snowflake.Report{
+		Count:        48,
+		Duplicates:   2,
+		First:        epoch,
+		Last:         epoch.Add(2999 * time.Millisecond),
+		RateP50:      10,
+		RateP90:      30,
+		RateP99:      30,
+		RateMax:      30,
+		Busiest:      epoch.Add(time.Second),
+		BusiestCount: 20,
+		Fields:       map[uint64]int{1: 20, 2: 20, 3: 5, 4: 1},
+		MaxSequence:  19,
+		Outliers:     []uint64{outlier},
+	}
+	if !reflect.DeepEqual(r, expected) {
+		t.Errorf("expected %+v got %+v", expected, r)
+	}
+	if r.Span() != 2999*time.Millisecond {
+		t.Errorf("expected span %v got %v", 2999*time.Millisecond, r.Span())
+	}
+
+	s := r.String()
+	for _, line := range []string{
+		"ids          48 (2 duplicates)",
+		"ids/s        p50 10, p90 30, p99 30, max 30",
+		"busiest ms   " + epoch.Add(time.Second).Format(time.RFC3339Nano) + " (20 IDs)",
+		"fields       1: 20, 2: 20, 3: 5, 4: 1",
+		"outliers     1: ",
+	} {
+		if !strings.Contains(s, line) {
+			t.Errorf("expected %q in\n%s", line, s)
+		}
+	}
+}
+
+func TestAnalyze_Generated(t *testing.T) {
+	clock := &tickingClock{t: snowflake.Epoch().Add(time.Hour), step: time.Millisecond}
+	ids := snowflake.New(7, snowflake.WithClock(clock)).AppendIDs(nil, 5000)
+
+	r := snowflake.Analyze(ids)
+	if r.Count != 5000 || r.Duplicates != 0 || len(r.Outliers) != 0 {
+		t.Errorf("expected 5000 IDs without duplicates or outliers got %+v", r)
+	}
+	if r.Fields[7] != 5000 || len(r.Fields) != 1 {
+		t.Errorf("expected all IDs in field 7 got %v", r.Fields)
+	}
+	if r.First != time.UnixMilli(snowflake.Parse(ids[0]).Timestamp).UTC() || r.Last != time.UnixMilli(snowflake.Parse(ids[4999]).Timestamp).UTC() {
+		t.Errorf("expected the timestamps of the first and last IDs got %v and %v", r.First, r.Last)
+	}
+}
+
+func TestAnalyze_Empty(t *testing.T) {
+	r := snowflake.Analyze(nil)
+	if r.Count != 0 || !r.First.IsZero() || r.RateMax != 0 {
+		t.Errorf("expected an empty report got %+v", r)
+	}
+	if s := r.String(); s != "ids          0 (0 duplicates)\n" {
+		t.Errorf("expected a one line table got %q", s)
+	}
+}
@@ -0,0 +1,65 @@
+package snowflake
+
+import "time"
+
+// anomaly is a kind of event reported to the logger of a generator.
+// (internal-use only)
+type anomaly int
+
+const (
+	anomalyClockBackwards anomaly = iota
+	anomalySlowWait
+	anomalyFieldReset
+	anomalyStateSave
+	anomalyStateLoad
+	anomalyFallback
+	anomalyKinds
+)
+
+const (
+	// anomalyInterval is the minimum time between two records of the same
+	// kind of anomaly. (internal-use only)
+	anomalyInterval = time.Second
+	// slowWait is how long a wait for the next millisecond may take before
+	// it's reported; it normally takes well under a millisecond.
+	// (internal-use only)
+	slowWait = 2 * time.Millisecond
+)
+
+// anomalyLog rate limits the anomalies reported by a generator.
+// (internal-use only)
+type anomalyLog struct {
+	emit       func(msg string, field uint64, key string, value interface{}, suppressed uint64)
+	last       [anomalyKinds]time.Time
+	suppressed [anomalyKinds]uint64
+}
+
+// report logs an anomaly, unless one of the same kind was logged less than
+// anomalyInterval ago; those are only counted. The rate limit follows the
+// system clock, since the generator's clock may be the broken one.
+// (internal-use only)
+func (g *generator) report(kind anomaly, msg string, field uint64, key string, value interface{}) {
+	l := g.anomalies
+	if l == nil {
+		return
+	}
+
+	now := time.Now()
+	if !l.last[kind].IsZero() && now.Sub(l.last[kind]) < anomalyInterval {
+		l.suppressed[kind]++
+		return
+	}
+	l.last[kind] = now
+
+	suppressed := l.suppressed[kind]
+	l.suppressed[kind] = 0
+	l.emit(msg, field, key, value, suppressed)
+}
+
+// checkField reports field being reset to 0 for exceeding max.
+// (internal-use only)
+func (g *generator) checkField(field, max uint64) {
+	if field > max {
+		g.report(anomalyFieldReset, "snowflake: field out of range, reset to 0", field, "max", max)
+	}
+}
@@ -0,0 +1,55 @@
+package snowflake_test
+
+import (
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestAppendIDs(t *testing.T) {
+	sf := snowflake.New(1)
+
+	dst := make([]uint64, 0, 8192)
+	dst = sf.AppendIDs(dst, 8192)
+	if len(dst) != 8192 {
+		t.Fatalf("expected %d IDs got %d", 8192, len(dst))
+	}
+
+	for i := 1; i < len(dst); i++ {
+		if dst[i] <= dst[i-1] {
+			t.Errorf("expected to be increasing, but got %d at %d", dst[i], i)
+			break
+		}
+	}
+
+	// appending keeps what's already there
+	dst = sf.AppendIDs(dst, 10)
+	if len(dst) != 8202 {
+		t.Errorf("expected %d IDs got %d", 8202, len(dst))
+	}
+	if dst[8192] <= dst[8191] {
+		t.Error("expected the appended IDs to follow the existing ones")
+	}
+
+	if got := sf.AppendIDs(nil, 0); got != nil {
+		t.Errorf("expected nil for n=0 got %v", got)
+	}
+}
+
+func TestAppendIDs2(t *testing.T) {
+	sf := snowflake.New2(1, 2)
+
+	ids := sf.AppendIDs(nil, 5000)
+	seen := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatal("expected to be unique, but got a repeated ID")
+		}
+		seen[id] = true
+
+		sid := snowflake.Parse2(id)
+		if sid.Field1 != 1 || sid.Field2 != 2 {
+			t.Fatalf("expected fields 1,2 got %d,%d", sid.Field1, sid.Field2)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package snowflake
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sync/atomic"
+)
+
+const (
+	// binaryVersion is the version of the format written by MarshalBinary.
+	binaryVersion = 1
+	// binarySize is the size of a marshaled generator: version, kind,
+	// layout, epoch, elapsed time, sequence, 2 fields and a CRC-32 of all
+	// of them.
+	binarySize = 1 + 1 + 3 + 8 + 8 + 2 + 2 + 2 + 4
+
+	timestampBits = 63 - fieldBits - sequenceBits
+)
+
+// binaryKind tells the generators apart in their binary form.
+// (internal-use only)
+type binaryKind byte
+
+const (
+	kindID  binaryKind = 1 // one 10-bit field
+	kindID2 binaryKind = 2 // two 5-bit fields
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*ID)(nil)
+	_ encoding.BinaryUnmarshaler = (*ID)(nil)
+	_ encoding.BinaryMarshaler   = (*ID2)(nil)
+	_ encoding.BinaryUnmarshaler = (*ID2)(nil)
+)
+
+// MarshalBinary implements encoding.BinaryMarshaler, so generators can be
+// part of checkpoints encoded with gob and the like. The encoding holds a
+// format version, the epoch, the bit layout, the field and the position
+// of the generator, but none of its options.
+func (id *ID) MarshalBinary() ([]byte, error) { return id.marshalBinary(kindID), nil }
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, resuming id from
+// the position of a generator encoded by MarshalBinary. The options of id
+// are kept.
+//
+// Returns an error wrapping ErrInvalidState if data is corrupted or of an
+// unknown version, ErrLayoutMismatch if it was encoded by an ID2 or with
+// another bit layout, and ErrEpochMismatch if it was encoded under another
+// epoch.
+func (id *ID) UnmarshalBinary(data []byte) error {
+	field, _, err := id.unmarshalBinary(kindID, data)
+	if err != nil {
+		return err
+	}
+	id.field = field
+	return nil
+}
+
+// MarshalBinary is like (*ID).MarshalBinary.
+func (id *ID2) MarshalBinary() ([]byte, error) { return id.marshalBinary(kindID2), nil }
+
+// UnmarshalBinary is like (*ID).UnmarshalBinary, but data must have been
+// encoded by an ID2.
+func (id *ID2) UnmarshalBinary(data []byte) error {
+	field1, field2, err := id.unmarshalBinary(kindID2, data)
+	if err != nil {
+		return err
+	}
+	id.field1, id.field2 = field1, field2
+	return nil
+}
+
+// marshalBinary encodes g as a generator of the given kind.
+// (internal-use only)
+func (g *generator) marshalBinary(kind binaryKind) []byte {
+	g.mtx.Lock()
+	s := g.state()
+	g.mtx.Unlock()
+
+	field1, field2 := s.field, uint64(0)
+	if kind == kindID2 {
+		field1, field2 = s.field&maxFieldHalfBits, s.field>>(fieldBits/2)
+	}
+
+	b := make([]byte, binarySize)
+	b[0] = binaryVersion
+	b[1] = byte(kind)
+	b[2], b[3], b[4] = timestampBits, fieldBits, sequenceBits
+	binary.BigEndian.PutUint64(b[5:], uint64(s.epochMillis))
+	binary.BigEndian.PutUint64(b[13:], uint64(s.elapsedTime))
+	binary.BigEndian.PutUint16(b[21:], uint16(s.sequence))
+	binary.BigEndian.PutUint16(b[23:], uint16(field1))
+	binary.BigEndian.PutUint16(b[25:], uint16(field2))
+	binary.BigEndian.PutUint32(b[27:], crc32.ChecksumIEEE(b[:27]))
+	return b
+}
+
+// unmarshalBinary decodes a generator of the given kind into g, returning
+// its fields. (internal-use only)
+func (g *generator) unmarshalBinary(kind binaryKind, b []byte) (field1, field2 uint64, err error) {
+	switch {
+	case len(b) == 0:
+		return 0, 0, fmt.Errorf("snowflake: empty generator: %w", ErrInvalidState)
+	case b[0] != binaryVersion:
+		return 0, 0, fmt.Errorf("snowflake: unknown generator version %d: %w", b[0], ErrInvalidState)
+	case len(b) != binarySize:
+		return 0, 0, fmt.Errorf("snowflake: generator is %d bytes, expected %d: %w", len(b), binarySize, ErrInvalidState)
+	case crc32.ChecksumIEEE(b[:27]) != binary.BigEndian.Uint32(b[27:]):
+		return 0, 0, fmt.Errorf("snowflake: generator checksum mismatch: %w", ErrInvalidState)
+	case binaryKind(b[1]) != kind:
+		return 0, 0, fmt.Errorf("snowflake: generator of kind %d, expected %d: %w", b[1], kind, ErrLayoutMismatch)
+	case b[2] != timestampBits || b[3] != fieldBits || b[4] != sequenceBits:
+		return 0, 0, fmt.Errorf("snowflake: generator layout %d/%d/%d bits, expected %d/%d/%d: %w",
+			b[2], b[3], b[4], timestampBits, fieldBits, sequenceBits, ErrLayoutMismatch)
+	}
+
+	epoch := int64(binary.BigEndian.Uint64(b[5:]))
+	if current := atomic.LoadInt64(&epochMillis); epoch != current {
+		return 0, 0, fmt.Errorf("snowflake: generator encoded under epoch %d, not %d: %w", epoch, current, ErrEpochMismatch)
+	}
+
+	elapsedTime := int64(binary.BigEndian.Uint64(b[13:]))
+	sequence := uint64(binary.BigEndian.Uint16(b[21:]))
+	field1 = uint64(binary.BigEndian.Uint16(b[23:]))
+	field2 = uint64(binary.BigEndian.Uint16(b[25:]))
+
+	segment := field1
+	max1, max2 := uint64(maxFieldBits), uint64(0)
+	if kind == kindID2 {
+		segment = field2<<(fieldBits/2) | field1
+		max1, max2 = maxFieldHalfBits, maxFieldHalfBits
+	}
+	if elapsedTime < 0 || elapsedTime > maxTimestamp || sequence > maxSeqBits || field1 > max1 || field2 > max2 {
+		return 0, 0, fmt.Errorf("snowflake: generator out of range: %w", ErrInvalidState)
+	}
+
+	g.mtx.Lock()
+	g.fieldSegment = segment << sequenceBits
+	g.elapsedTime = elapsedTime
+	g.sequence = sequence
+	g.sinceCheck = 0
+	g.mtx.Unlock()
+	return field1, field2, nil
+}
@@ -0,0 +1,151 @@
+package snowflake_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"hash/crc32"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// resign recomputes the checksum of a marshaled generator after b was
+// tampered with.
+func resign(b []byte) []byte {
+	n := len(b) - 4
+	binary.BigEndian.PutUint32(b[n:], crc32.ChecksumIEEE(b[:n]))
+	return b
+}
+
+func TestID_MarshalBinary(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(700, snowflake.WithClock(clock))
+	var last uint64
+	for i := 0; i < 10; i++ {
+		last = sf.NextID()
+	}
+
+	data, err := sf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	restored := snowflake.New(0, snowflake.WithClock(clock))
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	if id := restored.NextID(); id != last+1 {
+		t.Errorf("expected %d got %d", last+1, id)
+	}
+}
+
+func TestID2_MarshalBinary(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New2(3, 24, snowflake.WithClock(clock))
+	last := sf.NextID()
+
+	data, err := sf.MarshalBinary()
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	restored := snowflake.New2(0, 0, snowflake.WithClock(clock))
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	id := restored.NextID()
+	if id != last+1 {
+		t.Errorf("expected %d got %d", last+1, id)
+	}
+	if sid := snowflake.Parse2(id); sid.Field1 != 3 || sid.Field2 != 24 {
+		t.Errorf("expected fields 3 and 24 got %d and %d", sid.Field1, sid.Field2)
+	}
+}
+
+func TestMarshalBinary_Gob(t *testing.T) {
+	type checkpoint struct {
+		Offset    int64
+		Generator *snowflake.ID
+	}
+
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(5, snowflake.WithClock(clock))
+	last := sf.NextID()
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(checkpoint{Offset: 42, Generator: sf}); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	var cp checkpoint
+	if err := gob.NewDecoder(&buf).Decode(&cp); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	if cp.Offset != 42 {
+		t.Errorf("expected offset %d got %d", 42, cp.Offset)
+	}
+	if id := cp.Generator.NextID(); id <= last || snowflake.Parse(id).MachineID != 5 {
+		t.Errorf("expected an ID of field 5 after %d got %d", last, id)
+	}
+}
+
+func TestUnmarshalBinary_Invalid(t *testing.T) {
+	data, err := snowflake.New(5).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := snowflake.New2(1, 2).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tamper := func(f func(b []byte)) []byte {
+		b := append([]byte(nil), data...)
+		f(b)
+		return b
+	}
+
+	tc := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"Should return ErrInvalidState for an empty generator", nil, snowflake.ErrInvalidState},
+		{"Should return ErrInvalidState for an unknown version", tamper(func(b []byte) { b[0] = 2; resign(b) }), snowflake.ErrInvalidState},
+		{"Should return ErrInvalidState for a truncated generator", data[:len(data)-2], snowflake.ErrInvalidState},
+		{"Should return ErrInvalidState for a bad checksum", tamper(func(b []byte) { b[15] ^= 1 }), snowflake.ErrInvalidState},
+		{"Should return ErrLayoutMismatch for an ID2", data2, snowflake.ErrLayoutMismatch},
+		{"Should return ErrLayoutMismatch for another timestamp size", tamper(func(b []byte) { b[2] = 42; resign(b) }), snowflake.ErrLayoutMismatch},
+		{"Should return ErrLayoutMismatch for another field size", tamper(func(b []byte) { b[3] = 8; resign(b) }), snowflake.ErrLayoutMismatch},
+		{"Should return ErrLayoutMismatch for another sequence size", tamper(func(b []byte) { b[4] = 14; resign(b) }), snowflake.ErrLayoutMismatch},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var id snowflake.ID
+			if err := id.UnmarshalBinary(tt.data); !errors.Is(err, tt.expected) {
+				t.Errorf("expected error %v got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalBinary_EpochMismatch(t *testing.T) {
+	defer snowflake.SetEpoch(snowflake.Epoch())
+
+	data, err := snowflake.New2(1, 2).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := snowflake.SetEpoch(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	var id snowflake.ID2
+	if err := id.UnmarshalBinary(data); !errors.Is(err, snowflake.ErrEpochMismatch) {
+		t.Errorf("expected error %v got %v", snowflake.ErrEpochMismatch, err)
+	}
+}
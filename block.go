@@ -0,0 +1,133 @@
+package snowflake
+
+import (
+	"sync"
+	"time"
+)
+
+// Block is a run of consecutive snowflake IDs, FirstID to
+// FirstID+Count-1, sharing one millisecond and field. Count is at most
+// 4096.
+type Block struct {
+	FirstID uint64
+	Count   uint32
+}
+
+// BlockIssuer hands out blocks of IDs to clients that can't hold a field of
+// their own, such as short-lived functions. Blocks never overlap and each
+// one starts after the previous one ended, so IDs remain unique and
+// time-ordered across all clients of an issuer. Unused IDs of a block are
+// simply lost; the issuer doesn't track blocks.
+type BlockIssuer struct {
+	g generator
+}
+
+// NewBlockIssuer returns a new snowflake.BlockIssuer issuing IDs with the
+// given field (max field value: 1023), which no other generator may use.
+// A field bigger than the max is reset to 0.
+func NewBlockIssuer(field uint64, opts ...Option) *BlockIssuer {
+	b := &BlockIssuer{}
+	if field <= maxFieldBits {
+		b.g.fieldSegment = field << sequenceBits
+	}
+	b.g.apply(opts)
+	b.g.checkField(field, maxFieldBits)
+	return b
+}
+
+// Issue returns a block of up to n IDs. The block ends with the current
+// millisecond, so it can hold fewer IDs than asked for, but always at
+// least one. With WithRateLimit, every ID asked for takes a token.
+func (b *BlockIssuer) Issue(n uint32) Block {
+	if n == 0 {
+		n = 1
+	}
+	return b.g.reserve(n)
+}
+
+// reserve issues up to n consecutive IDs of the current millisecond.
+// (internal-use only)
+func (g *generator) reserve(n uint32) Block {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if g.limiter != nil {
+		if wait := g.limiter.reserveN(g.wallNow(), int(n)); wait > 0 {
+			g.mtx.Unlock()
+			time.Sleep(wait)
+			g.mtx.Lock()
+		}
+	}
+
+	fallbacks := g.counters.Fallbacks
+	first := g.generate()
+	if g.counters.Fallbacks != fallbacks || g.fieldLow {
+		// the clock is unusable, a random ID can't start a block, or the
+		// IDs of the sequence aren't consecutive
+		return Block{FirstID: first, Count: 1}
+	}
+	seq := first & maxSeqBits
+
+	count := uint64(n)
+	if left := maxSeqBits - seq + 1; count > left {
+		count = left
+	}
+
+	// the rest of the block is issued too; the next ID follows it
+	g.sequence = seq + count - 1
+	g.counters.IDs += count - 1
+	g.trackSequence()
+	if g.checkEvery > 0 {
+		g.sinceCheck += int(count) - 1
+	}
+
+	return Block{FirstID: first, Count: uint32(count)}
+}
+
+// BlockConsumer issues IDs from blocks fetched from a BlockIssuer, fetching
+// the next block when the current one runs out. How blocks are fetched
+// (HTTP, gRPC, ...) is up to the fetch function.
+type BlockConsumer struct {
+	mtx   sync.Mutex
+	fetch func() (Block, error)
+	next  uint64
+	left  uint32
+}
+
+// NewBlockConsumer returns a new snowflake.BlockConsumer fetching blocks
+// with fetch, e.g. by calling BlockIssuer.Issue on a server.
+func NewBlockConsumer(fetch func() (Block, error)) *BlockConsumer {
+	return &BlockConsumer{fetch: fetch}
+}
+
+// NextID returns the next ID of the current block, fetching a new block
+// first if it is used up. Errors from fetch are returned as is; a block
+// with no IDs returns ErrEmptyBlock.
+func (c *BlockConsumer) NextID() (uint64, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.left == 0 {
+		block, err := c.fetch()
+		if err != nil {
+			return 0, err
+		}
+		if block.Count == 0 {
+			return 0, ErrEmptyBlock
+		}
+		c.next, c.left = block.FirstID, block.Count
+	}
+
+	id := c.next
+	c.next++
+	c.left--
+	return id, nil
+}
+
+// Discard drops the rest of the current block, so the next ID comes from
+// a freshly fetched block.
+func (c *BlockConsumer) Discard() {
+	c.mtx.Lock()
+	c.left = 0
+	c.mtx.Unlock()
+}
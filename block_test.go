@@ -0,0 +1,143 @@
+package snowflake_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestBlockIssuer_Issue(t *testing.T) {
+	clock := newFakeClock(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	issuer := snowflake.NewBlockIssuer(7, snowflake.WithClock(clock))
+
+	first := issuer.Issue(1000)
+	if first.Count != 1000 {
+		t.Errorf("expected %d IDs got %d", 1000, first.Count)
+	}
+
+	// only 3096 IDs are left in this millisecond
+	second := issuer.Issue(4000)
+	if second.Count != 3096 {
+		t.Errorf("expected %d IDs got %d", 3096, second.Count)
+	}
+	if second.FirstID != first.FirstID+1000 {
+		t.Errorf("expected the block to start at %d got %d", first.FirstID+1000, second.FirstID)
+	}
+
+	last := snowflake.Parse(second.FirstID + uint64(second.Count) - 1)
+	if last.Sequence != 4095 || last.MachineID != 7 {
+		t.Errorf("expected the block to end at sequence 4095 of field 7 got %d of field %d", last.Sequence, last.MachineID)
+	}
+
+	clock.Add(time.Millisecond)
+	third := issuer.Issue(0)
+	if third.Count != 1 {
+		t.Errorf("expected at least one ID got %d", third.Count)
+	}
+	if ts := snowflake.Parse(third.FirstID).Timestamp; ts != last.Timestamp+1 {
+		t.Errorf("expected the block to be in the next millisecond %d got %d", last.Timestamp+1, ts)
+	}
+}
+
+func TestBlockConsumer(t *testing.T) {
+	blocks := []snowflake.Block{{FirstID: 100, Count: 2}, {FirstID: 200, Count: 1}}
+	fetchErr := errors.New("issuer unavailable")
+
+	fetches := 0
+	consumer := snowflake.NewBlockConsumer(func() (snowflake.Block, error) {
+		fetches++
+		if len(blocks) == 0 {
+			return snowflake.Block{}, fetchErr
+		}
+		block := blocks[0]
+		blocks = blocks[1:]
+		return block, nil
+	})
+
+	for _, expected := range []uint64{100, 101, 200} {
+		id, err := consumer.NextID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != expected {
+			t.Errorf("expected ID %d got %d", expected, id)
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("expected %d fetches got %d", 2, fetches)
+	}
+
+	if _, err := consumer.NextID(); err != fetchErr {
+		t.Errorf("expected error %v got %v", fetchErr, err)
+	}
+
+	blocks = []snowflake.Block{{FirstID: 300, Count: 5}, {FirstID: 400, Count: 5}, {}}
+	consumer.NextID()
+	consumer.Discard()
+	if id, _ := consumer.NextID(); id != 400 {
+		t.Errorf("expected Discard to move on to the next block got ID %d", id)
+	}
+
+	consumer.Discard()
+	if _, err := consumer.NextID(); err != snowflake.ErrEmptyBlock {
+		t.Errorf("expected error %v got %v", snowflake.ErrEmptyBlock, err)
+	}
+}
+
+func TestBlockConsumer_Concurrent(t *testing.T) {
+	issuer := snowflake.NewBlockIssuer(1)
+
+	var mtx sync.Mutex
+	var issued []snowflake.Block
+	fetch := func() (snowflake.Block, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		block := issuer.Issue(100)
+		issued = append(issued, block)
+		return block, nil
+	}
+
+	consumers := 16
+	perConsumer := 5000
+	ch := make(chan uint64, consumers*perConsumer)
+
+	var wg sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			consumer := snowflake.NewBlockConsumer(fetch)
+			for i := 0; i < perConsumer; i++ {
+				id, err := consumer.NextID()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ch <- id
+				if i%777 == 0 {
+					consumer.Discard() // e.g. the function instance was recycled
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+	close(ch)
+
+	ids := make(map[uint64]bool, consumers*perConsumer)
+	for id := range ch {
+		if ids[id] {
+			t.Fatalf("duplicate ID %d", id)
+		}
+		ids[id] = true
+	}
+
+	for i := 1; i < len(issued); i++ {
+		prevLast := issued[i-1].FirstID + uint64(issued[i-1].Count) - 1
+		if issued[i].FirstID <= prevLast {
+			t.Fatalf("expected block %d to start after %d got %d", i, prevLast, issued[i].FirstID)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package snowflake
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// MinIDForTime returns the smallest ID that can be issued at t, to look up
+// IDs by time: the IDs issued from t on are those at least MinIDForTime(t).
+// Times before the epoch give 0, times after the lifetime of the epoch give
+// the smallest ID of its last millisecond.
+func MinIDForTime(t time.Time) uint64 {
+	elapsed := t.UnixMilli() - atomic.LoadInt64(&epochMillis)
+	switch {
+	case elapsed < 0:
+		return 0
+	case elapsed > maxTimestamp:
+		elapsed = maxTimestamp
+	}
+	return uint64(elapsed) << (sequenceBits + fieldBits)
+}
+
+// DayBoundaries returns the MinIDForTime of each UTC midnight in
+// [start, end), e.g. to delete data one day at a time: the IDs of day i are
+// from boundaries[i] (included) to boundaries[i+1] (excluded). The range is
+// cut to the lifetime of the epoch; it returns nil if nothing is left.
+func DayBoundaries(start, end time.Time) []uint64 {
+	first := atomic.LoadInt64(&epochMillis)
+	if lower := time.UnixMilli(first); start.Before(lower) {
+		start = lower
+	}
+	if upper := time.UnixMilli(first + maxTimestamp + 1); end.After(upper) {
+		end = upper
+	}
+
+	// days are 24 hours in UTC, and the zero time is a UTC midnight
+	midnight := start.UTC().Truncate(day)
+	if midnight.Before(start) {
+		midnight = midnight.Add(day)
+	}
+	if !midnight.Before(end) {
+		return nil
+	}
+
+	boundaries, _ := Boundaries(midnight, end, day)
+	return boundaries
+}
+
+// Boundaries returns the MinIDForTime of start and of every step after it,
+// until end (excluded). Returns an error wrapping ErrInvalidRange if step is
+// less than a millisecond or start isn't before end, ErrBeforeEpoch if start
+// is before the epoch and ErrTimestampOverflow if end is after the lifetime
+// of the epoch.
+func Boundaries(start, end time.Time, step time.Duration) ([]uint64, error) {
+	first := atomic.LoadInt64(&epochMillis)
+	switch {
+	case step < time.Millisecond:
+		return nil, fmt.Errorf("snowflake: step %s is less than a millisecond: %w", step, ErrInvalidRange)
+	case !start.Before(end):
+		return nil, fmt.Errorf("snowflake: start %s isn't before end %s: %w", start, end, ErrInvalidRange)
+	case start.UnixMilli() < first:
+		return nil, fmt.Errorf("snowflake: start %s: %w", start, ErrBeforeEpoch)
+	case end.UnixMilli() > first+maxTimestamp+1:
+		return nil, fmt.Errorf("snowflake: end %s: %w", end, ErrTimestampOverflow)
+	}
+
+	// the lifetime is far shorter than the longest Duration, so is the range
+	n := (end.Sub(start) + step - 1) / step
+	boundaries := make([]uint64, 0, n)
+	for t := start; t.Before(end); t = t.Add(step) {
+		boundaries = append(boundaries, MinIDForTime(t))
+	}
+	return boundaries, nil
+}
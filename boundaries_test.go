@@ -0,0 +1,140 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestMinIDForTime(t *testing.T) {
+	epoch := snowflake.Epoch()
+
+	tc := []struct {
+		name     string
+		t        time.Time
+		expected uint64
+	}{
+		{"Should return 0 at the epoch", epoch, 0},
+		{"Should return 0 before the epoch", epoch.Add(-time.Hour), 0},
+		{"Should truncate to the millisecond", epoch.Add(1500 * time.Microsecond), 1 << 22},
+		{"Should clamp to the lifetime", epoch.Add((1 << 42) * time.Millisecond), (1<<41 - 1) << 22},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if id := snowflake.MinIDForTime(tt.t); id != tt.expected {
+				t.Errorf("expected %d got %d", tt.expected, id)
+			}
+		})
+	}
+}
+
+func TestDayBoundaries(t *testing.T) {
+	midnight := snowflake.Epoch().AddDate(1, 0, 0)
+
+	tc := []struct {
+		name       string
+		start, end time.Time
+		count      int
+	}{
+		{"Should count the days of a month", midnight, midnight.AddDate(0, 1, 0), 31},
+		{"Should start at the next midnight", midnight.Add(time.Hour), midnight.AddDate(0, 0, 3), 2},
+		{"Should include the last midnight before end", midnight, midnight.AddDate(0, 0, 3).Add(time.Millisecond), 4},
+		{"Should be empty within a day", midnight.Add(time.Hour), midnight.Add(23 * time.Hour), 0},
+		{"Should be empty for an inverted range", midnight.AddDate(0, 0, 3), midnight, 0},
+		{"Should cut the range to the epoch", snowflake.Epoch().AddDate(0, 0, -10), snowflake.Epoch().AddDate(0, 0, 10), 10},
+		// 2013-03-28 to 2023-03-28, over 2016 and 2020
+		{"Should cover multiple years", midnight, midnight.AddDate(10, 0, 0), 10*365 + 2},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			boundaries := snowflake.DayBoundaries(tt.start, tt.end)
+			if len(boundaries) != tt.count {
+				t.Fatalf("expected %d boundaries got %d", tt.count, len(boundaries))
+			}
+			for i, b := range boundaries {
+				if at := time.UnixMilli(snowflake.Parse(b).Timestamp).UTC(); !at.Equal(at.Truncate(day)) || at.Before(tt.start) {
+					t.Errorf("expected boundary %d at a midnight after %s got %s", i, tt.start, at)
+				}
+			}
+		})
+	}
+}
+
+func TestDayBoundaries_Days(t *testing.T) {
+	midnight := snowflake.Epoch().AddDate(1, 0, 0)
+	boundaries := snowflake.DayBoundaries(midnight, midnight.AddDate(0, 0, 8))
+
+	// the IDs of day N fall between boundaries N and N+1
+	for n := 0; n < len(boundaries)-1; n++ {
+		for _, offset := range []time.Duration{0, 12 * time.Hour, day - time.Millisecond} {
+			clock := newFakeClock(midnight.AddDate(0, 0, n).Add(offset))
+			id := snowflake.New(1023, snowflake.WithClock(clock)).NextID()
+			if id < boundaries[n] || id >= boundaries[n+1] {
+				t.Errorf("expected an ID of day %d between %d and %d got %d", n, boundaries[n], boundaries[n+1], id)
+			}
+		}
+	}
+}
+
+func TestBoundaries(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+
+	boundaries, err := snowflake.Boundaries(start, start.Add(time.Hour), 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint64{
+		snowflake.MinIDForTime(start),
+		snowflake.MinIDForTime(start.Add(15 * time.Minute)),
+		snowflake.MinIDForTime(start.Add(30 * time.Minute)),
+		snowflake.MinIDForTime(start.Add(45 * time.Minute)),
+	}
+	if len(boundaries) != len(expected) {
+		t.Fatalf("expected %v got %v", expected, boundaries)
+	}
+	for i := range expected {
+		if boundaries[i] != expected[i] {
+			t.Errorf("expected %v got %v", expected, boundaries)
+		}
+	}
+
+	// a range that isn't a whole number of steps ends with a partial one
+	if boundaries, _ := snowflake.Boundaries(start, start.Add(time.Hour+time.Millisecond), 15*time.Minute); len(boundaries) != 5 {
+		t.Errorf("expected 5 boundaries got %d", len(boundaries))
+	}
+
+	// the boundaries of many years take a single allocation
+	end := start.AddDate(20, 0, 0)
+	if allocs := testing.AllocsPerRun(10, func() { snowflake.Boundaries(start, end, time.Hour) }); allocs != 1 {
+		t.Errorf("expected 1 allocation got %v", allocs)
+	}
+}
+
+func TestBoundaries_Errors(t *testing.T) {
+	epoch := snowflake.Epoch()
+
+	tc := []struct {
+		name       string
+		start, end time.Time
+		step       time.Duration
+		expected   error
+	}{
+		{"Should reject a step under a millisecond", epoch, epoch.Add(time.Hour), time.Microsecond, snowflake.ErrInvalidRange},
+		{"Should reject an empty range", epoch.Add(time.Hour), epoch.Add(time.Hour), time.Minute, snowflake.ErrInvalidRange},
+		{"Should reject an inverted range", epoch.Add(time.Hour), epoch, time.Minute, snowflake.ErrInvalidRange},
+		{"Should return ErrBeforeEpoch", epoch.Add(-time.Millisecond), epoch.Add(time.Hour), time.Minute, snowflake.ErrBeforeEpoch},
+		{"Should return ErrTimestampOverflow", epoch, epoch.Add((1<<41 + 1) * time.Millisecond), 24 * time.Hour, snowflake.ErrTimestampOverflow},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := snowflake.Boundaries(tt.start, tt.end, tt.step); !errors.Is(err, tt.expected) {
+				t.Errorf("expected error %v got %v", tt.expected, err)
+			}
+		})
+	}
+}
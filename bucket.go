@@ -0,0 +1,53 @@
+package snowflake
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// day is the length of a UTC day. (internal-use only)
+const day = 24 * time.Hour
+
+// Bucket returns the index of the time bucket of id, e.g. its day number
+// for partitioning by day. Buckets are aligned on the Unix epoch, so on UTC
+// days (and weeks start on Thursdays), and counted from the one holding the
+// epoch: with the default epoch, day 0 is 2012-03-28.
+//
+// Panics if granularity isn't a whole number of milliseconds that evenly
+// divides a day or is a multiple of a day.
+func Bucket(id uint64, granularity time.Duration) int64 {
+	g := checkGranularity(granularity)
+	return floorDiv(getTimestamp(id), g) - floorDiv(atomic.LoadInt64(&epochMillis), g)
+}
+
+// BucketTime returns the start of the time bucket of id, in UTC.
+// See Bucket.
+func BucketTime(id uint64, granularity time.Duration) time.Time {
+	g := checkGranularity(granularity)
+	return time.UnixMilli(floorDiv(getTimestamp(id), g) * g).UTC()
+}
+
+// PartitionKey returns the timestamp of id in UTC formatted with layout,
+// as by time.Format, e.g. "2006-01-02" for daily partitions.
+func PartitionKey(id uint64, layout string) string {
+	return time.UnixMilli(getTimestamp(id)).UTC().Format(layout)
+}
+
+// checkGranularity returns granularity in milliseconds, panicking if it
+// isn't a valid bucket size. (internal-use only)
+func checkGranularity(granularity time.Duration) int64 {
+	if granularity < time.Millisecond || granularity%time.Millisecond != 0 ||
+		(day%granularity != 0 && granularity%day != 0) {
+		panic("snowflake: bucket granularity " + granularity.String() + " neither divides nor is a multiple of a day")
+	}
+	return granularity.Milliseconds()
+}
+
+// floorDiv returns a/b rounded down. (internal-use only)
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
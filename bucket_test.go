@@ -0,0 +1,72 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// day is the length of a UTC day.
+const day = 24 * time.Hour
+
+func TestBucket(t *testing.T) {
+	epoch := snowflake.Epoch()
+	midnight := epoch.AddDate(0, 0, 100)
+	at := func(t time.Time) uint64 { return snowflakeAt(t, epoch) | 5<<12 | 7 }
+
+	tc := []struct {
+		name        string
+		id          uint64
+		granularity time.Duration
+		bucket      int64
+		start       time.Time
+	}{
+		{"Should bucket the epoch", at(epoch), day, 0, epoch},
+		{"Should bucket midnight", at(midnight), day, 100, midnight},
+		{"Should bucket the last millisecond of a day", at(midnight.Add(-time.Millisecond)), day, 99, midnight.AddDate(0, 0, -1)},
+		{"Should bucket hours", at(midnight.Add(90 * time.Minute)), time.Hour, 2401, midnight.Add(time.Hour)},
+		{"Should bucket quarters", at(midnight.Add(15*time.Minute - time.Millisecond)), 15 * time.Minute, 9600, midnight},
+		{"Should bucket milliseconds", at(midnight.Add(3 * time.Millisecond)), time.Millisecond, midnight.Add(3 * time.Millisecond).Sub(epoch).Milliseconds(), midnight.Add(3 * time.Millisecond)},
+		// weeks start on Thursdays, like the Unix epoch
+		{"Should bucket weeks", at(midnight), 7 * day, 15, epoch.AddDate(0, 0, 99)},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if b := snowflake.Bucket(tt.id, tt.granularity); b != tt.bucket {
+				t.Errorf("expected bucket %d got %d", tt.bucket, b)
+			}
+			if start := snowflake.BucketTime(tt.id, tt.granularity); !start.Equal(tt.start) || start.Location() != time.UTC {
+				t.Errorf("expected start %v got %v", tt.start, start)
+			}
+		})
+	}
+}
+
+func TestBucket_InvalidGranularity(t *testing.T) {
+	for _, g := range []time.Duration{0, -time.Hour, time.Microsecond, 1500 * time.Microsecond, 7 * time.Hour, 36 * time.Hour} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected granularity %v to panic", g)
+				}
+			}()
+			snowflake.Bucket(42, g)
+		}()
+	}
+}
+
+func TestPartitionKey(t *testing.T) {
+	midnight := snowflake.Epoch().AddDate(0, 0, 100)
+
+	if key := snowflake.PartitionKey(snowflakeAt(midnight, snowflake.Epoch()), "2006-01-02"); key != midnight.Format("2006-01-02") {
+		t.Errorf("expected %s got %s", midnight.Format("2006-01-02"), key)
+	}
+	if key := snowflake.PartitionKey(snowflakeAt(midnight.Add(-time.Millisecond), snowflake.Epoch()), "2006-01-02"); key != midnight.AddDate(0, 0, -1).Format("2006-01-02") {
+		t.Errorf("expected %s got %s", midnight.AddDate(0, 0, -1).Format("2006-01-02"), key)
+	}
+	if key := snowflake.PartitionKey(snowflakeAt(midnight.Add(13*time.Hour), snowflake.Epoch()), "20060102_15"); key != midnight.Format("20060102")+"_13" {
+		t.Errorf("expected %s_13 got %s", midnight.Format("20060102"), key)
+	}
+}
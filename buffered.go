@@ -0,0 +1,73 @@
+package snowflake
+
+import "sync"
+
+// Buffered is a Generator that pre-generates IDs in the background so bursts
+// can be served from memory.
+//
+// Buffered IDs carry the timestamp of when they were generated, not when they
+// are handed out, so they can be older than the call to NextID by as long as
+// they sat in the buffer. When the buffer is empty NextID falls back to the
+// inner generator, which means IDs are unique but not strictly increasing
+// across calls.
+type Buffered struct {
+	inner     Generator
+	ch        chan uint64
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewBuffered returns a new snowflake.Buffered that keeps up to size IDs
+// from inner ready. A size less than 1 is treated as 1.
+// Call Close to stop the background goroutine.
+func NewBuffered(inner Generator, size int) *Buffered {
+	if size < 1 {
+		size = 1
+	}
+
+	b := &Buffered{
+		inner: inner,
+		ch:    make(chan uint64, size),
+		done:  make(chan struct{}),
+	}
+
+	b.wg.Add(1)
+	go b.fill()
+
+	return b
+}
+
+// NextID returns a buffered snowflake ID, or a fresh one from the inner
+// generator when the buffer is empty.
+func (b *Buffered) NextID() uint64 {
+	select {
+	case id := <-b.ch:
+		return id
+	default:
+		return b.inner.NextID()
+	}
+}
+
+// Close stops the background goroutine and waits for it to exit.
+// IDs left in the buffer are still handed out by NextID after Close.
+func (b *Buffered) Close() {
+	b.closeOnce.Do(func() {
+		close(b.done)
+		b.wg.Wait()
+	})
+}
+
+// fill keeps the buffer topped up until Close is called. (internal-use only)
+func (b *Buffered) fill() {
+	defer b.wg.Done()
+
+	for {
+		id := b.inner.NextID()
+		select {
+		case b.ch <- id:
+		case <-b.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package snowflake_test
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// slowGenerator wraps a generator and sleeps before every ID.
+type slowGenerator struct {
+	inner snowflake.Generator
+	delay time.Duration
+}
+
+func (g slowGenerator) NextID() uint64 {
+	time.Sleep(g.delay)
+	return g.inner.NextID()
+}
+
+func TestBuffered_Unique(t *testing.T) {
+	n := 100000
+	b := snowflake.NewBuffered(snowflake.New(1), 1024)
+	defer b.Close()
+
+	ids := make(map[uint64]bool)
+	for i := 0; i < n; i++ {
+		id := b.NextID()
+		if _, exists := ids[id]; exists {
+			t.Errorf("expected to be unique, but got a repeated ID (%d)", id)
+			break
+		}
+
+		ids[id] = true
+	}
+}
+
+func TestBuffered_DrainFasterThanRefill(t *testing.T) {
+	n := 1000
+	b := snowflake.NewBuffered(slowGenerator{snowflake.New(1), time.Millisecond}, 8)
+	defer b.Close()
+
+	ch := make(chan uint64, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ch <- b.NextID()
+		}()
+	}
+	wg.Wait()
+	close(ch)
+
+	ids := make(map[uint64]bool)
+	for id := range ch {
+		if _, ok := ids[id]; ok {
+			t.Error("expected to be unique, but got a repeated ID")
+			break
+		}
+		ids[id] = true
+	}
+	if len(ids) != n {
+		t.Errorf("expected map length %d got %d", n, len(ids))
+	}
+}
+
+func TestBuffered_Close(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	b := snowflake.NewBuffered(snowflake.New(1), 4096)
+	for i := 0; i < 100; i++ {
+		b.NextID()
+	}
+	b.Close()
+	b.Close() // should be safe to call twice
+
+	// NextID keeps working after Close
+	if b.NextID() == 0 {
+		t.Error("expected a non-zero ID after Close")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("expected %d goroutines after Close got %d", before, after)
+	}
+}
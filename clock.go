@@ -0,0 +1,97 @@
+package snowflake
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Clock is a source of the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// preciseClock is implemented by clocks that serve a cached time and can
+// read the real one on demand. Generators use it while waiting for the next
+// millisecond. (internal-use only)
+type preciseClock interface {
+	Clock
+	precise() time.Time
+}
+
+// defaultCoarseInterval is how often a CoarseClock refreshes by default.
+const defaultCoarseInterval = 200 * time.Microsecond
+
+// CoarseClock is a Clock that refreshes a cached time from a background
+// goroutine, so reading it is a single atomic load.
+//
+// The cached time lags the real one by up to the refresh interval (more if
+// the refresher goroutine is starved). Generators never issue IDs going
+// backwards because of that lag, and they read the real time when they
+// wait for the next millisecond. After Close, Now reads the real time.
+//
+//	clock := snowflake.NewCoarseClock(0)
+//	defer clock.Close()
+//	sf := snowflake.New(1, snowflake.WithClock(clock))
+type CoarseClock struct {
+	nanos     int64 // keep first for 64-bit alignment of atomic operations
+	closed    int32
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewCoarseClock returns a new snowflake.CoarseClock refreshing every
+// interval. An interval of 0 or less defaults to 200µs.
+func NewCoarseClock(interval time.Duration) *CoarseClock {
+	if interval <= 0 {
+		interval = defaultCoarseInterval
+	}
+
+	c := &CoarseClock{
+		nanos: time.Now().UnixNano(),
+		done:  make(chan struct{}),
+	}
+
+	c.wg.Add(1)
+	go c.refresh(interval)
+
+	return c
+}
+
+// Now returns the cached time.
+func (c *CoarseClock) Now() time.Time {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return time.Now()
+	}
+	return time.Unix(0, atomic.LoadInt64(&c.nanos))
+}
+
+// Close stops the background goroutine and waits for it to exit.
+func (c *CoarseClock) Close() {
+	c.closeOnce.Do(func() {
+		atomic.StoreInt32(&c.closed, 1)
+		close(c.done)
+		c.wg.Wait()
+	})
+}
+
+// precise returns the real time. (internal-use only)
+func (c *CoarseClock) precise() time.Time { return time.Now() }
+
+// refresh updates the cached time until Close is called. (internal-use only)
+func (c *CoarseClock) refresh(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			atomic.StoreInt64(&c.nanos, now.UnixNano())
+		case <-c.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package snowflake_test
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// fakeClock is a Clock that only moves when told to.
+type fakeClock struct {
+	mtx sync.Mutex
+	t   time.Time
+}
+
+func newFakeClock(t time.Time) *fakeClock { return &fakeClock{t: t} }
+
+func (c *fakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.t = t
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func TestWithClock(t *testing.T) {
+	now := snowflake.Epoch().Add(time.Hour)
+	sf := snowflake.New(1, snowflake.WithClock(newFakeClock(now)))
+
+	sid := snowflake.Parse(sf.NextID())
+	if sid.Timestamp != now.UnixMilli() {
+		t.Errorf("expected timestamp %d got %d", now.UnixMilli(), sid.Timestamp)
+	}
+}
+
+func TestNextID_ClockLagging(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start.Add(5 * time.Millisecond))
+	sf := snowflake.New(1, snowflake.WithClock(clock))
+
+	var ids []uint64
+	for i := 0; i < 3; i++ {
+		ids = append(ids, sf.NextID())
+	}
+
+	clock.Set(start) // the clock lags behind the last issued millisecond
+	for i := 0; i < 3; i++ {
+		ids = append(ids, sf.NextID())
+	}
+
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Errorf("expected to be increasing, but got %d after %d", ids[i], ids[i-1])
+		}
+	}
+
+	last := snowflake.Parse(ids[len(ids)-1])
+	if expected := start.Add(5 * time.Millisecond).UnixMilli(); last.Timestamp != expected {
+		t.Errorf("expected timestamp %d got %d", expected, last.Timestamp)
+	}
+	if last.Sequence != 5 {
+		t.Errorf("expected sequence 5 got %d", last.Sequence)
+	}
+}
+
+func TestCoarseClock(t *testing.T) {
+	clock := snowflake.NewCoarseClock(0)
+	defer clock.Close()
+
+	n := 100000
+	sf := snowflake.New(1, snowflake.WithClock(clock))
+	ids := make(map[uint64]bool)
+	var last uint64
+	for i := 0; i < n; i++ {
+		id := sf.NextID()
+		if _, exists := ids[id]; exists {
+			t.Errorf("expected to be unique, but got a repeated ID (%d)", id)
+			break
+		}
+		if id < last {
+			t.Errorf("expected to be increasing, but got %d after %d", id, last)
+			break
+		}
+
+		ids[id] = true
+		last = id
+	}
+
+	if d := time.Since(clock.Now()); d < 0 || d > 100*time.Millisecond {
+		t.Errorf("expected the cached time to be close to now, off by %s", d)
+	}
+}
+
+func TestCoarseClock_Close(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	clock := snowflake.NewCoarseClock(time.Millisecond)
+	clock.Close()
+	clock.Close() // should be safe to call twice
+
+	if d := time.Since(clock.Now()); d < 0 || d > time.Second {
+		t.Errorf("expected Now to read the real time after Close, off by %s", d)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("expected %d goroutines after Close got %d", before, after)
+	}
+}
+
+// countingClock counts how many times the time was read.
+type countingClock struct {
+	Clock snowflake.Clock
+	reads int64
+}
+
+func (c *countingClock) Now() time.Time {
+	atomic.AddInt64(&c.reads, 1)
+	return c.Clock.Now()
+}
+
+// systemClock reads the real time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func TestWithClockCheckEvery(t *testing.T) {
+	clock := &countingClock{Clock: newFakeClock(snowflake.Epoch().Add(time.Hour))}
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithClockCheckEvery(64))
+
+	for i := 0; i < 640; i++ {
+		sf.NextID()
+	}
+
+	if reads := atomic.LoadInt64(&clock.reads); reads != 10 {
+		t.Errorf("expected %d clock reads got %d", 10, reads)
+	}
+}
+
+func TestWithClockCheckEvery_Drift(t *testing.T) {
+	fake := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(1, snowflake.WithClock(fake), snowflake.WithClockCheckEvery(64))
+
+	sf.NextID()
+	fake.Add(time.Second) // the clock jumps forward
+
+	caughtUp := false
+	for i := 0; i < 64; i++ {
+		sid := snowflake.Parse(sf.NextID())
+		if sid.Timestamp > fake.Now().UnixMilli() {
+			t.Fatalf("expected timestamp to never lead the clock, got %d ahead of %d",
+				sid.Timestamp, fake.Now().UnixMilli())
+		}
+		if sid.Timestamp == fake.Now().UnixMilli() {
+			caughtUp = true
+		}
+	}
+
+	if !caughtUp {
+		t.Error("expected the timestamp to catch up with the clock within 64 IDs")
+	}
+}
+
+func TestWithClockCheckEvery_Exhaustion(t *testing.T) {
+	n := 20000
+	sf := snowflake.New(1, snowflake.WithClockCheckEvery(64))
+
+	var last uint64
+	for i := 0; i < n; i++ {
+		id := sf.NextID()
+		if id <= last {
+			t.Fatalf("expected to be increasing, but got %d after %d", id, last)
+		}
+		last = id
+	}
+}
@@ -0,0 +1,199 @@
+package snowflake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultAWSMetadataURL is the link-local EC2 instance metadata service.
+	defaultAWSMetadataURL = "http://169.254.169.254"
+	// defaultGCPMetadataURL is the GCE and Cloud Run metadata server.
+	defaultGCPMetadataURL = "http://metadata.google.internal"
+	// defaultMetadataTimeout bounds metadata queries when the context has no
+	// deadline. The metadata services answer within milliseconds, while
+	// outside the cloud their link-local addresses just don't respond.
+	defaultMetadataTimeout = 2 * time.Second
+	// maxMetadataSize bounds metadata responses read into memory.
+	maxMetadataSize = 1 << 20
+)
+
+// MachineIDFromAWS derives a machine ID by hashing (64-bit FNV-1a) the
+// identity of the instance: the task ARN on ECS, or the EC2 instance ID
+// fetched through IMDSv2 otherwise. Like MachineIDFromMAC, different
+// instances can hash to the same machine ID.
+// If ctx has no deadline, the queries time out after 2 seconds.
+// Returns an error wrapping ErrNotOnAWS when the metadata can't be reached.
+func MachineIDFromAWS(ctx context.Context) (uint64, error) { return Host{}.MachineIDFromAWS(ctx) }
+
+// MachineIDFromAWS is like the package-level MachineIDFromAWS but queries
+// the metadata services of h.
+func (h Host) MachineIDFromAWS(ctx context.Context) (uint64, error) {
+	ctx, cancel := metadataContext(ctx)
+	defer cancel()
+
+	if ecs := h.ecsMetadataURL(); ecs != "" {
+		body, err := h.metadata(ctx, http.MethodGet, ecs+"/task", nil)
+		if err != nil {
+			return 0, fmt.Errorf("snowflake: ECS task metadata: %w: %v", ErrNotOnAWS, err)
+		}
+
+		var task struct{ TaskARN string }
+		if err := json.Unmarshal(body, &task); err != nil || task.TaskARN == "" {
+			return 0, fmt.Errorf("snowflake: ECS task metadata has no TaskARN: %w", ErrNotOnAWS)
+		}
+		return hashField([]byte(task.TaskARN)), nil
+	}
+
+	imds := h.AWSMetadataURL
+	if imds == "" {
+		imds = defaultAWSMetadataURL
+	}
+
+	token, err := h.metadata(ctx, http.MethodPut, imds+"/latest/api/token", http.Header{
+		"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"60"},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("snowflake: IMDSv2 token: %w: %v", ErrNotOnAWS, err)
+	}
+
+	id, err := h.metadata(ctx, http.MethodGet, imds+"/latest/meta-data/instance-id", http.Header{
+		"X-Aws-Ec2-Metadata-Token": {string(token)},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("snowflake: EC2 instance ID: %w: %v", ErrNotOnAWS, err)
+	}
+
+	return hashField(id), nil
+}
+
+// MachineIDFromGCP derives a machine ID by hashing (64-bit FNV-1a) the
+// instance ID served by the GCE or Cloud Run metadata server.
+//
+// Hashing into 1024 slots collides often: any two instances share a machine
+// ID with ~0.1% probability, but 38 instances already have even odds of at
+// least one collision. Callers that would rather fail than risk that can
+// use GCPInstanceID and map the identity to a field externally.
+// If ctx has no deadline, the query times out after 2 seconds.
+// Returns an error wrapping ErrNotOnGCP when the metadata can't be reached.
+func MachineIDFromGCP(ctx context.Context) (uint64, error) { return Host{}.MachineIDFromGCP(ctx) }
+
+// MachineIDFromGCP is like the package-level MachineIDFromGCP but queries
+// the metadata server of h.
+func (h Host) MachineIDFromGCP(ctx context.Context) (uint64, error) {
+	id, err := h.gcpInstanceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return hashField(id), nil
+}
+
+// GCPInstanceID returns the unhashed 64-bit ID of the GCE instance, for
+// deduplicating machine IDs externally, e.g. by registering it with an
+// allocator. Fails with an error wrapping ErrMachineIDNotNumeric when the
+// instance ID isn't a 64-bit number, as on Cloud Run, rather than hashing
+// it. Returns an error wrapping ErrNotOnGCP when the metadata can't be
+// reached.
+func GCPInstanceID(ctx context.Context) (uint64, error) { return Host{}.GCPInstanceID(ctx) }
+
+// GCPInstanceID is like the package-level GCPInstanceID but queries the
+// metadata server of h.
+func (h Host) GCPInstanceID(ctx context.Context) (uint64, error) {
+	id, err := h.gcpInstanceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := strconv.ParseUint(string(id), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("snowflake: GCP instance ID %q: %w", id, ErrMachineIDNotNumeric)
+	}
+	return n, nil
+}
+
+// gcpInstanceID fetches the instance ID from the metadata server of h.
+// (internal-use only)
+func (h Host) gcpInstanceID(ctx context.Context) ([]byte, error) {
+	ctx, cancel := metadataContext(ctx)
+	defer cancel()
+
+	url := h.GCPMetadataURL
+	if url == "" {
+		url = defaultGCPMetadataURL
+	}
+
+	id, err := h.metadata(ctx, http.MethodGet, url+"/computeMetadata/v1/instance/id", http.Header{
+		"Metadata-Flavor": {"Google"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("snowflake: GCP instance ID: %w: %v", ErrNotOnGCP, err)
+	}
+	return id, nil
+}
+
+// metadataContext bounds ctx by defaultMetadataTimeout unless it already
+// has a deadline. (internal-use only)
+func metadataContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, defaultMetadataTimeout)
+}
+
+// ecsMetadataURL returns the ECS task metadata endpoint of h, or "" when
+// not running on ECS. (internal-use only)
+func (h Host) ecsMetadataURL() string {
+	if h.ECSMetadataURL != "" {
+		return h.ECSMetadataURL
+	}
+	return os.Getenv("ECS_CONTAINER_METADATA_URI_V4")
+}
+
+// metadata requests url from a metadata service and returns the trimmed
+// response body. A Metadata-Flavor request header must be echoed by the
+// response, which tells a real metadata server apart from whatever else
+// answers on its name. (internal-use only)
+func (h Host) metadata(ctx context.Context, method, url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	client := h.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s", method, url, resp.Status)
+	}
+	if flavor := header.Get("Metadata-Flavor"); flavor != "" && resp.Header.Get("Metadata-Flavor") != flavor {
+		return nil, fmt.Errorf("%s %s: not a %s metadata server", method, url, flavor)
+	}
+
+	body = []byte(strings.TrimSpace(string(body)))
+	if len(body) == 0 {
+		return nil, fmt.Errorf("%s %s: empty response", method, url)
+	}
+	return body, nil
+}
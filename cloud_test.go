@@ -0,0 +1,246 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// imds serves recorded EC2 instance metadata, enforcing IMDSv2 tokens.
+func imds(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			if r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				http.Error(w, "", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("AQAEAFTNrA4eEGx0AQgJ1arIq_Cc-t4tWt3fB0Hd8RKhXlKc5ccvhg=="))
+		case r.Method == http.MethodGet && r.URL.Path == "/latest/meta-data/instance-id":
+			if r.Header.Get("X-aws-ec2-metadata-token") != "AQAEAFTNrA4eEGx0AQgJ1arIq_Cc-t4tWt3fB0Hd8RKhXlKc5ccvhg==" {
+				http.Error(w, "", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("i-0abcd1234ef567890"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestMachineIDFromAWS(t *testing.T) {
+	srv := imds(t)
+
+	field, err := snowflake.Host{
+		HTTPClient:     srv.Client(),
+		AWSMetadataURL: srv.URL,
+	}.MachineIDFromAWS(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := snowflake.Host{Hostname: hostname("i-0abcd1234ef567890")}.MachineIDFromHostname()
+	if field != expected {
+		t.Errorf("expected machine ID %d (hash of the instance ID) got %d", expected, field)
+	}
+}
+
+func TestMachineIDFromAWS_ECS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v4/abc123/task" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+			"Cluster": "arn:aws:ecs:us-west-2:111122223333:cluster/default",
+			"TaskARN": "arn:aws:ecs:us-west-2:111122223333:task/default/158d1c8083dd49d6b527399fd6414f5c",
+			"Family": "curltest",
+			"Revision": "26"
+		}`))
+	}))
+	defer srv.Close()
+
+	field, err := snowflake.Host{
+		HTTPClient:     srv.Client(),
+		ECSMetadataURL: srv.URL + "/v4/abc123",
+	}.MachineIDFromAWS(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := snowflake.Host{
+		Hostname: hostname("arn:aws:ecs:us-west-2:111122223333:task/default/158d1c8083dd49d6b527399fd6414f5c"),
+	}.MachineIDFromHostname()
+	if field != expected {
+		t.Errorf("expected machine ID %d (hash of the task ARN) got %d", expected, field)
+	}
+}
+
+func TestMachineIDFromAWS_NotOnAWS(t *testing.T) {
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	tests := []struct {
+		name string
+		host snowflake.Host
+		ctx  func() (context.Context, context.CancelFunc)
+	}{
+		{
+			name: "Should return ErrNotOnAWS when the metadata service is unreachable",
+			host: snowflake.Host{AWSMetadataURL: unreachable.URL},
+		},
+		{
+			name: "Should return ErrNotOnAWS when there is no instance metadata",
+			host: snowflake.Host{AWSMetadataURL: imds(t).URL + "/elsewhere"},
+		},
+		{
+			name: "Should return ErrNotOnAWS when the metadata service times out",
+			host: snowflake.Host{AWSMetadataURL: slow.URL},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+		},
+		{
+			name: "Should return ErrNotOnAWS when the ECS task metadata is unreachable",
+			host: snowflake.Host{ECSMetadataURL: unreachable.URL},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tc.ctx != nil {
+				ctx, cancel = tc.ctx()
+			}
+			defer cancel()
+
+			_, err := tc.host.MachineIDFromAWS(ctx)
+			if !errors.Is(err, snowflake.ErrNotOnAWS) {
+				t.Errorf("expected error %v got %v", snowflake.ErrNotOnAWS, err)
+			}
+		})
+	}
+}
+
+// gce serves a recorded GCE or Cloud Run instance ID, enforcing the
+// Metadata-Flavor header.
+func gce(t *testing.T, id string) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/computeMetadata/v1/instance/id" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "Missing required header: Metadata-Flavor", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Metadata-Flavor", "Google")
+		w.Write([]byte(id))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestMachineIDFromGCP(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{name: "GCE", id: "4520031799277581759"},
+		{name: "Cloud Run", id: "00bf4bf02d1f6bd9d9c4a5e1c6a5d3e1f8f84a2d8f9e6b5c2c4f1e0d3b5a7c9e1f2d4b6a8c0e"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := gce(t, tc.id)
+
+			field, err := snowflake.Host{
+				HTTPClient:     srv.Client(),
+				GCPMetadataURL: srv.URL,
+			}.MachineIDFromGCP(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected, _ := snowflake.Host{Hostname: hostname(tc.id)}.MachineIDFromHostname()
+			if field != expected {
+				t.Errorf("expected machine ID %d (hash of the instance ID) got %d", expected, field)
+			}
+		})
+	}
+}
+
+func TestGCPInstanceID(t *testing.T) {
+	id, err := snowflake.Host{GCPMetadataURL: gce(t, "4520031799277581759").URL}.GCPInstanceID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 4520031799277581759 {
+		t.Errorf("expected instance ID %d got %d", uint64(4520031799277581759), id)
+	}
+
+	_, err = snowflake.Host{GCPMetadataURL: gce(t, "00bf4bf02d1f6bd9d9c4a5e1").URL}.GCPInstanceID(context.Background())
+	if !errors.Is(err, snowflake.ErrMachineIDNotNumeric) {
+		t.Errorf("expected error %v got %v", snowflake.ErrMachineIDNotNumeric, err)
+	}
+}
+
+func TestMachineIDFromGCP_NotOnGCP(t *testing.T) {
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	// something answering on metadata.google.internal that isn't the
+	// metadata server
+	impostor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("4520031799277581759"))
+	}))
+	defer impostor.Close()
+
+	tests := []struct {
+		name string
+		host snowflake.Host
+	}{
+		{
+			name: "Should return ErrNotOnGCP when the metadata server is unreachable",
+			host: snowflake.Host{GCPMetadataURL: unreachable.URL},
+		},
+		{
+			name: "Should return ErrNotOnGCP when the response lacks Metadata-Flavor",
+			host: snowflake.Host{GCPMetadataURL: impostor.URL},
+		},
+		{
+			name: "Should return ErrNotOnGCP when there is no instance ID",
+			host: snowflake.Host{GCPMetadataURL: gce(t, "").URL},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.host.MachineIDFromGCP(context.Background())
+			if !errors.Is(err, snowflake.ErrNotOnGCP) {
+				t.Errorf("expected error %v got %v", snowflake.ErrNotOnGCP, err)
+			}
+		})
+	}
+}
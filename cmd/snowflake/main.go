@@ -0,0 +1,186 @@
+// Command snowflake mints snowflake IDs from the shell, e.g. to create
+// fixture IDs or sanity-check a configuration.
+//
+//	snowflake generate -n 100 -machine-id 3 -epoch 2015-01-01
+//	snowflake generate -fields 1,24 -format base58
+//	snowflake parse -format json < ids.txt
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+const usage = `usage: snowflake <command> [flags]
+
+commands:
+  generate   print new snowflake IDs, one per line
+  parse      print the timestamp, field(s) and sequence of IDs
+
+Run 'snowflake <command> -h' for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line args and returns the exit code: 0 on
+// success, 1 when the command fails and 2 on usage errors.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "generate":
+		err = generate(args[1:], stdout, stderr)
+	case "parse":
+		err = parse(args[1:], stdin, stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "snowflake: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+}
+
+// errUsage is returned for flag errors, which the flag package has
+// already reported.
+var errUsage = errors.New("usage error")
+
+// generate implements 'snowflake generate'.
+func generate(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	n := fs.Int("n", 1, "number of IDs to print")
+	machineID := fs.Uint64("machine-id", 0, "machine ID (field) of the IDs, 0 to 1023")
+	fields := fs.String("fields", "", "use the two-field layout with `field1,field2`, 0 to 31 each")
+	epoch := fs.String("epoch", "", "custom epoch, as YYYY-MM-DD or RFC3339 (default 2012-03-28)")
+	format := fs.String("format", "decimal", "output `encoding`: decimal, hex, base58 or padded")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		fs.Usage()
+		return errUsage
+	}
+
+	if *n < 1 {
+		return fmt.Errorf("snowflake: -n must be at least 1, got %d", *n)
+	}
+
+	enc, err := snowflake.ParseEncoding(*format)
+	if err != nil {
+		return err
+	}
+
+	if *epoch != "" {
+		t, err := parseEpoch(*epoch)
+		if err != nil {
+			return err
+		}
+		if err := snowflake.SetEpoch(t); err != nil {
+			return fmt.Errorf("snowflake: -epoch %s: %w", *epoch, err)
+		}
+	}
+
+	var sf snowflake.Generator
+	if *fields != "" {
+		if isSet(fs, "machine-id") {
+			return errors.New("snowflake: -machine-id and -fields are mutually exclusive")
+		}
+
+		field1, field2, err := parseFields(*fields)
+		if err != nil {
+			return err
+		}
+
+		// NewDatacenterWorker validates New2's fields: field2 is the upper one
+		id2, err := snowflake.NewDatacenterWorker(field2, field1)
+		if err != nil {
+			return fmt.Errorf("snowflake: -fields %s: %w", *fields, err)
+		}
+		sf = id2
+	} else {
+		// New resets a field out of range to 0 rather than fail
+		if *machineID > snowflake.MaxField() {
+			return fmt.Errorf("snowflake: -machine-id %d: %w", *machineID, snowflake.ErrFieldOutOfRange)
+		}
+		sf = snowflake.New(*machineID)
+	}
+
+	w := bufio.NewWriter(stdout)
+	buf := make([]byte, 0, 21)
+	for i := 0; i < *n; i++ {
+		buf = append(enc.Append(buf[:0], sf.NextID()), '\n')
+		w.Write(buf)
+	}
+	return w.Flush()
+}
+
+// parseEpoch parses a date or an RFC3339 time.
+func parseEpoch(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("snowflake: -epoch %s: expected YYYY-MM-DD or RFC3339", s)
+	}
+	return t, nil
+}
+
+// parseFields parses the two comma separated fields of -fields.
+func parseFields(s string) (uint64, uint64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("snowflake: -fields %s: expected field1,field2", s)
+	}
+
+	field1, err1 := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 64)
+	field2, err2 := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("snowflake: -fields %s: %w", s, snowflake.ErrMachineIDNotNumeric)
+	}
+	return field1, field2, nil
+}
+
+// isSet reports whether the flag name was given on the command line.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestGenerate(t *testing.T) {
+	defer snowflake.SetEpoch(snowflake.Epoch())
+
+	tests := []struct {
+		name   string
+		args   []string
+		n      int
+		enc    snowflake.Encoding
+		field  uint64
+		fields [2]uint64
+		two    bool
+	}{
+		{
+			name: "Should print one decimal ID by default",
+			n:    1,
+			enc:  snowflake.Decimal,
+		},
+		{
+			name:  "Should print n IDs of the machine ID",
+			args:  []string{"-n", "100", "-machine-id", "3", "-epoch", "2015-01-01"},
+			n:     100,
+			enc:   snowflake.Decimal,
+			field: 3,
+		},
+		{
+			name:  "Should print IDs in the requested format",
+			args:  []string{"-n", "5", "-machine-id", "1023", "-format", "base58"},
+			n:     5,
+			enc:   snowflake.Base58,
+			field: 1023,
+		},
+		{
+			name:   "Should use the two-field layout with -fields",
+			args:   []string{"-n", "10", "-fields", "1,24", "-format", "hex"},
+			n:      10,
+			enc:    snowflake.Hex,
+			fields: [2]uint64{1, 24},
+			two:    true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if err := generate(tc.args, &stdout, &stderr); err != nil {
+				t.Fatal(err)
+			}
+
+			lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+			if len(lines) != tc.n {
+				t.Fatalf("expected %d IDs got %d", tc.n, len(lines))
+			}
+
+			var prev uint64
+			for _, line := range lines {
+				id, err := tc.enc.Parse(line)
+				if err != nil {
+					t.Fatalf("expected a %s ID got %q: %v", tc.enc, line, err)
+				}
+				if id <= prev {
+					t.Errorf("expected increasing IDs got %d after %d", id, prev)
+				}
+				prev = id
+
+				if tc.two {
+					sid := snowflake.Parse2(id)
+					if sid.Field1 != tc.fields[0] || sid.Field2 != tc.fields[1] {
+						t.Errorf("expected fields %d,%d got %d,%d", tc.fields[0], tc.fields[1], sid.Field1, sid.Field2)
+					}
+				} else if sid := snowflake.Parse(id); sid.MachineID != tc.field {
+					t.Errorf("expected field %d got %d", tc.field, sid.MachineID)
+				}
+			}
+		})
+	}
+
+	expected := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	if snowflake.Epoch() != expected {
+		t.Errorf("expected epoch %v got %v", expected, snowflake.Epoch())
+	}
+}
+
+func TestGenerate_Registry(t *testing.T) {
+	// generate leaves the process registry alone
+	held, err := snowflake.NewRegistered(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Release()
+
+	var stdout, stderr bytes.Buffer
+	if err := generate([]string{"-n", "1", "-machine-id", "3"}, &stdout, &stderr); err != nil {
+		t.Errorf("expected no error for a registered machine ID got %v", err)
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	defer snowflake.SetEpoch(snowflake.Epoch())
+
+	tests := []struct {
+		name string
+		args []string
+		err  error
+	}{
+		{
+			name: "Should return ErrFieldOutOfRange",
+			args: []string{"-machine-id", "1024"},
+			err:  snowflake.ErrFieldOutOfRange,
+		},
+		{
+			name: "Should return ErrFieldOutOfRange for -fields",
+			args: []string{"-fields", "1,32"},
+			err:  snowflake.ErrFieldOutOfRange,
+		},
+		{
+			name: "Should return ErrMachineIDNotNumeric for -fields",
+			args: []string{"-fields", "1,a"},
+			err:  snowflake.ErrMachineIDNotNumeric,
+		},
+		{
+			name: "Should return ErrUnknownEncoding",
+			args: []string{"-format", "base64"},
+			err:  snowflake.ErrUnknownEncoding,
+		},
+		{
+			name: "Should return ErrEpochFuture",
+			args: []string{"-epoch", "2999-01-01"},
+			err:  snowflake.ErrEpochFuture,
+		},
+		{
+			name: "Should return errUsage for unknown flags",
+			args: []string{"-count", "3"},
+			err:  errUsage,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := generate(tc.args, &stdout, &stderr)
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("expected no output got %q", stdout.String())
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+	}{
+		{name: "generate", args: []string{"generate", "-n", "2"}, code: 0},
+		{name: "parse", args: []string{"parse"}, stdin: "1292053924173320192\n", code: 0},
+		{name: "parse malformed", args: []string{"parse", "12x"}, code: 1},
+		{name: "no command", args: nil, code: 2},
+		{name: "unknown command", args: []string{"mint"}, code: 2},
+		{name: "bad flag value", args: []string{"generate", "-machine-id", "4096"}, code: 1},
+		{name: "help", args: []string{"generate", "-h"}, code: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr); code != tc.code {
+				t.Errorf("expected exit code %d got %d (stderr: %s)", tc.code, code, stderr.String())
+			}
+		})
+	}
+}
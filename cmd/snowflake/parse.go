@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// parse implements 'snowflake parse'.
+func parse(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	epoch := fs.String("epoch", "", "custom epoch, as YYYY-MM-DD or RFC3339 (default 2012-03-28)")
+	layout := fs.String("layout", "single", "field `layout`: single (one 10-bit field) or double (two 5-bit fields)")
+	format := fs.String("format", "table", "output `format`: table, csv or json (one object per line)")
+	encoding := fs.String("encoding", "decimal", "input `encoding`: decimal, hex, base58 or padded")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), "usage: snowflake parse [flags] [id ...]\n\n"+
+			"Prints the timestamp, field(s) and sequence of the IDs given as arguments,\n"+
+			"or of the newline-delimited IDs read from stdin.\n\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return errUsage
+	}
+
+	if *layout != "single" && *layout != "double" {
+		return fmt.Errorf("snowflake: -layout %s: expected single or double", *layout)
+	}
+	w, err := newRowWriter(*format, stdout, *layout == "double")
+	if err != nil {
+		return err
+	}
+	enc, err := snowflake.ParseEncoding(*encoding)
+	if err != nil {
+		return err
+	}
+	if *epoch != "" {
+		t, err := parseEpoch(*epoch)
+		if err != nil {
+			return err
+		}
+		if err := snowflake.SetEpoch(t); err != nil {
+			return fmt.Errorf("snowflake: -epoch %s: %w", *epoch, err)
+		}
+	}
+
+	malformed := 0
+	line := func(where, s string) {
+		id, err := parseID(enc, s)
+		if err != nil {
+			malformed++
+			fmt.Fprintf(stderr, "%s: %v\n", where, err)
+			return
+		}
+		w.write(id)
+	}
+
+	if fs.NArg() > 0 {
+		for i, arg := range fs.Args() {
+			line("argument "+strconv.Itoa(i+1), arg)
+		}
+	} else {
+		err := readLines(stdin, func(n int, b []byte, tooLong bool) {
+			where := "line " + strconv.Itoa(n)
+			switch s := strings.TrimSpace(string(b)); {
+			case tooLong:
+				// like snowflake.ParseReader, without stopping there
+				malformed++
+				fmt.Fprintf(stderr, "%s: snowflake: line is too long: %v\n", where, snowflake.ErrInvalidID)
+			case s != "":
+				line(where, s)
+			}
+		})
+		if err != nil {
+			w.flush()
+			return fmt.Errorf("snowflake: reading stdin: %w", err)
+		}
+	}
+
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if malformed > 0 {
+		return fmt.Errorf("snowflake: %d malformed IDs", malformed)
+	}
+	return nil
+}
+
+// parseID decodes s with enc, rejecting IDs above 2^63-1, whose timestamp
+// would overflow, and decimal IDs with leading zeros, as
+// snowflake.ParseString does.
+func parseID(enc snowflake.Encoding, s string) (uint64, error) {
+	if enc == snowflake.Decimal {
+		if _, err := snowflake.ParseString(s); err != nil {
+			return 0, err
+		}
+	}
+	id, err := enc.Parse(s)
+	if err == nil && id > math.MaxInt64 {
+		return 0, fmt.Errorf("snowflake: %q overflows the timestamp: %w", s, snowflake.ErrInvalidID)
+	}
+	return id, err
+}
+
+// readLines calls fn with every line of r, numbered from 1, without its
+// line ending. A line longer than the buffer of bufio.Reader is skipped
+// rather than buffered whole, and passed as nil with tooLong set, so that
+// one bad line doesn't stop a dump of any size.
+func readLines(r io.Reader, fn func(n int, b []byte, tooLong bool)) error {
+	br := bufio.NewReader(r)
+	for n := 1; ; n++ {
+		b, err := br.ReadSlice('\n')
+		tooLong := err == bufio.ErrBufferFull
+		for err == bufio.ErrBufferFull {
+			_, err = br.ReadSlice('\n')
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(b) == 0 && err == io.EOF {
+			return nil
+		}
+
+		if tooLong {
+			b = nil // overwritten by the rest of the line
+		} else {
+			b = bytes.TrimSuffix(b, []byte("\n"))
+		}
+		fn(n, b, tooLong)
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// row is a parsed ID as printed by 'snowflake parse'.
+type row struct {
+	ID        string  `json:"id"` // a string, so that JSON tools don't round it
+	Fallback  bool    `json:"fallback,omitempty"`
+	Timestamp string  `json:"timestamp,omitempty"`
+	Field     *uint64 `json:"field,omitempty"`
+	Field1    *uint64 `json:"field1,omitempty"`
+	Field2    *uint64 `json:"field2,omitempty"`
+	Sequence  *uint64 `json:"sequence,omitempty"`
+}
+
+// rowWriter prints parsed IDs in one of the formats of 'snowflake parse'.
+type rowWriter struct {
+	double bool
+	write  func(id uint64)
+	flush  func() error
+}
+
+// newRowWriter returns a rowWriter printing to w in format, with the
+// fields of the two-field layout if double is set.
+func newRowWriter(format string, w io.Writer, double bool) (*rowWriter, error) {
+	rw := &rowWriter{double: double}
+	header := []string{"id", "timestamp", "field", "sequence"}
+	if double {
+		header = []string{"id", "timestamp", "field1", "field2", "sequence"}
+	}
+
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+		rw.write = func(id uint64) {
+			columns := rw.columns(id)
+			for i, c := range columns {
+				if c == "" {
+					columns[i] = "-"
+				}
+			}
+			fmt.Fprintln(tw, strings.Join(columns, "\t"))
+		}
+		rw.flush = tw.Flush
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		rw.write = func(id uint64) { cw.Write(rw.columns(id)) }
+		rw.flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case "json":
+		bw := bufio.NewWriter(w)
+		je := json.NewEncoder(bw)
+		rw.write = func(id uint64) { je.Encode(rw.row(id)) }
+		rw.flush = bw.Flush
+	default:
+		return nil, fmt.Errorf("snowflake: -format %s: expected table, csv or json", format)
+	}
+	return rw, nil
+}
+
+// row returns the parsed form of id.
+func (rw *rowWriter) row(id uint64) row {
+	r := row{ID: strconv.FormatUint(id, 10)}
+	if rw.double {
+		sid := snowflake.Parse2(id)
+		if sid.Fallback {
+			r.Fallback = true
+			return r
+		}
+		r.Timestamp = formatTimestamp(sid.Timestamp)
+		r.Field1, r.Field2, r.Sequence = &sid.Field1, &sid.Field2, &sid.Sequence
+		return r
+	}
+
+	sid := snowflake.Parse(id)
+	if sid.Fallback {
+		r.Fallback = true
+		return r
+	}
+	r.Timestamp = formatTimestamp(sid.Timestamp)
+	r.Field, r.Sequence = &sid.MachineID, &sid.Sequence
+	return r
+}
+
+// columns returns the parsed form of id as table or CSV columns: random
+// fallback IDs have "fallback" for timestamp and empty fields and sequence.
+func (rw *rowWriter) columns(id uint64) []string {
+	r := rw.row(id)
+	if r.Fallback {
+		if rw.double {
+			return []string{r.ID, "fallback", "", "", ""}
+		}
+		return []string{r.ID, "fallback", "", ""}
+	}
+	if rw.double {
+		return []string{r.ID, r.Timestamp, strconv.FormatUint(*r.Field1, 10), strconv.FormatUint(*r.Field2, 10), strconv.FormatUint(*r.Sequence, 10)}
+	}
+	return []string{r.ID, r.Timestamp, strconv.FormatUint(*r.Field, 10), strconv.FormatUint(*r.Sequence, 10)}
+}
+
+// formatTimestamp formats a Unix millisecond timestamp as RFC3339 in UTC.
+func formatTimestamp(ms int64) string {
+	return time.UnixMilli(ms).UTC().Format(time.RFC3339Nano)
+}
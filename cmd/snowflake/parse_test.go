@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the tests")
+
+func TestParse(t *testing.T) {
+	defer snowflake.SetEpoch(snowflake.Epoch())
+
+	input, err := os.ReadFile(filepath.Join("testdata", "ids.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the malformed lines of testdata/ids.txt
+	malformed := "line 5: snowflake: \"not-an-id\" is not a decimal ID: invalid snowflake ID\n" +
+		"line 7: snowflake: \"18446744073709551616\" is not a decimal ID: invalid snowflake ID\n"
+
+	tests := []struct {
+		name   string
+		args   []string
+		golden string
+	}{
+		{name: "Should print a table by default", golden: "parse_table.golden"},
+		{name: "Should print CSV", args: []string{"-format", "csv"}, golden: "parse_csv.golden"},
+		{name: "Should print JSON", args: []string{"-format", "json"}, golden: "parse_json.golden"},
+		{name: "Should print the fields of the two-field layout", args: []string{"-layout", "double"}, golden: "parse_double.golden"},
+		{name: "Should print JSON of the two-field layout", args: []string{"-layout", "double", "-format", "json"}, golden: "parse_double_json.golden"},
+		{name: "Should offset the timestamps by the epoch", args: []string{"-epoch", "2015-01-01"}, golden: "parse_epoch.golden"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defer snowflake.SetEpoch(snowflake.Epoch())
+
+			var stdout, stderr bytes.Buffer
+			if err := parse(tc.args, bytes.NewReader(input), &stdout, &stderr); err == nil {
+				t.Error("expected an error for the malformed lines got nil")
+			}
+			if stderr.String() != malformed {
+				t.Errorf("expected stderr %q got %q", malformed, stderr.String())
+			}
+
+			golden := filepath.Join("testdata", tc.golden)
+			if *update {
+				if err := os.WriteFile(golden, stdout.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stdout.String() != string(expected) {
+				t.Errorf("expected output\n%s\ngot\n%s", expected, stdout.String())
+			}
+		})
+	}
+}
+
+func TestParse_Args(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	// the arguments take precedence over stdin
+	err := parse([]string{"-format", "csv", "4198498303", "bad"}, strings.NewReader("0\n"), &stdout, &stderr)
+	if err == nil {
+		t.Error("expected an error for the malformed argument got nil")
+	}
+
+	at := snowflake.Epoch().Add(time.Second).UTC().Format(time.RFC3339Nano)
+	expected := "id,timestamp,field,sequence\n4198498303," + at + ",1023,4095\n"
+	if stdout.String() != expected {
+		t.Errorf("expected %q got %q", expected, stdout.String())
+	}
+	if !strings.HasPrefix(stderr.String(), "argument 2: ") {
+		t.Errorf("expected the malformed argument to be reported got %q", stderr.String())
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	// a line far longer than bufio.Scanner's 64KB limit, and IDs that
+	// parse as uint64 but not as snowflake IDs
+	input := "4198498303\n" + strings.Repeat("1", 1<<20) + "\n9223372036854775808\n00042\n0\n"
+
+	var stdout, stderr bytes.Buffer
+	err := parse([]string{"-format", "csv"}, strings.NewReader(input), &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "3 malformed IDs") {
+		t.Errorf("expected 3 malformed IDs got %v", err)
+	}
+
+	ids := []string{}
+	for _, record := range strings.Split(strings.TrimSpace(stdout.String()), "\n")[1:] {
+		ids = append(ids, strings.Split(record, ",")[0])
+	}
+	if strings.Join(ids, " ") != "4198498303 0" {
+		t.Errorf("expected the rows of the lines around the malformed ones got %v", ids)
+	}
+
+	for _, where := range []string{"line 2: ", "line 3: ", "line 4: "} {
+		if !strings.Contains(stderr.String(), where) {
+			t.Errorf("expected %q to be reported got %q", where, stderr.String())
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		err  error
+	}{
+		{name: "Should reject an unknown flag", args: []string{"-x"}, err: errUsage},
+		{name: "Should reject an unknown layout", args: []string{"-layout", "triple"}},
+		{name: "Should reject an unknown format", args: []string{"-format", "yaml"}},
+		{name: "Should reject an unknown encoding", args: []string{"-encoding", "base64"}},
+		{name: "Should reject a malformed epoch", args: []string{"-epoch", "yesterday"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := parse(tc.args, strings.NewReader("0\n"), &stdout, &stderr)
+			if err == nil || (tc.err != nil && !errors.Is(err, tc.err)) {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("expected no output got %q", stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+// Package bwmarrin mirrors the API of github.com/bwmarrin/snowflake on top
+// of this module, for migrating off it with a one-line import change:
+//
+//	import snowflake "github.com/HotPotatoC/snowflake/compat/bwmarrin"
+//
+//	node, err := snowflake.NewNode(1)
+//	id := node.Generate()
+//	fmt.Println(id, id.Base58(), id.Time())
+//
+// IDs use bwmarrin's default epoch and layout (41 bits of time, 10 bits of
+// node and 12 bits of step, the same as this module's), so stored IDs keep
+// parsing and encoding exactly as before. Customizing the epoch or the
+// node and step bits isn't supported.
+package bwmarrin
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// Epoch is the twitter snowflake epoch of Nov 04 2010 01:42:54 UTC in
+// milliseconds, bwmarrin's default.
+const Epoch int64 = 1288834974657
+
+const (
+	nodeBits  = 10
+	stepBits  = 12
+	timeShift = nodeBits + stepBits
+	nodeMax   = -1 ^ (-1 << nodeBits)
+	nodeMask  = nodeMax << stepBits
+	stepMask  = -1 ^ (-1 << stepBits)
+)
+
+const (
+	encodeBase32Map = "ybndrfg8ejkmcpqxot1uwisza345h769"
+	encodeBase58Map = "123456789abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+)
+
+var (
+	decodeBase32Map = decodeMap(encodeBase32Map)
+	decodeBase58Map = decodeMap(encodeBase58Map)
+)
+
+// decodeMap maps a byte to its digit in alphabet, or 0xFF.
+func decodeMap(alphabet string) (m [256]byte) {
+	for i := range m {
+		m[i] = 0xFF
+	}
+	for i := 0; i < len(alphabet); i++ {
+		m[alphabet[i]] = byte(i)
+	}
+	return m
+}
+
+var (
+	// ErrInvalidBase58 is returned by ParseBase58 when given an invalid []byte.
+	ErrInvalidBase58 = errors.New("invalid base58")
+	// ErrInvalidBase32 is returned by ParseBase32 when given an invalid []byte.
+	ErrInvalidBase32 = errors.New("invalid base32")
+)
+
+// A JSONSyntaxError is returned from UnmarshalJSON if an invalid ID is provided.
+type JSONSyntaxError struct{ original []byte }
+
+func (j JSONSyntaxError) Error() string {
+	return fmt.Sprintf("invalid snowflake ID %q", string(j.original))
+}
+
+// A Node generates snowflake IDs for one node number.
+type Node struct {
+	id *snowflake.ID
+}
+
+// NewNode returns a new Node generating IDs with the given node number.
+// Returns an error wrapping snowflake.ErrFieldOutOfRange if node isn't
+// between 0 and 1023.
+func NewNode(node int64) (*Node, error) {
+	if node < 0 || node > nodeMax {
+		return nil, fmt.Errorf("bwmarrin: node %d must be between 0 and %d: %w", node, nodeMax, snowflake.ErrFieldOutOfRange)
+	}
+	return &Node{id: snowflake.New(uint64(node))}, nil
+}
+
+// Generate returns a new unique snowflake ID.
+func (n *Node) Generate() ID {
+	// the layouts only differ by epoch, so shift the timestamp from this
+	// module's epoch to bwmarrin's
+	offset := snowflake.Epoch().UnixMilli() - Epoch
+	return ID(int64(n.id.NextID()) + offset<<timeShift)
+}
+
+// An ID is a snowflake ID in bwmarrin's layout.
+type ID int64
+
+// Int64 returns an int64 of the snowflake ID.
+func (f ID) Int64() int64 { return int64(f) }
+
+// ParseInt64 converts an int64 into a snowflake ID.
+func ParseInt64(id int64) ID { return ID(id) }
+
+// String returns a string of the snowflake ID.
+func (f ID) String() string { return strconv.FormatInt(int64(f), 10) }
+
+// ParseString converts a string into a snowflake ID.
+func ParseString(id string) (ID, error) {
+	i, err := strconv.ParseInt(id, 10, 64)
+	return ID(i), err
+}
+
+// Base2 returns a string base2 of the snowflake ID.
+func (f ID) Base2() string { return strconv.FormatInt(int64(f), 2) }
+
+// ParseBase2 converts a Base2 string into a snowflake ID.
+func ParseBase2(id string) (ID, error) {
+	i, err := strconv.ParseInt(id, 2, 64)
+	return ID(i), err
+}
+
+// Base32 uses the z-base-32 character set but encodes like base58,
+// most significant digit first.
+func (f ID) Base32() string { return f.encode(encodeBase32Map) }
+
+// ParseBase32 parses a base32 []byte into a snowflake ID.
+func ParseBase32(b []byte) (ID, error) {
+	id, ok := decode(b, &decodeBase32Map, 32)
+	if !ok {
+		return -1, ErrInvalidBase32
+	}
+	return id, nil
+}
+
+// Base36 returns a base36 string of the snowflake ID.
+func (f ID) Base36() string { return strconv.FormatInt(int64(f), 36) }
+
+// ParseBase36 converts a Base36 string into a snowflake ID.
+func ParseBase36(id string) (ID, error) {
+	i, err := strconv.ParseInt(id, 36, 64)
+	return ID(i), err
+}
+
+// Base58 returns a base58 string of the snowflake ID, using the Flickr
+// alphabet.
+func (f ID) Base58() string { return f.encode(encodeBase58Map) }
+
+// ParseBase58 parses a base58 []byte into a snowflake ID.
+func ParseBase58(b []byte) (ID, error) {
+	id, ok := decode(b, &decodeBase58Map, 58)
+	if !ok {
+		return -1, ErrInvalidBase58
+	}
+	return id, nil
+}
+
+// Base64 returns a base64 string of the decimal snowflake ID.
+func (f ID) Base64() string { return base64.StdEncoding.EncodeToString(f.Bytes()) }
+
+// ParseBase64 converts a base64 string into a snowflake ID.
+func ParseBase64(id string) (ID, error) {
+	b, err := base64.StdEncoding.DecodeString(id)
+	if err != nil {
+		return -1, err
+	}
+	return ParseBytes(b)
+}
+
+// Bytes returns a byte slice of the decimal snowflake ID.
+func (f ID) Bytes() []byte { return []byte(f.String()) }
+
+// ParseBytes converts a byte slice into a snowflake ID.
+func ParseBytes(id []byte) (ID, error) {
+	i, err := strconv.ParseInt(string(id), 10, 64)
+	return ID(i), err
+}
+
+// IntBytes returns the snowflake ID as a big endian integer.
+func (f ID) IntBytes() [8]byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(f))
+	return b
+}
+
+// ParseIntBytes converts a big endian integer into a snowflake ID.
+func ParseIntBytes(id [8]byte) ID {
+	return ID(int64(binary.BigEndian.Uint64(id[:])))
+}
+
+// Time returns the Unix time of the snowflake ID in milliseconds.
+func (f ID) Time() int64 { return int64(f)>>timeShift + Epoch }
+
+// Node returns the node number of the snowflake ID.
+func (f ID) Node() int64 { return int64(f) & nodeMask >> stepBits }
+
+// Step returns the step (or sequence) number of the snowflake ID.
+func (f ID) Step() int64 { return int64(f) & stepMask }
+
+// MarshalJSON returns the snowflake ID as a JSON string.
+func (f ID) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 0, 22)
+	buf = append(buf, '"')
+	buf = strconv.AppendInt(buf, int64(f), 10)
+	buf = append(buf, '"')
+	return buf, nil
+}
+
+// UnmarshalJSON converts a JSON string of a snowflake ID into an ID.
+func (f *ID) UnmarshalJSON(b []byte) error {
+	if len(b) < 3 || b[0] != '"' || b[len(b)-1] != '"' {
+		return JSONSyntaxError{b}
+	}
+
+	i, err := strconv.ParseInt(string(b[1:len(b)-1]), 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*f = ID(i)
+	return nil
+}
+
+// encode returns f in the base of alphabet, most significant digit first.
+func (f ID) encode(alphabet string) string {
+	base := ID(len(alphabet))
+	if f < base {
+		return string(alphabet[f])
+	}
+
+	b := make([]byte, 0, 13)
+	for f >= base {
+		b = append(b, alphabet[f%base])
+		f /= base
+	}
+	b = append(b, alphabet[f])
+
+	for x, y := 0, len(b)-1; x < y; x, y = x+1, y-1 {
+		b[x], b[y] = b[y], b[x]
+	}
+	return string(b)
+}
+
+// decode parses b in the given base, most significant digit first.
+// Overflows wrap around, like bwmarrin's.
+func decode(b []byte, m *[256]byte, base int64) (ID, bool) {
+	var id int64
+	for _, c := range b {
+		if m[c] == 0xFF {
+			return 0, false
+		}
+		id = id*base + int64(m[c])
+	}
+	return ID(id), true
+}
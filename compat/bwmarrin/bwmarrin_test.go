@@ -0,0 +1,157 @@
+package bwmarrin_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/compat/bwmarrin"
+)
+
+// golden are IDs generated by github.com/bwmarrin/snowflake v0.3.0 with the
+// results of its methods.
+var golden = []struct {
+	id     int64
+	time   int64
+	node   int64
+	step   int64
+	base2  string
+	base32 string
+	base36 string
+	base58 string
+	base64 string
+	json   string
+}{
+	{2111134705033150464, 1792168711323, 0, 0, "1110101001100010000011101010000110110100000000000000000000000", "b4unb4o5eyyyy", "g1f2smo5xuyo", "5Ue7GR9BxQL", "MjExMTEzNDcwNTAzMzE1MDQ2NA==", "\"2111134705033150464\""},
+	{2111134705033150465, 1792168711323, 0, 1, "1110101001100010000011101010000110110100000000000000000000001", "b4unb4o5eyyyb", "g1f2smo5xuyp", "5Ue7GR9BxQM", "MjExMTEzNDcwNTAzMzE1MDQ2NQ==", "\"2111134705033150465\""},
+	{2111134705033154560, 1792168711323, 1, 0, "1110101001100010000011101010000110110100000000001000000000000", "b4unb4o5eyryy", "g1f2smo5xy4g", "5Ue7GR9Bz4o", "MjExMTEzNDcwNTAzMzE1NDU2MA==", "\"2111134705033154560\""},
+	{2111134705033154561, 1792168711323, 1, 1, "1110101001100010000011101010000110110100000000001000000000001", "b4unb4o5eyryb", "g1f2smo5xy4h", "5Ue7GR9Bz4p", "MjExMTEzNDcwNTAzMzE1NDU2MQ==", "\"2111134705033154561\""},
+	{2111134705035247616, 1792168711323, 512, 0, "1110101001100010000011101010000110110101000000000000000000000", "b4unb4o5kyyyy", "g1f2smo76t4w", "5Ue7GR9Nify", "MjExMTEzNDcwNTAzNTI0NzYxNg==", "\"2111134705035247616\""},
+	{2111134705035247617, 1792168711323, 512, 1, "1110101001100010000011101010000110110101000000000000000000001", "b4unb4o5kyyyb", "g1f2smo76t4x", "5Ue7GR9Nifz", "MjExMTEzNDcwNTAzNTI0NzYxNw==", "\"2111134705035247617\""},
+	{2111134705037340672, 1792168711323, 1023, 0, "1110101001100010000011101010000110110101111111111000000000000", "b4unb4o5m9hyy", "g1f2smo8fo5c", "5Ue7GR9Z2rJ", "MjExMTEzNDcwNTAzNzM0MDY3Mg==", "\"2111134705037340672\""},
+	{2111134705037340673, 1792168711323, 1023, 1, "1110101001100010000011101010000110110101111111111000000000001", "b4unb4o5m9hyb", "g1f2smo8fo5d", "5Ue7GR9Z2rK", "MjExMTEzNDcwNTAzNzM0MDY3Mw==", "\"2111134705037340673\""},
+	{0, 1288834974657, 0, 0, "0", "y", "0", "1", "MA==", "\"0\""},
+	{57, 1288834974657, 0, 57, "111001", "b3", "1l", "Z", "NTc=", "\"57\""},
+	{58, 1288834974657, 0, 58, "111010", "b4", "1m", "21", "NTg=", "\"58\""},
+	{9223372036854775807, 3487858230208, 1023, 4095, "111111111111111111111111111111111111111111111111111111111111111", "8999999999999", "1y2p0ij32e8e7", "npL6MjP8Qfc", "OTIyMzM3MjAzNjg1NDc3NTgwNw==", "\"9223372036854775807\""},
+}
+
+func TestID_Golden(t *testing.T) {
+	for _, g := range golden {
+		id := bwmarrin.ParseInt64(g.id)
+
+		if id.Time() != g.time {
+			t.Errorf("%d: expected time %d got %d", g.id, g.time, id.Time())
+		}
+		if id.Node() != g.node {
+			t.Errorf("%d: expected node %d got %d", g.id, g.node, id.Node())
+		}
+		if id.Step() != g.step {
+			t.Errorf("%d: expected step %d got %d", g.id, g.step, id.Step())
+		}
+
+		encodings := []struct {
+			name      string
+			got, want string
+		}{
+			{"base2", id.Base2(), g.base2},
+			{"base32", id.Base32(), g.base32},
+			{"base36", id.Base36(), g.base36},
+			{"base58", id.Base58(), g.base58},
+			{"base64", id.Base64(), g.base64},
+		}
+		for _, e := range encodings {
+			if e.got != e.want {
+				t.Errorf("%d: expected %s %q got %q", g.id, e.name, e.want, e.got)
+			}
+		}
+
+		if b, _ := id.MarshalJSON(); string(b) != g.json {
+			t.Errorf("%d: expected JSON %s got %s", g.id, g.json, b)
+		}
+	}
+}
+
+func TestID_RoundTrip(t *testing.T) {
+	for _, g := range golden {
+		want := bwmarrin.ID(g.id)
+
+		parsers := []struct {
+			name  string
+			parse func() (bwmarrin.ID, error)
+		}{
+			{"string", func() (bwmarrin.ID, error) { return bwmarrin.ParseString(want.String()) }},
+			{"base2", func() (bwmarrin.ID, error) { return bwmarrin.ParseBase2(g.base2) }},
+			{"base32", func() (bwmarrin.ID, error) { return bwmarrin.ParseBase32([]byte(g.base32)) }},
+			{"base36", func() (bwmarrin.ID, error) { return bwmarrin.ParseBase36(g.base36) }},
+			{"base58", func() (bwmarrin.ID, error) { return bwmarrin.ParseBase58([]byte(g.base58)) }},
+			{"base64", func() (bwmarrin.ID, error) { return bwmarrin.ParseBase64(g.base64) }},
+			{"bytes", func() (bwmarrin.ID, error) { return bwmarrin.ParseBytes(want.Bytes()) }},
+			{"int bytes", func() (bwmarrin.ID, error) { return bwmarrin.ParseIntBytes(want.IntBytes()), nil }},
+			{"json", func() (bwmarrin.ID, error) {
+				var id bwmarrin.ID
+				err := json.Unmarshal([]byte(g.json), &id)
+				return id, err
+			}},
+		}
+		for _, p := range parsers {
+			got, err := p.parse()
+			if err != nil {
+				t.Errorf("%d: expected no %s error got %v", g.id, p.name, err)
+			} else if got != want {
+				t.Errorf("%d: expected %s to parse to %d got %d", g.id, p.name, want, got)
+			}
+		}
+	}
+}
+
+func TestID_ParseInvalid(t *testing.T) {
+	if _, err := bwmarrin.ParseBase58([]byte("5Ue7GR9B0QL")); !errors.Is(err, bwmarrin.ErrInvalidBase58) {
+		t.Errorf("expected error %v got %v", bwmarrin.ErrInvalidBase58, err)
+	}
+	if _, err := bwmarrin.ParseBase32([]byte("b4unb4o5eyyyv")); !errors.Is(err, bwmarrin.ErrInvalidBase32) {
+		t.Errorf("expected error %v got %v", bwmarrin.ErrInvalidBase32, err)
+	}
+
+	var id bwmarrin.ID
+	var syntaxErr bwmarrin.JSONSyntaxError
+	if err := json.Unmarshal([]byte("2111134705033150464"), &id); !errors.As(err, &syntaxErr) {
+		t.Errorf("expected a JSONSyntaxError got %v", err)
+	}
+}
+
+func TestNewNode(t *testing.T) {
+	for _, node := range []int64{-1, 1024} {
+		if _, err := bwmarrin.NewNode(node); !errors.Is(err, snowflake.ErrFieldOutOfRange) {
+			t.Errorf("expected error %v for node %d got %v", snowflake.ErrFieldOutOfRange, node, err)
+		}
+	}
+}
+
+func TestNode_Generate(t *testing.T) {
+	node, err := bwmarrin.NewNode(512)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().UnixMilli()
+	prev := node.Generate()
+	after := time.Now().UnixMilli()
+
+	if prev.Time() < before || prev.Time() > after {
+		t.Errorf("expected time between %d and %d got %d", before, after, prev.Time())
+	}
+	if prev.Node() != 512 {
+		t.Errorf("expected node %d got %d", 512, prev.Node())
+	}
+
+	for i := 0; i < 10000; i++ {
+		id := node.Generate()
+		if id <= prev {
+			t.Fatalf("expected %d to be greater than %d", id, prev)
+		}
+		prev = id
+	}
+}
@@ -0,0 +1,131 @@
+// Package godruoyi mirrors the package-global API of
+// github.com/godruoyi/go-snowflake on top of this module, for migrating off
+// it with a one-line import change:
+//
+//	import snowflake "github.com/HotPotatoC/snowflake/compat/godruoyi"
+//
+//	snowflake.SetMachineID(1)
+//	id := snowflake.ID()
+//	sid := snowflake.ParseID(id)
+//
+// IDs use godruoyi's default start time and layout (41 bits of time, 10
+// bits of machine ID and 12 bits of sequence, the same as this module's),
+// so IDs issued before and after the switch decompose the same way and
+// interleave in time order. Sequences come from this module's generator,
+// so custom sequence resolvers aren't supported.
+package godruoyi
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// These constants are the bit lengths of snowflake ID parts.
+const (
+	TimestampLength = 41
+	MachineIDLength = 10
+	SequenceLength  = 12
+	MaxSequence     = 1<<SequenceLength - 1
+	MaxTimestamp    = 1<<TimestampLength - 1
+	MaxMachineID    = 1<<MachineIDLength - 1
+
+	timestampMoveLength = MachineIDLength + SequenceLength
+)
+
+// errLifeCycle is returned by NextID once IDs no longer fit 41 bits of
+// time since the start time.
+var errLifeCycle = errors.New("godruoyi: the maximum life cycle of the snowflake algorithm is 2^41-1 milliseconds, please check the start time")
+
+var (
+	mtx       sync.Mutex
+	machineID uint16
+	gen       = snowflake.New(0)
+	startTime = time.Date(2008, 11, 10, 23, 0, 0, 0, time.UTC)
+)
+
+// ID returns a new snowflake ID, ignoring errors. Use NextID for them.
+// It is safe for concurrent use.
+func ID() uint64 {
+	id, _ := NextID()
+	return id
+}
+
+// NextID returns a new snowflake ID. It is safe for concurrent use.
+func NextID() (uint64, error) {
+	mtx.Lock()
+	g, start := gen, startTime
+	mtx.Unlock()
+
+	id := g.NextID()
+
+	// the layouts only differ by epoch, so shift the timestamp from this
+	// module's epoch to the start time
+	ms := int64(id>>timestampMoveLength) + snowflake.Epoch().UnixMilli() - start.UnixMilli()
+	if ms < 0 || ms > MaxTimestamp {
+		return 0, errLifeCycle
+	}
+	return uint64(ms)<<timestampMoveLength | id&(1<<timestampMoveLength-1), nil
+}
+
+// SetStartTime sets the time IDs count milliseconds from. It panics if s
+// is zero, in the future or more than 2^41 milliseconds (69 years) ago.
+func SetStartTime(s time.Time) {
+	s = s.UTC()
+	switch {
+	case s.IsZero():
+		panic("godruoyi: the start time cannot be zero")
+	case s.After(time.Now()):
+		panic("godruoyi: the start time cannot be in the future")
+	case time.Now().UnixMilli()-s.UnixMilli() > MaxTimestamp:
+		panic("godruoyi: the maximum life cycle of the snowflake algorithm is 69 years")
+	}
+
+	mtx.Lock()
+	startTime = s
+	mtx.Unlock()
+}
+
+// SetMachineID sets the machine ID of new IDs. It panics if m is bigger
+// than 1023.
+func SetMachineID(m uint16) {
+	if m > MaxMachineID {
+		panic("godruoyi: the machine ID cannot be greater than 1023")
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if m != machineID {
+		machineID = m
+		gen = snowflake.New(uint64(m))
+	}
+}
+
+// SID is a parsed snowflake ID.
+type SID struct {
+	Sequence  uint64
+	MachineID uint64
+	Timestamp uint64
+	ID        uint64
+}
+
+// GenerateTime returns the time the ID was generated at, in UTC.
+func (id *SID) GenerateTime() time.Time {
+	mtx.Lock()
+	start := startTime
+	mtx.Unlock()
+
+	return time.UnixMilli(start.UnixMilli() + int64(id.Timestamp)).UTC()
+}
+
+// ParseID parses a snowflake ID.
+func ParseID(id uint64) SID {
+	return SID{
+		ID:        id,
+		Sequence:  id & MaxSequence,
+		MachineID: id >> SequenceLength & MaxMachineID,
+		Timestamp: id >> timestampMoveLength,
+	}
+}
@@ -0,0 +1,124 @@
+package godruoyi_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake/compat/godruoyi"
+)
+
+// golden are IDs generated or parsed by github.com/godruoyi/go-snowflake
+// v0.0.1 with its decomposition of them.
+var golden = []struct {
+	id        uint64
+	timestamp uint64
+	machineID uint64
+	sequence  uint64
+	time      string
+}{
+	{2373182429859938304, 565810782876, 0, 0, "2026-10-16T16:39:42.876Z"},
+	{2373182429859942401, 565810782876, 1, 1, "2026-10-16T16:39:42.876Z"},
+	{2373182429864128514, 565810782876, 1023, 2, "2026-10-16T16:39:42.876Z"},
+	{0, 0, 0, 0, "2008-11-10T23:00:00Z"},
+	{4095, 0, 0, 4095, "2008-11-10T23:00:00Z"},
+	{9223372036854775807, 2199023255551, 1023, 4095, "2078-07-18T14:47:35.551Z"},
+}
+
+func TestParseID_Golden(t *testing.T) {
+	for _, g := range golden {
+		sid := godruoyi.ParseID(g.id)
+
+		if sid.ID != g.id {
+			t.Errorf("%d: expected ID %d got %d", g.id, g.id, sid.ID)
+		}
+		if sid.Timestamp != g.timestamp {
+			t.Errorf("%d: expected timestamp %d got %d", g.id, g.timestamp, sid.Timestamp)
+		}
+		if sid.MachineID != g.machineID {
+			t.Errorf("%d: expected machine ID %d got %d", g.id, g.machineID, sid.MachineID)
+		}
+		if sid.Sequence != g.sequence {
+			t.Errorf("%d: expected sequence %d got %d", g.id, g.sequence, sid.Sequence)
+		}
+		if got := sid.GenerateTime().Format(time.RFC3339Nano); got != g.time {
+			t.Errorf("%d: expected time %s got %s", g.id, g.time, got)
+		}
+	}
+}
+
+func TestNextID(t *testing.T) {
+	godruoyi.SetMachineID(1023)
+	defer godruoyi.SetMachineID(0)
+
+	before := time.Now().Truncate(time.Millisecond)
+	id, err := godruoyi.NextID()
+	after := time.Now()
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	sid := godruoyi.ParseID(id)
+	if sid.MachineID != 1023 {
+		t.Errorf("expected machine ID %d got %d", 1023, sid.MachineID)
+	}
+	if at := sid.GenerateTime(); at.Before(before) || at.After(after) {
+		t.Errorf("expected time between %s and %s got %s", before, after, at)
+	}
+
+	// IDs godruoyi issued earlier sort before
+	for _, g := range golden[:3] {
+		if id <= g.id {
+			t.Errorf("expected %d to be greater than %d", id, g.id)
+		}
+	}
+
+	prev := id
+	for i := 0; i < 10000; i++ {
+		id := godruoyi.ID()
+		if id <= prev {
+			t.Fatalf("expected %d to be greater than %d", id, prev)
+		}
+		prev = id
+	}
+}
+
+func TestSetStartTime(t *testing.T) {
+	defer godruoyi.SetStartTime(time.Date(2008, 11, 10, 23, 0, 0, 0, time.UTC))
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	godruoyi.SetStartTime(start)
+
+	sid := godruoyi.ParseID(godruoyi.ID())
+	if d := time.Duration(sid.Timestamp) * time.Millisecond; d < time.Hour || d > time.Hour+time.Minute {
+		t.Errorf("expected about an hour since the start time got %s", d)
+	}
+
+	tc := []struct {
+		name  string
+		start time.Time
+	}{
+		{"Should panic on a zero start time", time.Time{}},
+		{"Should panic on a start time in the future", time.Now().Add(time.Hour)},
+		{"Should panic on a start time over 69 years ago", time.Now().AddDate(-70, 0, 0)},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			godruoyi.SetStartTime(tt.start)
+		})
+	}
+}
+
+func TestSetMachineID_Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	godruoyi.SetMachineID(1024)
+}
@@ -0,0 +1,86 @@
+package snowflake
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// compressVersion is the format version of CompressSorted. (internal-use only)
+const compressVersion = 1
+
+// CompressSorted packs ids, sorted in ascending order, into a compact
+// binary form, e.g. to ship manifests of millions of IDs. Sorted IDs differ
+// little from one to the next, since their timestamps cluster, so most of
+// them take 1 to 4 bytes rather than 8. Returns an error wrapping
+// ErrNotSorted if ids aren't sorted; duplicates are kept.
+//
+// The format is a version byte, the number of IDs and the first ID as
+// uvarints, the difference between each ID and the previous one as
+// uvarints, then the CRC-32 (IEEE) of all of that, big-endian.
+func CompressSorted(ids []uint64) ([]byte, error) {
+	buf := make([]byte, 0, 1+2*binary.MaxVarintLen64+2*len(ids)+crc32.Size)
+	var tmp [binary.MaxVarintLen64]byte
+
+	buf = append(buf, compressVersion)
+	buf = append(buf, tmp[:binary.PutUvarint(tmp[:], uint64(len(ids)))]...)
+	for i, id := range ids {
+		delta := id
+		if i > 0 {
+			if id < ids[i-1] {
+				return nil, fmt.Errorf("snowflake: ID %d at %d is less than %d before it: %w", id, i, ids[i-1], ErrNotSorted)
+			}
+			delta = id - ids[i-1]
+		}
+		buf = append(buf, tmp[:binary.PutUvarint(tmp[:], delta)]...)
+	}
+
+	var sum [crc32.Size]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(buf))
+	return append(buf, sum[:]...), nil
+}
+
+// Decompress unpacks IDs packed by CompressSorted. Returns an error
+// wrapping ErrCorruptData if data is truncated, corrupted or of an unknown
+// version.
+func Decompress(data []byte) ([]uint64, error) {
+	if len(data) < 1+crc32.Size {
+		return nil, fmt.Errorf("snowflake: %d bytes of compressed IDs are too short: %w", len(data), ErrCorruptData)
+	}
+	body, sum := data[:len(data)-crc32.Size], binary.BigEndian.Uint32(data[len(data)-crc32.Size:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return nil, fmt.Errorf("snowflake: checksum mismatch: %w", ErrCorruptData)
+	}
+	if body[0] != compressVersion {
+		return nil, fmt.Errorf("snowflake: compressed IDs of version %d: %w", body[0], ErrCorruptData)
+	}
+
+	count, n := binary.Uvarint(body[1:])
+	if n <= 0 {
+		return nil, fmt.Errorf("snowflake: malformed count: %w", ErrCorruptData)
+	}
+	body = body[1+n:]
+	// every ID takes at least a byte, don't trust count for more
+	if count > uint64(len(body)) {
+		return nil, fmt.Errorf("snowflake: %d IDs in %d bytes: %w", count, len(body), ErrCorruptData)
+	}
+
+	ids := make([]uint64, 0, count)
+	var id uint64
+	for i := uint64(0); i < count; i++ {
+		delta, n := binary.Uvarint(body)
+		if n <= 0 {
+			return nil, fmt.Errorf("snowflake: malformed ID %d: %w", i, ErrCorruptData)
+		}
+		body = body[n:]
+		if id+delta < id {
+			return nil, fmt.Errorf("snowflake: ID %d overflows: %w", i, ErrCorruptData)
+		}
+		id += delta
+		ids = append(ids, id)
+	}
+	if len(body) > 0 {
+		return nil, fmt.Errorf("snowflake: %d bytes after the last ID: %w", len(body), ErrCorruptData)
+	}
+	return ids, nil
+}
@@ -0,0 +1,151 @@
+package snowflake_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// workload returns n sorted IDs of fields generators, issuing perMs IDs
+// each per millisecond.
+func workload(n, generators, perMs int) []uint64 {
+	clock := newFakeClock(snowflake.Epoch().AddDate(10, 0, 0))
+	sfs := make([]*snowflake.ID, generators)
+	for i := range sfs {
+		sfs[i] = snowflake.New(uint64(i), snowflake.WithClock(clock))
+	}
+
+	ids := make([]uint64, 0, n)
+	for len(ids) < n {
+		for _, sf := range sfs {
+			ids = sf.AppendIDs(ids, perMs)
+		}
+		clock.Add(time.Millisecond)
+	}
+	ids = ids[:n]
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// withCRC appends the checksum of body to it.
+func withCRC(body []byte) []byte {
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(body))
+	return append(body, sum[:]...)
+}
+
+func TestCompressSorted(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	// a trickle of IDs, one every 0 to 20ms
+	var sparse []uint64
+	for id := uint64(1292053924173320192); len(sparse) < 100000; id += uint64(rng.Intn(20)) << 22 {
+		sparse = append(sparse, id|uint64(rng.Intn(1024))<<12)
+	}
+	sort.Slice(sparse, func(i, j int) bool { return sparse[i] < sparse[j] })
+
+	tc := []struct {
+		name     string
+		ids      []uint64
+		minRatio float64
+	}{
+		{"Should compress a busy generator", workload(1000000, 1, 500), 7},
+		{"Should compress busy generators", workload(1000000, 16, 50), 3.5},
+		{"Should compress a sparse workload", sparse, 2},
+		{"Should compress duplicates", []uint64{1, 1, 2, 2, 2}, 0},
+		{"Should compress no IDs", nil, 0},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := snowflake.CompressSorted(tt.ids)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ratio := float64(8*len(tt.ids)) / float64(len(data)); ratio < tt.minRatio {
+				t.Errorf("expected a compression ratio of %v or more got %v", tt.minRatio, ratio)
+			}
+
+			ids, err := snowflake.Decompress(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != len(tt.ids) {
+				t.Fatalf("expected %d IDs got %d", len(tt.ids), len(ids))
+			}
+			for i := range ids {
+				if ids[i] != tt.ids[i] {
+					t.Fatalf("expected ID %d to be %d got %d", i, tt.ids[i], ids[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompressSorted_Unsorted(t *testing.T) {
+	if _, err := snowflake.CompressSorted([]uint64{1, 3, 2}); !errors.Is(err, snowflake.ErrNotSorted) {
+		t.Errorf("expected error %v got %v", snowflake.ErrNotSorted, err)
+	}
+}
+
+func TestDecompress_Corrupt(t *testing.T) {
+	data, err := snowflake.CompressSorted(workload(10000, 4, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+
+	// every truncation
+	for n := 0; n < len(data); n++ {
+		if _, err := snowflake.Decompress(data[:n]); !errors.Is(err, snowflake.ErrCorruptData) {
+			t.Fatalf("expected error %v truncating to %d bytes got %v", snowflake.ErrCorruptData, n, err)
+		}
+	}
+
+	// random byte changes and trailing garbage
+	corrupt := make([]byte, len(data))
+	for i := 0; i < 1000; i++ {
+		copy(corrupt, data)
+		corrupt[rng.Intn(len(corrupt))] ^= byte(1 + rng.Intn(255))
+		if _, err := snowflake.Decompress(corrupt); !errors.Is(err, snowflake.ErrCorruptData) {
+			t.Fatalf("expected error %v got %v", snowflake.ErrCorruptData, err)
+		}
+	}
+	if _, err := snowflake.Decompress(append(data[:len(data):len(data)], 0)); !errors.Is(err, snowflake.ErrCorruptData) {
+		t.Errorf("expected error %v got %v", snowflake.ErrCorruptData, err)
+	}
+
+	// random data, mostly rejected by the checksum, must not panic
+	for i := 0; i < 1000; i++ {
+		garbage := make([]byte, rng.Intn(64))
+		rng.Read(garbage)
+		snowflake.Decompress(garbage)
+	}
+}
+
+func TestDecompress_Forged(t *testing.T) {
+	tc := []struct {
+		name string
+		body []byte
+	}{
+		{"Should reject an unknown version", []byte{2, 0}},
+		{"Should reject a count larger than the data", []byte{1, 0xFF, 0xFF, 0xFF, 0xFF, 0x0F, 1}},
+		{"Should reject a malformed ID", []byte{1, 1, 0xFF}},
+		{"Should reject an overflowing ID", []byte{1, 2, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0x01, 1}},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			// a valid checksum over invalid contents
+			if _, err := snowflake.Decompress(withCRC(tt.body)); !errors.Is(err, snowflake.ErrCorruptData) {
+				t.Errorf("expected error %v got %v", snowflake.ErrCorruptData, err)
+			}
+		})
+	}
+}
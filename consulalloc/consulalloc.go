@@ -0,0 +1,263 @@
+// Package consulalloc leases snowflake field values from Consul, so that
+// every member of an elastic fleet gets a distinct machine ID.
+//
+// A field is held by acquiring the KV lock on prefix<n> with a Consul
+// session, which is renewed in the background.
+//
+//	client, _ := api.NewClient(api.DefaultConfig())
+//
+//	lease, err := consulalloc.Acquire(ctx, client.Session(), client.KV(), consulalloc.DefaultPrefix, 15*time.Second)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer lease.Close()
+//
+//	sf := snowflake.New(lease.Field())
+//
+//	go func() {
+//		<-lease.Done() // the field may now be handed to someone else
+//		log.Fatal("lost snowflake field lease: ", lease.Err())
+//	}()
+package consulalloc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/hashicorp/consul/api"
+)
+
+// DefaultPrefix is the KV prefix the field locks are usually kept under.
+const DefaultPrefix = "snowflake/fields/"
+
+var (
+	// ErrNoFreeField is returned when every field value is leased. It wraps
+	// snowflake.ErrNoFreeField.
+	ErrNoFreeField = fmt.Errorf("consulalloc: %w", snowflake.ErrNoFreeField)
+	// ErrLeaseLost is reported by Lease.Err when the session was invalidated
+	// before it could be renewed.
+	ErrLeaseLost = errors.New("consulalloc: lease lost")
+)
+
+// Sessions is the subset of *api.Session used by Acquire.
+type Sessions interface {
+	Create(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error)
+	Renew(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error)
+	Destroy(id string, q *api.WriteOptions) (*api.WriteMeta, error)
+}
+
+// KV is the subset of *api.KV used by Acquire.
+type KV interface {
+	List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
+	Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+	Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error)
+}
+
+// Lease is a field value locked in Consul by a session. The session is
+// renewed every half of its TTL until Close is called or it is lost.
+type Lease struct {
+	sessions Sessions
+	kv       KV
+	session  string
+	key      string
+	field    uint64
+	ttl      time.Duration
+
+	done      chan struct{}
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	lostOnce  sync.Once
+
+	mtx sync.Mutex
+	err error
+}
+
+// Acquire creates a session with the given ttl and uses it to lock the
+// lowest free field value under prefix (keys are prefix0 to prefix1023).
+// Consul only accepts session TTLs between 10s and 24h.
+// Returns ErrNoFreeField when every field value is taken.
+func Acquire(ctx context.Context, sessions Sessions, kv KV, prefix string, ttl time.Duration) (*Lease, error) {
+	wopts := (&api.WriteOptions{}).WithContext(ctx)
+
+	session, _, err := sessions.Create(&api.SessionEntry{
+		Name:     "snowflake field",
+		TTL:      ttl.String(),
+		Behavior: api.SessionBehaviorDelete,
+	}, wopts)
+	if err != nil {
+		return nil, err
+	}
+
+	field, err := claim(ctx, kv, prefix, session)
+	if err != nil {
+		sessions.Destroy(session, nil)
+		return nil, err
+	}
+
+	l := &Lease{
+		sessions: sessions,
+		kv:       kv,
+		session:  session,
+		key:      prefix + strconv.FormatUint(field, 10),
+		field:    field,
+		ttl:      ttl,
+		done:     make(chan struct{}),
+		stop:     make(chan struct{}),
+	}
+
+	l.wg.Add(1)
+	go l.renew()
+
+	return l, nil
+}
+
+// Allocator is a snowflake.FieldAllocator acquiring leases under Prefix
+// with Acquire, for snowflake.NewLeased.
+//
+//	sf, err := snowflake.NewLeased(ctx, consulalloc.Allocator{
+//		Sessions: client.Session(), KV: client.KV(), Prefix: consulalloc.DefaultPrefix, TTL: 15 * time.Second,
+//	})
+type Allocator struct {
+	Sessions Sessions
+	KV       KV
+	Prefix   string
+	TTL      time.Duration
+}
+
+var _ snowflake.FieldAllocator = Allocator{}
+
+// Acquire is like the package-level Acquire.
+func (a Allocator) Acquire(ctx context.Context) (snowflake.Lease, error) {
+	l, err := Acquire(ctx, a.Sessions, a.KV, a.Prefix, a.TTL)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Field returns the leased field value.
+func (l *Lease) Field() uint64 { return l.field }
+
+// Done is closed when the lease is lost, after which another process may
+// be handed the same field value. It is not closed by Close.
+func (l *Lease) Done() <-chan struct{} { return l.done }
+
+// Err returns why the lease was lost, or nil while it is held.
+func (l *Lease) Err() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.err
+}
+
+// Close stops renewing the session, releases the field lock and destroys
+// the session. Releasing first avoids the lock-delay Consul applies to
+// locks of invalidated sessions.
+func (l *Lease) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.stop)
+		l.wg.Wait()
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.ttl)
+		defer cancel()
+		wopts := (&api.WriteOptions{}).WithContext(ctx)
+
+		_, _, err = l.kv.Release(&api.KVPair{Key: l.key, Session: l.session}, wopts)
+		if _, derr := l.sessions.Destroy(l.session, wopts); err == nil {
+			err = derr
+		}
+	})
+	return err
+}
+
+// renew renews the session every half of its TTL. If Consul no longer
+// knows the session, or renewals keep failing for a whole TTL, the lease
+// is lost. (internal-use only)
+func (l *Lease) renew() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.ttl / 2)
+	defer ticker.Stop()
+
+	lastRenewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.ttl/2)
+		entry, _, err := l.sessions.Renew(l.session, (&api.WriteOptions{}).WithContext(ctx))
+		cancel()
+
+		switch {
+		case err == nil && entry != nil:
+			lastRenewed = time.Now()
+		case err == nil:
+			l.lose(ErrLeaseLost)
+			return
+		case time.Since(lastRenewed) >= l.ttl:
+			l.lose(err)
+			return
+		}
+	}
+}
+
+// lose marks the lease as lost. (internal-use only)
+func (l *Lease) lose(err error) {
+	l.lostOnce.Do(func() {
+		l.mtx.Lock()
+		l.err = err
+		l.mtx.Unlock()
+		close(l.done)
+	})
+}
+
+// claim locks the first free field key with session. Consul grants a lock
+// to a single session, so two claimants can never hold the same field.
+// (internal-use only)
+func claim(ctx context.Context, kv KV, prefix, session string) (uint64, error) {
+	pairs, _, err := kv.List(prefix, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+
+	held := make(map[uint64]bool, len(pairs))
+	for _, pair := range pairs {
+		if pair.Session == "" {
+			continue
+		}
+		field, err := strconv.ParseUint(strings.TrimPrefix(pair.Key, prefix), 10, 64)
+		if err == nil {
+			held[field] = true
+		}
+	}
+
+	wopts := (&api.WriteOptions{}).WithContext(ctx)
+	for field := uint64(0); field <= snowflake.MaxField(); field++ {
+		if held[field] {
+			continue
+		}
+
+		acquired, _, err := kv.Acquire(&api.KVPair{
+			Key:     prefix + strconv.FormatUint(field, 10),
+			Session: session,
+		}, wopts)
+		if err != nil {
+			return 0, err
+		}
+		if acquired {
+			return field, nil
+		}
+	}
+
+	return 0, ErrNoFreeField
+}
@@ -0,0 +1,272 @@
+package consulalloc_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/consulalloc"
+	"github.com/hashicorp/consul/api"
+)
+
+// fakeConsul is an in-memory Consul with sessions and KV locks.
+type fakeConsul struct {
+	mtx      sync.Mutex
+	sessions map[string]bool
+	locks    map[string]string // key -> session
+	nextID   int
+	renewErr error
+}
+
+func newFakeConsul() *fakeConsul {
+	return &fakeConsul{
+		sessions: make(map[string]bool),
+		locks:    make(map[string]string),
+	}
+}
+
+func (c *fakeConsul) Create(se *api.SessionEntry, q *api.WriteOptions) (string, *api.WriteMeta, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.nextID++
+	id := "session-" + strconv.Itoa(c.nextID)
+	c.sessions[id] = true
+	return id, nil, nil
+}
+
+func (c *fakeConsul) Renew(id string, q *api.WriteOptions) (*api.SessionEntry, *api.WriteMeta, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.renewErr != nil {
+		return nil, nil, c.renewErr
+	}
+	if !c.sessions[id] {
+		return nil, nil, nil
+	}
+	return &api.SessionEntry{ID: id}, nil, nil
+}
+
+func (c *fakeConsul) Destroy(id string, q *api.WriteOptions) (*api.WriteMeta, error) {
+	c.invalidate(id)
+	return nil, nil
+}
+
+// invalidate drops the session and every lock it holds.
+func (c *fakeConsul) invalidate(id string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	delete(c.sessions, id)
+	for k, session := range c.locks {
+		if session == id {
+			delete(c.locks, k)
+		}
+	}
+}
+
+func (c *fakeConsul) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var pairs api.KVPairs
+	for k, session := range c.locks {
+		if strings.HasPrefix(k, prefix) {
+			pairs = append(pairs, &api.KVPair{Key: k, Session: session})
+		}
+	}
+	return pairs, nil, nil
+}
+
+func (c *fakeConsul) Acquire(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !c.sessions[p.Session] {
+		return false, nil, errors.New("invalid session")
+	}
+	if holder, ok := c.locks[p.Key]; ok && holder != p.Session {
+		return false, nil, nil
+	}
+	c.locks[p.Key] = p.Session
+	return true, nil, nil
+}
+
+func (c *fakeConsul) Release(p *api.KVPair, q *api.WriteOptions) (bool, *api.WriteMeta, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.locks[p.Key] != p.Session {
+		return false, nil, nil
+	}
+	delete(c.locks, p.Key)
+	return true, nil, nil
+}
+
+func (c *fakeConsul) holder(key string) string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.locks[key]
+}
+
+func TestAcquire(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeConsul()
+
+	first, err := consulalloc.Acquire(ctx, c, c, consulalloc.DefaultPrefix, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := consulalloc.Acquire(ctx, c, c, consulalloc.DefaultPrefix, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Field() != 0 || second.Field() != 1 {
+		t.Errorf("expected fields 0 and 1 got %d and %d", first.Field(), second.Field())
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c.holder("snowflake/fields/0") != "" {
+		t.Error("expected Close to release the field")
+	}
+
+	third, err := consulalloc.Acquire(ctx, c, c, consulalloc.DefaultPrefix, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Field() != 0 {
+		t.Errorf("expected field 0 to be reused got %d", third.Field())
+	}
+
+	second.Close()
+	third.Close()
+}
+
+func TestAcquire_NoFreeField(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeConsul()
+
+	for i := 0; i < 1024; i++ {
+		if _, err := consulalloc.Acquire(ctx, c, c, consulalloc.DefaultPrefix, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := consulalloc.Acquire(ctx, c, c, consulalloc.DefaultPrefix, time.Minute)
+	if err != consulalloc.ErrNoFreeField {
+		t.Errorf("expected error %v got %v", consulalloc.ErrNoFreeField, err)
+	}
+	if !errors.Is(err, snowflake.ErrNoFreeField) {
+		t.Errorf("expected error %v to wrap %v", err, snowflake.ErrNoFreeField)
+	}
+
+	c.mtx.Lock()
+	sessions := len(c.sessions)
+	c.mtx.Unlock()
+	if sessions != 1024 {
+		t.Errorf("expected the failed claim to destroy its session got %d sessions", sessions)
+	}
+}
+
+func TestLease_SessionInvalidated(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeConsul()
+
+	lease, err := consulalloc.Acquire(ctx, c, c, consulalloc.DefaultPrefix, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	// e.g. the node's health check failed
+	c.invalidate(c.holder("snowflake/fields/0"))
+
+	select {
+	case <-lease.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the lease to be lost")
+	}
+
+	if lease.Err() != consulalloc.ErrLeaseLost {
+		t.Errorf("expected error %v got %v", consulalloc.ErrLeaseLost, lease.Err())
+	}
+}
+
+func TestLease_RenewalFailure(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeConsul()
+
+	lease, err := consulalloc.Acquire(ctx, c, c, consulalloc.DefaultPrefix, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	unreachable := errors.New("connection refused")
+	c.mtx.Lock()
+	c.renewErr = unreachable
+	c.mtx.Unlock()
+
+	select {
+	case <-lease.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the lease to be lost once renewals failed for a whole TTL")
+	}
+
+	if lease.Err() != unreachable {
+		t.Errorf("expected error %v got %v", unreachable, lease.Err())
+	}
+}
+
+func TestLease_Renewed(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeConsul()
+
+	lease, err := consulalloc.Acquire(ctx, c, c, consulalloc.DefaultPrefix, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	time.Sleep(300 * time.Millisecond)
+
+	select {
+	case <-lease.Done():
+		t.Errorf("expected the lease to be held got %v", lease.Err())
+	default:
+	}
+}
+
+func TestAllocator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := newFakeConsul()
+
+	sf, err := snowflake.NewLeased(ctx, consulalloc.Allocator{Sessions: c, KV: c, Prefix: consulalloc.DefaultPrefix, TTL: 100 * time.Millisecond}, snowflake.WithLeasePolicy(snowflake.ReacquireOnLeaseLoss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field := snowflake.Parse(sf.NextID()).MachineID; field != 0 {
+		t.Fatalf("expected field 0 got %d", field)
+	}
+
+	c.invalidate(c.holder("snowflake/fields/0"))
+
+	// the generator carries on with the next lease
+	deadline := time.Now().Add(5 * time.Second)
+	for snowflake.Parse(sf.NextID()).MachineID != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected field 0 to be leased once field 0 was lost")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,98 @@
+package snowflake
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// containerIDPattern matches a 64 hex digit container ID making up a whole
+// path element or the middle of a systemd scope name, e.g.
+// /docker/<id>, /kubepods/.../<id> or /system.slice/docker-<id>.scope.
+// (internal-use only)
+var containerIDPattern = regexp.MustCompile(`(?:^|[/-])([0-9a-f]{64})(?:$|[/.])`)
+
+// MachineIDFromContainer derives a machine ID by hashing (64-bit FNV-1a) the
+// ID of the Docker, containerd or CRI-O container the process runs in.
+//
+// The ID is looked up in /proc/self/cgroup, which has it under cgroups v1.
+// Under cgroups v2 with a private cgroup namespace the cgroup path is just
+// "/", so /proc/self/mountinfo is searched instead for the container
+// directory the runtime bind-mounts /etc/hostname and friends from.
+// Like MachineIDFromMAC, different containers can hash to the same machine
+// ID. Returns an error wrapping ErrNotInContainer when no container ID is
+// found, so callers can fall back to another strategy.
+func MachineIDFromContainer() (uint64, error) { return Host{}.MachineIDFromContainer() }
+
+// MachineIDFromContainer is like the package-level MachineIDFromContainer
+// but reads the files of h.
+func (h Host) MachineIDFromContainer() (uint64, error) {
+	id, err := h.containerID()
+	if err != nil {
+		return 0, err
+	}
+	return hashField([]byte(id)), nil
+}
+
+// containerID finds the container ID in the cgroup file of h, falling back
+// to its mountinfo file. (internal-use only)
+func (h Host) containerID() (string, error) {
+	cgroup := h.CgroupFile
+	if cgroup == "" {
+		cgroup = "/proc/self/cgroup"
+	}
+	mountinfo := h.MountinfoFile
+	if mountinfo == "" {
+		mountinfo = "/proc/self/mountinfo"
+	}
+
+	// <hierarchy>:<controllers>:<path>
+	id, err := findContainerID(cgroup, func(line string) string {
+		fields := strings.SplitN(line, ":", 3)
+		if len(fields) != 3 {
+			return ""
+		}
+		return fields[2]
+	})
+	if id != "" || (err != nil && !os.IsNotExist(err)) {
+		return id, err
+	}
+
+	// <id> <parent> <major:minor> <root> <mount point> ...
+	id, err = findContainerID(mountinfo, func(line string) string {
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			return ""
+		}
+		switch fields[4] {
+		case "/etc/hostname", "/etc/hosts", "/etc/resolv.conf":
+			return fields[3]
+		}
+		return ""
+	})
+	if id != "" || (err != nil && !os.IsNotExist(err)) {
+		return id, err
+	}
+
+	return "", fmt.Errorf("snowflake: no container ID in %s or %s: %w", cgroup, mountinfo, ErrNotInContainer)
+}
+
+// findContainerID returns the first container ID in the path that path
+// extracts from a line of the file name. (internal-use only)
+func findContainerID(name string, path func(line string) string) (string, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		if m := containerIDPattern.FindStringSubmatch(path(s.Text())); m != nil {
+			return m[1], nil
+		}
+	}
+	return "", s.Err()
+}
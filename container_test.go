@@ -0,0 +1,82 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestMachineIDFromContainer(t *testing.T) {
+	tests := []struct {
+		name        string
+		cgroup      string
+		mountinfo   string
+		containerID string
+		err         error
+	}{
+		{
+			name:        "dockerd cgroups v1",
+			cgroup:      "testdata/container/cgroup-docker",
+			mountinfo:   "testdata/container/mountinfo-host",
+			containerID: "3f6e1a3c9c0a4b8e2d1f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d",
+		},
+		{
+			name:        "containerd cgroups v1 (kubernetes)",
+			cgroup:      "testdata/container/cgroup-containerd",
+			mountinfo:   "testdata/container/mountinfo-host",
+			containerID: "8b2e6a8d4c5f1e3a7b9c0d2e4f6a8b0c1d3e5f7a9b1c3d5e7f9a1b3c5d7e9f1a",
+		},
+		{
+			name:        "containerd systemd cgroup driver",
+			cgroup:      "testdata/container/cgroup-systemd",
+			mountinfo:   "testdata/container/mountinfo-host",
+			containerID: "c1f9e7d5b3a1f9e7d5c3b1a9f7e5d3c1b9a7f5e3d1c9b7a5f3e1d9c7b5a3f1e9",
+		},
+		{
+			name:        "dockerd cgroups v2",
+			cgroup:      "testdata/container/cgroup-v2",
+			mountinfo:   "testdata/container/mountinfo-docker-v2",
+			containerID: "3f6e1a3c9c0a4b8e2d1f7a6b5c4d3e2f1a0b9c8d7e6f5a4b3c2d1e0f9a8b7c6d",
+		},
+		{
+			name:        "containerd cgroups v2 (kubernetes)",
+			cgroup:      "testdata/container/cgroup-v2",
+			mountinfo:   "testdata/container/mountinfo-containerd-v2",
+			containerID: "5a7c9e1b3d5f7a9c1e3b5d7f9a1c3e5b7d9f1a3c5e7b9d1f3a5c7e9b1d3f5a7c",
+		},
+		{
+			name:      "Should return ErrNotInContainer on a host",
+			cgroup:    "testdata/container/cgroup-host",
+			mountinfo: "testdata/container/mountinfo-host",
+			err:       snowflake.ErrNotInContainer,
+		},
+		{
+			name:      "Should return ErrNotInContainer without /proc",
+			cgroup:    "testdata/container/missing",
+			mountinfo: "testdata/container/missing",
+			err:       snowflake.ErrNotInContainer,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			field, err := snowflake.Host{
+				CgroupFile:    tc.cgroup,
+				MountinfoFile: tc.mountinfo,
+			}.MachineIDFromContainer()
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v got %v", tc.err, err)
+			}
+			if tc.err != nil {
+				return
+			}
+
+			expected, _ := snowflake.Host{Hostname: hostname(tc.containerID)}.MachineIDFromHostname()
+			if field != expected {
+				t.Errorf("expected machine ID %d (hash of the container ID) got %d", expected, field)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package snowflake
+
+// descendingMask flips the timestamp and sequence of a SeqLow ID, leaving
+// the field. (internal-use only)
+const descendingMask = maxTimestamp<<(sequenceBits+fieldBits) | maxSeqBits
+
+// DescendingID is a snowflake ID generator whose IDs decrease over time,
+// for newest-first scans of stores that only scan keys in ascending order.
+// Its IDs hold the complement of the timestamp and sequence of an ID, so
+// that the later of two IDs, even within a millisecond, is the smaller.
+// Use ParseDescending to parse them.
+type DescendingID struct {
+	g generator
+}
+
+// NewDescending returns a new snowflake.DescendingID (max field value: 1023)
+// A field bigger than the max is reset to 0.
+func NewDescending(field uint64, opts ...Option) *DescendingID {
+	id := &DescendingID{}
+	if field <= maxFieldBits {
+		id.g.fieldSegment = field << sequenceBits
+	}
+	id.g.apply(opts)
+	id.g.checkField(field, maxFieldBits)
+	return id
+}
+
+// NextID returns a new descending snowflake ID.
+//
+//	Format:
+//	110100110110010010100110101000011111110101111111111111111111110
+//	|--------------~timestamp---------------|--disc---|---~seq----|
+func (id *DescendingID) NextID() uint64 { return id.g.nextID() ^ id.g.order().descendingMask() }
+
+// ParseDescending parses an existing descending snowflake ID, recovering
+// the timestamp and sequence it was issued with. Use
+// FieldLow.ParseDescending for IDs issued with WithSegmentOrder(FieldLow).
+func ParseDescending(sid uint64) SID { return Parse(sid ^ descendingMask) }
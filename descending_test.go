@@ -0,0 +1,67 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestDescendingID(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start)
+	sf := snowflake.NewDescending(42, snowflake.WithClock(clock))
+
+	var prev uint64
+	for i := 0; i < 1000; i++ {
+		// several IDs per millisecond, then the next one
+		at := start.Add(time.Duration(i/3) * time.Millisecond)
+		clock.Set(at)
+		id := sf.NextID()
+
+		if i > 0 && id >= prev {
+			t.Fatalf("expected %d to be smaller than %d", id, prev)
+		}
+		prev = id
+
+		sid := snowflake.ParseDescending(id)
+		if sid.Timestamp != at.UnixMilli() {
+			t.Errorf("expected timestamp %d got %d", at.UnixMilli(), sid.Timestamp)
+		}
+		if sid.MachineID != 42 || sid.Sequence != uint64(i%3) {
+			t.Errorf("expected field 42 and sequence %d got %d and %d", i%3, sid.MachineID, sid.Sequence)
+		}
+	}
+}
+
+func TestDescendingID_Int64(t *testing.T) {
+	// the complemented timestamp stays within 63 bits
+	id := snowflake.NewDescending(1023, snowflake.WithClock(newFakeClock(snowflake.Epoch()))).NextID()
+	if id>>63 != 0 {
+		t.Errorf("expected an ID below 2^63 got %d", id)
+	}
+}
+
+func TestDescendingID_FieldLow(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start)
+	sf := snowflake.NewDescending(42, snowflake.WithClock(clock), snowflake.WithSegmentOrder(snowflake.FieldLow))
+
+	var prev uint64
+	for i := 0; i < 100; i++ {
+		at := start.Add(time.Duration(i/10) * time.Millisecond)
+		clock.Set(at)
+		id := sf.NextID()
+
+		if i > 0 && id >= prev {
+			t.Fatalf("expected %d to be smaller than %d", id, prev)
+		}
+		prev = id
+
+		sid := snowflake.FieldLow.ParseDescending(id)
+		if sid.Timestamp != at.UnixMilli() || sid.MachineID != 42 || sid.Sequence != uint64(i%10) {
+			t.Errorf("expected timestamp %d, field 42 and sequence %d got %d, %d and %d",
+				at.UnixMilli(), i%10, sid.Timestamp, sid.MachineID, sid.Sequence)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package snowflake
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// segment is a part of the bit layout of an ID, from the highest bits.
+// (internal-use only)
+type segment struct {
+	name  string
+	short string // name to use when name doesn't fit the diagram
+	bits  int
+}
+
+var (
+	// idLayout is the bit layout of the IDs of ID. (internal-use only)
+	idLayout = []segment{
+		{"timestamp", "ts", timestampBits},
+		{"field", "f", fieldBits},
+		{"sequence", "seq", sequenceBits},
+	}
+	// id2Layout is the bit layout of the IDs of ID2. (internal-use only)
+	id2Layout = []segment{
+		{"timestamp", "ts", timestampBits},
+		{"field2", "f2", fieldBits / 2},
+		{"field1", "f1", fieldBits / 2},
+		{"sequence", "seq", sequenceBits},
+	}
+)
+
+// Describe explains the layout of the IDs of the generator: its epoch, how
+// the bits are allocated, the ranges of the values and when the timestamps
+// run out, with a diagram of the bits, e.g.
+//
+//	epoch     2012-03-28T00:00:00Z
+//	lifetime  until 2081-12-02T15:47:35.551Z
+//	layout    41 timestamp bits, 10 field bits, 12 sequence bits
+//	field     700 (max 1023)
+//	sequence  max 4095 per millisecond
+//	|---------------timestamp---------------|--field--|-sequence--|
+func (id *ID) Describe() string {
+	return describe(idLayout, fmt.Sprintf("field     %d (max %d)\n", id.currentField(), maxFieldBits))
+}
+
+// Describe is like (*ID).Describe.
+func (id *ID2) Describe() string {
+	field := id.currentField()
+	return describe(id2Layout, fmt.Sprintf("field1    %d (max %d)\nfield2    %d (max %d)\n",
+		field&maxFieldHalfBits, maxFieldHalfBits, field>>(fieldBits/2), maxFieldHalfBits))
+}
+
+// currentField returns the field bits of g. (internal-use only)
+func (g *generator) currentField() uint64 {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.fieldSegment >> sequenceBits
+}
+
+// describe explains layout, with fields describing the fields of a
+// generator. (internal-use only)
+func describe(layout []segment, fields string) string {
+	epoch := atomic.LoadInt64(&epochMillis)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "epoch     %s\n", time.UnixMilli(epoch).UTC().Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "lifetime  until %s\n", time.UnixMilli(epoch+maxTimestamp).UTC().Format(time.RFC3339Nano))
+	b.WriteString("layout    ")
+	for i, s := range layout {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%d %s bits", s.bits, s.name)
+	}
+	b.WriteString("\n")
+	b.WriteString(fields)
+	fmt.Fprintf(&b, "sequence  max %d per millisecond\n", maxSeqBits)
+	b.WriteString(diagram(layout))
+	b.WriteString("\n")
+	return b.String()
+}
+
+// DescribeID explains the ID id of an ID: its 63 bits, split by the
+// layout of ID, and the values they hold, e.g.
+//
+//	001000111101110010011000011001011001101000000000001000000000000
+//	|---------------timestamp---------------|--field--|-sequence--|
+//	timestamp 308049660724 (2021-12-31T09:21:00.724Z)
+//	field     1
+//	sequence  0
+func DescribeID(id uint64) string {
+	sid := Parse(id)
+	return describeID(id, idLayout, fmt.Sprintf("field     %d\n", sid.MachineID))
+}
+
+// DescribeID2 is like DescribeID for the IDs of ID2.
+func DescribeID2(id uint64) string {
+	sid := Parse2(id)
+	return describeID(id, id2Layout, fmt.Sprintf("field1    %d\nfield2    %d\n", sid.Field1, sid.Field2))
+}
+
+// describeID explains id laid out by layout, with fields describing its
+// fields. (internal-use only)
+func describeID(id uint64, layout []segment, fields string) string {
+	elapsed := id & (1<<63 - 1) >> (sequenceBits + fieldBits)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%063b\n", id&(1<<63-1))
+	b.WriteString(diagram(layout))
+	fmt.Fprintf(&b, "\ntimestamp %d (%s)\n", elapsed,
+		time.UnixMilli(int64(elapsed)+atomic.LoadInt64(&epochMillis)).UTC().Format(time.RFC3339Nano))
+	b.WriteString(fields)
+	fmt.Fprintf(&b, "sequence  %d\n", getSequence(id))
+	if id>>63 != 0 {
+		b.WriteString("overflow  the highest bit is set\n")
+	}
+	return b.String()
+}
+
+// diagram draws layout one character per bit, each segment ending with a
+// bar and the first one starting with one. (internal-use only)
+func diagram(layout []segment) string {
+	var b strings.Builder
+	for i, s := range layout {
+		width := s.bits - 1
+		if i == 0 {
+			b.WriteString("|")
+			width--
+		}
+		name := s.name
+		if len(name) > width {
+			name = s.short
+		}
+		left := (width - len(name)) / 2
+		b.WriteString(strings.Repeat("-", left))
+		b.WriteString(name)
+		b.WriteString(strings.Repeat("-", width-len(name)-left))
+		b.WriteString("|")
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+package snowflake_test
+
+import (
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestDescribe(t *testing.T) {
+	tc := []struct {
+		name     string
+		actual   string
+		expected string
+	}{
+		{
+			"Should describe an ID",
+			snowflake.New(700).Describe(),
+			"epoch     2012-03-28T00:00:00Z\n" +
+				"lifetime  until 2081-12-02T15:47:35.551Z\n" +
+				"layout    41 timestamp bits, 10 field bits, 12 sequence bits\n" +
+				"field     700 (max 1023)\n" +
+				"sequence  max 4095 per millisecond\n" +
+				"|---------------timestamp---------------|--field--|-sequence--|\n",
+		},
+		{
+			"Should describe an ID2",
+			snowflake.New2(3, 24).Describe(),
+			"epoch     2012-03-28T00:00:00Z\n" +
+				"lifetime  until 2081-12-02T15:47:35.551Z\n" +
+				"layout    41 timestamp bits, 5 field2 bits, 5 field1 bits, 12 sequence bits\n" +
+				"field1    3 (max 31)\n" +
+				"field2    24 (max 31)\n" +
+				"sequence  max 4095 per millisecond\n" +
+				"|---------------timestamp---------------|-f2-|-f1-|-sequence--|\n",
+		},
+		{
+			"Should describe an ID of ID",
+			snowflake.DescribeID(1292053924173320192 | 5<<12 | 42),
+			"001000111101110010011000011001011001101000000000101000000101010\n" +
+				"|---------------timestamp---------------|--field--|-sequence--|\n" +
+				"timestamp 308049660724 (2021-12-31T09:21:00.724Z)\n" +
+				"field     5\n" +
+				"sequence  42\n",
+		},
+		{
+			"Should describe an ID of ID2",
+			snowflake.DescribeID2(1292053924173320192 | 5<<12 | 42),
+			"001000111101110010011000011001011001101000000000101000000101010\n" +
+				"|---------------timestamp---------------|-f2-|-f1-|-sequence--|\n" +
+				"timestamp 308049660724 (2021-12-31T09:21:00.724Z)\n" +
+				"field1    5\n" +
+				"field2    0\n" +
+				"sequence  42\n",
+		},
+		{
+			"Should flag an overflowing ID",
+			snowflake.DescribeID(1<<63 | 1),
+			"000000000000000000000000000000000000000000000000000000000000001\n" +
+				"|---------------timestamp---------------|--field--|-sequence--|\n" +
+				"timestamp 0 (2012-03-28T00:00:00Z)\n" +
+				"field     0\n" +
+				"sequence  1\n" +
+				"overflow  the highest bit is set\n",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.actual != tt.expected {
+				t.Errorf("expected\n%s\ngot\n%s", tt.expected, tt.actual)
+			}
+		})
+	}
+}
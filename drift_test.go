@@ -0,0 +1,87 @@
+package snowflake_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// tickingClock advances by step every time it is read.
+type tickingClock struct {
+	mtx  sync.Mutex
+	t    time.Time
+	step time.Duration
+}
+
+func (c *tickingClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.t = c.t.Add(c.step)
+	return c.t
+}
+
+func (c *tickingClock) peek() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.t
+}
+
+func TestWithMaxForwardDrift(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithMaxForwardDrift(10*time.Millisecond))
+
+	// 5 milliseconds worth of IDs without the clock moving, this would
+	// block forever without drift
+	n := 5 * 4096
+	var last uint64
+	for i := 0; i < n; i++ {
+		id := sf.NextID()
+		if id <= last {
+			t.Fatalf("expected to be increasing, but got %d after %d", id, last)
+		}
+		last = id
+	}
+
+	sid := snowflake.Parse(last)
+	if ahead := sid.Timestamp - clock.Now().UnixMilli(); ahead != 4 {
+		t.Errorf("expected to be 4ms ahead of the clock got %dms", ahead)
+	}
+
+	// real time catches up
+	clock.Add(10 * time.Millisecond)
+	sid = snowflake.Parse(sf.NextID())
+	if sid.Timestamp != clock.Now().UnixMilli() {
+		t.Errorf("expected timestamp %d got %d", clock.Now().UnixMilli(), sid.Timestamp)
+	}
+	if sid.Sequence != 0 {
+		t.Errorf("expected sequence 0 got %d", sid.Sequence)
+	}
+}
+
+func TestWithMaxForwardDrift_Bound(t *testing.T) {
+	maxDrift := 10 * time.Millisecond
+	clock := &tickingClock{t: snowflake.Epoch().Add(time.Hour), step: time.Microsecond}
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithMaxForwardDrift(maxDrift))
+
+	n := 100000
+	ids := make(map[uint64]bool, n)
+	var last uint64
+	for i := 0; i < n; i++ {
+		id := sf.NextID()
+		if ids[id] {
+			t.Fatal("expected to be unique, but got a repeated ID")
+		}
+		if id <= last {
+			t.Fatalf("expected to be increasing, but got %d after %d", id, last)
+		}
+		ids[id] = true
+		last = id
+
+		ahead := snowflake.Parse(id).Timestamp - clock.peek().UnixMilli()
+		if ahead > maxDrift.Milliseconds() {
+			t.Fatalf("expected to be at most %s ahead of the clock got %dms", maxDrift, ahead)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package snowflake
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Encoding is a textual representation of snowflake IDs.
+type Encoding int
+
+const (
+	// Decimal is the plain base 10 representation, e.g. 1292053924173320192.
+	Decimal Encoding = iota
+	// Hex is the lowercase base 16 representation, e.g. 11ee4c32cd001000.
+	Hex
+	// Base58 uses the Bitcoin alphabet, which leaves out 0, O, I and l,
+	// e.g. 3zxCYejPpJT. It's the shortest of the encodings.
+	Base58
+	// Padded is the decimal representation zero-padded to 20 digits, so
+	// that string order matches numeric order.
+	Padded
+)
+
+// base58Alphabet is the Bitcoin base58 alphabet. (internal-use only)
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// base58Index maps a byte to its base58 digit, or 0xFF. (internal-use only)
+var base58Index = func() (index [256]byte) {
+	for i := range index {
+		index[i] = 0xFF
+	}
+	for i := 0; i < len(base58Alphabet); i++ {
+		index[base58Alphabet[i]] = byte(i)
+	}
+	return index
+}()
+
+// encodingNames are the names accepted by ParseEncoding. (internal-use only)
+var encodingNames = [...]string{
+	Decimal: "decimal",
+	Hex:     "hex",
+	Base58:  "base58",
+	Padded:  "padded",
+}
+
+// ParseEncoding returns the encoding called name: decimal, hex, base58 or
+// padded. Returns an error wrapping ErrUnknownEncoding otherwise.
+func ParseEncoding(name string) (Encoding, error) {
+	for e, n := range encodingNames {
+		if n == name {
+			return Encoding(e), nil
+		}
+	}
+	return 0, fmt.Errorf("snowflake: %q: %w", name, ErrUnknownEncoding)
+}
+
+// String returns the name of e, as accepted by ParseEncoding.
+func (e Encoding) String() string {
+	if e < 0 || int(e) >= len(encodingNames) {
+		return "Encoding(" + strconv.Itoa(int(e)) + ")"
+	}
+	return encodingNames[e]
+}
+
+// Format returns id encoded with e.
+func (e Encoding) Format(id uint64) string {
+	var buf [20]byte
+	return string(e.Append(buf[:0], id))
+}
+
+// NextStringID returns a new snowflake ID in decimal, with a single
+// allocation.
+func (id *ID) NextStringID() string { return Decimal.Format(id.nextID()) }
+
+// NextEncodedID returns a new snowflake ID encoded with enc, with a single
+// allocation.
+func (id *ID) NextEncodedID(enc Encoding) string { return enc.Format(id.nextID()) }
+
+// NextStringID is like (*ID).NextStringID.
+func (id *ID2) NextStringID() string { return Decimal.Format(id.nextID()) }
+
+// NextEncodedID is like (*ID).NextEncodedID.
+func (id *ID2) NextEncodedID(enc Encoding) string { return enc.Format(id.nextID()) }
+
+// Append appends id encoded with e to dst and returns the extended slice.
+// Unknown encodings fall back to Decimal.
+func (e Encoding) Append(dst []byte, id uint64) []byte {
+	switch e {
+	case Hex:
+		return strconv.AppendUint(dst, id, 16)
+	case Base58:
+		var buf [11]byte // 58^11 > 2^64
+		i := len(buf)
+		for {
+			i--
+			buf[i] = base58Alphabet[id%58]
+			id /= 58
+			if id == 0 {
+				break
+			}
+		}
+		return append(dst, buf[i:]...)
+	case Padded:
+		var buf [20]byte
+		digits := strconv.AppendUint(buf[:0], id, 10)
+		for i := len(digits); i < len(buf); i++ {
+			dst = append(dst, '0')
+		}
+		return append(dst, digits...)
+	default:
+		return strconv.AppendUint(dst, id, 10)
+	}
+}
+
+// Parse decodes an ID encoded with e. Returns an error wrapping
+// ErrInvalidID if s is malformed or overflows 64 bits.
+func (e Encoding) Parse(s string) (uint64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("snowflake: empty %s ID: %w", e, ErrInvalidID)
+	}
+
+	var id uint64
+	var err error
+	switch e {
+	case Hex:
+		id, err = strconv.ParseUint(s, 16, 64)
+	case Base58:
+		id, err = parseBase58(s)
+	default:
+		id, err = strconv.ParseUint(s, 10, 64)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("snowflake: %q is not a %s ID: %w", s, e, ErrInvalidID)
+	}
+	return id, nil
+}
+
+// parseBase58 decodes a Base58 ID. (internal-use only)
+func parseBase58(s string) (uint64, error) {
+	var id uint64
+	for i := 0; i < len(s); i++ {
+		digit := base58Index[s[i]]
+		if digit == 0xFF {
+			return 0, strconv.ErrSyntax
+		}
+		if id > (1<<64-1-uint64(digit))/58 {
+			return 0, strconv.ErrRange
+		}
+		id = id*58 + uint64(digit)
+	}
+	return id, nil
+}
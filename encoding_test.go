@@ -0,0 +1,129 @@
+package snowflake_test
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestEncoding(t *testing.T) {
+	id := uint64(1292053924173320192)
+
+	tc := []struct {
+		enc      snowflake.Encoding
+		name     string
+		expected string
+	}{
+		{snowflake.Decimal, "decimal", "1292053924173320192"},
+		{snowflake.Hex, "hex", "11ee4c32cd001000"},
+		{snowflake.Base58, "base58", "3zxCYejPpJT"},
+		{snowflake.Padded, "padded", "01292053924173320192"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if s := tt.enc.Format(id); s != tt.expected {
+				t.Errorf("expected %s got %s", tt.expected, s)
+			}
+			if s := string(tt.enc.Append([]byte("id:"), id)); s != "id:"+tt.expected {
+				t.Errorf("expected id:%s got %s", tt.expected, s)
+			}
+
+			for _, id := range []uint64{0, 1, 57, 58, id, math.MaxUint64} {
+				parsed, err := tt.enc.Parse(tt.enc.Format(id))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if parsed != id {
+					t.Errorf("expected %d to round trip got %d", id, parsed)
+				}
+			}
+
+			enc, err := snowflake.ParseEncoding(tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if enc != tt.enc || enc.String() != tt.name {
+				t.Errorf("expected encoding %s got %s", tt.name, enc)
+			}
+		})
+	}
+
+	if _, err := snowflake.ParseEncoding("base64"); !errors.Is(err, snowflake.ErrUnknownEncoding) {
+		t.Errorf("expected error %v got %v", snowflake.ErrUnknownEncoding, err)
+	}
+}
+
+func TestEncoding_Padded_SortsNumerically(t *testing.T) {
+	ids := []uint64{9, 10, 1292053924173320192, 100, math.MaxUint64}
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = snowflake.Padded.Format(id)
+	}
+	sort.Strings(strs)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for i, s := range strs {
+		if s != snowflake.Padded.Format(ids[i]) {
+			t.Errorf("expected %s at %d got %s", snowflake.Padded.Format(ids[i]), i, s)
+		}
+	}
+}
+
+func TestEncoding_Parse_Invalid(t *testing.T) {
+	tc := []struct {
+		name string
+		enc  snowflake.Encoding
+		s    string
+	}{
+		{"Should return ErrInvalidID for an empty string", snowflake.Decimal, ""},
+		{"Should return ErrInvalidID for a decimal overflow", snowflake.Decimal, "18446744073709551616"},
+		{"Should return ErrInvalidID for a negative number", snowflake.Decimal, "-1"},
+		{"Should return ErrInvalidID for a non-hex digit", snowflake.Hex, "11ee4g"},
+		{"Should return ErrInvalidID for a base58 overflow", snowflake.Base58, "jpXCZedGfVR"},
+		{"Should return ErrInvalidID for a character outside base58", snowflake.Base58, "3zxC0ejPpJT"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.enc.Parse(tt.s)
+			if !errors.Is(err, snowflake.ErrInvalidID) {
+				t.Errorf("expected error %v got %v", snowflake.ErrInvalidID, err)
+			}
+		})
+	}
+}
+
+func TestNextStringID(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(5, snowflake.WithClock(clock))
+	sf2 := snowflake.New2(5, 6, snowflake.WithClock(clock))
+
+	for _, enc := range []snowflake.Encoding{snowflake.Decimal, snowflake.Hex, snowflake.Base58, snowflake.Padded} {
+		t.Run(enc.String(), func(t *testing.T) {
+			for _, s := range []string{sf.NextEncodedID(enc), sf2.NextEncodedID(enc)} {
+				id, err := enc.Parse(s)
+				if err != nil {
+					t.Fatalf("expected no error got %v", err)
+				}
+				if ts := snowflake.Parse(id).Timestamp; ts != snowflake.Epoch().Add(time.Hour).UnixMilli() {
+					t.Errorf("expected timestamp %d got %d", snowflake.Epoch().Add(time.Hour).UnixMilli(), ts)
+				}
+			}
+		})
+	}
+
+	last := sf.NextID()
+	if s := sf.NextStringID(); s != strconv.FormatUint(last+1, 10) {
+		t.Errorf("expected %d got %s", last+1, s)
+	}
+	last = sf2.NextID()
+	if s := sf2.NextStringID(); s != strconv.FormatUint(last+1, 10) {
+		t.Errorf("expected %d got %s", last+1, s)
+	}
+}
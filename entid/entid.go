@@ -0,0 +1,87 @@
+// Package entid provides snowflake ID fields for ent schemas.
+//
+//	var gen = snowflake.New(1)
+//
+//	func (User) Fields() []ent.Field {
+//		return []ent.Field{
+//			field.Uint64("id").DefaultFunc(entid.Default(gen)).Immutable(),
+//		}
+//	}
+//
+// or, with the typed ID:
+//
+//	func (User) Fields() []ent.Field {
+//		return []ent.Field{
+//			entid.Field("id", gen),
+//		}
+//	}
+package entid
+
+import (
+	"strconv"
+	"sync"
+
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"github.com/HotPotatoC/snowflake"
+)
+
+// ID is a snowflake ID, stored as an unsigned BIGINT.
+type ID uint64
+
+// String returns the decimal representation of id.
+func (id ID) String() string { return strconv.FormatUint(uint64(id), 10) }
+
+// Default returns a DefaultFunc for field.Uint64 issuing the IDs of gen.
+//
+// ent calls default funcs from concurrent mutations, so gen must be safe
+// for concurrent use. *snowflake.UnsafeID isn't and gets wrapped in a
+// mutex, the same one for every schema sharing it; every other generator
+// in the snowflake package is.
+func Default(gen snowflake.Generator) func() uint64 {
+	if u, ok := gen.(*snowflake.UnsafeID); ok {
+		gen = lockedUnsafe(u)
+	}
+	return gen.NextID
+}
+
+// DefaultID is like Default but returns IDs typed as ID.
+func DefaultID(gen snowflake.Generator) func() ID {
+	next := Default(gen)
+	return func() ID { return ID(next()) }
+}
+
+// Field returns an immutable uint64 field typed as ID, which defaults to
+// the next ID of gen.
+func Field(name string, gen snowflake.Generator) ent.Field {
+	return field.Uint64(name).
+		GoType(ID(0)).
+		DefaultFunc(DefaultID(gen)).
+		Immutable()
+}
+
+// locked holds the lockedGenerator of every *snowflake.UnsafeID passed to
+// Default, for the life of the process. (internal-use only)
+var locked sync.Map
+
+// lockedUnsafe returns the lockedGenerator of u. (internal-use only)
+func lockedUnsafe(u *snowflake.UnsafeID) *lockedGenerator {
+	if l, ok := locked.Load(u); ok {
+		return l.(*lockedGenerator)
+	}
+	l, _ := locked.LoadOrStore(u, &lockedGenerator{g: u})
+	return l.(*lockedGenerator)
+}
+
+// lockedGenerator serializes the calls to a generator that isn't safe for
+// concurrent use. (internal-use only)
+type lockedGenerator struct {
+	mtx sync.Mutex
+	g   snowflake.Generator
+}
+
+func (l *lockedGenerator) NextID() uint64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.g.NextID()
+}
@@ -0,0 +1,152 @@
+package entid_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"entgo.io/ent/dialect"
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/entid"
+	"github.com/HotPotatoC/snowflake/entid/internal/ent/enttest"
+	"github.com/HotPotatoC/snowflake/entid/internal/ent/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestDefault_Concurrent(t *testing.T) {
+	client := enttest.Open(t, dialect.SQLite, "file:entid?mode=memory&cache=shared&_fk=1")
+	defer client.Close()
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 200)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				if _, err := client.User.Create().SetName(fmt.Sprintf("user-%d-%d", i, j)).Save(ctx); err != nil {
+					errs <- err
+					return
+				}
+				if _, err := client.Post.Create().SetTitle(fmt.Sprintf("post-%d-%d", i, j)).Save(ctx); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	users, err := client.User.Query().All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts, err := client.Post.Query().All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 200 || len(posts) != 200 {
+		t.Fatalf("expected 200 users and posts got %d and %d", len(users), len(posts))
+	}
+
+	seen := make(map[uint64]bool)
+	check := func(id uint64) {
+		if seen[id] {
+			t.Errorf("duplicate ID %d", id)
+		}
+		seen[id] = true
+		if field := snowflake.Parse(id).MachineID; field != schema.Field {
+			t.Errorf("expected field %d got %d", schema.Field, field)
+		}
+	}
+	for _, u := range users {
+		check(u.ID)
+	}
+	for _, p := range posts {
+		check(uint64(p.ID))
+	}
+}
+
+func TestDefault_PresetID(t *testing.T) {
+	client := enttest.Open(t, dialect.SQLite, "file:entid-preset?mode=memory&cache=shared&_fk=1")
+	defer client.Close()
+
+	ctx := context.Background()
+
+	u, err := client.User.Create().SetID(1292053924173320192).SetName("preset").Save(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := client.User.Get(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != 1292053924173320192 {
+		t.Errorf("expected ID %d got %d", uint64(1292053924173320192), got.ID)
+	}
+
+}
+
+func TestDefault_Unsafe(t *testing.T) {
+	next := entid.Default(snowflake.NewUnsafe(1))
+
+	var mtx sync.Mutex
+	seen := make(map[uint64]bool)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				id := next()
+
+				mtx.Lock()
+				if seen[id] {
+					t.Errorf("duplicate ID %d", id)
+				}
+				seen[id] = true
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDefault_UnsafeShared(t *testing.T) {
+	// schemas sharing an UnsafeID must share its lock
+	gen := snowflake.NewUnsafe(1)
+	nexts := []func() uint64{entid.Default(gen), entid.Default(gen)}
+	nextID := entid.DefaultID(gen)
+	nexts = append(nexts, func() uint64 { return uint64(nextID()) })
+
+	var mtx sync.Mutex
+	seen := make(map[uint64]bool)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 9; i++ {
+		wg.Add(1)
+		go func(next func() uint64) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				id := next()
+
+				mtx.Lock()
+				if seen[id] {
+					t.Errorf("duplicate ID %d", id)
+				}
+				seen[id] = true
+				mtx.Unlock()
+			}
+		}(nexts[i%len(nexts)])
+	}
+	wg.Wait()
+}
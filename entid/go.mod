@@ -0,0 +1,25 @@
+module github.com/HotPotatoC/snowflake/entid
+
+go 1.23.0
+
+require (
+	entgo.io/ent v0.14.1
+	github.com/HotPotatoC/snowflake v0.0.0-00010101000000-000000000000
+	github.com/mattn/go-sqlite3 v1.14.22
+)
+
+require (
+	ariga.io/atlas v0.19.1-0.20240203083654-5948b60a8e43 // indirect
+	github.com/agext/levenshtein v1.2.1 // indirect
+	github.com/apparentlymart/go-textseg/v13 v13.0.0 // indirect
+	github.com/go-openapi/inflect v0.19.0 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/google/uuid v1.3.0 // indirect
+	github.com/hashicorp/hcl/v2 v2.13.0 // indirect
+	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
+	github.com/zclconf/go-cty v1.8.0 // indirect
+	golang.org/x/mod v0.20.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
+)
+
+replace github.com/HotPotatoC/snowflake => ../
@@ -0,0 +1,4 @@
+// Package ent is the ent client generated for the entid tests.
+package ent
+
+//go:generate go run -mod=mod entgo.io/ent/cmd/ent generate ./schema
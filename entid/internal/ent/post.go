@@ -0,0 +1,104 @@
+// Code generated by ent, DO NOT EDIT.
+
+package ent
+
+import (
+	"fmt"
+	"strings"
+
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/sql"
+	"github.com/HotPotatoC/snowflake/entid"
+	"github.com/HotPotatoC/snowflake/entid/internal/ent/post"
+)
+
+// Post is the model entity for the Post schema.
+type Post struct {
+	config `json:"-"`
+	// ID of the ent.
+	ID entid.ID `json:"id,omitempty"`
+	// Title holds the value of the "title" field.
+	Title        string `json:"title,omitempty"`
+	selectValues sql.SelectValues
+}
+
+// scanValues returns the types for scanning values from sql.Rows.
+func (*Post) scanValues(columns []string) ([]any, error) {
+	values := make([]any, len(columns))
+	for i := range columns {
+		switch columns[i] {
+		case post.FieldID:
+			values[i] = new(sql.NullInt64)
+		case post.FieldTitle:
+			values[i] = new(sql.NullString)
+		default:
+			values[i] = new(sql.UnknownType)
+		}
+	}
+	return values, nil
+}
+
+// assignValues assigns the values that were returned from sql.Rows (after scanning)
+// to the Post fields.
+func (po *Post) assignValues(columns []string, values []any) error {
+	if m, n := len(values), len(columns); m < n {
+		return fmt.Errorf("mismatch number of scan values: %d != %d", m, n)
+	}
+	for i := range columns {
+		switch columns[i] {
+		case post.FieldID:
+			if value, ok := values[i].(*sql.NullInt64); !ok {
+				return fmt.Errorf("unexpected type %T for field id", values[i])
+			} else if value.Valid {
+				po.ID = entid.ID(value.Int64)
+			}
+		case post.FieldTitle:
+			if value, ok := values[i].(*sql.NullString); !ok {
+				return fmt.Errorf("unexpected type %T for field title", values[i])
+			} else if value.Valid {
+				po.Title = value.String
+			}
+		default:
+			po.selectValues.Set(columns[i], values[i])
+		}
+	}
+	return nil
+}
+
+// Value returns the ent.Value that was dynamically selected and assigned to the Post.
+// This includes values selected through modifiers, order, etc.
+func (po *Post) Value(name string) (ent.Value, error) {
+	return po.selectValues.Get(name)
+}
+
+// Update returns a builder for updating this Post.
+// Note that you need to call Post.Unwrap() before calling this method if this Post
+// was returned from a transaction, and the transaction was committed or rolled back.
+func (po *Post) Update() *PostUpdateOne {
+	return NewPostClient(po.config).UpdateOne(po)
+}
+
+// Unwrap unwraps the Post entity that was returned from a transaction after it was closed,
+// so that all future queries will be executed through the driver which created the transaction.
+func (po *Post) Unwrap() *Post {
+	_tx, ok := po.config.driver.(*txDriver)
+	if !ok {
+		panic("ent: Post is not a transactional entity")
+	}
+	po.config.driver = _tx.drv
+	return po
+}
+
+// String implements the fmt.Stringer.
+func (po *Post) String() string {
+	var builder strings.Builder
+	builder.WriteString("Post(")
+	builder.WriteString(fmt.Sprintf("id=%v, ", po.ID))
+	builder.WriteString("title=")
+	builder.WriteString(po.Title)
+	builder.WriteByte(')')
+	return builder.String()
+}
+
+// Posts is a parsable slice of Post.
+type Posts []*Post
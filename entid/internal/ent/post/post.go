@@ -0,0 +1,53 @@
+// Code generated by ent, DO NOT EDIT.
+
+package post
+
+import (
+	"entgo.io/ent/dialect/sql"
+	"github.com/HotPotatoC/snowflake/entid"
+)
+
+const (
+	// Label holds the string label denoting the post type in the database.
+	Label = "post"
+	// FieldID holds the string denoting the id field in the database.
+	FieldID = "id"
+	// FieldTitle holds the string denoting the title field in the database.
+	FieldTitle = "title"
+	// Table holds the table name of the post in the database.
+	Table = "posts"
+)
+
+// Columns holds all SQL columns for post fields.
+var Columns = []string{
+	FieldID,
+	FieldTitle,
+}
+
+// ValidColumn reports if the column name is valid (part of the table columns).
+func ValidColumn(column string) bool {
+	for i := range Columns {
+		if column == Columns[i] {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// DefaultID holds the default value on creation for the "id" field.
+	DefaultID func() entid.ID
+)
+
+// OrderOption defines the ordering options for the Post queries.
+type OrderOption func(*sql.Selector)
+
+// ByID orders the results by the id field.
+func ByID(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldID, opts...).ToFunc()
+}
+
+// ByTitle orders the results by the title field.
+func ByTitle(opts ...sql.OrderTermOption) OrderOption {
+	return sql.OrderByField(FieldTitle, opts...).ToFunc()
+}
@@ -0,0 +1,13 @@
+// Code generated by ent, DO NOT EDIT.
+
+package predicate
+
+import (
+	"entgo.io/ent/dialect/sql"
+)
+
+// Post is the predicate function for post builders.
+type Post func(*sql.Selector)
+
+// User is the predicate function for user builders.
+type User func(*sql.Selector)
@@ -0,0 +1,40 @@
+// Package schema holds the ent schemas used by the entid tests.
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/entid"
+)
+
+// Field is the field of the IDs issued to the test entities.
+const Field = 3
+
+var gen = snowflake.New(Field)
+
+// User has a plain uint64 snowflake ID.
+type User struct {
+	ent.Schema
+}
+
+// Fields of the User.
+func (User) Fields() []ent.Field {
+	return []ent.Field{
+		field.Uint64("id").DefaultFunc(entid.Default(gen)).Immutable(),
+		field.String("name"),
+	}
+}
+
+// Post has a typed snowflake ID.
+type Post struct {
+	ent.Schema
+}
+
+// Fields of the Post.
+func (Post) Fields() []ent.Field {
+	return []ent.Field{
+		entid.Field("id", gen),
+		field.String("title"),
+	}
+}
@@ -0,0 +1,40 @@
+package snowflake
+
+// NewEntropy returns a new snowflake.ID without a machine ID, for
+// serverless functions and other short-lived instances with no stable
+// identity to coordinate. The 10 field bits hold random bits from
+// crypto/rand instead, drawn anew for every millisecond and kept within
+// it, so that the sequence still tells the IDs of an instance apart: an
+// instance never issues the same ID twice. Parse works as usual, the
+// machine ID it reads is just meaningless.
+//
+// Two instances issue the same ID only if they issue in the same
+// millisecond, draw the same 10 random bits for it and reach the same
+// sequence. Since every millisecond's sequence starts at 0, the first two
+// are as good as the last: n instances issuing in the same millisecond
+// collide with odds of about 1 - e^(-n(n-1)/2048), as with
+// RandomMachineID, only drawn again every millisecond:
+//
+//	instances per millisecond   collision odds
+//	                        2   0.1%
+//	                       10   4.3%
+//
+// The odds add up over the milliseconds in which instances overlap, e.g.
+// two instances issuing in 1000 of the same milliseconds collide with odds
+// of 62%. Entropy IDs suit traffic that rarely has instances issuing in the
+// same millisecond; use a machine ID where it doesn't.
+func NewEntropy(opts ...Option) *ID {
+	id := &ID{}
+	id.entropy = true
+	id.apply(opts)
+	return id
+}
+
+// rollField replaces the field with random bits, keeping the version tag
+// if any. (internal-use only)
+func (g *generator) rollField() {
+	g.fieldSegment = RandomMachineID() << sequenceBits
+	if g.versionTag != 0 {
+		g.fieldSegment = g.fieldSegment&^versionMask | g.versionTag<<versionShift
+	}
+}
@@ -0,0 +1,63 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestNewEntropy(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.NewEntropy(snowflake.WithClock(clock))
+
+	// 1000 milliseconds of 300 IDs
+	fields := make(map[uint64]bool)
+	for ms := 0; ms < 1000; ms++ {
+		ids := sf.AppendIDs(nil, 300)
+		first := snowflake.Parse(ids[0])
+		for i, id := range ids {
+			sid := snowflake.Parse(id)
+			if sid.Timestamp != clock.Now().UnixMilli() || sid.MachineID != first.MachineID || sid.Sequence != uint64(i) {
+				t.Fatalf("expected sequence %d of field %d at %d got %+v", i, first.MachineID, clock.Now().UnixMilli(), sid)
+			}
+		}
+		fields[first.MachineID] = true
+		clock.Add(time.Millisecond)
+	}
+
+	// 1000 draws of 1024 values cover about 63% of them
+	if len(fields) < 500 {
+		t.Errorf("expected the field to vary across milliseconds got %d distinct values", len(fields))
+	}
+}
+
+func TestNewEntropy_Unique(t *testing.T) {
+	// a clock read per ID, 10000 IDs a millisecond rolling the sequence over
+	clock := &tickingClock{t: snowflake.Epoch().Add(time.Hour), step: 100 * time.Nanosecond}
+	sf := snowflake.NewEntropy(snowflake.WithClock(clock))
+
+	ids := sf.AppendIDs(nil, 200000)
+	seen := make(map[uint64]bool, len(ids))
+	for i, id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate ID %d", id)
+		}
+		seen[id] = true
+		if i > 0 && snowflake.Parse(id).Timestamp < snowflake.Parse(ids[i-1]).Timestamp {
+			t.Fatalf("expected increasing timestamps got %d after %d", id, ids[i-1])
+		}
+	}
+}
+
+func TestNewEntropy_VersionTag(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.NewEntropy(snowflake.WithClock(clock), snowflake.WithVersionTag(2))
+
+	for ms := 0; ms < 100; ms++ {
+		if v, ok := snowflake.VersionOf(sf.NextID()); !ok || v != 2 {
+			t.Fatalf("expected version 2 got %d", v)
+		}
+		clock.Add(time.Millisecond)
+	}
+}
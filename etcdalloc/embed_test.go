@@ -0,0 +1,159 @@
+package etcdalloc_test
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake/etcdalloc"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// startEtcd runs a single-member etcd in a temporary directory.
+func startEtcd(t *testing.T) *clientv3.Client {
+	t.Helper()
+
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+
+	clientURL, peerURL := freeURL(t), freeURL(t)
+	cfg.ListenClientUrls, cfg.AdvertiseClientUrls = []url.URL{clientURL}, []url.URL{clientURL}
+	cfg.ListenPeerUrls, cfg.AdvertisePeerUrls = []url.URL{peerURL}, []url.URL{peerURL}
+	cfg.InitialCluster = cfg.InitialClusterFromName(cfg.Name)
+
+	e, err := embed.StartEtcd(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(e.Close)
+
+	select {
+	case <-e.Server.ReadyNotify():
+	case <-time.After(10 * time.Second):
+		t.Fatal("etcd did not start")
+	}
+
+	cli, err := clientv3.New(clientv3.Config{Endpoints: []string{clientURL.String()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cli.Close() })
+
+	return cli
+}
+
+func freeURL(t *testing.T) url.URL {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	return url.URL{Scheme: "http", Host: l.Addr().String()}
+}
+
+func TestAcquire_Etcd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts an embedded etcd")
+	}
+
+	ctx := context.Background()
+	cli := startEtcd(t)
+
+	// everything but the last field is taken
+	for i := 0; i < 1023; i++ {
+		lease, err := etcdalloc.Acquire(ctx, cli, "/fields/", 10*time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer lease.Close()
+	}
+
+	var wg sync.WaitGroup
+	leases := make(chan *etcdalloc.Lease, 8)
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lease, err := etcdalloc.Acquire(ctx, cli, "/fields/", 10*time.Second)
+			if err != nil {
+				errs <- err
+				return
+			}
+			leases <- lease
+		}()
+	}
+	wg.Wait()
+	close(leases)
+	close(errs)
+
+	if len(leases) != 1 {
+		t.Fatalf("expected exactly one winner got %d", len(leases))
+	}
+	lease := <-leases
+	if lease.Field() != 1023 {
+		t.Errorf("expected field 1023 got %d", lease.Field())
+	}
+	for err := range errs {
+		if err != etcdalloc.ErrNoFreeField {
+			t.Errorf("expected error %v got %v", etcdalloc.ErrNoFreeField, err)
+		}
+	}
+
+	// releasing the field hands it to the next claimant
+	if err := lease.Close(); err != nil {
+		t.Fatal(err)
+	}
+	next, err := etcdalloc.Acquire(ctx, cli, "/fields/", 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer next.Close()
+	if next.Field() != 1023 {
+		t.Errorf("expected the released field 1023 got %d", next.Field())
+	}
+}
+
+func TestLease_Lost_Etcd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts an embedded etcd")
+	}
+
+	ctx := context.Background()
+	cli := startEtcd(t)
+
+	lease, err := etcdalloc.Acquire(ctx, cli, "/fields/", 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	// an operator revoking every lease
+	leases, err := cli.Leases(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range leases.Leases {
+		if _, err := cli.Revoke(ctx, l.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case <-lease.Done():
+	case <-time.After(15 * time.Second):
+		t.Fatal("expected the lease to be lost")
+	}
+
+	if lease.Err() != etcdalloc.ErrLeaseLost {
+		t.Errorf("expected error %v got %v", etcdalloc.ErrLeaseLost, lease.Err())
+	}
+}
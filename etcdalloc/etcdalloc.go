@@ -0,0 +1,215 @@
+// Package etcdalloc leases snowflake field values from etcd, so that every
+// member of an elastic fleet gets a distinct machine ID.
+//
+//	lease, err := etcdalloc.Acquire(ctx, cli, "/snowflake/fields/", 10*time.Second)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer lease.Close()
+//
+//	sf := snowflake.New(lease.Field())
+//
+//	go func() {
+//		<-lease.Done() // the field may now be handed to someone else
+//		log.Fatal("lost snowflake field lease: ", lease.Err())
+//	}()
+package etcdalloc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+var (
+	// ErrNoFreeField is returned when every field value is leased. It wraps
+	// snowflake.ErrNoFreeField.
+	ErrNoFreeField = fmt.Errorf("etcdalloc: %w", snowflake.ErrNoFreeField)
+	// ErrLeaseLost is reported by Lease.Err when the etcd lease expired or
+	// was revoked before it could be kept alive.
+	ErrLeaseLost = errors.New("etcdalloc: lease lost")
+)
+
+// Client is the subset of *clientv3.Client used by Acquire.
+type Client interface {
+	Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error)
+	Txn(ctx context.Context) clientv3.Txn
+	Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error)
+	KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error)
+	Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error)
+}
+
+// Lease is a field value held in etcd under a lease. The lease is kept
+// alive in the background until Close is called or it is lost.
+type Lease struct {
+	client  Client
+	id      clientv3.LeaseID
+	field   uint64
+	timeout time.Duration
+
+	done      chan struct{}
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	mtx sync.Mutex
+	err error
+}
+
+// Acquire claims the lowest free field value under prefix (keys are
+// prefix0 to prefix1023), binding the key to a new etcd lease of ttl, and
+// starts keeping it alive. ttl is rounded up to whole seconds.
+// Returns ErrNoFreeField when every field value is taken.
+func Acquire(ctx context.Context, client Client, prefix string, ttl time.Duration) (*Lease, error) {
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	grant, err := client.Grant(ctx, seconds)
+	if err != nil {
+		return nil, err
+	}
+
+	field, err := claim(ctx, client, prefix, grant.ID)
+	if err != nil {
+		client.Revoke(context.Background(), grant.ID)
+		return nil, err
+	}
+
+	keepAliveCtx, cancel := context.WithCancel(context.Background())
+	responses, err := client.KeepAlive(keepAliveCtx, grant.ID)
+	if err != nil {
+		cancel()
+		client.Revoke(context.Background(), grant.ID)
+		return nil, err
+	}
+
+	l := &Lease{
+		client:  client,
+		id:      grant.ID,
+		field:   field,
+		timeout: time.Duration(seconds) * time.Second,
+		done:    make(chan struct{}),
+		cancel:  cancel,
+	}
+
+	l.wg.Add(1)
+	go l.keepAlive(keepAliveCtx, responses)
+
+	return l, nil
+}
+
+// Allocator is a snowflake.FieldAllocator acquiring leases under Prefix
+// with Acquire, for snowflake.NewLeased.
+//
+//	sf, err := snowflake.NewLeased(ctx, etcdalloc.Allocator{Client: cli, Prefix: "/snowflake/fields/", TTL: 10 * time.Second})
+type Allocator struct {
+	Client Client
+	Prefix string
+	TTL    time.Duration
+}
+
+var _ snowflake.FieldAllocator = Allocator{}
+
+// Acquire is like the package-level Acquire.
+func (a Allocator) Acquire(ctx context.Context) (snowflake.Lease, error) {
+	l, err := Acquire(ctx, a.Client, a.Prefix, a.TTL)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Field returns the leased field value.
+func (l *Lease) Field() uint64 { return l.field }
+
+// Done is closed when the lease is lost, after which another process may
+// be handed the same field value. It is not closed by Close.
+func (l *Lease) Done() <-chan struct{} { return l.done }
+
+// Err returns why the lease was lost, or nil while it is held.
+func (l *Lease) Err() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.err
+}
+
+// Close stops keeping the lease alive and revokes it, which deletes the
+// field key.
+func (l *Lease) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		l.cancel()
+		l.wg.Wait()
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+		defer cancel()
+		_, err = l.client.Revoke(ctx, l.id)
+	})
+	return err
+}
+
+// keepAlive drains the keep-alive responses. The channel is closed by the
+// client when the lease can no longer be kept alive, or after Close.
+// (internal-use only)
+func (l *Lease) keepAlive(ctx context.Context, responses <-chan *clientv3.LeaseKeepAliveResponse) {
+	defer l.wg.Done()
+
+	for range responses {
+	}
+
+	if ctx.Err() == nil {
+		l.mtx.Lock()
+		l.err = ErrLeaseLost
+		l.mtx.Unlock()
+		close(l.done)
+	}
+}
+
+// claim creates the first free field key bound to lease. Each key is
+// created in a transaction that only succeeds if it does not exist yet, so
+// two claimants can never hold the same field. The key holds the lease ID
+// in hex, as printed by etcdctl. (internal-use only)
+func claim(ctx context.Context, client Client, prefix string, lease clientv3.LeaseID) (uint64, error) {
+	taken, err := client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return 0, err
+	}
+
+	used := make(map[uint64]bool, len(taken.Kvs))
+	for _, kv := range taken.Kvs {
+		field, err := strconv.ParseUint(strings.TrimPrefix(string(kv.Key), prefix), 10, 64)
+		if err == nil {
+			used[field] = true
+		}
+	}
+
+	holder := strconv.FormatInt(int64(lease), 16)
+	for field := uint64(0); field <= snowflake.MaxField(); field++ {
+		if used[field] {
+			continue
+		}
+
+		key := prefix + strconv.FormatUint(field, 10)
+		resp, err := client.Txn(ctx).
+			If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+			Then(clientv3.OpPut(key, holder, clientv3.WithLease(lease))).
+			Commit()
+		if err != nil {
+			return 0, err
+		}
+		if resp.Succeeded {
+			return field, nil
+		}
+	}
+
+	return 0, ErrNoFreeField
+}
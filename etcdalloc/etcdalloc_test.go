@@ -0,0 +1,274 @@
+package etcdalloc_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/etcdalloc"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeClient is an in-memory etcd holding keys bound to leases.
+type fakeClient struct {
+	mtx    sync.Mutex
+	keys   map[string]clientv3.LeaseID
+	leases map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse
+	nextID clientv3.LeaseID
+
+	// staleGets makes Get report no keys, so that claimants only find out
+	// about each other through the transaction.
+	staleGets bool
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		keys:   make(map[string]clientv3.LeaseID),
+		leases: make(map[clientv3.LeaseID]chan *clientv3.LeaseKeepAliveResponse),
+	}
+}
+
+func (c *fakeClient) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	resp := &clientv3.GetResponse{}
+	if c.staleGets {
+		return resp, nil
+	}
+	for k := range c.keys {
+		if strings.HasPrefix(k, key) {
+			resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(k)})
+		}
+	}
+	return resp, nil
+}
+
+func (c *fakeClient) Txn(ctx context.Context) clientv3.Txn { return &fakeTxn{c: c} }
+
+func (c *fakeClient) Grant(ctx context.Context, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.nextID++
+	c.leases[c.nextID] = make(chan *clientv3.LeaseKeepAliveResponse)
+	return &clientv3.LeaseGrantResponse{ID: c.nextID, TTL: ttl}, nil
+}
+
+func (c *fakeClient) KeepAlive(ctx context.Context, id clientv3.LeaseID) (<-chan *clientv3.LeaseKeepAliveResponse, error) {
+	c.mtx.Lock()
+	ch := c.leases[id]
+	c.mtx.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		c.expire(id)
+	}()
+	return ch, nil
+}
+
+func (c *fakeClient) Revoke(ctx context.Context, id clientv3.LeaseID) (*clientv3.LeaseRevokeResponse, error) {
+	c.expire(id)
+	return &clientv3.LeaseRevokeResponse{}, nil
+}
+
+// expire drops the lease and every key bound to it.
+func (c *fakeClient) expire(id clientv3.LeaseID) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if ch, ok := c.leases[id]; ok {
+		close(ch)
+		delete(c.leases, id)
+	}
+	for k, lease := range c.keys {
+		if lease == id {
+			delete(c.keys, k)
+		}
+	}
+}
+
+func (c *fakeClient) has(key string) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	_, ok := c.keys[key]
+	return ok
+}
+
+// fakeTxn only understands the create-if-absent transaction used by Acquire.
+type fakeTxn struct {
+	c   *fakeClient
+	ops []clientv3.Op
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn   { return t }
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn { t.ops = ops; return t }
+func (t *fakeTxn) Else(ops ...clientv3.Op) clientv3.Txn { return t }
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	t.c.mtx.Lock()
+	defer t.c.mtx.Unlock()
+
+	key := string(t.ops[0].KeyBytes())
+	if _, ok := t.c.keys[key]; ok {
+		return &clientv3.TxnResponse{Succeeded: false}, nil
+	}
+	id, err := strconv.ParseInt(string(t.ops[0].ValueBytes()), 16, 64)
+	if err != nil {
+		return nil, err
+	}
+	t.c.keys[key] = clientv3.LeaseID(id)
+	return &clientv3.TxnResponse{Succeeded: true}, nil
+}
+
+func TestAcquire(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient()
+
+	first, err := etcdalloc.Acquire(ctx, c, "/fields/", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := etcdalloc.Acquire(ctx, c, "/fields/", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Field() != 0 || second.Field() != 1 {
+		t.Errorf("expected fields 0 and 1 got %d and %d", first.Field(), second.Field())
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c.has("/fields/0") {
+		t.Error("expected Close to release the field")
+	}
+	select {
+	case <-first.Done():
+		t.Error("expected Done not to be closed by Close")
+	default:
+	}
+
+	second.Close()
+}
+
+func TestAcquire_NoFreeField(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient()
+
+	for i := 0; i < 1024; i++ {
+		if _, err := etcdalloc.Acquire(ctx, c, "/fields/", time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := etcdalloc.Acquire(ctx, c, "/fields/", time.Second)
+	if err != etcdalloc.ErrNoFreeField {
+		t.Errorf("expected error %v got %v", etcdalloc.ErrNoFreeField, err)
+	}
+	if !errors.Is(err, snowflake.ErrNoFreeField) {
+		t.Errorf("expected error %v to wrap %v", err, snowflake.ErrNoFreeField)
+	}
+
+	c.mtx.Lock()
+	leases := len(c.leases)
+	c.mtx.Unlock()
+	if leases != 1024 {
+		t.Errorf("expected the failed claim to revoke its lease got %d leases", leases)
+	}
+}
+
+func TestAcquire_ConcurrentClaimants(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient()
+	c.staleGets = true
+
+	var wg sync.WaitGroup
+	leases := make([]*etcdalloc.Lease, 16)
+	for i := range leases {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lease, err := etcdalloc.Acquire(ctx, c, "/fields/", time.Second)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			leases[i] = lease
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[uint64]bool)
+	for _, lease := range leases {
+		if lease == nil {
+			continue
+		}
+		if seen[lease.Field()] {
+			t.Errorf("expected unique fields got %d twice", lease.Field())
+		}
+		seen[lease.Field()] = true
+		lease.Close()
+	}
+}
+
+func TestLease_Lost(t *testing.T) {
+	ctx := context.Background()
+	c := newFakeClient()
+
+	lease, err := etcdalloc.Acquire(ctx, c, "/fields/", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	c.mtx.Lock()
+	id := c.nextID
+	c.mtx.Unlock()
+	c.expire(id)
+
+	select {
+	case <-lease.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the lease to be lost")
+	}
+
+	if lease.Err() != etcdalloc.ErrLeaseLost {
+		t.Errorf("expected error %v got %v", etcdalloc.ErrLeaseLost, lease.Err())
+	}
+}
+
+func TestAllocator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := newFakeClient()
+
+	sf, err := snowflake.NewLeased(ctx, etcdalloc.Allocator{Client: c, Prefix: "/fields/", TTL: time.Second}, snowflake.WithLeasePolicy(snowflake.ReacquireOnLeaseLoss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field := snowflake.Parse(sf.NextID()).MachineID; field != 0 {
+		t.Fatalf("expected field 0 got %d", field)
+	}
+
+	c.mtx.Lock()
+	id := c.nextID
+	c.mtx.Unlock()
+	c.expire(id)
+
+	// the generator carries on with the next lease
+	deadline := time.Now().Add(5 * time.Second)
+	for snowflake.Parse(sf.NextID()).MachineID != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected field 0 to be leased once field 0 was lost")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -2,21 +2,27 @@ package main
 
 import (
 	"fmt"
+	"log"
 
 	"github.com/HotPotatoC/snowflake"
 )
 
 func main() {
-	machineID := uint64(1)
-	processID := uint64(24)
-	sf := snowflake.New2(machineID, processID)
+	datacenterID := uint64(24)
+	workerID := uint64(1)
+	sf, err := snowflake.NewDatacenterWorker(datacenterID, workerID)
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	id := sf.NextID()
 	fmt.Println(id)
 	// 1292062458947571712
 
-	// or
-	id = snowflake.New2(machineID, processID).NextID()
-	fmt.Println(id)
-	// 1292062458947571712
-}
\ No newline at end of file
+	// or use the process ID (masked into 5 bits) as the 2nd field
+	sf, err = snowflake.New2WithPID(workerID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(sf.NextID())
+}
@@ -7,10 +7,10 @@ import (
 )
 
 func main() {
-	parsed := snowflake.Parse2(1292065108376162304)
+	parsed := snowflake.ParseDatacenterWorker(1292065108376162304)
 
-	fmt.Printf("Timestamp: %d\n", parsed.Timestamp)       // 1640945127245
-	fmt.Printf("Sequence: %d\n", parsed.Sequence)         // 0
-	fmt.Printf("Machine ID: %d\n", parsed.Field1) // 1
-	fmt.Printf("Process ID: %d\n", parsed.Field2) // 24
+	fmt.Printf("Timestamp: %d\n", parsed.Timestamp)        // 1640945127245
+	fmt.Printf("Sequence: %d\n", parsed.Sequence)          // 0
+	fmt.Printf("Datacenter ID: %d\n", parsed.DatacenterID) // 24
+	fmt.Printf("Worker ID: %d\n", parsed.WorkerID)         // 1
 }
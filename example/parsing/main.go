@@ -11,5 +11,5 @@ func main() {
 
 	fmt.Printf("Timestamp: %d\n", parsed.Timestamp)      // 1640942460724
 	fmt.Printf("Sequence: %d\n", parsed.Sequence)        // 0
-	fmt.Printf("Machine ID: %d\n", parsed.Field) // 1
+	fmt.Printf("Machine ID: %d\n", parsed.MachineID) // 1
 }
@@ -0,0 +1,71 @@
+package snowflake
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"time"
+)
+
+const (
+	// fallbackMask is the marker of random fallback IDs: all the field
+	// bits set, and the highest 4 bits of the timestamp, which real IDs
+	// only reach after 65 of the 69 years of the epoch. (internal-use only)
+	fallbackMask = 0xF<<59 | maxFieldBits<<sequenceBits
+	// fallbackRandomBits are the bits of random fallback IDs drawn from
+	// crypto/rand: the rest of the timestamp and the sequence.
+	// (internal-use only)
+	fallbackRandomBits = (1<<63 - 1) &^ fallbackMask
+	// fallbackRegression is how far behind its previous reading the clock
+	// must be for a generator to fall back to random IDs rather than wait
+	// for it. (internal-use only)
+	fallbackRegression = time.Second
+)
+
+// WithRandomFallback makes the generator issue random IDs rather than
+// stop serving while its clock is unusable: before the epoch, too late for
+// timestamps to fit their 41 bits, or over a second behind its previous
+// reading (the generator would otherwise wait for it to catch up once the
+// sequence of the last millisecond runs out).
+//
+// Random IDs have a marker in the field bits and 49 bits from crypto/rand,
+// so they're unique but unsorted, and Parse reports them as Fallback.
+// They're counted in Stats and reported to the logger set with WithLogger.
+func WithRandomFallback() Option {
+	return func(g *generator) {
+		g.randomFallback = true
+	}
+}
+
+// IsFallback reports whether id is a random ID issued by a generator with
+// WithRandomFallback while its clock was unusable.
+func IsFallback(id uint64) bool { return id&fallbackMask == fallbackMask }
+
+// unusableClock returns why the clock reading now, in milliseconds since
+// the epoch, is unusable, or "" if it's usable. g.mtx must be held.
+// (internal-use only)
+func (g *generator) unusableClock(now int64) string {
+	switch {
+	case now < 0:
+		return "before the epoch"
+	case now > maxTimestamp:
+		return "timestamp overflow"
+	case g.lastNow-now > fallbackRegression.Milliseconds():
+		return "clock regression"
+	}
+	return ""
+}
+
+// fallbackID returns a random ID marked as a fallback, because the clock
+// is unusable for reason. g.mtx must be held. (internal-use only)
+func (g *generator) fallbackID(reason string) uint64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("snowflake: reading crypto/rand: " + err.Error())
+	}
+
+	g.counters.IDs++
+	g.counters.Fallbacks++
+	g.report(anomalyFallback, "snowflake: clock unusable, issuing a random ID", g.fieldSegment>>sequenceBits,
+		"reason", reason)
+	return fallbackMask | binary.LittleEndian.Uint64(b[:])&fallbackRandomBits
+}
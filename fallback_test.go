@@ -0,0 +1,80 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestWithRandomFallback(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+
+	tc := []struct {
+		name  string
+		clock func(*fakeClock)
+	}{
+		{"Should fall back before the epoch", func(c *fakeClock) { c.Set(snowflake.Epoch().Add(-time.Millisecond)) }},
+		{"Should fall back on timestamp overflow", func(c *fakeClock) { c.Set(snowflake.Epoch().Add((1 << 41) * time.Millisecond)) }},
+		{"Should fall back on a clock regression", func(c *fakeClock) { c.Add(-time.Second - time.Millisecond) }},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock(start)
+			sf := snowflake.New(5, snowflake.WithClock(clock), snowflake.WithRandomFallback())
+			issuer := snowflake.NewBlockIssuer(6, snowflake.WithClock(clock), snowflake.WithRandomFallback())
+			last := sf.NextID()
+			issuer.Issue(1)
+
+			tt.clock(clock)
+			n := 10000
+			seen := make(map[uint64]bool)
+			for _, id := range sf.AppendIDs(nil, n) {
+				if !snowflake.IsFallback(id) || !snowflake.Parse(id).Fallback || id>>63 != 0 {
+					t.Fatalf("expected a fallback ID got %064b", id)
+				}
+				if seen[id] {
+					t.Fatalf("expected unique IDs, got %d twice", id)
+				}
+				seen[id] = true
+			}
+			if block := issuer.Issue(100); block.Count != 1 || !snowflake.IsFallback(block.FirstID) {
+				t.Errorf("expected a block of a fallback ID got %+v", block)
+			}
+
+			if stats := sf.Stats(); stats.Fallbacks != uint64(n) || stats.IDs != uint64(n)+1 {
+				t.Errorf("expected %d fallbacks of %d IDs got %d of %d", n, n+1, stats.Fallbacks, stats.IDs)
+			}
+
+			// back to sorted IDs once the clock is usable
+			clock.Set(start)
+			if id := sf.NextID(); snowflake.IsFallback(id) || id <= last {
+				t.Errorf("expected an ID bigger than %d got %d", last, id)
+			}
+			if block := issuer.Issue(100); block.Count != 100 || snowflake.IsFallback(block.FirstID) {
+				t.Errorf("expected a block of 100 IDs got %+v", block)
+			}
+		})
+	}
+}
+
+func TestWithRandomFallback_SmallRegression(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start)
+	sf := snowflake.New(5, snowflake.WithClock(clock), snowflake.WithRandomFallback())
+	sf.NextID()
+
+	// a regression the generator can ride out isn't a fallback
+	clock.Add(-time.Second)
+	if id := sf.NextID(); snowflake.IsFallback(id) {
+		t.Errorf("expected a regular ID got %d", id)
+	}
+}
+
+func TestWithRandomFallback_Disabled(t *testing.T) {
+	sf := snowflake.New(5, snowflake.WithClock(newFakeClock(snowflake.Epoch().Add((1<<41)*time.Millisecond))))
+	if sf.NextID(); sf.Stats().Fallbacks != 0 {
+		t.Errorf("expected no fallbacks got %d", sf.Stats().Fallbacks)
+	}
+}
@@ -0,0 +1,64 @@
+// Package filelock allocates snowflake field values to processes sharing a
+// host, using one lock file per field value.
+//
+//	field, release, err := filelock.AcquireLocalField("/var/run/snowflake")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer release()
+//
+//	sf := snowflake.New(field)
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+var (
+	// ErrNoFreeField is returned when every field value is locked. It wraps
+	// snowflake.ErrNoFreeField.
+	ErrNoFreeField = fmt.Errorf("filelock: %w", snowflake.ErrNoFreeField)
+	// ErrUnsupported is returned on platforms without flock.
+	ErrUnsupported = errors.New("filelock: not supported on this platform")
+)
+
+// AcquireLocalField locks the first free field value in dir, creating the
+// directory if needed. Lock files are named 0.lock to 1023.lock.
+//
+// The lock is held until release is called, which unlocks and removes the
+// lock file, or until the process exits: the operating system drops the
+// lock of a dead process, so its field value can be reused right away.
+func AcquireLocalField(dir string) (field uint64, release func(), err error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, nil, err
+	}
+
+	for field = 0; field <= snowflake.MaxField(); field++ {
+		path := filepath.Join(dir, fmt.Sprintf("%d.lock", field))
+
+		f, ok, err := tryLock(path)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		release = func() {
+			// remove before unlocking so nobody can lock a file that's
+			// about to disappear
+			os.Remove(path)
+			unlock(f)
+			f.Close()
+		}
+
+		return field, release, nil
+	}
+
+	return 0, nil, ErrNoFreeField
+}
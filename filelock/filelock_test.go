@@ -0,0 +1,226 @@
+package filelock_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/filelock"
+)
+
+// When set, the test binary acts as a separate process that acquires
+// a field, prints it and waits for stdin to close, then exits releasing
+// the field if FILELOCK_TEST_CHILD_RELEASE is 1, or without releasing.
+const childEnv = "FILELOCK_TEST_CHILD_DIR"
+
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(childEnv); dir != "" {
+		field, release, err := filelock.AcquireLocalField(dir)
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		fmt.Println(field)
+
+		// hold the lock until the parent closes stdin
+		bufio.NewReader(os.Stdin).ReadString('\n')
+		if os.Getenv("FILELOCK_TEST_CHILD_RELEASE") == "1" {
+			release()
+		}
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestAcquireLocalField(t *testing.T) {
+	dir := t.TempDir()
+
+	first, release1, err := filelock.AcquireLocalField(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, release2, err := filelock.AcquireLocalField(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != 0 || second != 1 {
+		t.Errorf("expected fields 0 and 1 got %d and %d", first, second)
+	}
+
+	release1()
+	if _, err := os.Stat(fmt.Sprintf("%s/0.lock", dir)); !os.IsNotExist(err) {
+		t.Errorf("expected release to remove the lock file got %v", err)
+	}
+
+	// released fields are handed out again
+	third, release3, err := filelock.AcquireLocalField(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third != 0 {
+		t.Errorf("expected field 0 to be reused got %d", third)
+	}
+
+	release2()
+	release3()
+}
+
+func TestAcquireLocalField_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	n := 64
+
+	var mtx sync.Mutex
+	held := make(map[uint64]bool)
+	var releases []func()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			field, release, err := filelock.AcquireLocalField(dir)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if held[field] {
+				t.Errorf("expected field %d to be acquired once", field)
+			}
+			held[field] = true
+			releases = append(releases, release)
+		}()
+	}
+	wg.Wait()
+
+	if len(held) != n {
+		t.Errorf("expected %d distinct fields got %d", n, len(held))
+	}
+	for _, release := range releases {
+		release()
+	}
+}
+
+func TestAcquireLocalField_NoFreeField(t *testing.T) {
+	dir := t.TempDir()
+
+	for i := uint64(0); i <= snowflake.MaxField(); i++ {
+		_, release, err := filelock.AcquireLocalField(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer release()
+	}
+
+	_, _, err := filelock.AcquireLocalField(dir)
+	if err != filelock.ErrNoFreeField {
+		t.Errorf("expected error %v got %v", filelock.ErrNoFreeField, err)
+	}
+	if !errors.Is(err, snowflake.ErrNoFreeField) {
+		t.Errorf("expected error %v to wrap %v", err, snowflake.ErrNoFreeField)
+	}
+}
+
+// startChild runs a process that acquires a field in dir and returns the
+// field along with a function that makes the child exit.
+func startChild(t *testing.T, dir string, release bool) (uint64, func()) {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), childEnv+"="+dir)
+	if release {
+		cmd.Env = append(cmd.Env, "FILELOCK_TEST_CHILD_RELEASE=1")
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	field, err := strconv.ParseUint(strings.TrimSpace(line), 10, 64)
+	if err != nil {
+		t.Fatalf("unexpected child output %q", line)
+	}
+
+	return field, func() {
+		stdin.Close()
+		cmd.Wait()
+	}
+}
+
+func TestAcquireLocalField_Subprocess(t *testing.T) {
+	dir := t.TempDir()
+
+	mine, release, err := filelock.AcquireLocalField(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	theirs, stop := startChild(t, dir, false)
+	if theirs == mine {
+		t.Errorf("expected the child to get a different field than %d", mine)
+	}
+
+	// the child dies without releasing, its lock goes with it
+	stop()
+
+	reclaimed, releaseReclaimed, err := filelock.AcquireLocalField(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseReclaimed()
+	if reclaimed != theirs {
+		t.Errorf("expected the stale field %d to be reclaimed got %d", theirs, reclaimed)
+	}
+}
+
+func TestAcquireLocalField_SubprocessRelease(t *testing.T) {
+	dir := t.TempDir()
+
+	theirs, stop := startChild(t, dir, true)
+	mine, release, err := filelock.AcquireLocalField(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if mine == theirs {
+		t.Errorf("expected a different field than the child's %d", theirs)
+	}
+
+	// the child releases before exiting, removing its lock file
+	stop()
+	if _, err := os.Stat(fmt.Sprintf("%s/%d.lock", dir, theirs)); !os.IsNotExist(err) {
+		t.Errorf("expected the child to remove its lock file got %v", err)
+	}
+
+	next, releaseNext, err := filelock.AcquireLocalField(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseNext()
+	if next != theirs {
+		t.Errorf("expected the released field %d got %d", theirs, next)
+	}
+}
@@ -0,0 +1,59 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package filelock
+
+import (
+	"os"
+	"syscall"
+)
+
+// tryLock opens path and takes an exclusive lock on it without blocking.
+// ok is false when another process (or goroutine) holds the lock.
+// (internal-use only)
+func tryLock(path string) (f *os.File, ok bool, err error) {
+	for {
+		f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, false, err
+		}
+
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == syscall.EWOULDBLOCK {
+			f.Close()
+			return nil, false, nil
+		}
+		if err != nil {
+			f.Close()
+			return nil, false, err
+		}
+
+		// the holder may have removed the file between our open and lock,
+		// in which case we locked a file nobody else can see: start over
+		if sameFile(f, path) {
+			return f, true, nil
+		}
+		unlock(f)
+		f.Close()
+	}
+}
+
+// unlock releases the lock on f. (internal-use only)
+func unlock(f *os.File) {
+	syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
+
+// sameFile reports whether f is still the file at path. (internal-use only)
+func sameFile(f *os.File, path string) bool {
+	opened, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return os.SameFile(opened, current)
+}
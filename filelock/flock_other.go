@@ -0,0 +1,14 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package filelock
+
+import "os"
+
+// tryLock always fails with ErrUnsupported. (internal-use only)
+func tryLock(path string) (f *os.File, ok bool, err error) {
+	return nil, false, ErrUnsupported
+}
+
+// unlock does nothing. (internal-use only)
+func unlock(f *os.File) {}
@@ -0,0 +1,66 @@
+package snowflake
+
+// WithFieldFolding makes New fold a machine ID bigger than the max into
+// range rather than reset it to 0, for machine IDs assigned by something
+// else, such as the arbitrary worker numbers of an orchestrator. Machine
+// IDs in range are kept as they are. RawField returns the machine ID given
+// to New.
+//
+// The folded machine ID is the highest 10 bits (8 with WithVersionTag) of
+// the MurmurHash3 64-bit finalizer of the machine ID, so that sequential
+// machine IDs spread over the whole range rather than wrapping around as
+// with a modulo, and the same machine ID always folds the same way, across
+// processes and versions of this package. Folding can't tell 2^64 values
+// apart in 10 bits though: two machine IDs may fold into the same one, or
+// into a machine ID in range used by another generator, and then issue the
+// same IDs. Check the folded machine IDs of a fleet for collisions, e.g.
+// with FoldField.
+func WithFieldFolding() Option {
+	return func(g *generator) {
+		g.foldFields = true
+	}
+}
+
+// FoldField returns the machine ID that WithFieldFolding folds machineID
+// into, for untagged generators.
+func FoldField(machineID uint64) uint64 {
+	if machineID <= maxFieldBits {
+		return machineID
+	}
+	return fold(machineID, fieldBits)
+}
+
+// fold returns the highest bits of the MurmurHash3 64-bit finalizer of x.
+// (internal-use only)
+func fold(x uint64, bits uint) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x >> (64 - bits)
+}
+
+// foldField folds the machine ID of g into range if it doesn't fit the
+// layout, keeping the version tag if any. (internal-use only)
+func (g *generator) foldField(machineID uint64) {
+	if g.versionTag == 0 {
+		if machineID > maxFieldBits {
+			g.fieldSegment = fold(machineID, fieldBits) << sequenceBits
+		}
+		return
+	}
+
+	if machineID <= maxTaggedField || machineID <= maxFieldBits && machineID>>(fieldBits-2) == g.versionTag {
+		return
+	}
+	g.fieldSegment = fold(machineID, fieldBits-2)<<sequenceBits | g.versionTag<<versionShift
+}
+
+// RawField returns the machine ID given to New, or SetField, before
+// WithFieldFolding folded it, if it did.
+func (id *ID) RawField() uint64 {
+	id.mtx.Lock()
+	defer id.mtx.Unlock()
+	return id.field
+}
@@ -0,0 +1,68 @@
+package snowflake_test
+
+import (
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestWithFieldFolding(t *testing.T) {
+	tc := []struct {
+		name           string
+		raw, machineID uint64
+		opts           []snowflake.Option
+	}{
+		// pinned: the folding must never change across versions
+		{"Should fold 1024", 1024, 845, nil},
+		{"Should spread sequential machine IDs", 1025, 942, nil},
+		{"Should spread sequential machine IDs again", 1026, 21, nil},
+		{"Should fold 2^32", 1 << 32, 744, nil},
+		{"Should fold the max uint64", 1<<64 - 1, 402, nil},
+		{"Should keep a machine ID in range", 1023, 1023, nil},
+		{"Should keep the version tag", 1024, 1<<8 | 211, []snowflake.Option{snowflake.WithVersionTag(1)}},
+		{"Should fold a machine ID out of a tagged range", 2<<8 | 5, 1<<8 | 41, []snowflake.Option{snowflake.WithVersionTag(1)}},
+		{"Should keep a machine ID holding the version tag", 1<<8 | 44, 1<<8 | 44, []snowflake.Option{snowflake.WithVersionTag(1)}},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := snowflake.New(tt.raw, append(tt.opts, snowflake.WithFieldFolding())...)
+			if machineID := snowflake.Parse(sf.NextID()).MachineID; machineID != tt.machineID {
+				t.Errorf("expected machine ID %d got %d", tt.machineID, machineID)
+			}
+			if raw := sf.RawField(); raw != tt.raw {
+				t.Errorf("expected raw field %d got %d", tt.raw, raw)
+			}
+		})
+	}
+}
+
+func TestWithFieldFolding_Deterministic(t *testing.T) {
+	seen := make(map[uint64]int)
+	for raw := uint64(1 << 20); raw < 1<<20+4096; raw++ {
+		a := snowflake.New(raw, snowflake.WithFieldFolding())
+		b := snowflake.New(raw, snowflake.WithFieldFolding())
+		fa, fb := snowflake.Parse(a.NextID()).MachineID, snowflake.Parse(b.NextID()).MachineID
+		if fa != fb || fa != snowflake.FoldField(raw) {
+			t.Fatalf("expected %d to fold the same way got %d, %d and %d", raw, fa, fb, snowflake.FoldField(raw))
+		}
+		seen[fa]++
+	}
+
+	// 4096 sequential machine IDs cover most of the range, 4 each on
+	// average, where a modulo would cover it exactly
+	if len(seen) < 900 {
+		t.Errorf("expected sequential machine IDs to spread got %d distinct foldings", len(seen))
+	}
+}
+
+func TestNew_FieldOutOfRange(t *testing.T) {
+	// without folding, a machine ID out of range is reset to 0
+	sf := snowflake.New(5000)
+	if machineID := snowflake.Parse(sf.NextID()).MachineID; machineID != 0 {
+		t.Errorf("expected machine ID 0 got %d", machineID)
+	}
+	if raw := sf.RawField(); raw != 5000 {
+		t.Errorf("expected raw field 5000 got %d", raw)
+	}
+}
@@ -0,0 +1,17 @@
+package snowflake
+
+// Generator is implemented by anything that issues snowflake IDs,
+// such as *ID, *ID2 and *ShardedGenerator.
+type Generator interface {
+	NextID() uint64
+}
+
+var (
+	_ Generator = (*ID)(nil)
+	_ Generator = (*ID2)(nil)
+	_ Generator = (*ShardedGenerator)(nil)
+	_ Generator = (*Buffered)(nil)
+	_ Generator = (*ActorGen)(nil)
+	_ Generator = (*UnsafeID)(nil)
+	_ Generator = GeneratorFunc(nil)
+)
@@ -3,6 +3,6 @@ module github.com/HotPotatoC/snowflake
 go 1.17
 
 require (
-	github.com/bwmarrin/snowflake v0.3.0 // indirect
-	github.com/godruoyi/go-snowflake v0.0.1 // indirect
+	github.com/bwmarrin/snowflake v0.3.0
+	github.com/godruoyi/go-snowflake v0.0.1
 )
@@ -0,0 +1,18 @@
+module github.com/HotPotatoC/snowflake/gormtype
+
+go 1.18
+
+require (
+	github.com/HotPotatoC/snowflake v0.0.0-00010101000000-000000000000
+	gorm.io/driver/sqlite v1.5.6
+	gorm.io/gorm v1.25.12
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/mattn/go-sqlite3 v1.14.22 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/HotPotatoC/snowflake => ../
@@ -0,0 +1,118 @@
+// Package gormtype provides a snowflake ID type for GORM models, filled
+// in on create by a registered callback.
+//
+//	type Order struct {
+//		ID    gormtype.ID
+//		Total int
+//	}
+//
+//	db, err := gorm.Open(sqlite.Open("orders.db"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	if err := gormtype.Register(db, snowflake.New(1)); err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	db.Create(&Order{Total: 42}) // ID is set to a new snowflake ID
+package gormtype
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/HotPotatoC/snowflake"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// callbackName is the name of the create callback registered by Register.
+const callbackName = "snowflake:assign_id"
+
+// ID is a snowflake ID stored as a BIGINT column.
+type ID uint64
+
+// idType is the reflect.Type of ID. (internal-use only)
+var idType = reflect.TypeOf(ID(0))
+
+// GormDataType maps ID columns to BIGINT.
+func (ID) GormDataType() string { return "bigint" }
+
+// Value implements driver.Valuer. Snowflake IDs fit in 63 bits, so values
+// beyond math.MaxInt64 are rejected rather than stored negative.
+func (id ID) Value() (driver.Value, error) {
+	if uint64(id) > math.MaxInt64 {
+		return nil, fmt.Errorf("gormtype: ID %d overflows BIGINT", uint64(id))
+	}
+	return int64(id), nil
+}
+
+// Scan implements sql.Scanner.
+func (id *ID) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		if v < 0 {
+			return fmt.Errorf("gormtype: cannot scan negative %d into ID", v)
+		}
+		*id = ID(v)
+	case []byte:
+		return id.Scan(string(v))
+	case string:
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("gormtype: cannot scan %q into ID", v)
+		}
+		*id = ID(n)
+	default:
+		return fmt.Errorf("gormtype: cannot scan %T into ID", src)
+	}
+	return nil
+}
+
+// Register adds a callback to db that sets zero ID primary keys to the
+// next ID of g before records are created. Preset IDs are kept, and ID
+// fields that aren't primary keys, such as foreign keys, are left alone.
+func Register(db *gorm.DB, g snowflake.Generator) error {
+	return db.Callback().Create().Before("gorm:create").Register(callbackName, assignIDs(g))
+}
+
+// assignIDs returns the create callback of Register. (internal-use only)
+func assignIDs(g snowflake.Generator) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Error != nil || db.Statement.Schema == nil {
+			return
+		}
+
+		var fields []*schema.Field
+		for _, field := range db.Statement.Schema.PrimaryFields {
+			if field.FieldType == idType {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			return
+		}
+
+		assign := func(rv reflect.Value) {
+			for _, field := range fields {
+				if _, zero := field.ValueOf(db.Statement.Context, rv); zero {
+					db.AddError(field.Set(db.Statement.Context, rv, ID(g.NextID())))
+				}
+			}
+		}
+
+		switch rv := db.Statement.ReflectValue; rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < rv.Len(); i++ {
+				if elem := reflect.Indirect(rv.Index(i)); elem.Kind() == reflect.Struct {
+					assign(elem)
+				}
+			}
+		case reflect.Struct:
+			assign(rv)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package gormtype_test
+
+import (
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/gormtype"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type order struct {
+	ID       gormtype.ID
+	ParentID gormtype.ID
+	Total    int
+}
+
+func open(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := gormtype.Register(db, snowflake.New(9)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&order{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestRegister_ZeroID(t *testing.T) {
+	db := open(t)
+
+	o := order{Total: 42}
+	if err := db.Create(&o).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if o.ID == 0 {
+		t.Fatal("expected the ID to be set")
+	}
+	if field := snowflake.Parse(uint64(o.ID)).MachineID; field != 9 {
+		t.Errorf("expected field 9 got %d", field)
+	}
+	if o.ParentID != 0 {
+		t.Errorf("expected non-primary ID fields to be left alone got %d", o.ParentID)
+	}
+
+	var got order
+	if err := db.First(&got, "id = ?", o.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got != o {
+		t.Errorf("expected %+v got %+v", o, got)
+	}
+}
+
+func TestRegister_PresetID(t *testing.T) {
+	db := open(t)
+
+	o := order{ID: 1292053924173320192, ParentID: 7, Total: 1}
+	if err := db.Create(&o).Error; err != nil {
+		t.Fatal(err)
+	}
+	if o.ID != 1292053924173320192 {
+		t.Errorf("expected the preset ID to be kept got %d", o.ID)
+	}
+
+	var got order
+	if err := db.Where("parent_id = ?", 7).First(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got != o {
+		t.Errorf("expected %+v got %+v", o, got)
+	}
+}
+
+func TestRegister_Batch(t *testing.T) {
+	db := open(t)
+
+	orders := []order{{Total: 1}, {ID: 5, Total: 2}, {Total: 3}}
+	if err := db.Create(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if orders[0].ID == 0 || orders[2].ID <= orders[0].ID {
+		t.Errorf("expected increasing IDs got %d and %d", orders[0].ID, orders[2].ID)
+	}
+	if orders[1].ID != 5 {
+		t.Errorf("expected the preset ID to be kept got %d", orders[1].ID)
+	}
+
+	var got []order
+	if err := db.Order("total").Find(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 orders got %d", len(got))
+	}
+	for i := range got {
+		if got[i] != orders[i] {
+			t.Errorf("expected %+v got %+v", orders[i], got[i])
+		}
+	}
+}
+
+func TestID_Scan(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      interface{}
+		expected gormtype.ID
+		err      bool
+	}{
+		{name: "int64", src: int64(1292053924173320192), expected: 1292053924173320192},
+		{name: "[]byte", src: []byte("1292053924173320192"), expected: 1292053924173320192},
+		{name: "string", src: "1292053924173320192", expected: 1292053924173320192},
+		{name: "Should return an error for negative values", src: int64(-1), err: true},
+		{name: "Should return an error for NULL", src: nil, err: true},
+		{name: "Should return an error for non-numeric text", src: "abc", err: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var id gormtype.ID
+			err := id.Scan(tc.src)
+			if (err != nil) != tc.err {
+				t.Fatalf("expected error %v got %v", tc.err, err)
+			}
+			if id != tc.expected {
+				t.Errorf("expected %d got %d", tc.expected, id)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package snowflake
+
+import "time"
+
+// maxGranularityReads bounds how many times measureGranularity reads a
+// clock waiting for it to tick, so a stopped clock can't hang it.
+// (internal-use only)
+const maxGranularityReads = 1 << 24
+
+// WithClockGranularity tells the generator the clock only advances every d,
+// as is the case of the system clock on Windows (about 15.6ms) and of
+// timers in browsers. Every tick of the clock then spans d worth of
+// milliseconds, so once the sequence of a millisecond is exhausted the
+// generator moves on to the next millisecond of the tick rather than
+// waiting for the clock: capacity is 4096 IDs per millisecond of the tick,
+// not per tick, and timestamps lead the clock by less than a tick.
+//
+// d is rounded up to whole milliseconds. The default, or d of 1ms or less,
+// is 1ms, except for the system clock on js and Windows, whose granularity
+// is measured once per process.
+func WithClockGranularity(d time.Duration) Option {
+	return func(g *generator) {
+		g.tick = int64((d + time.Millisecond - 1) / time.Millisecond)
+	}
+}
+
+// SystemClockGranularity returns how often the system clock advances, as
+// assumed by the generators without WithClock or WithClockGranularity:
+// 1ms, except on js and Windows, where it's measured the first time it's
+// needed, e.g. about 15.6ms under the default Windows timer resolution.
+func SystemClockGranularity() time.Duration {
+	return time.Duration(systemTick()) * time.Millisecond
+}
+
+// drift returns how many milliseconds timestamps may lead the clock: the
+// drift allowed with WithMaxForwardDrift, or less than a tick of the
+// clock. (internal-use only)
+func (g *generator) drift() int64 {
+	if g.tick-1 > g.maxDrift {
+		return g.tick - 1
+	}
+	return g.maxDrift
+}
+
+// measureGranularity returns how often now advances, in whole milliseconds
+// rounded up, from the smallest of a few steps it's seen taking. Returns 1
+// if now doesn't advance. (internal-use only)
+func measureGranularity(now func() time.Time) int64 {
+	var step time.Duration
+	prev := now()
+	// the first step starts mid-tick, only count the ones after it
+	for samples := -1; samples < 5; samples++ {
+		reads := 0
+		t := now()
+		for ; t.Equal(prev) && reads < maxGranularityReads; reads++ {
+			t = now()
+		}
+		if reads == maxGranularityReads {
+			break
+		}
+		if d := t.Sub(prev); samples >= 0 && d > 0 && (step == 0 || d < step) {
+			step = d
+		}
+		prev = t
+	}
+	if step < time.Millisecond {
+		return 1
+	}
+	return int64((step + time.Millisecond - 1) / time.Millisecond)
+}
@@ -0,0 +1,91 @@
+package snowflake_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// coarseClock simulates a clock that only advances every tick, while the
+// real time behind it advances by step every time it's read.
+type coarseClock struct {
+	mtx   sync.Mutex
+	real  time.Time
+	step  time.Duration
+	tick  time.Duration
+	reads int
+}
+
+func (c *coarseClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.real = c.real.Add(c.step)
+	c.reads++
+	return c.real.Truncate(c.tick)
+}
+
+// peek returns the real time and the number of reads so far.
+func (c *coarseClock) peek() (time.Time, int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.real, c.reads
+}
+
+func TestWithClockGranularity(t *testing.T) {
+	tc := []struct {
+		name               string
+		tick, granularity  time.Duration
+		minReads, maxReads int // clock reads per ID
+	}{
+		// 4096 IDs per millisecond of each tick, reading the clock about
+		// 2.5 times per ID at 10 reads per µs
+		{"Should scale capacity to the tick", 16 * time.Millisecond, 16 * time.Millisecond, 1, 3},
+		// the default Windows timer resolution, rounded up to 16ms
+		{"Should scale capacity to a fractional tick", 15625 * time.Microsecond, 15625 * time.Microsecond, 1, 3},
+		// 4096 IDs per tick, then spinning through the rest of it
+		{"Should spin without granularity", 16 * time.Millisecond, 0, 30, 40},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			tick := tt.tick
+			clock := &coarseClock{real: snowflake.Epoch().Add(time.Hour), step: 100 * time.Nanosecond, tick: tick}
+			sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithClockGranularity(tt.granularity))
+
+			n := 100000
+			var prev uint64
+			for i := 0; i < n; i++ {
+				id := sf.NextID()
+				if id <= prev {
+					t.Fatalf("expected IDs to increase, got %d after %d", id, prev)
+				}
+				prev = id
+
+				real, _ := clock.peek()
+				if lead := time.UnixMilli(snowflake.Parse(id).Timestamp).Sub(real); lead >= tick {
+					t.Fatalf("expected timestamps to lead real time by less than %v, led by %v", tick, lead)
+				}
+			}
+
+			if _, reads := clock.peek(); reads < tt.minReads*n || reads > tt.maxReads*n {
+				t.Errorf("expected %d to %d clock reads got %d", tt.minReads*n, tt.maxReads*n, reads)
+			}
+		})
+	}
+}
+
+func TestWithClockGranularity_Health(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithClockGranularity(15600*time.Microsecond))
+
+	// 16 milliseconds worth of IDs within a tick of 15.6ms is expected
+	// and healthy
+	for i := 0; i < 16*4096; i++ {
+		sf.NextID()
+	}
+	if err := sf.Health(); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+}
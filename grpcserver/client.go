@@ -0,0 +1,104 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"google.golang.org/grpc"
+)
+
+// DefaultTimeout bounds Client.NextID, retries included.
+const DefaultTimeout = time.Second
+
+var _ snowflake.FallibleGenerator = (*Client)(nil)
+
+// Client fetches IDs from a Snowflake server.
+type Client struct {
+	c        SnowflakeClient
+	timeout  time.Duration
+	prefetch int
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client)
+
+// WithTimeout bounds NextID, retries included, and the wait of
+// Stream.NextID for an ID. Defaults to DefaultTimeout.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *Client) { c.timeout = d }
+}
+
+// NewClient returns a Client calling the server on cc.
+func NewClient(cc grpc.ClientConnInterface, opts ...ClientOption) *Client {
+	c := &Client{c: NewSnowflakeClient(cc), timeout: DefaultTimeout, prefetch: DefaultPrefetch}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// NextID fetches a new ID from the server, calling it again with a
+// backoff while it fails with a transient error, such as codes.Unavailable
+// while the server restarts, up to the timeout of the Client. It panics
+// when it gives up, since snowflake.Generator can't report errors; use
+// NextIDErr, or snowflake.NextIDErr where the Client is one of several
+// generators, to handle them instead.
+func (c *Client) NextID() uint64 {
+	id, err := c.NextIDErr()
+	if err != nil {
+		panic("grpcserver: " + err.Error())
+	}
+	return id
+}
+
+// NextIDErr is like NextID but returns the error of the last call rather
+// than panic.
+func (c *Client) NextIDErr() (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	for backoff := minBackoff; ; {
+		ids, err := c.NextIDs(ctx, 1)
+		if err == nil {
+			return ids[0], nil
+		}
+		if !transient(err) || ctx.Err() != nil {
+			return 0, err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, err
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// NextIDs fetches n new IDs from the server in one call.
+func (c *Client) NextIDs(ctx context.Context, n uint32) ([]uint64, error) {
+	resp, err := c.c.Generate(ctx, &GenerateRequest{Count: n})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetIds(), nil
+}
+
+// Parse decomposes id with the epoch of the server.
+func (c *Client) Parse(ctx context.Context, id uint64) (snowflake.SID, error) {
+	resp, err := c.c.Parse(ctx, &ParseRequest{Id: id})
+	if err != nil {
+		return snowflake.SID{}, err
+	}
+	return snowflake.SID{
+		Timestamp: resp.GetTimestamp(),
+		Sequence:  resp.GetSequence(),
+		MachineID: resp.GetField(),
+		Field:     resp.GetField(),
+	}, nil
+}
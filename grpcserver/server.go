@@ -0,0 +1,171 @@
+// Package grpcserver serves snowflake IDs over gRPC, so that a sidecar
+// owning the field (machine ID) of a host can hand out IDs to the local
+// processes.
+//
+// The sidecar registers a Server:
+//
+//	s := grpc.NewServer()
+//	grpcserver.RegisterSnowflakeServer(s, grpcserver.NewServer(snowflake.New(field)))
+//	s.Serve(lis)
+//
+// and applications use a Client, which is a snowflake.Generator:
+//
+//	conn, err := grpc.NewClient("unix:///run/snowflake.sock",
+//		grpc.WithTransportCredentials(insecure.NewCredentials()))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	var sf snowflake.Generator = grpcserver.NewClient(conn)
+//
+// Its NextID retries while the server is unavailable, up to the timeout
+// of the Client, then panics; callers that need to handle the failure use
+// NextIDErr, or snowflake.NextIDErr, instead.
+//
+// High-throughput consumers stream batches of IDs instead, through a
+// Stream, which is a snowflake.Generator too:
+//
+//	stream := grpcserver.NewClient(conn).Stream(1000, 0)
+//	defer stream.Close()
+package grpcserver
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative snowflake.proto
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultMaxCount is the default limit of IDs per Generate call and per
+// batch of a Stream.
+const DefaultMaxCount = 10000
+
+// DefaultBatchSize is the size of the batches of a Stream that doesn't ask
+// for one.
+const DefaultBatchSize = 1000
+
+// Server implements SnowflakeServer on top of a snowflake.Generator.
+type Server struct {
+	UnimplementedSnowflakeServer
+
+	g        snowflake.Generator
+	maxCount uint32
+}
+
+// ServerOption configures a Server.
+type ServerOption func(*Server)
+
+// WithMaxCount limits the IDs a single Generate call, or a batch of a
+// Stream, can ask for. Defaults to DefaultMaxCount.
+func WithMaxCount(n uint32) ServerOption {
+	return func(s *Server) { s.maxCount = n }
+}
+
+// NewServer returns a Server issuing the IDs of g.
+func NewServer(g snowflake.Generator, opts ...ServerOption) *Server {
+	s := &Server{g: g, maxCount: DefaultMaxCount}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Generate returns req.Count IDs, or 1 if it's 0. Fails with
+// codes.InvalidArgument when more IDs are asked for than the server's
+// max count.
+func (s *Server) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	n := req.GetCount()
+	if n == 0 {
+		n = 1
+	}
+	if n > s.maxCount {
+		return nil, status.Errorf(codes.InvalidArgument, "count %d exceeds the max of %d", n, s.maxCount)
+	}
+
+	return &GenerateResponse{Ids: s.generate(n)}, nil
+}
+
+// Stream sends batches of req.BatchSize IDs, or DefaultBatchSize if it's
+// 0, until the client cancels the call, at most req.MaxRate IDs per second
+// if it's set. Send blocks while the flow control window of the stream is
+// full, so the server generates IDs no faster than the client takes them.
+// Fails with codes.InvalidArgument when the batch size is over the
+// server's max count.
+func (s *Server) Stream(req *StreamRequest, stream Snowflake_StreamServer) error {
+	n := req.GetBatchSize()
+	if n == 0 {
+		n = DefaultBatchSize
+	}
+	if n > s.maxCount {
+		return status.Errorf(codes.InvalidArgument, "batch size %d exceeds the max of %d", n, s.maxCount)
+	}
+
+	// a batch every interval keeps to the rate; the deadline of the next
+	// batch advances by interval, so that the time spent sending doesn't
+	// slow the stream below the rate
+	var interval time.Duration
+	if rate := req.GetMaxRate(); rate > 0 {
+		interval = time.Duration(float64(n) / float64(rate) * float64(time.Second))
+	}
+	ctx := stream.Context()
+	next := time.Now()
+	for {
+		if interval > 0 {
+			if wait := time.Until(next); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return status.FromContextError(ctx.Err()).Err()
+				}
+			}
+			// a client that fell behind doesn't get a burst to catch up
+			if now := time.Now(); next.Before(now.Add(-interval)) {
+				next = now
+			}
+			next = next.Add(interval)
+		}
+
+		if err := stream.Send(&IDBatch{Ids: s.generate(n)}); err != nil {
+			return err
+		}
+	}
+}
+
+// generate returns n IDs of the generator.
+func (s *Server) generate(n uint32) []uint64 {
+	if b, ok := s.g.(interface {
+		AppendIDs(dst []uint64, n int) []uint64
+	}); ok {
+		return b.AppendIDs(make([]uint64, 0, n), int(n))
+	}
+
+	ids := make([]uint64, n)
+	for i := range ids {
+		ids[i] = s.g.NextID()
+	}
+	return ids
+}
+
+// Parse decomposes req.Id with the epoch of the server. Fails with
+// codes.InvalidArgument when the ID is above math.MaxInt64, which no
+// snowflake ID is.
+func (s *Server) Parse(ctx context.Context, req *ParseRequest) (*ParseResponse, error) {
+	id := req.GetId()
+	if id > math.MaxInt64 {
+		return nil, status.Errorf(codes.InvalidArgument, "ID %d is out of range", id)
+	}
+
+	sid := snowflake.Parse(id)
+	return &ParseResponse{
+		Timestamp: sid.Timestamp,
+		Sequence:  sid.Sequence,
+		Field:     sid.MachineID,
+	}, nil
+}
@@ -0,0 +1,193 @@
+package grpcserver_test
+
+import (
+	"context"
+	"math"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/grpcserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serve runs srv on an in-memory listener and returns a client
+// connection to it.
+func serve(t *testing.T, srv grpcserver.SnowflakeServer) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	grpcserver.RegisterSnowflakeServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestClient_NextID(t *testing.T) {
+	var sf snowflake.Generator = grpcserver.NewClient(serve(t, grpcserver.NewServer(snowflake.New(5))))
+
+	var mtx sync.Mutex
+	seen := make(map[uint64]bool)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id := sf.NextID()
+
+				mtx.Lock()
+				if seen[id] {
+					t.Errorf("duplicate ID %d", id)
+				}
+				seen[id] = true
+				mtx.Unlock()
+
+				if field := snowflake.Parse(id).MachineID; field != 5 {
+					t.Errorf("expected field 5 got %d", field)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestClient_NextIDs(t *testing.T) {
+	tests := []struct {
+		name  string
+		g     snowflake.Generator
+		count uint32
+		n     int
+	}{
+		{name: "Should return 1 ID for a count of 0", g: snowflake.New(1), count: 0, n: 1},
+		{name: "Should return count IDs", g: snowflake.New(1), count: 5000, n: 5000},
+		{name: "Should return count IDs of a plain Generator", g: snowflake.NewUnsafe(1), count: 100, n: 100},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := grpcserver.NewClient(serve(t, grpcserver.NewServer(tc.g)))
+
+			ids, err := c.NextIDs(context.Background(), tc.count)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != tc.n {
+				t.Fatalf("expected %d IDs got %d", tc.n, len(ids))
+			}
+			for i := 1; i < len(ids); i++ {
+				if ids[i] <= ids[i-1] {
+					t.Fatalf("expected increasing IDs got %d after %d", ids[i], ids[i-1])
+				}
+			}
+		})
+	}
+}
+
+func TestClient_NextIDs_MaxCount(t *testing.T) {
+	conn := serve(t, grpcserver.NewServer(snowflake.New(1), grpcserver.WithMaxCount(10)))
+	c := grpcserver.NewClient(conn)
+
+	if _, err := c.NextIDs(context.Background(), 10); err != nil {
+		t.Errorf("expected no error at the max count got %v", err)
+	}
+
+	_, err := c.NextIDs(context.Background(), 11)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected code %v got %v", codes.InvalidArgument, err)
+	}
+
+	conn.Close()
+	if _, err := snowflake.NextIDErr(c); err == nil {
+		t.Error("expected NextIDErr to fail when the call fails")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NextID to panic when the call fails")
+		}
+	}()
+	c.NextID()
+}
+
+// unavailableServer is a Server whose first fail Generate calls fail
+// with codes.Unavailable, or every call if fail is negative.
+type unavailableServer struct {
+	*grpcserver.Server
+	fail  int32
+	calls int32
+}
+
+func (u *unavailableServer) Generate(ctx context.Context, req *grpcserver.GenerateRequest) (*grpcserver.GenerateResponse, error) {
+	if n := atomic.AddInt32(&u.calls, 1); u.fail < 0 || n <= u.fail {
+		return nil, status.Error(codes.Unavailable, "restarting")
+	}
+	return u.Server.Generate(ctx, req)
+}
+
+func TestClient_NextID_Retry(t *testing.T) {
+	srv := &unavailableServer{Server: grpcserver.NewServer(snowflake.New(5)), fail: 3}
+	c := grpcserver.NewClient(serve(t, srv))
+
+	if field := snowflake.Parse(c.NextID()).MachineID; field != 5 {
+		t.Errorf("expected field 5 got %d", field)
+	}
+	if calls := atomic.LoadInt32(&srv.calls); calls != 4 {
+		t.Errorf("expected 4 calls got %d", calls)
+	}
+
+	// gives up at the timeout
+	srv = &unavailableServer{Server: grpcserver.NewServer(snowflake.New(5)), fail: -1}
+	c = grpcserver.NewClient(serve(t, srv), grpcserver.WithTimeout(100*time.Millisecond))
+
+	start := time.Now()
+	// the timeout may also cut a call short
+	if _, err := c.NextIDErr(); status.Code(err) != codes.Unavailable && status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected code %v got %v", codes.Unavailable, err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("expected to retry up to the timeout got %s", elapsed)
+	}
+	if calls := atomic.LoadInt32(&srv.calls); calls < 2 {
+		t.Errorf("expected several calls got %d", calls)
+	}
+}
+
+func TestClient_Parse(t *testing.T) {
+	c := grpcserver.NewClient(serve(t, grpcserver.NewServer(snowflake.New(1))))
+
+	sid, err := c.Parse(context.Background(), 1292053924173320192)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := snowflake.Parse(1292053924173320192)
+	if sid != expected {
+		t.Errorf("expected %+v got %+v", expected, sid)
+	}
+
+	if _, err := c.Parse(context.Background(), math.MaxInt64+1); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected code %v got %v", codes.InvalidArgument, err)
+	}
+}
@@ -0,0 +1,217 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: snowflake.proto
+
+package grpcserver
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Snowflake_Generate_FullMethodName = "/snowflake.v1.Snowflake/Generate"
+	Snowflake_Parse_FullMethodName    = "/snowflake.v1.Snowflake/Parse"
+	Snowflake_Stream_FullMethodName   = "/snowflake.v1.Snowflake/Stream"
+)
+
+// SnowflakeClient is the client API for Snowflake service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Snowflake issues IDs from the generator of the serving process, which
+// owns the field (machine ID) of its host.
+type SnowflakeClient interface {
+	// Generate returns count new IDs in increasing order.
+	Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error)
+	// Parse decomposes an ID using the epoch of the server.
+	Parse(ctx context.Context, in *ParseRequest, opts ...grpc.CallOption) (*ParseResponse, error)
+	// Stream pushes batches of new IDs, in increasing order, until the client
+	// cancels it. A slow client holds the server back through the flow
+	// control window of the stream, rather than having IDs pile up for it.
+	Stream(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[IDBatch], error)
+}
+
+type snowflakeClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSnowflakeClient(cc grpc.ClientConnInterface) SnowflakeClient {
+	return &snowflakeClient{cc}
+}
+
+func (c *snowflakeClient) Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GenerateResponse)
+	err := c.cc.Invoke(ctx, Snowflake_Generate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *snowflakeClient) Parse(ctx context.Context, in *ParseRequest, opts ...grpc.CallOption) (*ParseResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ParseResponse)
+	err := c.cc.Invoke(ctx, Snowflake_Parse_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *snowflakeClient) Stream(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[IDBatch], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Snowflake_ServiceDesc.Streams[0], Snowflake_Stream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamRequest, IDBatch]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Snowflake_StreamClient = grpc.ServerStreamingClient[IDBatch]
+
+// SnowflakeServer is the server API for Snowflake service.
+// All implementations must embed UnimplementedSnowflakeServer
+// for forward compatibility.
+//
+// Snowflake issues IDs from the generator of the serving process, which
+// owns the field (machine ID) of its host.
+type SnowflakeServer interface {
+	// Generate returns count new IDs in increasing order.
+	Generate(context.Context, *GenerateRequest) (*GenerateResponse, error)
+	// Parse decomposes an ID using the epoch of the server.
+	Parse(context.Context, *ParseRequest) (*ParseResponse, error)
+	// Stream pushes batches of new IDs, in increasing order, until the client
+	// cancels it. A slow client holds the server back through the flow
+	// control window of the stream, rather than having IDs pile up for it.
+	Stream(*StreamRequest, grpc.ServerStreamingServer[IDBatch]) error
+	mustEmbedUnimplementedSnowflakeServer()
+}
+
+// UnimplementedSnowflakeServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSnowflakeServer struct{}
+
+func (UnimplementedSnowflakeServer) Generate(context.Context, *GenerateRequest) (*GenerateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Generate not implemented")
+}
+func (UnimplementedSnowflakeServer) Parse(context.Context, *ParseRequest) (*ParseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Parse not implemented")
+}
+func (UnimplementedSnowflakeServer) Stream(*StreamRequest, grpc.ServerStreamingServer[IDBatch]) error {
+	return status.Errorf(codes.Unimplemented, "method Stream not implemented")
+}
+func (UnimplementedSnowflakeServer) mustEmbedUnimplementedSnowflakeServer() {}
+func (UnimplementedSnowflakeServer) testEmbeddedByValue()                   {}
+
+// UnsafeSnowflakeServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SnowflakeServer will
+// result in compilation errors.
+type UnsafeSnowflakeServer interface {
+	mustEmbedUnimplementedSnowflakeServer()
+}
+
+func RegisterSnowflakeServer(s grpc.ServiceRegistrar, srv SnowflakeServer) {
+	// If the following call pancis, it indicates UnimplementedSnowflakeServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Snowflake_ServiceDesc, srv)
+}
+
+func _Snowflake_Generate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnowflakeServer).Generate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Snowflake_Generate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnowflakeServer).Generate(ctx, req.(*GenerateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Snowflake_Parse_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ParseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnowflakeServer).Parse(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Snowflake_Parse_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnowflakeServer).Parse(ctx, req.(*ParseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Snowflake_Stream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SnowflakeServer).Stream(m, &grpc.GenericServerStream[StreamRequest, IDBatch]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Snowflake_StreamServer = grpc.ServerStreamingServer[IDBatch]
+
+// Snowflake_ServiceDesc is the grpc.ServiceDesc for Snowflake service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Snowflake_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "snowflake.v1.Snowflake",
+	HandlerType: (*SnowflakeServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Generate",
+			Handler:    _Snowflake_Generate_Handler,
+		},
+		{
+			MethodName: "Parse",
+			Handler:    _Snowflake_Parse_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Stream",
+			Handler:       _Snowflake_Stream_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "snowflake.proto",
+}
@@ -0,0 +1,193 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultPrefetch is the number of batches a Stream buffers ahead of
+// NextID, unless changed with WithPrefetch.
+const DefaultPrefetch = 4
+
+// ErrStreamClosed is returned by Stream.Next once the Stream is closed.
+var ErrStreamClosed = errors.New("grpcserver: stream closed")
+
+// Backoff of Client.NextID between calls, and of a Stream between
+// reconnections. (internal-use only)
+const (
+	minBackoff = 10 * time.Millisecond
+	maxBackoff = 5 * time.Second
+)
+
+var _ snowflake.FallibleGenerator = (*Stream)(nil)
+
+// Stream is a snowflake.Generator taking its IDs from a Stream call, so
+// that high-throughput consumers don't pay a round trip per batch. A
+// goroutine keeps up to the prefetch of the Client in batches ahead of
+// NextID, and calls Stream again, with a backoff, when the call fails with
+// a transient error such as codes.Unavailable.
+type Stream struct {
+	c      *Client
+	req    *StreamRequest
+	ctx    context.Context // done once closed
+	cancel context.CancelFunc
+
+	batches chan []uint64 // closed when the goroutine is done
+	err     error         // why the goroutine is done, nil if closed
+
+	mu  sync.Mutex
+	ids []uint64 // the rest of the current batch
+}
+
+// WithPrefetch sets the number of batches a Stream buffers ahead of
+// NextID. Defaults to DefaultPrefetch.
+func WithPrefetch(batches int) ClientOption {
+	return func(c *Client) { c.prefetch = batches }
+}
+
+// Stream starts streaming batches of batchSize IDs (0 for the default of
+// the server) at most maxRate IDs per second (0 for no cap). Close the
+// Stream to end the call.
+func (c *Client) Stream(batchSize, maxRate uint32) *Stream {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Stream{
+		c:       c,
+		req:     &StreamRequest{BatchSize: batchSize, MaxRate: maxRate},
+		ctx:     ctx,
+		cancel:  cancel,
+		batches: make(chan []uint64, c.prefetch),
+	}
+	go s.run(ctx)
+	return s
+}
+
+// NextID returns the next ID of the stream, waiting up to the timeout of
+// the Client for one. It panics when there is none, since
+// snowflake.Generator can't report errors; use NextIDErr, snowflake.NextIDErr
+// or Next to handle them instead.
+func (s *Stream) NextID() uint64 {
+	id, err := s.NextIDErr()
+	if err != nil {
+		panic("grpcserver: " + err.Error())
+	}
+	return id
+}
+
+// NextIDErr is like NextID but returns why there is no ID rather than
+// panic.
+func (s *Stream) NextIDErr() (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.c.timeout)
+	defer cancel()
+
+	return s.Next(ctx)
+}
+
+// Next returns the next ID of the stream, waiting until there is one or
+// ctx is done. Returns ErrStreamClosed once the Stream is closed, or the
+// error of the call if it failed with a non-transient error.
+func (s *Stream) Next(ctx context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return 0, ErrStreamClosed
+	}
+	for len(s.ids) == 0 {
+		select {
+		case batch, ok := <-s.batches:
+			if !ok {
+				if s.err != nil {
+					return 0, s.err
+				}
+				return 0, ErrStreamClosed
+			}
+			s.ids = batch
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	id := s.ids[0]
+	s.ids = s.ids[1:]
+	return id, nil
+}
+
+// Close ends the call. The IDs buffered but not yet returned are dropped.
+func (s *Stream) Close() error {
+	s.cancel()
+	return nil
+}
+
+// run receives batches into s.batches until ctx is done or the call fails
+// with a non-transient error.
+func (s *Stream) run(ctx context.Context) {
+	defer close(s.batches)
+
+	backoff := minBackoff
+	for {
+		err := s.receive(ctx, func() { backoff = minBackoff })
+		if ctx.Err() != nil {
+			return
+		}
+		if !transient(err) {
+			s.err = err
+			return
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// receive makes a single Stream call and buffers its batches until it
+// fails, calling received for every batch.
+func (s *Stream) receive(ctx context.Context, received func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := s.c.c.Stream(ctx, s.req)
+	if err != nil {
+		return err
+	}
+	for {
+		batch, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		received()
+
+		select {
+		case s.batches <- batch.GetIds():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// transient reports whether a call failing with err is worth retrying. A
+// server ending the call, with io.EOF, is shutting down.
+func transient(err error) bool {
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,169 @@
+package grpcserver_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/grpcserver"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyServer is a Server whose Stream calls fail with codes.Unavailable
+// after fail batches, if fail isn't 0, and report how they ended on done,
+// if it isn't nil.
+type flakyServer struct {
+	*grpcserver.Server
+	fail  int
+	calls atomic.Int32
+	done  chan error
+}
+
+func (f *flakyServer) Stream(req *grpcserver.StreamRequest, stream grpcserver.Snowflake_StreamServer) error {
+	f.calls.Add(1)
+	err := f.Server.Stream(req, &failingStream{Snowflake_StreamServer: stream, fail: f.fail})
+	if f.done != nil {
+		f.done <- err
+	}
+	return err
+}
+
+type failingStream struct {
+	grpcserver.Snowflake_StreamServer
+	fail, sent int
+}
+
+func (s *failingStream) Send(batch *grpcserver.IDBatch) error {
+	if s.fail > 0 && s.sent == s.fail {
+		return status.Error(codes.Unavailable, "going away")
+	}
+	s.sent++
+	return s.Snowflake_StreamServer.Send(batch)
+}
+
+// take returns the next n IDs of s, checking that they are increasing and
+// of field.
+func take(t *testing.T, s *grpcserver.Stream, n int, field uint64) []uint64 {
+	t.Helper()
+
+	ids := make([]uint64, n)
+	for i := range ids {
+		id, err := s.Next(context.Background())
+		if err != nil {
+			t.Fatalf("expected ID %d got error %v", i, err)
+		}
+		if i > 0 && id <= ids[i-1] {
+			t.Fatalf("expected increasing IDs got %d after %d", id, ids[i-1])
+		}
+		if f := snowflake.Parse(id).MachineID; f != field {
+			t.Fatalf("expected field %d got %d", field, f)
+		}
+		ids[i] = id
+	}
+	return ids
+}
+
+func TestStream(t *testing.T) {
+	c := grpcserver.NewClient(serve(t, grpcserver.NewServer(snowflake.New(5))))
+	s := c.Stream(1000, 0)
+	defer s.Close()
+
+	take(t, s, 100000, 5)
+
+	var sf snowflake.Generator = s
+	if field := snowflake.Parse(sf.NextID()).MachineID; field != 5 {
+		t.Errorf("expected field 5 got %d", field)
+	}
+}
+
+func TestStream_MaxRate(t *testing.T) {
+	c := grpcserver.NewClient(serve(t, grpcserver.NewServer(snowflake.New(5))))
+	s := c.Stream(100, 2000)
+	defer s.Close()
+
+	// the first batch comes at once, the next 9 every 50ms
+	start := time.Now()
+	take(t, s, 1000, 5)
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("expected 1000 IDs at 2000 per second to take at least 400ms got %v", elapsed)
+	}
+}
+
+func TestStream_Cancel(t *testing.T) {
+	srv := &flakyServer{Server: grpcserver.NewServer(snowflake.New(5)), done: make(chan error, 1)}
+	c := grpcserver.NewClient(serve(t, srv))
+	s := c.Stream(10, 0)
+
+	take(t, s, 25, 5)
+	s.Close()
+
+	select {
+	case err := <-srv.done:
+		if status.Code(err) != codes.Canceled {
+			t.Errorf("expected the server to end with code %v got %v", codes.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the server to end the canceled call")
+	}
+
+	if _, err := s.Next(context.Background()); !errors.Is(err, grpcserver.ErrStreamClosed) {
+		t.Errorf("expected error %v got %v", grpcserver.ErrStreamClosed, err)
+	}
+	if calls := srv.calls.Load(); calls != 1 {
+		t.Errorf("expected 1 call got %d", calls)
+	}
+}
+
+func TestStream_Reconnect(t *testing.T) {
+	srv := &flakyServer{Server: grpcserver.NewServer(snowflake.New(5)), fail: 3}
+	c := grpcserver.NewClient(serve(t, srv), grpcserver.WithPrefetch(1))
+	s := c.Stream(100, 0)
+	defer s.Close()
+
+	// 30 batches over calls of 3 batches each
+	ids := take(t, s, 3000, 5)
+
+	seen := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatalf("duplicate ID %d", id)
+		}
+		seen[id] = true
+	}
+	if calls := srv.calls.Load(); calls < 10 {
+		t.Errorf("expected at least 10 calls got %d", calls)
+	}
+}
+
+func TestStream_Errors(t *testing.T) {
+	srv := &flakyServer{Server: grpcserver.NewServer(snowflake.New(5), grpcserver.WithMaxCount(10))}
+	c := grpcserver.NewClient(serve(t, srv))
+	s := c.Stream(11, 0)
+	defer s.Close()
+
+	// a non-transient error ends the stream without retrying
+	if _, err := s.Next(context.Background()); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected code %v got %v", codes.InvalidArgument, err)
+	}
+	if _, err := s.Next(context.Background()); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected code %v again got %v", codes.InvalidArgument, err)
+	}
+	if calls := srv.calls.Load(); calls != 1 {
+		t.Errorf("expected 1 call got %d", calls)
+	}
+
+	if _, err := snowflake.NextIDErr(s); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected NextIDErr to fail with code %v got %v", codes.InvalidArgument, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NextID to panic when the stream failed")
+		}
+	}()
+	s.NextID()
+}
@@ -0,0 +1,102 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// HealthError lists the problems found by Health. errors.Is reports
+// whether any of them matches.
+type HealthError struct {
+	Problems []error
+}
+
+func (e *HealthError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, err := range e.Problems {
+		msgs[i] = err.Error()
+	}
+	return "snowflake: unhealthy: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether any of the problems matches target.
+func (e *HealthError) Is(target error) bool {
+	for _, err := range e.Problems {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Unwrap returns the problems.
+func (e *HealthError) Unwrap() []error { return e.Problems }
+
+// Health reports whether the generator can issue IDs right now, cheaply
+// enough for readiness probes. It returns a *HealthError listing every
+// problem found, or nil:
+//
+//   - ErrClockBehind if the clock is behind the last ID, by more than the
+//     drift allowed with WithMaxForwardDrift and the tolerance set with
+//     WithHealthThresholds; IDs then come from the last millisecond until
+//     it catches up
+//   - ErrTimestampOverflow if the timestamp no longer fits 41 bits
+//   - ErrLifetimeLow if the time left until then is below the floor set
+//     with WithHealthThresholds
+//   - ErrFieldReleased if the generator was released, see NewRegistered
+//   - ErrLeaseLost if the lease set with WithLease or acquired by NewLeased
+//     was lost
+//   - ErrClockOffset if the clock is an NTPClock that measured the local
+//     clock further off its servers than allowed, see WithNTPMaxOffset
+func (id *ID) Health() error {
+	var problems []error
+	if atomic.LoadUint32(&id.registered) == 2 {
+		problems = append(problems, ErrFieldReleased)
+	}
+	return id.health(problems)
+}
+
+// Health is like (*ID).Health.
+func (id *ID2) Health() error { return id.health(nil) }
+
+// health adds the problems of g to problems. (internal-use only)
+func (g *generator) health(problems []error) error {
+	g.mtx.Lock()
+	now, last, leaseDone := g.now(), g.elapsedTime, g.leaseDone
+	g.mtx.Unlock()
+
+	if behind := last - now; behind > g.drift()+g.clockTolerance {
+		problems = append(problems, fmt.Errorf("%w by %s", ErrClockBehind, time.Duration(behind)*time.Millisecond))
+	}
+
+	remaining := time.Duration(maxTimestamp-now) * time.Millisecond
+	switch {
+	case remaining < 0:
+		problems = append(problems, ErrTimestampOverflow)
+	case remaining < g.minRemaining:
+		problems = append(problems, fmt.Errorf("%w: %s left", ErrLifetimeLow, remaining))
+	}
+
+	if oc, ok := g.clock.(offsetClock); ok {
+		offset, synced := oc.offset()
+		if max := oc.maxClockOffset(); synced && max > 0 && (offset > max || offset < -max) {
+			problems = append(problems, fmt.Errorf("%w by %s", ErrClockOffset, offset))
+		}
+	}
+
+	if leaseDone != nil {
+		select {
+		case <-leaseDone:
+			problems = append(problems, ErrLeaseLost)
+		default:
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &HealthError{Problems: problems}
+}
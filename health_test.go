@@ -0,0 +1,125 @@
+package snowflake_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestHealth(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	overflow := snowflake.Epoch().Add((1 << 41) * time.Millisecond)
+
+	tc := []struct {
+		name     string
+		opts     []snowflake.Option
+		now      time.Time // the clock once an ID was issued at start
+		expected error
+	}{
+		{name: "Should be healthy", now: start},
+		{name: "Should be healthy with the clock moving on", now: start.Add(time.Second)},
+		{name: "Should return ErrClockBehind", now: start.Add(-5 * time.Millisecond), expected: snowflake.ErrClockBehind},
+		{
+			name: "Should allow the clock tolerance",
+			opts: []snowflake.Option{snowflake.WithHealthThresholds(10*time.Millisecond, 0)},
+			now:  start.Add(-5 * time.Millisecond),
+		},
+		{
+			name:     "Should return ErrClockBehind beyond the clock tolerance",
+			opts:     []snowflake.Option{snowflake.WithHealthThresholds(10*time.Millisecond, 0)},
+			now:      start.Add(-20 * time.Millisecond),
+			expected: snowflake.ErrClockBehind,
+		},
+		{
+			name: "Should allow the forward drift",
+			opts: []snowflake.Option{snowflake.WithMaxForwardDrift(10 * time.Millisecond)},
+			now:  start.Add(-5 * time.Millisecond),
+		},
+		{name: "Should return ErrTimestampOverflow", now: overflow, expected: snowflake.ErrTimestampOverflow},
+		{
+			name:     "Should return ErrLifetimeLow",
+			opts:     []snowflake.Option{snowflake.WithHealthThresholds(0, 100*365*24*time.Hour)},
+			now:      start,
+			expected: snowflake.ErrLifetimeLow,
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock(start)
+			sf := snowflake.New(1, append(tt.opts, snowflake.WithClock(clock))...)
+			sf.NextID()
+			clock.Set(tt.now)
+
+			err := sf.Health()
+			if tt.expected == nil {
+				if err != nil {
+					t.Errorf("expected no error got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected error %v got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestHealth_FieldReleased(t *testing.T) {
+	sf, err := snowflake.NewRegistered(900)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.Health(); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+
+	sf.Release()
+	if err := sf.Health(); !errors.Is(err, snowflake.ErrFieldReleased) {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldReleased, err)
+	}
+}
+
+func TestHealth_LeaseLost(t *testing.T) {
+	done := make(chan struct{})
+	sf := snowflake.New2(1, 2, snowflake.WithLease(done))
+	if err := sf.Health(); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+
+	close(done)
+	if err := sf.Health(); !errors.Is(err, snowflake.ErrLeaseLost) {
+		t.Errorf("expected error %v got %v", snowflake.ErrLeaseLost, err)
+	}
+}
+
+func TestHealth_Problems(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start)
+	done := make(chan struct{})
+	close(done)
+
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithLease(done))
+	sf.NextID()
+	clock.Set(start.Add(-time.Second))
+
+	err := sf.Health()
+	var health *snowflake.HealthError
+	if !errors.As(err, &health) {
+		t.Fatalf("expected a *HealthError got %v", err)
+	}
+	if len(health.Problems) != 2 {
+		t.Errorf("expected %d problems got %v", 2, health.Problems)
+	}
+	for _, expected := range []error{snowflake.ErrClockBehind, snowflake.ErrLeaseLost} {
+		if !errors.Is(err, expected) {
+			t.Errorf("expected error %v got %v", expected, err)
+		}
+		if !strings.Contains(err.Error(), expected.Error()) {
+			t.Errorf("expected %q to mention %q", err, expected)
+		}
+	}
+}
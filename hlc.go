@@ -0,0 +1,59 @@
+package snowflake
+
+import (
+	"fmt"
+	"time"
+)
+
+// Observe folds in an ID received from another node, making the generator
+// a hybrid logical clock: every ID it issues afterwards is bigger than
+// remoteID, even if remoteID is ahead of the clock. Until the clock catches
+// up, IDs are issued from the millisecond of remoteID (or the next one if
+// the field of remoteID isn't smaller, or under FieldLow or NewEntropy),
+// the sequence counting the IDs issued in it.
+//
+// remoteID may lead the clock by as much as the generator may drift (see
+// WithMaxForwardDrift and WithClockGranularity). Returns an error wrapping
+// ErrClockSkew if it leads by more, and ErrInvalidID if its highest bit is
+// set, leaving the generator unchanged.
+//
+//	sf := snowflake.New(1, snowflake.WithMaxForwardDrift(50*time.Millisecond))
+//	if err := sf.Observe(msg.ID); err != nil {
+//		// the sender's clock is off
+//	}
+//	reply.ID = sf.NextID() // ordered after msg.ID
+func (id *ID) Observe(remoteID uint64) error { return id.observe(remoteID) }
+
+// Observe is like (*ID).Observe.
+func (id *ID2) Observe(remoteID uint64) error { return id.observe(remoteID) }
+
+// observe makes the IDs issued by g bigger than remoteID. (internal-use only)
+func (g *generator) observe(remoteID uint64) error {
+	if remoteID>>63 != 0 {
+		return fmt.Errorf("snowflake: observed ID %d overflows 63 bits: %w", remoteID, ErrInvalidID)
+	}
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	timestamp := int64(remoteID >> (sequenceBits + fieldBits))
+	if lead := timestamp - g.now(); lead > g.drift() {
+		return fmt.Errorf("snowflake: observed ID %d leads the clock by %s: %w",
+			remoteID, time.Duration(lead)*time.Millisecond, ErrClockSkew)
+	}
+
+	// the first millisecond whose IDs are all bigger than remoteID: the
+	// IDs of a FieldLow millisecond sort by sequence, not by field, and the
+	// field of an entropy generator is drawn again for the next millisecond
+	earliest := timestamp
+	if g.fieldLow || g.entropy || g.fieldSegment>>sequenceBits <= getMachineID(remoteID) {
+		earliest++
+	}
+	if earliest > g.observed {
+		g.observed = earliest
+		// read the clock for the next ID rather than reusing the last
+		// timestamp, see WithClockCheckEvery
+		g.sinceCheck = 0
+	}
+	return nil
+}
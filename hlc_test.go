@@ -0,0 +1,146 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestObserve(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+
+	tc := []struct {
+		name        string
+		remoteField uint64
+		timestamp   time.Time // of the next local IDs
+	}{
+		{"Should issue from the millisecond of a smaller field", 0, start.Add(5 * time.Millisecond)},
+		{"Should issue after the millisecond of the same field", 1, start.Add(6 * time.Millisecond)},
+		{"Should issue after the millisecond of a bigger field", 2, start.Add(6 * time.Millisecond)},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock(start)
+			sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithMaxForwardDrift(10*time.Millisecond))
+			sf.NextID()
+
+			// a node whose clock is 5ms ahead
+			remote := snowflake.New(tt.remoteField, snowflake.WithClock(newFakeClock(start.Add(5*time.Millisecond))))
+			var remoteID uint64
+			for i := 0; i < 10; i++ {
+				remoteID = remote.NextID()
+			}
+
+			if err := sf.Observe(remoteID); err != nil {
+				t.Fatal(err)
+			}
+
+			// the sequence counts on while the clock doesn't move
+			prev := remoteID
+			for i := uint64(0); i < 5; i++ {
+				id := sf.NextID()
+				if id <= prev {
+					t.Fatalf("expected an ID bigger than %d got %d", prev, id)
+				}
+				prev = id
+
+				sid := snowflake.Parse(id)
+				if sid.Timestamp != tt.timestamp.UnixMilli() || sid.Sequence != i {
+					t.Errorf("expected timestamp %d and sequence %d got %+v", tt.timestamp.UnixMilli(), i, sid)
+				}
+			}
+
+			// until the clock catches up
+			clock.Add(20 * time.Millisecond)
+			if ts := snowflake.Parse(sf.NextID()).Timestamp; ts != start.Add(20*time.Millisecond).UnixMilli() {
+				t.Errorf("expected timestamp %d got %d", start.Add(20*time.Millisecond).UnixMilli(), ts)
+			}
+		})
+	}
+}
+
+func TestObserve_Past(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start)
+	sf := snowflake.New(1, snowflake.WithClock(clock))
+
+	remoteID := snowflake.New(2, snowflake.WithClock(newFakeClock(start.Add(-time.Second)))).NextID()
+	if err := sf.Observe(remoteID); err != nil {
+		t.Fatal(err)
+	}
+	if ts := snowflake.Parse(sf.NextID()).Timestamp; ts != start.UnixMilli() {
+		t.Errorf("expected timestamp %d got %d", start.UnixMilli(), ts)
+	}
+}
+
+func TestObserve_FieldLow(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	remote := snowflake.New(1, snowflake.WithSegmentOrder(snowflake.FieldLow),
+		snowflake.WithClock(newFakeClock(start.Add(10*time.Millisecond))))
+	var remoteID uint64
+	for i := 0; i < 100; i++ {
+		remoteID = remote.NextID()
+	}
+
+	// a bigger local field doesn't put the local IDs of the millisecond
+	// above remoteID, whose sequence is ahead
+	sf := snowflake.New(900, snowflake.WithSegmentOrder(snowflake.FieldLow),
+		snowflake.WithClock(newFakeClock(start)), snowflake.WithMaxForwardDrift(time.Second))
+	if err := sf.Observe(remoteID); err != nil {
+		t.Fatal(err)
+	}
+	if id := sf.NextID(); id <= remoteID {
+		t.Errorf("expected ID %d to be greater than %d", id, remoteID)
+	}
+}
+
+func TestObserve_Entropy(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	remote := snowflake.New(512, snowflake.WithClock(newFakeClock(start.Add(10*time.Millisecond))))
+	var remoteID uint64
+	for i := 0; i < 100; i++ {
+		remoteID = remote.NextID()
+	}
+
+	// the random field is drawn again for the millisecond of the next ID,
+	// so it can't tell whether that ID is above remoteID
+	for i := 0; i < 200; i++ {
+		sf := snowflake.NewEntropy(snowflake.WithClock(newFakeClock(start)), snowflake.WithMaxForwardDrift(time.Second))
+		sf.NextID()
+		if err := sf.Observe(remoteID); err != nil {
+			t.Fatal(err)
+		}
+		if id := sf.NextID(); id <= remoteID {
+			t.Fatalf("expected ID %d to be greater than %d", id, remoteID)
+		}
+	}
+}
+
+func TestObserve_Errors(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	remoteID := snowflake.New(2, snowflake.WithClock(newFakeClock(start.Add(11*time.Millisecond)))).NextID()
+
+	tc := []struct {
+		name     string
+		remoteID uint64
+		expected error
+	}{
+		{"Should return ErrClockSkew", remoteID, snowflake.ErrClockSkew},
+		{"Should return ErrInvalidID", 1<<63 | 42, snowflake.ErrInvalidID},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := snowflake.New2(1, 1, snowflake.WithClock(newFakeClock(start)), snowflake.WithMaxForwardDrift(10*time.Millisecond))
+			if err := sf.Observe(tt.remoteID); !errors.Is(err, tt.expected) {
+				t.Errorf("expected error %v got %v", tt.expected, err)
+			}
+			if ts := snowflake.Parse2(sf.NextID()).Timestamp; ts != start.UnixMilli() {
+				t.Errorf("expected the generator to be unchanged, got timestamp %d", ts)
+			}
+		})
+	}
+}
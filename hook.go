@@ -0,0 +1,49 @@
+package snowflake
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// gate runs calls on a goroutine of their own, one at a time; calls made
+// while one is running are dropped. (internal-use only)
+type gate struct {
+	running int32
+}
+
+// run calls f on a goroutine of its own, unless a call is still running.
+// It never blocks. (internal-use only)
+func (g *gate) run(f func()) {
+	if !atomic.CompareAndSwapInt32(&g.running, 0, 1) {
+		return
+	}
+	go func() {
+		defer atomic.StoreInt32(&g.running, 0)
+		f()
+	}()
+}
+
+// hook calls a callback on a goroutine of its own, so a slow callback
+// can't hold up the generator. Only one call runs at a time; events
+// firing while a call is running are dropped. (internal-use only)
+type hook struct {
+	gate
+	f func(time.Duration)
+}
+
+// newHook returns a hook calling f, or nil if f is nil. (internal-use only)
+func newHook(f func(time.Duration)) *hook {
+	if f == nil {
+		return nil
+	}
+	return &hook{f: f}
+}
+
+// fire calls the callback with d, unless a call is still running.
+// It never blocks. (internal-use only)
+func (h *hook) fire(d time.Duration) {
+	if h == nil {
+		return
+	}
+	h.run(func() { h.f(d) })
+}
@@ -0,0 +1,122 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestWithOnSequenceExhausted(t *testing.T) {
+	exhausted := make(chan time.Duration, 1)
+	clock := &tickingClock{t: snowflake.Epoch().Add(time.Hour), step: 100 * time.Nanosecond}
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithOnSequenceExhausted(func(waited time.Duration) {
+		exhausted <- waited
+	}))
+
+	for i := 0; i < 4096; i++ {
+		sf.NextID()
+	}
+	select {
+	case waited := <-exhausted:
+		t.Fatalf("expected no callback before the sequence ran out got %v", waited)
+	default:
+	}
+
+	sf.NextID()
+	select {
+	case waited := <-exhausted:
+		if waited <= 0 || waited > time.Millisecond {
+			t.Errorf("expected to wait up to a millisecond got %v", waited)
+		}
+		if stats := sf.Stats(); waited != stats.Waited {
+			t.Errorf("expected %v as in the stats got %v", stats.Waited, waited)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the callback to fire")
+	}
+}
+
+func TestWithOnSequenceExhausted_Drift(t *testing.T) {
+	exhausted := make(chan time.Duration, 1)
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithMaxForwardDrift(10*time.Millisecond),
+		snowflake.WithOnSequenceExhausted(func(waited time.Duration) {
+			exhausted <- waited
+		}))
+
+	for i := 0; i < 4097; i++ {
+		sf.NextID()
+	}
+	select {
+	case waited := <-exhausted:
+		if waited != 0 {
+			t.Errorf("expected no wait when drifting got %v", waited)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the callback to fire")
+	}
+}
+
+func TestWithOnSequenceExhausted_SlowCallback(t *testing.T) {
+	calls := make(chan time.Duration, 10)
+	release := make(chan struct{})
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithMaxForwardDrift(10*time.Millisecond),
+		snowflake.WithOnSequenceExhausted(func(waited time.Duration) {
+			calls <- waited
+			<-release
+		}))
+
+	// five exhaustions while the first call is blocked
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5*4096+1; i++ {
+			sf.NextID()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a blocked callback not to hold up the generator")
+	}
+
+	<-calls
+	close(release)
+	if n := len(calls); n != 0 {
+		t.Errorf("expected the exhaustions during the call to be dropped got %d more calls", n)
+	}
+}
+
+func TestWithOnClockBackwards(t *testing.T) {
+	regressions := make(chan time.Duration, 1)
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start)
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithOnClockBackwards(func(regression time.Duration) {
+		regressions <- regression
+	}))
+
+	last := sf.NextID()
+	clock.Add(time.Millisecond)
+	sf.NextID()
+	select {
+	case regression := <-regressions:
+		t.Fatalf("expected no callback while the clock moves forward got %v", regression)
+	default:
+	}
+
+	clock.Set(start.Add(-250 * time.Millisecond))
+	if id := sf.NextID(); id <= last {
+		t.Errorf("expected generation to carry on past %d got %d", last, id)
+	}
+	select {
+	case regression := <-regressions:
+		if regression != 251*time.Millisecond {
+			t.Errorf("expected a regression of %v got %v", 251*time.Millisecond, regression)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the callback to fire")
+	}
+}
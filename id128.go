@@ -0,0 +1,194 @@
+package snowflake
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	field128Bits    = 16
+	sequence128Bits = 48
+	maxField128     = 1<<field128Bits - 1    // 0xFFFF, the max field of ID128
+	maxSequence128  = 1<<sequence128Bits - 1 // 0xFFFFFFFFFFFF, the max sequence of ID128
+	// sequence128Start bounds the random start of the sequence of every
+	// millisecond, leaving at least half of it. (internal-use only)
+	sequence128Start = 1 << (sequence128Bits - 1)
+)
+
+// crockford is the alphabet of Crockford's base32, in ASCII order so that
+// encoded IDs sort like the IDs. (internal-use only)
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ID128Option configures an ID128.
+type ID128Option func(*ID128)
+
+// WithClock128 makes the ID128 read the time from c instead of the system
+// clock.
+func WithClock128(c Clock) ID128Option {
+	return func(id *ID128) {
+		id.clock = c
+	}
+}
+
+// ID128 is a generator of 128-bit snowflake IDs, for IDs with no practical
+// lifetime or throughput limit, e.g. the keys of an event store. Its IDs are
+// big-endian, so that they sort by time as byte strings:
+//
+//	Format:
+//	|---unix timestamp ms (64)---|--field (16)--|-----sequence (48)-----|
+//
+// The timestamp is in Unix milliseconds, independent of the epoch. The
+// sequence of every millisecond starts at a random value below 2^47, so
+// that the IDs of a millisecond aren't guessable from one another, and
+// counts up from there. Like ID, it never moves back in time: while the
+// clock is behind the last ID, it issues from the millisecond of the last
+// ID. It's safe for concurrent use.
+type ID128 struct {
+	mtx      sync.Mutex
+	field    uint64
+	clock    Clock // nil means the system clock
+	last     uint64
+	sequence uint64
+}
+
+// New128 returns a new snowflake.ID128 issuing IDs of field (max field:
+// 65535). A field bigger than the max is reset to 0.
+func New128(field uint64, opts ...ID128Option) *ID128 {
+	id := &ID128{}
+	if field <= maxField128 {
+		id.field = field
+	}
+	for _, opt := range opts {
+		opt(id)
+	}
+	return id
+}
+
+// NextID returns a new 128-bit snowflake ID.
+func (id *ID128) NextID() [16]byte {
+	now := time.Now()
+	if id.clock != nil {
+		now = id.clock.Now()
+	}
+	ms := uint64(now.UnixMilli())
+
+	id.mtx.Lock()
+	if ms > id.last {
+		id.last, id.sequence = ms, randomSequence128()
+	} else if id.sequence++; id.sequence > maxSequence128 {
+		// the sequence is used up, borrow the next millisecond
+		id.last++
+		id.sequence = randomSequence128()
+	}
+	ms, seq := id.last, id.sequence
+	id.mtx.Unlock()
+
+	var u [16]byte
+	binary.BigEndian.PutUint64(u[:8], ms)
+	binary.BigEndian.PutUint64(u[8:], id.field<<sequence128Bits|seq)
+	return u
+}
+
+// randomSequence128 returns a random start for the sequence of a
+// millisecond. (internal-use only)
+func randomSequence128() uint64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("snowflake: reading crypto/rand: " + err.Error())
+	}
+	return binary.BigEndian.Uint64(b[:]) % sequence128Start
+}
+
+// SID128 is the parsed representation of a 128-bit snowflake ID.
+type SID128 struct {
+	// Timestamp is the timestamp of the ID, in Unix milliseconds.
+	Timestamp int64
+	// Field is the field of the ID.
+	Field uint64
+	// Sequence is the sequence number of the ID.
+	Sequence uint64
+}
+
+// Parse128 parses an existing 128-bit snowflake ID.
+func Parse128(id [16]byte) SID128 {
+	low := binary.BigEndian.Uint64(id[8:])
+	return SID128{
+		Timestamp: int64(binary.BigEndian.Uint64(id[:8])),
+		Field:     low >> sequence128Bits,
+		Sequence:  low & maxSequence128,
+	}
+}
+
+// Format128Hex returns id as 32 lowercase hex digits, which sort like the
+// IDs.
+func Format128Hex(id [16]byte) string { return hex.EncodeToString(id[:]) }
+
+// Parse128Hex parses an ID formatted by Format128Hex, in either case.
+// Returns an error wrapping ErrInvalidID if s isn't 32 hex digits.
+func Parse128Hex(s string) ([16]byte, error) {
+	var id [16]byte
+	if len(s) != 2*len(id) {
+		return id, fmt.Errorf("snowflake: %q isn't 32 hex digits: %w", s, ErrInvalidID)
+	}
+	if _, err := hex.Decode(id[:], []byte(s)); err != nil {
+		return [16]byte{}, fmt.Errorf("snowflake: %q isn't 32 hex digits: %w", s, ErrInvalidID)
+	}
+	return id, nil
+}
+
+// Format128Base32 returns id as 26 digits of Crockford's base32, in
+// uppercase, which sort like the IDs. The first digit only holds the
+// highest 3 bits, the 2 above them being 0.
+func Format128Base32(id [16]byte) string {
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+
+	var b [26]byte
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = crockford[lo&0x1F]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(b[:])
+}
+
+// Parse128Base32 parses an ID formatted by Format128Base32, in either case.
+// Returns an error wrapping ErrInvalidID if s isn't 26 base32 digits or
+// overflows 128 bits.
+func Parse128Base32(s string) ([16]byte, error) {
+	var id [16]byte
+	if len(s) != 26 {
+		return id, fmt.Errorf("snowflake: %q isn't 26 base32 digits: %w", s, ErrInvalidID)
+	}
+
+	var hi, lo uint64
+	for i := 0; i < len(s); i++ {
+		d := crockfordIndex[s[i]]
+		if d == 0xFF || i == 0 && d > 7 {
+			return id, fmt.Errorf("snowflake: %q isn't a base32 ID: %w", s, ErrInvalidID)
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(d)
+	}
+	binary.BigEndian.PutUint64(id[:8], hi)
+	binary.BigEndian.PutUint64(id[8:], lo)
+	return id, nil
+}
+
+// crockfordIndex maps a byte to its base32 digit, in either case, or 0xFF.
+// (internal-use only)
+var crockfordIndex = func() (index [256]byte) {
+	for i := range index {
+		index[i] = 0xFF
+	}
+	for i := 0; i < len(crockford); i++ {
+		index[crockford[i]] = byte(i)
+		if c := crockford[i]; c >= 'A' && c <= 'Z' {
+			index[c+'a'-'A'] = byte(i)
+		}
+	}
+	return index
+}()
@@ -0,0 +1,195 @@
+package snowflake_test
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestNew128(t *testing.T) {
+	tc := []struct {
+		name  string
+		field uint64
+		want  uint64
+	}{
+		{"Should keep field 0", 0, 0},
+		{"Should keep a field bigger than 10 bits", 5000, 5000},
+		{"Should keep the max field", 65535, 65535},
+		{"Should reset a field bigger than the max", 65536, 0},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if field := snowflake.Parse128(snowflake.New128(tt.field).NextID()).Field; field != tt.want {
+				t.Errorf("expected field %d got %d", tt.want, field)
+			}
+		})
+	}
+}
+
+func TestID128_Parse(t *testing.T) {
+	now := time.Date(2030, 1, 2, 3, 4, 5, 6e6, time.UTC)
+	sf := snowflake.New128(4321, snowflake.WithClock128(newFakeClock(now)))
+
+	first, second := snowflake.Parse128(sf.NextID()), snowflake.Parse128(sf.NextID())
+	if first.Timestamp != now.UnixMilli() {
+		t.Errorf("expected timestamp %d got %d", now.UnixMilli(), first.Timestamp)
+	}
+	if first.Field != 4321 {
+		t.Errorf("expected field 4321 got %d", first.Field)
+	}
+	if first.Sequence >= 1<<47 {
+		t.Errorf("expected the sequence to start below 2^47 got %d", first.Sequence)
+	}
+	if second.Timestamp != first.Timestamp || second.Sequence != first.Sequence+1 {
+		t.Errorf("expected %+v to follow %+v", second, first)
+	}
+}
+
+func TestID128_Unique(t *testing.T) {
+	sf := snowflake.New128(1)
+
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[[16]byte]struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([][16]byte, 10000)
+			for j := range ids {
+				ids[j] = sf.NextID()
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			for _, id := range ids {
+				if _, ok := seen[id]; ok {
+					t.Errorf("expected unique IDs got %x twice", id)
+				}
+				seen[id] = struct{}{}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestID128_Monotonic(t *testing.T) {
+	clock := newFakeClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	sf := snowflake.New128(1, snowflake.WithClock128(clock))
+
+	steps := []time.Duration{0, 0, time.Millisecond, -5 * time.Millisecond, 0, 10 * time.Millisecond, -time.Hour, 0}
+	last := sf.NextID()
+	for i, step := range steps {
+		clock.Add(step)
+		id := sf.NextID()
+		if bytes.Compare(id[:], last[:]) <= 0 {
+			t.Fatalf("step %d: expected ID %x to be greater than %x", i, id, last)
+		}
+		last = id
+	}
+}
+
+func TestID128_ByteOrder(t *testing.T) {
+	clock := newFakeClock(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))
+	sf := snowflake.New128(1, snowflake.WithClock128(clock))
+
+	// span byte boundaries of the timestamp
+	issued := make([][16]byte, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		clock.Add(time.Duration(i%7) * 37 * time.Millisecond)
+		issued = append(issued, sf.NextID())
+	}
+
+	sorted := append([][16]byte(nil), issued...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i][:], sorted[j][:]) < 0 })
+	for i := range issued {
+		if sorted[i] != issued[i] {
+			t.Fatalf("expected IDs to sort as byte strings in the order they were issued, %x at %d", issued[i], i)
+		}
+	}
+
+	hexes, base32s := make([]string, len(issued)), make([]string, len(issued))
+	for i, id := range issued {
+		hexes[i], base32s[i] = snowflake.Format128Hex(id), snowflake.Format128Base32(id)
+	}
+	if !sort.StringsAreSorted(hexes) {
+		t.Errorf("expected hex IDs to sort in the order they were issued")
+	}
+	if !sort.StringsAreSorted(base32s) {
+		t.Errorf("expected base32 IDs to sort in the order they were issued")
+	}
+}
+
+func TestID128_Encoding(t *testing.T) {
+	tc := []struct {
+		name        string
+		id          [16]byte
+		hex, base32 string
+	}{
+		{"Should encode zero", [16]byte{}, "00000000000000000000000000000000", "00000000000000000000000000"},
+		{"Should encode the max", [16]byte{0: 0xFF, 1: 0xFF, 2: 0xFF, 3: 0xFF, 4: 0xFF, 5: 0xFF, 6: 0xFF, 7: 0xFF, 8: 0xFF, 9: 0xFF, 10: 0xFF, 11: 0xFF, 12: 0xFF, 13: 0xFF, 14: 0xFF, 15: 0xFF},
+			"ffffffffffffffffffffffffffffffff", "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		{"Should encode the lowest bit", [16]byte{15: 1}, "00000000000000000000000000000001", "00000000000000000000000001"},
+		{"Should encode the highest bit", [16]byte{0: 0x80}, "80000000000000000000000000000000", "40000000000000000000000000"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if s := snowflake.Format128Hex(tt.id); s != tt.hex {
+				t.Errorf("expected hex %s got %s", tt.hex, s)
+			}
+			if s := snowflake.Format128Base32(tt.id); s != tt.base32 {
+				t.Errorf("expected base32 %s got %s", tt.base32, s)
+			}
+			if id, err := snowflake.Parse128Hex(strings.ToUpper(tt.hex)); err != nil || id != tt.id {
+				t.Errorf("expected %x got %x %v", tt.id, id, err)
+			}
+			if id, err := snowflake.Parse128Base32(strings.ToLower(tt.base32)); err != nil || id != tt.id {
+				t.Errorf("expected %x got %x %v", tt.id, id, err)
+			}
+		})
+	}
+}
+
+func TestID128_RoundTrip(t *testing.T) {
+	sf := snowflake.New128(65535)
+	for i := 0; i < 1000; i++ {
+		id := sf.NextID()
+		if got, err := snowflake.Parse128Hex(snowflake.Format128Hex(id)); err != nil || got != id {
+			t.Fatalf("expected %x got %x %v", id, got, err)
+		}
+		if got, err := snowflake.Parse128Base32(snowflake.Format128Base32(id)); err != nil || got != id {
+			t.Fatalf("expected %x got %x %v", id, got, err)
+		}
+	}
+}
+
+func TestID128_ParseInvalid(t *testing.T) {
+	tc := []struct {
+		name  string
+		parse func(string) ([16]byte, error)
+		s     string
+	}{
+		{"Should reject short hex", snowflake.Parse128Hex, "0123"},
+		{"Should reject long hex", snowflake.Parse128Hex, strings.Repeat("0", 33)},
+		{"Should reject non-hex digits", snowflake.Parse128Hex, strings.Repeat("g", 32)},
+		{"Should reject short base32", snowflake.Parse128Base32, "0123"},
+		{"Should reject long base32", snowflake.Parse128Base32, strings.Repeat("0", 27)},
+		{"Should reject letters outside the alphabet", snowflake.Parse128Base32, strings.Repeat("U", 26)},
+		{"Should reject overflowing 128 bits", snowflake.Parse128Base32, "8" + strings.Repeat("0", 25)},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.parse(tt.s); !errors.Is(err, snowflake.ErrInvalidID) {
+				t.Errorf("expected error %v got %v", snowflake.ErrInvalidID, err)
+			}
+		})
+	}
+}
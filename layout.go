@@ -0,0 +1,177 @@
+package snowflake
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// layoutSkew is how far ahead of the clock ParseAuto finds timestamps
+// plausible, for IDs of generators whose clocks run ahead.
+// (internal-use only)
+const layoutSkew = time.Minute
+
+// ToID2Layout translates an ID of the ID layout into the ID of the ID2
+// layout with the same timestamp and sequence, and the fields mapFn returns
+// for its field. A nil mapFn reinterprets the bits as they are: the low 5
+// bits of the field become field1 and the high 5 bits field2.
+//
+// Returns the error of mapFn, wrapped, or an error wrapping
+// ErrFieldOutOfRange if it returns a field bigger than 31.
+func ToID2Layout(id uint64, mapFn func(field uint64) (f1, f2 uint64, err error)) (uint64, error) {
+	field := getMachineID(id)
+	if mapFn == nil {
+		return id, nil
+	}
+
+	f1, f2, err := mapFn(field)
+	if err != nil {
+		return 0, fmt.Errorf("snowflake: mapping field %d of ID %d: %w", field, id, err)
+	}
+	if f1 > maxFieldHalfBits || f2 > maxFieldHalfBits {
+		return 0, fmt.Errorf("snowflake: field %d of ID %d mapped to %d and %d: %w", field, id, f1, f2, ErrFieldOutOfRange)
+	}
+	return withField(id, f2<<(fieldBits/2)|f1), nil
+}
+
+// FromID2Layout is the inverse of ToID2Layout: it translates an ID of the
+// ID2 layout into the ID of the ID layout with the field mapFn returns for
+// its fields. A nil mapFn reinterprets the bits as they are.
+//
+// Returns the error of mapFn, wrapped, or an error wrapping
+// ErrFieldOutOfRange if it returns a field bigger than 1023.
+func FromID2Layout(id uint64, mapFn func(f1, f2 uint64) (field uint64, err error)) (uint64, error) {
+	f1, f2 := getWorkerID(id), getDatacenterID(id)
+	if mapFn == nil {
+		return id, nil
+	}
+
+	field, err := mapFn(f1, f2)
+	if err != nil {
+		return 0, fmt.Errorf("snowflake: mapping fields %d and %d of ID %d: %w", f1, f2, id, err)
+	}
+	if field > maxFieldBits {
+		return 0, fmt.Errorf("snowflake: fields %d and %d of ID %d mapped to %d: %w", f1, f2, id, field, ErrFieldOutOfRange)
+	}
+	return withField(id, field), nil
+}
+
+// withField returns id with its field bits replaced by field.
+// (internal-use only)
+func withField(id, field uint64) uint64 {
+	return id&^(maxFieldBits<<sequenceBits) | field<<sequenceBits
+}
+
+// Layout describes how a 63-bit snowflake ID is laid out, from the highest
+// bits to the lowest: timestamp, field and sequence, or timestamp, sequence
+// and field under the FieldLow order.
+type Layout struct {
+	// Name identifies the layout in errors.
+	Name string
+	// Epoch is the epoch of the timestamps, which are in milliseconds.
+	Epoch time.Time
+	// TimestampBits, FieldBits and SequenceBits are the number of bits of
+	// each part, 63 at most altogether.
+	TimestampBits, FieldBits, SequenceBits int
+	// MaxField, if positive, is the highest field in use, IDs with a
+	// bigger field being less plausible.
+	MaxField uint64
+	// Order is the order of the field and the sequence, see
+	// WithSegmentOrder.
+	Order SegmentOrder
+}
+
+// DefaultLayout returns the layout of the IDs of this package, under the
+// current epoch.
+func DefaultLayout() Layout {
+	return Layout{
+		Name:          "snowflake",
+		Epoch:         Epoch(),
+		TimestampBits: timestampBits,
+		FieldBits:     fieldBits,
+		SequenceBits:  sequenceBits,
+	}
+}
+
+// Decomposed is an ID split into its parts by a Layout.
+type Decomposed struct {
+	// Timestamp is the timestamp of the ID, to the millisecond.
+	Timestamp time.Time
+	// Field is the field of the ID.
+	Field uint64
+	// Sequence is the sequence number of the ID.
+	Sequence uint64
+}
+
+// Decompose splits id into its parts. ok is false if the layout doesn't fit
+// 63 bits or id overflows it.
+func (l Layout) Decompose(id uint64) (d Decomposed, ok bool) {
+	if l.TimestampBits < 1 || l.FieldBits < 0 || l.SequenceBits < 0 || l.TimestampBits+l.FieldBits+l.SequenceBits > 63 {
+		return Decomposed{}, false
+	}
+	shift := uint(l.FieldBits + l.SequenceBits)
+	elapsed, epoch := id>>shift, l.Epoch.UnixMilli()
+	if elapsed >= 1<<uint(l.TimestampBits) || int64(elapsed) > math.MaxInt64-epoch {
+		return Decomposed{}, false
+	}
+	fieldMask, seqMask := uint64(1)<<uint(l.FieldBits)-1, uint64(1)<<uint(l.SequenceBits)-1
+	field, seq := id>>uint(l.SequenceBits)&fieldMask, id&seqMask
+	if l.Order == FieldLow {
+		field, seq = id&fieldMask, id>>uint(l.FieldBits)&seqMask
+	}
+	return Decomposed{
+		Timestamp: time.UnixMilli(epoch + int64(elapsed)).UTC(),
+		Field:     field,
+		Sequence:  seq,
+	}, true
+}
+
+// score rates how plausible it is that id was issued with l: 0 if it
+// can't have been, 1 if its timestamp is plausible and 2 if its field is
+// too. (internal-use only)
+func (l Layout) score(id uint64, now time.Time) (Decomposed, int) {
+	d, ok := l.Decompose(id)
+	if !ok || d.Timestamp.After(now.Add(layoutSkew)) {
+		return d, 0
+	}
+	if l.MaxField > 0 && d.Field > l.MaxField {
+		return d, 1
+	}
+	return d, 2
+}
+
+// ParseAuto parses id under the most plausible of candidates, e.g. for
+// IDs of unknown provenance. An ID is plausible under a layout if its
+// timestamp is between the epoch and a minute from now, and more so if its
+// field is up to the MaxField of the layout.
+//
+// Returns an error wrapping ErrNoPlausibleLayout if id is implausible under
+// all the candidates, and ErrAmbiguousLayout, naming them, if it's as
+// plausible under several of them.
+func ParseAuto(id uint64, candidates []Layout) (Decomposed, Layout, error) {
+	now := time.Now()
+
+	best, bestScore := -1, 0
+	var ties []string
+	var decomposed Decomposed
+	for i, l := range candidates {
+		d, score := l.score(id, now)
+		switch {
+		case score == 0 || score < bestScore:
+		case score > bestScore:
+			best, bestScore, decomposed = i, score, d
+			ties = append(ties[:0], l.Name)
+		default:
+			ties = append(ties, l.Name)
+		}
+	}
+
+	switch {
+	case best < 0:
+		return Decomposed{}, Layout{}, fmt.Errorf("snowflake: ID %d is implausible under %d layouts: %w", id, len(candidates), ErrNoPlausibleLayout)
+	case len(ties) > 1:
+		return Decomposed{}, Layout{}, fmt.Errorf("snowflake: ID %d is as plausible under %s: %w", id, strings.Join(ties, ", "), ErrAmbiguousLayout)
+	}
+	return decomposed, candidates[best], nil
+}
@@ -0,0 +1,183 @@
+package snowflake_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+var errNoMapping = errors.New("no mapping")
+
+func TestToID2Layout_Identity(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	identity := func(field uint64) (uint64, uint64, error) { return field & 0x1F, field >> 5, nil }
+
+	for _, field := range []uint64{0, 1, 31, 32, 700, 1023} {
+		id := snowflake.New(field, snowflake.WithClock(clock)).NextID()
+		sid := snowflake.Parse(id)
+
+		for _, mapFn := range []func(uint64) (uint64, uint64, error){nil, identity} {
+			id2, err := snowflake.ToID2Layout(id, mapFn)
+			if err != nil {
+				t.Fatalf("expected no error got %v", err)
+			}
+			sid2 := snowflake.Parse2(id2)
+			if sid2.Timestamp != sid.Timestamp || sid2.Sequence != sid.Sequence {
+				t.Errorf("expected timestamp %d and sequence %d got %d and %d", sid.Timestamp, sid.Sequence, sid2.Timestamp, sid2.Sequence)
+			}
+			if sid2.Field1 != field&0x1F || sid2.Field2 != field>>5 {
+				t.Errorf("expected fields %d and %d got %d and %d", field&0x1F, field>>5, sid2.Field1, sid2.Field2)
+			}
+
+			back, err := snowflake.FromID2Layout(id2, func(f1, f2 uint64) (uint64, error) { return f2<<5 | f1, nil })
+			if err != nil {
+				t.Fatalf("expected no error got %v", err)
+			}
+			if back != id {
+				t.Errorf("expected %d got %d", id, back)
+			}
+		}
+	}
+}
+
+func TestToID2Layout_LookupTable(t *testing.T) {
+	// fields 3 and 7 move to datacenter 2, field 5 has no mapping
+	table := map[uint64][2]uint64{3: {1, 2}, 7: {4, 2}}
+	mapFn := func(field uint64) (uint64, uint64, error) {
+		f, ok := table[field]
+		if !ok {
+			return 0, 0, fmt.Errorf("field %d: %w", field, errNoMapping)
+		}
+		return f[0], f[1], nil
+	}
+	reverse := func(f1, f2 uint64) (uint64, error) {
+		for field, f := range table {
+			if f == [2]uint64{f1, f2} {
+				return field, nil
+			}
+		}
+		return 0, errNoMapping
+	}
+
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	tc := []struct {
+		name     string
+		field    uint64
+		expected [2]uint64
+		err      error
+	}{
+		{"Should map field 3", 3, [2]uint64{1, 2}, nil},
+		{"Should map field 7", 7, [2]uint64{4, 2}, nil},
+		{"Should fail on a hole", 5, [2]uint64{}, errNoMapping},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			id := snowflake.New(tt.field, snowflake.WithClock(clock)).NextID()
+
+			id2, err := snowflake.ToID2Layout(id, mapFn)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if sid2 := snowflake.Parse2(id2); sid2.Field1 != tt.expected[0] || sid2.Field2 != tt.expected[1] {
+				t.Errorf("expected fields %v got %d and %d", tt.expected, sid2.Field1, sid2.Field2)
+			}
+			if id2>>22 != id>>22 || id2&0xFFF != id&0xFFF {
+				t.Errorf("expected the timestamp and sequence of %d got %d", id, id2)
+			}
+
+			back, err := snowflake.FromID2Layout(id2, reverse)
+			if err != nil || back != id {
+				t.Errorf("expected %d got %d (%v)", id, back, err)
+			}
+		})
+	}
+}
+
+func TestLayout_OutOfRange(t *testing.T) {
+	id := snowflake.New(1).NextID()
+
+	if _, err := snowflake.ToID2Layout(id, func(uint64) (uint64, uint64, error) { return 32, 0, nil }); !errors.Is(err, snowflake.ErrFieldOutOfRange) {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldOutOfRange, err)
+	}
+	if _, err := snowflake.ToID2Layout(id, func(uint64) (uint64, uint64, error) { return 0, 32, nil }); !errors.Is(err, snowflake.ErrFieldOutOfRange) {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldOutOfRange, err)
+	}
+	if _, err := snowflake.FromID2Layout(id, func(uint64, uint64) (uint64, error) { return 1024, nil }); !errors.Is(err, snowflake.ErrFieldOutOfRange) {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldOutOfRange, err)
+	}
+}
+
+func TestParseAuto(t *testing.T) {
+	epoch2000 := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := snowflake.Layout{Name: "old", Epoch: epoch2000, TimestampBits: 41, FieldBits: 10, SequenceBits: 12, MaxField: 1}
+	recent := snowflake.Layout{Name: "recent", Epoch: epoch2020, TimestampBits: 41, FieldBits: 10, SequenceBits: 12, MaxField: 7}
+	wide := snowflake.Layout{Name: "wide", Epoch: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), TimestampBits: 39, FieldBits: 8, SequenceBits: 16}
+	candidates := []snowflake.Layout{old, recent, wide}
+
+	lastWeek := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Millisecond).UTC()
+	encode := func(l snowflake.Layout, at time.Time, field, seq uint64) uint64 {
+		return uint64(at.Sub(l.Epoch).Milliseconds())<<(l.FieldBits+l.SequenceBits) | field<<l.SequenceBits | seq
+	}
+
+	tc := []struct {
+		name       string
+		candidates []snowflake.Layout
+		id         uint64
+		expected   string
+		err        error
+	}{
+		// under the later epochs, it lands decades from now
+		{"Should detect the layout of the only plausible timestamp", candidates, encode(old, lastWeek, 1, 5), "old", nil},
+		// under the old epoch it lands in 2006, but with field 2 it can't be
+		{"Should detect the layout of the only plausible field", candidates[:2], encode(recent, lastWeek, 2, 5), "recent", nil},
+		{"Should report ties", candidates[:2], encode(recent, lastWeek, 1, 5), "", snowflake.ErrAmbiguousLayout},
+		{"Should report IDs implausible under every layout", candidates, encode(old, lastWeek.AddDate(10, 0, 0), 1, 5), "", snowflake.ErrNoPlausibleLayout},
+		{"Should report no candidates", nil, 42, "", snowflake.ErrNoPlausibleLayout},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			d, l, err := snowflake.ParseAuto(tt.id, tt.candidates)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v got %v", tt.err, err)
+			}
+			if l.Name != tt.expected {
+				t.Errorf("expected layout %q got %q", tt.expected, l.Name)
+			}
+			if err != nil {
+				return
+			}
+			if !d.Timestamp.Equal(lastWeek) || d.Sequence != 5 {
+				t.Errorf("expected timestamp %v and sequence 5 got %+v", lastWeek, d)
+			}
+		})
+	}
+
+	if _, _, err := snowflake.ParseAuto(encode(recent, lastWeek, 1, 5), candidates[:2]); err == nil || !strings.Contains(err.Error(), "old, recent") {
+		t.Errorf("expected the ties named got %v", err)
+	}
+}
+
+func TestLayout_Decompose(t *testing.T) {
+	id := snowflake.New(700).NextID()
+	d, ok := snowflake.DefaultLayout().Decompose(id)
+	sid := snowflake.Parse(id)
+	if !ok || d.Timestamp.UnixMilli() != sid.Timestamp || d.Field != sid.MachineID || d.Sequence != sid.Sequence {
+		t.Errorf("expected %+v got %+v (%v)", sid, d, ok)
+	}
+
+	if _, ok := (snowflake.Layout{TimestampBits: 42, FieldBits: 10, SequenceBits: 12}).Decompose(id); ok {
+		t.Error("expected a layout over 63 bits to fail")
+	}
+	if _, ok := (snowflake.Layout{TimestampBits: 30, FieldBits: 10, SequenceBits: 12}).Decompose(id); ok {
+		t.Error("expected an ID overflowing the layout to fail")
+	}
+}
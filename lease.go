@@ -0,0 +1,307 @@
+package snowflake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FieldAllocator hands out fields to generators so that no two live
+// generators share one, e.g. backed by Redis, etcd, Consul or ZooKeeper.
+// See NewLeased.
+type FieldAllocator interface {
+	// Acquire claims a free field until the returned Lease is closed or
+	// lost.
+	Acquire(ctx context.Context) (Lease, error)
+}
+
+// Lease is a field held from a FieldAllocator.
+type Lease interface {
+	// Field returns the leased field.
+	Field() uint64
+	// Done is closed when the lease is lost, after which the field may be
+	// handed to another generator. It needn't be closed by Close.
+	Done() <-chan struct{}
+	// Close releases the field.
+	Close() error
+}
+
+// LeasePolicy is what a generator created with NewLeased does once its
+// lease is lost.
+type LeasePolicy int
+
+const (
+	// BlockOnLeaseLoss blocks the generator for good: the field may be
+	// another generator's by then, so no ID is issued with it anymore.
+	BlockOnLeaseLoss LeasePolicy = iota
+	// ReacquireOnLeaseLoss blocks the generator until a new lease is
+	// acquired, retrying with a backoff, then carries on with its field.
+	ReacquireOnLeaseLoss
+)
+
+// Backoff of the acquisitions of ReacquireOnLeaseLoss. (internal-use only)
+const (
+	minLeaseBackoff = 10 * time.Millisecond
+	maxLeaseBackoff = 10 * time.Second
+)
+
+// WithLeasePolicy sets what a generator created with NewLeased does once
+// its lease is lost, BlockOnLeaseLoss by default. Other generators ignore
+// it.
+func WithLeasePolicy(p LeasePolicy) Option {
+	return func(g *generator) {
+		g.leasePolicy = p
+	}
+}
+
+// NewLeased returns a new snowflake.ID issuing IDs with the field of a
+// lease acquired from alloc. Health reports ErrLeaseLost while the lease is
+// lost, and generation blocks until a new one is acquired, if ever, see
+// WithLeasePolicy. Cancel ctx to close the lease once the generator is
+// discarded; the generator must not be used afterwards.
+//
+//	alloc := redisalloc.Allocator{Client: rdb, Keyspace: "snowflake:fields", TTL: 10 * time.Second}
+//	sf, err := snowflake.NewLeased(ctx, alloc, snowflake.WithLeasePolicy(snowflake.ReacquireOnLeaseLoss))
+//
+// Returns the error of alloc, or an error wrapping ErrFieldOutOfRange if
+// the leased field is bigger than 1023.
+func NewLeased(ctx context.Context, alloc FieldAllocator, opts ...Option) (*ID, error) {
+	lease, err := acquireField(ctx, alloc)
+	if err != nil {
+		return nil, err
+	}
+
+	id := New(lease.Field(), opts...)
+	id.leaseDone = lease.Done()
+	id.leaseHeld = make(chan struct{})
+	close(id.leaseHeld)
+
+	go id.watchLease(ctx, alloc, lease)
+	return id, nil
+}
+
+// acquireField acquires a lease of alloc, closing it if its field is out
+// of range. (internal-use only)
+func acquireField(ctx context.Context, alloc FieldAllocator) (Lease, error) {
+	lease, err := alloc.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if field := lease.Field(); field > maxFieldBits {
+		lease.Close()
+		return nil, fmt.Errorf("snowflake: leased field %d is bigger than %d: %w", field, maxFieldBits, ErrFieldOutOfRange)
+	}
+	return lease, nil
+}
+
+// watchLease blocks generation whenever the lease is lost, and reacquires
+// one if the policy says so, until ctx is done. (internal-use only)
+func (id *ID) watchLease(ctx context.Context, alloc FieldAllocator, lease Lease) {
+	for {
+		select {
+		case <-lease.Done():
+		case <-ctx.Done():
+			id.blockLease()
+			lease.Close()
+			return
+		}
+
+		id.blockLease()
+		lease.Close()
+		if id.leasePolicy != ReacquireOnLeaseLoss {
+			return
+		}
+
+		var err error
+		for backoff := minLeaseBackoff; ; {
+			if lease, err = acquireField(ctx, alloc); err == nil {
+				break
+			}
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+			if backoff *= 2; backoff > maxLeaseBackoff {
+				backoff = maxLeaseBackoff
+			}
+		}
+
+		id.setField(lease.Field())
+		id.mtx.Lock()
+		id.leaseDone = lease.Done()
+		close(id.leaseHeld)
+		id.mtx.Unlock()
+	}
+}
+
+// blockLease blocks generation until the next lease. (internal-use only)
+func (id *ID) blockLease() {
+	id.mtx.Lock()
+	id.closeLeaseGate()
+	id.mtx.Unlock()
+}
+
+// closeLeaseGate makes generation wait for the next lease, if it doesn't
+// already. g.mtx must be held. (internal-use only)
+func (g *generator) closeLeaseGate() {
+	select {
+	case <-g.leaseHeld:
+		g.leaseHeld = make(chan struct{})
+	default:
+	}
+}
+
+// awaitLease waits until the generator holds a lease, if it was created
+// with NewLeased. g.mtx must be held, it is released while waiting.
+// (internal-use only)
+func (g *generator) awaitLease() {
+	for g.leaseHeld != nil {
+		select {
+		case <-g.leaseDone:
+			// lost, whether watchLease noticed yet or not
+			g.closeLeaseGate()
+		default:
+		}
+
+		held := g.leaseHeld
+		select {
+		case <-held:
+			return
+		default:
+		}
+
+		g.mtx.Unlock()
+		<-held
+		g.mtx.Lock()
+	}
+}
+
+// SetField switches the generator to field, e.g. the field of a new lease
+// after the last one was lost. The IDs issued afterwards start from the
+// next millisecond, so that they keep increasing. Returns an error
+// wrapping ErrFieldOutOfRange if field is bigger than 1023, or than 255
+// with WithVersionTag, and ErrFieldInUse if the generator was created with
+// NewRegistered and field is registered by another generator.
+func (id *ID) SetField(field uint64) error {
+	max := uint64(maxFieldBits)
+	if id.versionTag != 0 {
+		max = maxTaggedField
+	}
+	if field > max {
+		return fmt.Errorf("snowflake: field %d is bigger than %d: %w", field, max, ErrFieldOutOfRange)
+	}
+
+	if atomic.LoadUint32(&id.registered) == 1 {
+		registry.mtx.Lock()
+		defer registry.mtx.Unlock()
+		if field != id.field && registry.fields[field] {
+			return ErrFieldInUse
+		}
+		delete(registry.fields, id.field)
+		registry.fields[field] = true
+	}
+
+	id.setField(field)
+	return nil
+}
+
+// setField switches the generator to field, which is in range.
+// (internal-use only)
+func (id *ID) setField(field uint64) {
+	id.mtx.Lock()
+	defer id.mtx.Unlock()
+
+	id.field = field
+	id.fieldSegment = field<<sequenceBits | id.versionTag<<versionShift
+	if id.observed <= id.elapsedTime {
+		id.observed = id.elapsedTime + 1
+	}
+	id.sinceCheck = 0
+}
+
+// MemoryAllocator is a FieldAllocator handing out the fields of a single
+// process, e.g. to several generators of one binary or to tests. The zero
+// value is ready to use. Its leases are only lost when revoked.
+type MemoryAllocator struct {
+	mtx    sync.Mutex
+	leases map[uint64]*memoryLease
+}
+
+var _ FieldAllocator = (*MemoryAllocator)(nil)
+
+// Acquire claims the lowest free field. Returns ErrNoFreeField when all
+// 1024 are taken.
+func (a *MemoryAllocator) Acquire(ctx context.Context) (Lease, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.leases == nil {
+		a.leases = make(map[uint64]*memoryLease)
+	}
+	for field := uint64(0); field <= maxFieldBits; field++ {
+		if _, taken := a.leases[field]; !taken {
+			l := &memoryLease{a: a, field: field, done: make(chan struct{})}
+			a.leases[field] = l
+			return l, nil
+		}
+	}
+	return nil, ErrNoFreeField
+}
+
+// Revoke loses the lease of field, closing its Done channel and freeing
+// the field, as if it had expired. It reports whether field was leased.
+func (a *MemoryAllocator) Revoke(field uint64) bool {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	l, ok := a.leases[field]
+	if ok {
+		delete(a.leases, field)
+		close(l.done)
+	}
+	return ok
+}
+
+// Fields returns the leased fields, in increasing order.
+func (a *MemoryAllocator) Fields() []uint64 {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	var fields []uint64
+	for field := uint64(0); field <= maxFieldBits; field++ {
+		if _, taken := a.leases[field]; taken {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// memoryLease is a Lease of a MemoryAllocator. (internal-use only)
+type memoryLease struct {
+	a     *MemoryAllocator
+	field uint64
+	done  chan struct{}
+}
+
+func (l *memoryLease) Field() uint64         { return l.field }
+func (l *memoryLease) Done() <-chan struct{} { return l.done }
+
+func (l *memoryLease) Close() error {
+	l.a.mtx.Lock()
+	defer l.a.mtx.Unlock()
+
+	if l.a.leases[l.field] == l {
+		delete(l.a.leases, l.field)
+	}
+	return nil
+}
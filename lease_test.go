@@ -0,0 +1,273 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// nextIDWithin returns the next ID of sf, or false if it blocks for longer
+// than d.
+func nextIDWithin(sf *snowflake.ID, d time.Duration) (uint64, bool) {
+	ids := make(chan uint64, 1)
+	go func() { ids <- sf.NextID() }()
+
+	select {
+	case id := <-ids:
+		return id, true
+	case <-time.After(d):
+		return 0, false
+	}
+}
+
+func TestNewLeased(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var alloc snowflake.MemoryAllocator
+	a, err := snowflake.NewLeased(ctx, &alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := snowflake.NewLeased(ctx, &alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if field := snowflake.Parse(a.NextID()).MachineID; field != 0 {
+		t.Errorf("expected field 0 got %d", field)
+	}
+	if field := snowflake.Parse(b.NextID()).MachineID; field != 1 {
+		t.Errorf("expected field 1 got %d", field)
+	}
+	if err := a.Health(); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+
+	// canceling ctx closes the leases
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(alloc.Fields()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if fields := alloc.Fields(); len(fields) != 0 {
+		t.Errorf("expected no leased fields got %v", fields)
+	}
+}
+
+func TestNewLeased_BlockOnLeaseLoss(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var alloc snowflake.MemoryAllocator
+	sf, err := snowflake.NewLeased(ctx, &alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf.NextID()
+
+	alloc.Revoke(0)
+	if _, ok := nextIDWithin(sf, 100*time.Millisecond); ok {
+		t.Fatal("expected NextID to block once the lease is lost")
+	}
+	if err := sf.Health(); !errors.Is(err, snowflake.ErrLeaseLost) {
+		t.Errorf("expected error %v got %v", snowflake.ErrLeaseLost, err)
+	}
+	if fields := alloc.Fields(); len(fields) != 0 {
+		t.Errorf("expected no leased fields got %v", fields)
+	}
+}
+
+func TestNewLeased_ReacquireOnLeaseLoss(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var alloc snowflake.MemoryAllocator
+	sf, err := snowflake.NewLeased(ctx, &alloc, snowflake.WithLeasePolicy(snowflake.ReacquireOnLeaseLoss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := sf.NextID()
+
+	// hold field 1, the generator gets field 0 back
+	if _, err := alloc.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	alloc.Revoke(0)
+
+	after, ok := nextIDWithin(sf, 5*time.Second)
+	if !ok {
+		t.Fatal("expected NextID to resume once a lease is reacquired")
+	}
+	if field := snowflake.Parse(after).MachineID; field != 0 {
+		t.Errorf("expected field 0 got %d", field)
+	}
+	if after <= before {
+		t.Errorf("expected ID %d to be greater than %d", after, before)
+	}
+	if err := sf.Health(); errors.Is(err, snowflake.ErrLeaseLost) {
+		t.Errorf("expected the lease to be held got %v", err)
+	}
+	if fields := alloc.Fields(); !reflect.DeepEqual(fields, []uint64{0, 1}) {
+		t.Errorf("expected leased fields [0 1] got %v", fields)
+	}
+}
+
+// fixedAllocator leases field, which may be out of range.
+type fixedAllocator struct{ field uint64 }
+
+func (a fixedAllocator) Acquire(ctx context.Context) (snowflake.Lease, error) {
+	var alloc snowflake.MemoryAllocator
+	lease, err := alloc.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fixedLease{Lease: lease, field: a.field}, nil
+}
+
+type fixedLease struct {
+	snowflake.Lease
+	field uint64
+}
+
+func (l fixedLease) Field() uint64 { return l.field }
+
+func TestNewLeased_Errors(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tc := []struct {
+		name  string
+		ctx   context.Context
+		alloc snowflake.FieldAllocator
+		err   error
+	}{
+		{"Should return the error of the allocator", canceled, &snowflake.MemoryAllocator{}, context.Canceled},
+		{"Should return ErrFieldOutOfRange", context.Background(), fixedAllocator{1024}, snowflake.ErrFieldOutOfRange},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sf, err := snowflake.NewLeased(tt.ctx, tt.alloc)
+			if !errors.Is(err, tt.err) || sf != nil {
+				t.Errorf("expected error %v got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestMemoryAllocator(t *testing.T) {
+	ctx := context.Background()
+
+	var alloc snowflake.MemoryAllocator
+	leases := make([]snowflake.Lease, 1024)
+	for i := range leases {
+		lease, err := alloc.Acquire(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lease.Field() != uint64(i) {
+			t.Fatalf("expected field %d got %d", i, lease.Field())
+		}
+		leases[i] = lease
+	}
+
+	if _, err := alloc.Acquire(ctx); err != snowflake.ErrNoFreeField {
+		t.Fatalf("expected error %v got %v", snowflake.ErrNoFreeField, err)
+	}
+
+	leases[7].Close()
+	lease, err := alloc.Acquire(ctx)
+	if err != nil || lease.Field() != 7 {
+		t.Fatalf("expected field 7 got %v %v", lease, err)
+	}
+
+	if !alloc.Revoke(7) {
+		t.Error("expected field 7 to be revoked")
+	}
+	select {
+	case <-lease.Done():
+	default:
+		t.Error("expected the revoked lease to be done")
+	}
+	if alloc.Revoke(7) {
+		t.Error("expected field 7 to be revoked once")
+	}
+
+	// closing a lost lease doesn't free the field of the next one
+	again, _ := alloc.Acquire(ctx)
+	lease.Close()
+	if _, err := alloc.Acquire(ctx); err != snowflake.ErrNoFreeField {
+		t.Errorf("expected field %d to stay leased got %v", again.Field(), err)
+	}
+}
+
+func TestSetField(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(1, snowflake.WithClock(clock))
+	before := sf.NextID()
+
+	if err := sf.SetField(2); err != nil {
+		t.Fatal(err)
+	}
+	after := snowflake.Parse(sf.NextID())
+	if after.MachineID != 2 {
+		t.Errorf("expected field 2 got %d", after.MachineID)
+	}
+	// the clock hasn't moved, so the IDs of field 2 start a millisecond on
+	if after.Timestamp != snowflake.Parse(before).Timestamp+1 || after.Sequence != 0 {
+		t.Errorf("expected the next millisecond got %+v", after)
+	}
+
+	tc := []struct {
+		name  string
+		sf    *snowflake.ID
+		field uint64
+	}{
+		{"Should return ErrFieldOutOfRange", sf, 1024},
+		{"Should return ErrFieldOutOfRange with a version tag", snowflake.New(1, snowflake.WithVersionTag(1)), 256},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sf.SetField(tt.field); !errors.Is(err, snowflake.ErrFieldOutOfRange) {
+				t.Errorf("expected error %v got %v", snowflake.ErrFieldOutOfRange, err)
+			}
+		})
+	}
+
+	tagged := snowflake.New(1, snowflake.WithVersionTag(1))
+	if err := tagged.SetField(255); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := snowflake.VersionOf(tagged.NextID()); v != 1 {
+		t.Errorf("expected version 1 got %d", v)
+	}
+}
+
+func TestSetField_Registered(t *testing.T) {
+	a, err := snowflake.NewRegistered(110)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Release()
+	b, err := snowflake.NewRegistered(111)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Release()
+
+	if err := a.SetField(111); err != snowflake.ErrFieldInUse {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldInUse, err)
+	}
+	if err := a.SetField(112); err != nil {
+		t.Fatal(err)
+	}
+	if fields := snowflake.RegisteredFields(); !reflect.DeepEqual(fields, []uint64{111, 112}) {
+		t.Errorf("expected registered fields [111 112] got %v", fields)
+	}
+}
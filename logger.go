@@ -0,0 +1,48 @@
+//go:build go1.21
+// +build go1.21
+
+package snowflake
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger makes the generator log anomalies to l as warnings, with the
+// field of the generator as the "field" attribute:
+//
+//   - the clock going backwards, with the "regression" duration
+//   - a wait for the next millisecond taking over 2ms, with the "waited"
+//     duration
+//   - a field out of range being reset to 0 by the constructor, with the
+//     "max" field value
+//   - a save to the store set with WithStateStore failing, with the
+//     "error"
+//   - a random ID issued while the clock is unusable, see
+//     WithRandomFallback, with the "reason"
+//
+// Each kind of record is logged at most once per second, so a broken
+// clock doesn't flood the logs; the number of records dropped since is
+// attached to the next one as "suppressed". Records are written while the
+// generator is locked, so l shouldn't block.
+// A nil logger logs nothing, which is the default.
+//
+// WithLogger needs Go 1.21 or later, for log/slog.
+func WithLogger(l *slog.Logger) Option {
+	return func(g *generator) {
+		if l == nil {
+			g.anomalies = nil
+			return
+		}
+
+		g.anomalies = &anomalyLog{
+			emit: func(msg string, field uint64, key string, value interface{}, suppressed uint64) {
+				attrs := []slog.Attr{slog.Uint64("field", field), slog.Any(key, value)}
+				if suppressed > 0 {
+					attrs = append(attrs, slog.Uint64("suppressed", suppressed))
+				}
+				l.LogAttrs(context.Background(), slog.LevelWarn, msg, attrs...)
+			},
+		}
+	}
+}
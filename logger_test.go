@@ -0,0 +1,155 @@
+//go:build go1.21
+// +build go1.21
+
+package snowflake_test
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// recorder is a slog.Handler keeping the records it handles.
+type recorder struct {
+	mtx     sync.Mutex
+	records []slog.Record
+}
+
+func (r *recorder) Enabled(context.Context, slog.Level) bool { return true }
+
+func (r *recorder) Handle(_ context.Context, rec slog.Record) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.records = append(r.records, rec)
+	return nil
+}
+
+func (r *recorder) WithAttrs([]slog.Attr) slog.Handler { return r }
+
+func (r *recorder) WithGroup(string) slog.Handler { return r }
+
+// attrs returns the attributes of the i-th record.
+func (r *recorder) attrs(i int) map[string]slog.Value {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	attrs := make(map[string]slog.Value)
+	r.records[i].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value
+		return true
+	})
+	return attrs
+}
+
+// jumpClock stands still for a number of readings, then jumps ahead.
+type jumpClock struct {
+	mtx   sync.Mutex
+	t     time.Time
+	reads int
+	after int
+	jump  time.Duration
+}
+
+func (c *jumpClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.reads++
+	if c.reads == c.after+1 {
+		c.t = c.t.Add(c.jump)
+	}
+	return c.t
+}
+
+func TestWithLogger_ClockBackwards(t *testing.T) {
+	rec := &recorder{}
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start.Add(10 * time.Millisecond))
+	sf := snowflake.New(5, snowflake.WithClock(clock), snowflake.WithLogger(slog.New(rec)))
+
+	sf.NextID()
+	clock.Set(start)
+	sf.NextID()
+	sf.NextID()
+
+	// a second regression within a second is suppressed
+	clock.Set(start.Add(-time.Millisecond))
+	sf.NextID()
+
+	if len(rec.records) != 1 {
+		t.Fatalf("expected 1 record got %d", len(rec.records))
+	}
+	if rec.records[0].Level != slog.LevelWarn {
+		t.Errorf("expected level %v got %v", slog.LevelWarn, rec.records[0].Level)
+	}
+
+	attrs := rec.attrs(0)
+	if d := attrs["regression"].Duration(); d != 10*time.Millisecond {
+		t.Errorf("expected a regression of %v got %v", 10*time.Millisecond, d)
+	}
+	if field := attrs["field"].Uint64(); field != 5 {
+		t.Errorf("expected field 5 got %d", field)
+	}
+}
+
+func TestWithLogger_SlowWait(t *testing.T) {
+	rec := &recorder{}
+	// the 4097th ID exhausts the millisecond; the clock jumps 5ms while
+	// waiting for the next one
+	clock := &jumpClock{t: snowflake.Epoch().Add(time.Hour), after: 4098, jump: 5 * time.Millisecond}
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithLogger(slog.New(rec)))
+
+	for i := 0; i < 4097; i++ {
+		sf.NextID()
+	}
+
+	if len(rec.records) != 1 {
+		t.Fatalf("expected 1 record got %d", len(rec.records))
+	}
+	if d := rec.attrs(0)["waited"].Duration(); d != 5*time.Millisecond {
+		t.Errorf("expected a wait of %v got %v", 5*time.Millisecond, d)
+	}
+}
+
+func TestWithLogger_FieldReset(t *testing.T) {
+	rec := &recorder{}
+	snowflake.New2(40, 3, snowflake.WithLogger(slog.New(rec)))
+
+	if len(rec.records) != 1 {
+		t.Fatalf("expected 1 record got %d", len(rec.records))
+	}
+
+	attrs := rec.attrs(0)
+	if field, max := attrs["field"].Uint64(), attrs["max"].Uint64(); field != 40 || max != 31 {
+		t.Errorf("expected field 40 and max 31 got %d and %d", field, max)
+	}
+}
+
+func TestWithLogger_Nil(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start.Add(10 * time.Millisecond))
+	sf := snowflake.New(2000, snowflake.WithClock(clock), snowflake.WithLogger(nil))
+
+	sf.NextID()
+	clock.Set(start)
+	sf.NextID()
+}
+
+func TestWithLogger_Fallback(t *testing.T) {
+	rec := &recorder{}
+	clock := newFakeClock(snowflake.Epoch().Add(-time.Hour))
+	sf := snowflake.New(5, snowflake.WithClock(clock), snowflake.WithRandomFallback(), snowflake.WithLogger(slog.New(rec)))
+
+	sf.NextID()
+	sf.NextID()
+
+	if len(rec.records) != 1 {
+		t.Fatalf("expected 1 record got %d", len(rec.records))
+	}
+	if reason := rec.attrs(0)["reason"].String(); reason != "before the epoch" {
+		t.Errorf("expected reason %q got %q", "before the epoch", reason)
+	}
+}
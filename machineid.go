@@ -0,0 +1,359 @@
+package snowflake
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// virtualInterfacePrefixes are name prefixes of interfaces created by
+// container runtimes, bridges and VPNs. (internal-use only)
+var virtualInterfacePrefixes = []string{
+	"veth", "docker", "br-", "virbr", "vmnet", "vboxnet", "cni", "flannel",
+	"cali", "weave", "tun", "tap", "wg", "zt", "utun", "awdl", "llw",
+}
+
+// Interface is a network interface as seen by the machine ID helpers.
+type Interface struct {
+	Name         string
+	Flags        net.Flags
+	HardwareAddr net.HardwareAddr
+	Addrs        []net.Addr
+}
+
+// Host is where machine IDs are derived from. Every field is optional and
+// defaults to reading the real host, so the zero value is what the
+// package-level MachineIDFrom* functions use. Set fields to derive machine
+// IDs from somewhere else, e.g. in tests.
+type Host struct {
+	// Interfaces lists the network interfaces.
+	Interfaces func() ([]Interface, error)
+	// Hostname returns the host name.
+	Hostname func() (string, error)
+	// CgroupFile lists the cgroups of the process, /proc/self/cgroup by
+	// default.
+	CgroupFile string
+	// MountinfoFile lists the mounts of the process, /proc/self/mountinfo
+	// by default.
+	MountinfoFile string
+
+	// HTTPClient queries cloud metadata services.
+	HTTPClient *http.Client
+	// AWSMetadataURL is the EC2 instance metadata service,
+	// http://169.254.169.254 by default.
+	AWSMetadataURL string
+	// ECSMetadataURL is the ECS task metadata endpoint, read from
+	// ECS_CONTAINER_METADATA_URI_V4 by default.
+	ECSMetadataURL string
+	// GCPMetadataURL is the GCE and Cloud Run metadata server,
+	// http://metadata.google.internal by default.
+	GCPMetadataURL string
+}
+
+// MachineIDFromIP derives a machine ID from the low 10 bits of the first
+// private IPv4 address of an interface that is up and not a loopback,
+// e.g. 10.0.3.17 gives 785 (0x311 & 0x3FF).
+// Returns ErrNoSuitableInterface when there is no such address.
+func MachineIDFromIP() (uint64, error) { return Host{}.MachineIDFromIP() }
+
+// MachineIDFromIPv6 derives a machine ID by hashing the interface identifier
+// (the low 64 bits) of the first global or unique local IPv6 address of an
+// interface that is up and not a loopback.
+// Different hosts can hash to the same machine ID.
+// Returns ErrNoSuitableInterface when there is no such address.
+func MachineIDFromIPv6() (uint64, error) { return Host{}.MachineIDFromIPv6() }
+
+// MachineIDFromMAC derives a machine ID by hashing (64-bit FNV-1a) the
+// hardware address of the first physical interface: not a loopback, not
+// named like a bridge, container or VPN interface, and with a globally
+// unique MAC (locally administered MACs, as handed out to containers and
+// VMs, are skipped).
+//
+// Hashing into 1024 slots collides often: any two hosts share a machine ID
+// with ~0.1% probability, but a fleet of 38 hosts already has even odds of
+// at least one collision. Prefer an allocated machine ID for larger fleets.
+// Returns ErrNoSuitableInterface when there is no physical interface.
+func MachineIDFromMAC() (uint64, error) { return Host{}.MachineIDFromMAC() }
+
+// MachineIDFromHostname derives a machine ID by hashing (64-bit FNV-1a) the
+// host name. Like MachineIDFromMAC, different hosts can hash to the same
+// machine ID.
+func MachineIDFromHostname() (uint64, error) { return Host{}.MachineIDFromHostname() }
+
+// MachineIDFromHostnameSuffix uses the number the host name ends with as the
+// machine ID, e.g. worker-17 gives 17. Only the first label of a fully
+// qualified name is looked at, so worker-17.example.com gives 17 too.
+// This suits StatefulSet-style naming where the suffix is unique.
+// Can return 2 errors: ErrNoNumericSuffix and ErrFieldOutOfRange.
+func MachineIDFromHostnameSuffix() (uint64, error) { return Host{}.MachineIDFromHostnameSuffix() }
+
+// MachineIDFromEnv reads a decimal machine ID from the environment variable
+// key, e.g. SNOWFLAKE_MACHINE_ID. The returned errors wrap one of
+// ErrMachineIDUnset, ErrMachineIDNotNumeric and ErrFieldOutOfRange,
+// use errors.Is to tell them apart.
+func MachineIDFromEnv(key string) (uint64, error) {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return 0, fmt.Errorf("snowflake: %s: %w", key, ErrMachineIDUnset)
+	}
+
+	field, err := strconv.ParseUint(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+			return 0, fmt.Errorf("snowflake: %s=%q: %w", key, value, ErrFieldOutOfRange)
+		}
+		return 0, fmt.Errorf("snowflake: %s=%q: %w", key, value, ErrMachineIDNotNumeric)
+	}
+
+	if field > MaxField() {
+		return 0, fmt.Errorf("snowflake: %s=%q: %w (max %d)", key, value, ErrFieldOutOfRange, MaxField())
+	}
+
+	return field, nil
+}
+
+// MustMachineIDFromEnv is like MachineIDFromEnv but panics on error.
+// It's meant for wiring in main():
+//
+//	sf := snowflake.New(snowflake.MustMachineIDFromEnv("SNOWFLAKE_MACHINE_ID"))
+func MustMachineIDFromEnv(key string) uint64 {
+	field, err := MachineIDFromEnv(key)
+	if err != nil {
+		panic(err)
+	}
+	return field
+}
+
+// MachineIDFromPodOrdinal uses the ordinal of a Kubernetes StatefulSet pod
+// as the machine ID, e.g. myapp-3 gives 3. The pod name is read from the
+// POD_NAME environment variable (set it through the downward API), falling
+// back to HOSTNAME. The returned errors wrap one of ErrMachineIDUnset,
+// ErrNoNumericSuffix and ErrFieldOutOfRange.
+func MachineIDFromPodOrdinal() (uint64, error) { return MachineIDFromPodOrdinalOffset(0) }
+
+// MachineIDFromPodOrdinalOffset is like MachineIDFromPodOrdinal but adds
+// offset to the ordinal, so several StatefulSets can share the field range
+// without colliding, e.g. one with offset 0 and one with offset 512.
+func MachineIDFromPodOrdinalOffset(offset uint64) (uint64, error) {
+	key := "POD_NAME"
+	name := os.Getenv(key)
+	if name == "" {
+		key = "HOSTNAME"
+		name = os.Getenv(key)
+	}
+	if name == "" {
+		return 0, fmt.Errorf("snowflake: POD_NAME and HOSTNAME: %w", ErrMachineIDUnset)
+	}
+
+	suffix := name[strings.LastIndexByte(name, '-')+1:]
+	if suffix == name || suffix == "" || strings.Trim(suffix, "0123456789") != "" {
+		return 0, fmt.Errorf("snowflake: %s=%q is not a StatefulSet pod name (<name>-<ordinal>): %w",
+			key, name, ErrNoNumericSuffix)
+	}
+
+	ordinal, err := strconv.ParseUint(suffix, 10, 64)
+	if err != nil || ordinal > MaxField() {
+		return 0, fmt.Errorf("snowflake: %s=%q: ordinal %s: %w (max %d)",
+			key, name, suffix, ErrFieldOutOfRange, MaxField())
+	}
+
+	if offset > MaxField() || ordinal > MaxField()-offset {
+		return 0, fmt.Errorf("snowflake: %s=%q: ordinal %d + offset %d: %w (max %d)",
+			key, name, ordinal, offset, ErrFieldOutOfRange, MaxField())
+	}
+
+	return ordinal + offset, nil
+}
+
+// MachineIDFromIP is like the package-level MachineIDFromIP but reads from h.
+func (h Host) MachineIDFromIP() (uint64, error) {
+	ip, err := h.firstIP(func(ip net.IP) bool {
+		return ip.To4() != nil && ip.IsPrivate()
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ip = ip.To4()
+	return (uint64(ip[2])<<8 | uint64(ip[3])) & maxFieldBits, nil
+}
+
+// MachineIDFromIPv6 is like the package-level MachineIDFromIPv6 but reads from h.
+func (h Host) MachineIDFromIPv6() (uint64, error) {
+	ip, err := h.firstIP(func(ip net.IP) bool {
+		return ip.To4() == nil && (ip.IsGlobalUnicast() || ip.IsPrivate())
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return hashField(ip.To16()[8:]), nil
+}
+
+// MachineIDFromMAC is like the package-level MachineIDFromMAC but reads from h.
+func (h Host) MachineIDFromMAC() (uint64, error) {
+	ifaces, err := h.interfaces()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) == 0 {
+			continue
+		}
+
+		if iface.HardwareAddr[0]&0x02 != 0 { // locally administered
+			continue
+		}
+
+		if isVirtualInterface(iface.Name) {
+			continue
+		}
+
+		return hashField(iface.HardwareAddr), nil
+	}
+
+	return 0, ErrNoSuitableInterface
+}
+
+// isVirtualInterface reports whether name looks like a virtual interface.
+// (internal-use only)
+func isVirtualInterface(name string) bool {
+	for _, prefix := range virtualInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// MachineIDFromHostname is like the package-level MachineIDFromHostname but
+// reads from h.
+func (h Host) MachineIDFromHostname() (uint64, error) {
+	name, err := h.hostname()
+	if err != nil {
+		return 0, err
+	}
+
+	return hashField([]byte(name)), nil
+}
+
+// MachineIDFromHostnameSuffix is like the package-level
+// MachineIDFromHostnameSuffix but reads from h.
+func (h Host) MachineIDFromHostnameSuffix() (uint64, error) {
+	name, err := h.hostname()
+	if err != nil {
+		return 0, err
+	}
+
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		name = name[:i]
+	}
+
+	return numericSuffix(name)
+}
+
+// hostname returns the host name of h. (internal-use only)
+func (h Host) hostname() (string, error) {
+	if h.Hostname != nil {
+		return h.Hostname()
+	}
+	return os.Hostname()
+}
+
+// numericSuffix parses the decimal number name ends with as a field value.
+// (internal-use only)
+func numericSuffix(name string) (uint64, error) {
+	i := len(name)
+	for i > 0 && name[i-1] >= '0' && name[i-1] <= '9' {
+		i--
+	}
+
+	if i == len(name) {
+		return 0, ErrNoNumericSuffix
+	}
+
+	n, err := strconv.ParseUint(name[i:], 10, 64)
+	if err != nil || n > maxFieldBits {
+		return 0, ErrFieldOutOfRange
+	}
+
+	return n, nil
+}
+
+// firstIP returns the first address of an interface that is up and not
+// a loopback for which accept returns true. (internal-use only)
+func (h Host) firstIP(accept func(net.IP) bool) (net.IP, error) {
+	ifaces, err := h.interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		for _, addr := range iface.Addrs {
+			var ip net.IP
+			switch a := addr.(type) {
+			case *net.IPNet:
+				ip = a.IP
+			case *net.IPAddr:
+				ip = a.IP
+			}
+
+			if ip != nil && !ip.IsLoopback() && accept(ip) {
+				return ip, nil
+			}
+		}
+	}
+
+	return nil, ErrNoSuitableInterface
+}
+
+// interfaces lists the network interfaces of h. (internal-use only)
+func (h Host) interfaces() ([]Interface, error) {
+	if h.Interfaces != nil {
+		return h.Interfaces()
+	}
+	return systemInterfaces()
+}
+
+// systemInterfaces lists the network interfaces of the real host.
+// (internal-use only)
+func systemInterfaces() ([]Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Interface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, Interface{
+			Name:         iface.Name,
+			Flags:        iface.Flags,
+			HardwareAddr: iface.HardwareAddr,
+			Addrs:        addrs,
+		})
+	}
+
+	return result, nil
+}
+
+// hashField hashes b with 64-bit FNV-1a and reduces it into the field range.
+// (internal-use only)
+func hashField(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64() % (maxFieldBits + 1)
+}
@@ -0,0 +1,335 @@
+package snowflake_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func ipNet(s string) *net.IPNet {
+	ip, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	n.IP = ip
+	return n
+}
+
+func interfaces(ifaces ...snowflake.Interface) func() ([]snowflake.Interface, error) {
+	return func() ([]snowflake.Interface, error) { return ifaces, nil }
+}
+
+var (
+	loopback = snowflake.Interface{
+		Name:  "lo",
+		Flags: net.FlagUp | net.FlagLoopback,
+		Addrs: []net.Addr{ipNet("127.0.0.1/8"), ipNet("::1/128")},
+	}
+	eth0 = snowflake.Interface{
+		Name:         "eth0",
+		Flags:        net.FlagUp | net.FlagBroadcast,
+		HardwareAddr: net.HardwareAddr{0x00, 0x1a, 0x2b, 0x3c, 0x4d, 0x5e},
+		Addrs: []net.Addr{
+			ipNet("203.0.113.7/24"), // public, skipped by MachineIDFromIP
+			ipNet("10.0.3.17/16"),
+			ipNet("fe80::21a:2bff:fe3c:4d5e/64"), // link-local, skipped by MachineIDFromIPv6
+			ipNet("2001:db8::21a:2bff:fe3c:4d5e/64"),
+		},
+	}
+)
+
+func TestMachineIDFromIP(t *testing.T) {
+	down := eth0
+	down.Flags = 0
+
+	tc := []struct {
+		name     string
+		ifaces   []snowflake.Interface
+		expected uint64
+		err      error
+	}{
+		{"10.0.3.17", []snowflake.Interface{loopback, eth0}, 785, nil},
+		{"192.168.1.1", []snowflake.Interface{{
+			Name:  "wlan0",
+			Flags: net.FlagUp,
+			Addrs: []net.Addr{&net.IPAddr{IP: net.ParseIP("192.168.1.1")}},
+		}}, 257, nil},
+		{"Should return ErrNoSuitableInterface for loopback only", []snowflake.Interface{loopback}, 0, snowflake.ErrNoSuitableInterface},
+		{"Should return ErrNoSuitableInterface for interfaces down", []snowflake.Interface{down}, 0, snowflake.ErrNoSuitableInterface},
+		{"Should return ErrNoSuitableInterface for no interfaces", nil, 0, snowflake.ErrNoSuitableInterface},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			field, err := snowflake.Host{Interfaces: interfaces(tt.ifaces...)}.MachineIDFromIP()
+			if err != tt.err {
+				t.Errorf("expected error %v got %v", tt.err, err)
+			}
+			if field != tt.expected {
+				t.Errorf("expected machine ID %d got %d", tt.expected, field)
+			}
+		})
+	}
+}
+
+func TestMachineIDFromIPv6(t *testing.T) {
+	h := snowflake.Host{Interfaces: interfaces(loopback, eth0)}
+
+	field, err := h.MachineIDFromIPv6()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field > 1023 {
+		t.Errorf("expected machine ID within range got %d", field)
+	}
+
+	// only the interface identifier matters, not the prefix
+	other := eth0
+	other.Addrs = []net.Addr{ipNet("fd00:1234::21a:2bff:fe3c:4d5e/64")}
+	same, err := snowflake.Host{Interfaces: interfaces(other)}.MachineIDFromIPv6()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != field {
+		t.Errorf("expected machine ID %d got %d", field, same)
+	}
+
+	v4only := eth0
+	v4only.Addrs = []net.Addr{ipNet("10.0.3.17/16"), ipNet("fe80::1/64")}
+	_, err = snowflake.Host{Interfaces: interfaces(loopback, v4only)}.MachineIDFromIPv6()
+	if err != snowflake.ErrNoSuitableInterface {
+		t.Errorf("expected error %v got %v", snowflake.ErrNoSuitableInterface, err)
+	}
+}
+
+func TestMachineIDFromIP_ListerError(t *testing.T) {
+	listErr := errors.New("boom")
+	h := snowflake.Host{Interfaces: func() ([]snowflake.Interface, error) { return nil, listErr }}
+
+	if _, err := h.MachineIDFromIP(); err != listErr {
+		t.Errorf("expected error %v got %v", listErr, err)
+	}
+}
+
+func TestMachineIDFromMAC(t *testing.T) {
+	docker0 := snowflake.Interface{
+		Name:         "docker0",
+		Flags:        net.FlagUp,
+		HardwareAddr: net.HardwareAddr{0x00, 0x42, 0xac, 0x11, 0x00, 0x01},
+	}
+	container := snowflake.Interface{
+		Name:         "eth1",
+		Flags:        net.FlagUp,
+		HardwareAddr: net.HardwareAddr{0x02, 0x42, 0xac, 0x11, 0x00, 0x02}, // locally administered
+	}
+
+	field, err := snowflake.Host{Interfaces: interfaces(loopback, docker0, container, eth0)}.MachineIDFromMAC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := snowflake.Host{Interfaces: interfaces(eth0)}.MachineIDFromMAC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field != expected {
+		t.Errorf("expected machine ID %d (eth0) got %d", expected, field)
+	}
+	if field > 1023 {
+		t.Errorf("expected machine ID within range got %d", field)
+	}
+
+	// the same MAC always gives the same machine ID
+	again, _ := snowflake.Host{Interfaces: interfaces(eth0)}.MachineIDFromMAC()
+	if again != field {
+		t.Errorf("expected machine ID %d got %d", field, again)
+	}
+
+	_, err = snowflake.Host{Interfaces: interfaces(loopback, docker0, container)}.MachineIDFromMAC()
+	if err != snowflake.ErrNoSuitableInterface {
+		t.Errorf("expected error %v got %v", snowflake.ErrNoSuitableInterface, err)
+	}
+}
+
+func hostname(name string) func() (string, error) {
+	return func() (string, error) { return name, nil }
+}
+
+func TestMachineIDFromHostname(t *testing.T) {
+	field, err := snowflake.Host{Hostname: hostname("ingest-1")}.MachineIDFromHostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, _ := snowflake.Host{Hostname: hostname("ingest-1")}.MachineIDFromHostname()
+	if again != field {
+		t.Errorf("expected the same host name to give machine ID %d got %d", field, again)
+	}
+
+	// sequential host names should spread across the field range
+	n := 10240
+	buckets := make(map[uint64]int)
+	for i := 0; i < n; i++ {
+		field, err := snowflake.Host{Hostname: hostname(fmt.Sprintf("ingest-%d", i))}.MachineIDFromHostname()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if field > 1023 {
+			t.Fatalf("expected machine ID within range got %d", field)
+		}
+		buckets[field]++
+	}
+
+	if len(buckets) < 1000 {
+		t.Errorf("expected host names to cover most of the 1024 machine IDs, covered %d", len(buckets))
+	}
+	for field, count := range buckets {
+		if count > 40 { // 10 on average
+			t.Errorf("expected an even spread, machine ID %d got %d host names", field, count)
+		}
+	}
+}
+
+func TestMachineIDFromHostnameSuffix(t *testing.T) {
+	tc := []struct {
+		name     string
+		hostname string
+		expected uint64
+		err      error
+	}{
+		{"worker-17", "worker-17", 17, nil},
+		{"worker-0", "worker-0", 0, nil},
+		{"worker-007", "worker-007", 7, nil},
+		{"worker-1023", "worker-1023", 1023, nil},
+		{"fully qualified", "worker-42.eu-west-1.internal", 42, nil},
+		{"no separator", "node9", 9, nil},
+		{"Should return ErrFieldOutOfRange for 1024", "worker-1024", 0, snowflake.ErrFieldOutOfRange},
+		{"Should return ErrFieldOutOfRange for overflow", "worker-99999999999999999999999", 0, snowflake.ErrFieldOutOfRange},
+		{"Should return ErrNoNumericSuffix", "worker", 0, snowflake.ErrNoNumericSuffix},
+		{"Should return ErrNoNumericSuffix for a number in the domain", "worker.eu-west-1", 0, snowflake.ErrNoNumericSuffix},
+		{"Should return ErrNoNumericSuffix for empty", "", 0, snowflake.ErrNoNumericSuffix},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			field, err := snowflake.Host{Hostname: hostname(tt.hostname)}.MachineIDFromHostnameSuffix()
+			if err != tt.err {
+				t.Errorf("expected error %v got %v", tt.err, err)
+			}
+			if field != tt.expected {
+				t.Errorf("expected machine ID %d got %d", tt.expected, field)
+			}
+		})
+	}
+}
+
+func TestMachineIDFromEnv(t *testing.T) {
+	const key = "SNOWFLAKE_MACHINE_ID"
+
+	tc := []struct {
+		name     string
+		value    *string
+		expected uint64
+		err      error
+	}{
+		{"17", strPtr("17"), 17, nil},
+		{"0", strPtr("0"), 0, nil},
+		{"1023", strPtr("1023"), 1023, nil},
+		{"surrounding whitespace", strPtr(" 42\n"), 42, nil},
+		{"Should return ErrMachineIDUnset", nil, 0, snowflake.ErrMachineIDUnset},
+		{"Should return ErrMachineIDUnset for empty", strPtr(""), 0, snowflake.ErrMachineIDUnset},
+		{"Should return ErrMachineIDNotNumeric", strPtr("worker-1"), 0, snowflake.ErrMachineIDNotNumeric},
+		{"Should return ErrMachineIDNotNumeric for negative", strPtr("-1"), 0, snowflake.ErrMachineIDNotNumeric},
+		{"Should return ErrFieldOutOfRange", strPtr("1024"), 0, snowflake.ErrFieldOutOfRange},
+		{"Should return ErrFieldOutOfRange for overflow", strPtr("99999999999999999999999"), 0, snowflake.ErrFieldOutOfRange},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.value != nil {
+				t.Setenv(key, *tt.value)
+			} else {
+				t.Setenv(key, "")
+				os.Unsetenv(key)
+			}
+
+			field, err := snowflake.MachineIDFromEnv(key)
+			if !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+				t.Errorf("expected error %v got %v", tt.err, err)
+			}
+			if field != tt.expected {
+				t.Errorf("expected machine ID %d got %d", tt.expected, field)
+			}
+		})
+	}
+}
+
+func TestMustMachineIDFromEnv(t *testing.T) {
+	const key = "SNOWFLAKE_MACHINE_ID"
+
+	t.Setenv(key, "5")
+	if field := snowflake.MustMachineIDFromEnv(key); field != 5 {
+		t.Errorf("expected machine ID %d got %d", 5, field)
+	}
+
+	t.Setenv(key, "nope")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected MustMachineIDFromEnv to panic")
+		}
+	}()
+	snowflake.MustMachineIDFromEnv(key)
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestMachineIDFromPodOrdinal(t *testing.T) {
+	tc := []struct {
+		name     string
+		podName  string
+		hostname string
+		offset   uint64
+		expected uint64
+		err      error
+	}{
+		{"myapp-3", "myapp-3", "", 0, 3, nil},
+		{"myapp-0", "myapp-0", "", 0, 0, nil},
+		{"multi-dash-name-12", "multi-dash-name-12", "", 0, 12, nil},
+		{"falls back to HOSTNAME", "", "myapp-7", 0, 7, nil},
+		{"POD_NAME wins over HOSTNAME", "myapp-1", "myapp-7", 0, 1, nil},
+		{"offset", "myapp-3", "", 512, 515, nil},
+		{"offset up to the max", "myapp-511", "", 512, 1023, nil},
+		{"Should return ErrMachineIDUnset", "", "", 0, 0, snowflake.ErrMachineIDUnset},
+		{"Should return ErrNoNumericSuffix for a Deployment pod", "myapp-7d4b9c8f5-x2kq9", "", 0, 0, snowflake.ErrNoNumericSuffix},
+		{"Should return ErrNoNumericSuffix without a dash", "myapp3", "", 0, 0, snowflake.ErrNoNumericSuffix},
+		{"Should return ErrNoNumericSuffix for a trailing dash", "myapp-", "", 0, 0, snowflake.ErrNoNumericSuffix},
+		{"Should return ErrFieldOutOfRange", "myapp-1024", "", 0, 0, snowflake.ErrFieldOutOfRange},
+		{"Should return ErrFieldOutOfRange for offset overflow", "myapp-512", "", 512, 0, snowflake.ErrFieldOutOfRange},
+		{"Should return ErrFieldOutOfRange for a huge offset", "myapp-0", "", 1 << 63, 0, snowflake.ErrFieldOutOfRange},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("POD_NAME", tt.podName)
+			t.Setenv("HOSTNAME", tt.hostname)
+
+			field, err := snowflake.MachineIDFromPodOrdinalOffset(tt.offset)
+			if !errors.Is(err, tt.err) || (err == nil) != (tt.err == nil) {
+				t.Errorf("expected error %v got %v", tt.err, err)
+			}
+			if field != tt.expected {
+				t.Errorf("expected machine ID %d got %d", tt.expected, field)
+			}
+
+			if tt.offset == 0 {
+				if same, _ := snowflake.MachineIDFromPodOrdinal(); same != field {
+					t.Errorf("expected MachineIDFromPodOrdinal to give %d got %d", field, same)
+				}
+			}
+		})
+	}
+}
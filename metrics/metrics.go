@@ -0,0 +1,115 @@
+// Package metrics exports Prometheus metrics of snowflake generators.
+//
+//	sf, err := metrics.Wrap(snowflake.New(1), prometheus.DefaultRegisterer, "orders")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	id := sf.NextID()
+//
+// The following counters are exported, labeled generator="orders":
+//
+//	snowflake_ids_generated_total      IDs issued through the wrapper
+//	snowflake_sequence_rollovers_total times the sequence of a millisecond ran out
+//	snowflake_wait_seconds_total       time spent waiting for the next millisecond
+//	snowflake_clock_regressions_total  times the clock was read going backwards
+//
+// The last three are read from the Stats method of *snowflake.ID and
+// *snowflake.ID2 when the metrics are scraped, so they are only exported
+// for generators that have one.
+package metrics
+
+import (
+	"sync/atomic"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// statser is implemented by generators that keep counters, such as
+// *snowflake.ID and *snowflake.ID2.
+type statser interface {
+	Stats() snowflake.Stats
+}
+
+var _ snowflake.Generator = (*Generator)(nil)
+
+// Generator is a snowflake.Generator counting the IDs it issues.
+type Generator struct {
+	ids uint64 // accessed atomically
+	g   snowflake.Generator
+}
+
+// NextID returns the next ID of the wrapped generator.
+func (g *Generator) NextID() uint64 {
+	atomic.AddUint64(&g.ids, 1)
+	return g.g.NextID()
+}
+
+// Wrap returns a Generator issuing the IDs of g and registers its metrics
+// with reg, labeled by name. The hot path only adds an atomic increment
+// to g.NextID; the other counters are read from g when scraped.
+// Wrapping several generators with the same registry needs distinct
+// names; a reused name fails with a prometheus.AlreadyRegisteredError.
+func Wrap(g snowflake.Generator, reg prometheus.Registerer, name string) (*Generator, error) {
+	w := &Generator{g: g}
+
+	c := &collector{g: w}
+	if s, ok := g.(statser); ok {
+		c.stats = s.Stats
+	}
+	c.describe(name)
+
+	if err := reg.Register(c); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// collector collects the metrics of a Generator.
+type collector struct {
+	g     *Generator
+	stats func() snowflake.Stats // nil if the generator keeps no counters
+
+	ids         *prometheus.Desc
+	rollovers   *prometheus.Desc
+	waited      *prometheus.Desc
+	regressions *prometheus.Desc
+}
+
+// describe creates the descriptors of the metrics labeled by name.
+func (c *collector) describe(name string) {
+	labels := prometheus.Labels{"generator": name}
+
+	c.ids = prometheus.NewDesc("snowflake_ids_generated_total",
+		"Number of snowflake IDs generated.", nil, labels)
+	c.rollovers = prometheus.NewDesc("snowflake_sequence_rollovers_total",
+		"Number of times the sequence of a millisecond ran out.", nil, labels)
+	c.waited = prometheus.NewDesc("snowflake_wait_seconds_total",
+		"Time spent waiting for the next millisecond after a rollover.", nil, labels)
+	c.regressions = prometheus.NewDesc("snowflake_clock_regressions_total",
+		"Number of times the clock was read going backwards.", nil, labels)
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.ids
+	if c.stats != nil {
+		ch <- c.rollovers
+		ch <- c.waited
+		ch <- c.regressions
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(c.ids, prometheus.CounterValue, float64(atomic.LoadUint64(&c.g.ids)))
+	if c.stats == nil {
+		return
+	}
+
+	stats := c.stats()
+	ch <- prometheus.MustNewConstMetric(c.rollovers, prometheus.CounterValue, float64(stats.Rollovers))
+	ch <- prometheus.MustNewConstMetric(c.waited, prometheus.CounterValue, stats.Waited.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.regressions, prometheus.CounterValue, float64(stats.ClockRegressions))
+}
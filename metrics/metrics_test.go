@@ -0,0 +1,131 @@
+package metrics_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakeClock is a Clock that only moves when set.
+type fakeClock struct {
+	mtx sync.Mutex
+	t   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.t = t
+}
+
+func TestWrap(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := &fakeClock{t: start}
+
+	reg := prometheus.NewRegistry()
+	sf, err := metrics.Wrap(snowflake.New(1, snowflake.WithClock(clock), snowflake.WithMaxForwardDrift(10*time.Millisecond)), reg, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a burst of two and a half milliseconds worth of IDs
+	for i := 0; i < 2*4096+2048; i++ {
+		sf.NextID()
+	}
+	clock.Set(start.Add(-time.Second))
+	sf.NextID()
+
+	expected := `
+# HELP snowflake_clock_regressions_total Number of times the clock was read going backwards.
+# TYPE snowflake_clock_regressions_total counter
+snowflake_clock_regressions_total{generator="orders"} 1
+# HELP snowflake_ids_generated_total Number of snowflake IDs generated.
+# TYPE snowflake_ids_generated_total counter
+snowflake_ids_generated_total{generator="orders"} 10241
+# HELP snowflake_sequence_rollovers_total Number of times the sequence of a millisecond ran out.
+# TYPE snowflake_sequence_rollovers_total counter
+snowflake_sequence_rollovers_total{generator="orders"} 2
+# HELP snowflake_wait_seconds_total Time spent waiting for the next millisecond after a rollover.
+# TYPE snowflake_wait_seconds_total counter
+snowflake_wait_seconds_total{generator="orders"} 0
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWrap_Generator(t *testing.T) {
+	sharded, err := snowflake.NewSharded([]uint64{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reg := prometheus.NewRegistry()
+	sf, err := metrics.Wrap(sharded, reg, "sharded")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		sf.NextID()
+	}
+
+	// generators without Stats only export the ID count
+	expected := `
+# HELP snowflake_ids_generated_total Number of snowflake IDs generated.
+# TYPE snowflake_ids_generated_total counter
+snowflake_ids_generated_total{generator="sharded"} 100
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWrap_Names(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	if _, err := metrics.Wrap(snowflake.New(1), reg, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := metrics.Wrap(snowflake.New(2), reg, "b"); err != nil {
+		t.Errorf("expected another name to register got %v", err)
+	}
+
+	_, err := metrics.Wrap(snowflake.New(3), reg, "a")
+	var are prometheus.AlreadyRegisteredError
+	if !errors.As(err, &are) {
+		t.Errorf("expected a prometheus.AlreadyRegisteredError got %v", err)
+	}
+}
+
+func BenchmarkNextID(b *testing.B) {
+	b.Run("Unwrapped", func(b *testing.B) {
+		sf := snowflake.New(1)
+		for i := 0; i < b.N; i++ {
+			sf.NextID()
+		}
+	})
+
+	b.Run("Wrapped", func(b *testing.B) {
+		sf, err := metrics.Wrap(snowflake.New(1), prometheus.NewRegistry(), "bench")
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < b.N; i++ {
+			sf.NextID()
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package snowflake
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// GeneratorFunc adapts a function to a Generator, e.g. to wrap a
+// generator in a Middleware or to issue IDs from a remote client.
+type GeneratorFunc func() uint64
+
+// NextID calls f.
+func (f GeneratorFunc) NextID() uint64 { return f() }
+
+// Middleware wraps a Generator to add a cross-cutting concern to it, such
+// as metrics or rate limiting. A middleware must return the IDs of the
+// wrapped generator as they come, in order, so that it keeps their
+// uniqueness and ordering.
+type Middleware func(Generator) Generator
+
+// Chain wraps g with mw, the first middleware being the outermost: NextID
+// goes through mw[0], then mw[1] and so on, down to g.
+//
+//	var issued snowflake.Counter
+//	sf := snowflake.Chain(snowflake.New(1), issued.Middleware(), snowflake.RateLimited(10000))
+func Chain(g Generator, mw ...Middleware) Generator {
+	for i := len(mw) - 1; i >= 0; i-- {
+		g = mw[i](g)
+	}
+	return g
+}
+
+// Counter counts the IDs issued through its Middleware, e.g. to export
+// them as a metric. It's safe for concurrent use.
+type Counter struct {
+	n uint64 // accessed atomically
+}
+
+// Middleware returns a Middleware counting the IDs issued through it in c.
+func (c *Counter) Middleware() Middleware {
+	return func(g Generator) Generator {
+		return GeneratorFunc(func() uint64 {
+			id := g.NextID()
+			atomic.AddUint64(&c.n, 1)
+			return id
+		})
+	}
+}
+
+// Count returns the number of IDs issued through the middleware of c.
+func (c *Counter) Count() uint64 { return atomic.LoadUint64(&c.n) }
+
+// RateLimited returns a Middleware capping the generator at perSecond IDs
+// per second, like WithRateLimit but for any Generator: NextID waits for a
+// token of a bucket that holds up to perSecond of them. The generators
+// wrapped by the middleware share its bucket. A perSecond of 0 or less
+// means no limit.
+func RateLimited(perSecond int) Middleware {
+	if perSecond <= 0 {
+		return func(g Generator) Generator { return g }
+	}
+
+	var mtx sync.Mutex
+	limiter := newRateLimiter(perSecond)
+	return func(g Generator) Generator {
+		return GeneratorFunc(func() uint64 {
+			mtx.Lock()
+			wait := limiter.reserveN(time.Now(), 1)
+			mtx.Unlock()
+
+			if wait > 0 {
+				time.Sleep(wait)
+			}
+			return g.NextID()
+		})
+	}
+}
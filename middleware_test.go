@@ -0,0 +1,105 @@
+package snowflake_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// recording returns a Middleware appending name to calls on every NextID,
+// before calling through.
+func recording(name string, calls *[]string) snowflake.Middleware {
+	return func(g snowflake.Generator) snowflake.Generator {
+		return snowflake.GeneratorFunc(func() uint64 {
+			*calls = append(*calls, name)
+			return g.NextID()
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	var next uint64
+	fake := snowflake.GeneratorFunc(func() uint64 {
+		calls = append(calls, "generator")
+		next++
+		return next
+	})
+
+	var issued snowflake.Counter
+	sf := snowflake.Chain(fake, recording("outer", &calls), issued.Middleware(), snowflake.RateLimited(1000), recording("inner", &calls))
+
+	for want := uint64(1); want <= 3; want++ {
+		if id := sf.NextID(); id != want {
+			t.Errorf("expected ID %d to be passed through got %d", want, id)
+		}
+	}
+
+	want := []string{"outer", "inner", "generator", "outer", "inner", "generator", "outer", "inner", "generator"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls %v got %v", want, calls)
+	}
+	if n := issued.Count(); n != 3 {
+		t.Errorf("expected a count of 3 got %d", n)
+	}
+
+	if g := snowflake.Chain(fake); g.NextID() != 4 {
+		t.Error("expected Chain without middlewares to call the generator")
+	}
+}
+
+func TestChain_Unique(t *testing.T) {
+	var issued snowflake.Counter
+	sf := snowflake.Chain(snowflake.New(1), issued.Middleware(), snowflake.RateLimited(0), issued.Middleware())
+
+	var mtx sync.Mutex
+	seen := make(map[uint64]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var last uint64
+			for j := 0; j < 5000; j++ {
+				id := sf.NextID()
+				if id <= last {
+					t.Errorf("expected increasing IDs got %d after %d", id, last)
+					return
+				}
+				last = id
+
+				mtx.Lock()
+				if seen[id] {
+					t.Errorf("duplicate ID %d", id)
+				}
+				seen[id] = true
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// counted by both counter middlewares
+	if n := issued.Count(); n != 2*8*5000 {
+		t.Errorf("expected a count of %d got %d", 2*8*5000, n)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	limit := snowflake.RateLimited(100)
+	a := snowflake.Chain(snowflake.New(1), limit)
+	b := snowflake.Chain(snowflake.New(2), limit)
+
+	// a burst of 100 shared by both, then 10 more at 100 per second
+	start := time.Now()
+	for i := 0; i < 55; i++ {
+		a.NextID()
+		b.NextID()
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("expected 110 IDs at 100 per second to take at least 80ms got %v", elapsed)
+	}
+}
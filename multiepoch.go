@@ -0,0 +1,68 @@
+package snowflake
+
+import (
+	"fmt"
+	"time"
+)
+
+// MultiEpochParser parses snowflake IDs issued under one of several
+// epochs, e.g. while stored IDs are migrated from one epoch to another. It
+// tells the epochs apart by the timestamps they give: only one of them
+// should fall within the validity window, from NotBefore to NotAfter.
+//
+// The window should be shorter than the time between any two epochs, or
+// IDs will be ambiguous:
+//
+//	p := snowflake.NewMultiEpochParser(oldEpoch, newEpoch)
+//	p.NotBefore = serviceLaunch
+type MultiEpochParser struct {
+	epochs []time.Time
+
+	// NotBefore is the earliest plausible timestamp. Defaults to no bound
+	// but the epoch itself.
+	NotBefore time.Time
+	// NotAfter is the latest plausible timestamp. Defaults to the time of
+	// parsing.
+	NotAfter time.Time
+}
+
+// NewMultiEpochParser returns a new snowflake.MultiEpochParser for IDs
+// issued under any of epochs.
+func NewMultiEpochParser(epochs ...time.Time) *MultiEpochParser {
+	return &MultiEpochParser{epochs: append([]time.Time(nil), epochs...)}
+}
+
+// Parse parses id under the only epoch that gives it a plausible
+// timestamp, returning the index of that epoch. Returns an error wrapping
+// ErrNoPlausibleEpoch if no epoch does, and ErrAmbiguousEpoch if several
+// do.
+func (p *MultiEpochParser) Parse(id uint64) (SID, int, error) {
+	notAfter := p.NotAfter
+	if notAfter.IsZero() {
+		notAfter = time.Now()
+	}
+	elapsed := int64(id >> (sequenceBits + fieldBits))
+
+	match := -1
+	for i, epoch := range p.epochs {
+		ts := epoch.UnixMilli() + elapsed
+		if (!p.NotBefore.IsZero() && ts < p.NotBefore.UnixMilli()) || ts > notAfter.UnixMilli() {
+			continue
+		}
+		if match >= 0 {
+			return SID{}, -1, fmt.Errorf("snowflake: ID %d is plausible under epochs %v and %v: %w",
+				id, p.epochs[match].Format(time.RFC3339), epoch.Format(time.RFC3339), ErrAmbiguousEpoch)
+		}
+		match = i
+	}
+	if match < 0 {
+		return SID{}, -1, fmt.Errorf("snowflake: ID %d is plausible under none of %d epochs: %w", id, len(p.epochs), ErrNoPlausibleEpoch)
+	}
+
+	return SID{
+		Timestamp: p.epochs[match].UnixMilli() + elapsed,
+		Sequence:  getSequence(id),
+		MachineID: getMachineID(id),
+		Field:     getMachineID(id),
+	}, match, nil
+}
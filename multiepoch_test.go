@@ -0,0 +1,64 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestMultiEpochParser(t *testing.T) {
+	// 2012 and 2020 epochs with a 5 year window, shorter than the 7.7
+	// years between them
+	p := snowflake.NewMultiEpochParser(epoch2012, epoch2020)
+	p.NotBefore = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.NotAfter = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	issued := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	id := func(epoch time.Time) uint64 { return snowflakeAt(issued, epoch) | 7<<12 | 3 }
+
+	tc := []struct {
+		name     string
+		id       uint64
+		expected int
+		err      error
+	}{
+		{"Should classify an ID of the old epoch", id(epoch2012), 0, nil},
+		{"Should classify an ID of the new epoch", id(epoch2020), 1, nil},
+		{"Should reject an ID of neither epoch", snowflakeAt(issued, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)), -1, snowflake.ErrNoPlausibleEpoch},
+		{"Should reject an ID of the future", snowflakeAt(issued.AddDate(10, 0, 0), epoch2012), -1, snowflake.ErrNoPlausibleEpoch},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sid, epoch, err := p.Parse(tt.id)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v got %v", tt.err, err)
+			}
+			if epoch != tt.expected {
+				t.Errorf("expected epoch %d got %d", tt.expected, epoch)
+			}
+			if err != nil {
+				return
+			}
+			if sid.Timestamp != issued.UnixMilli() || sid.MachineID != 7 || sid.Sequence != 3 {
+				t.Errorf("expected timestamp %d, field 7 and sequence 3 got %+v", issued.UnixMilli(), sid)
+			}
+		})
+	}
+}
+
+func TestMultiEpochParser_Ambiguous(t *testing.T) {
+	// by default any timestamp up to now is plausible
+	p := snowflake.NewMultiEpochParser(epoch2012, epoch2020)
+
+	if _, epoch, err := p.Parse(snowflakeAt(epoch2012.AddDate(3, 0, 0), epoch2012)); !errors.Is(err, snowflake.ErrAmbiguousEpoch) || epoch != -1 {
+		t.Errorf("expected error %v got epoch %d (%v)", snowflake.ErrAmbiguousEpoch, epoch, err)
+	}
+
+	// unless only the older epoch puts it before now
+	if _, epoch, err := p.Parse(snowflakeAt(time.Now().AddDate(-1, 0, 0), epoch2012)); err != nil || epoch != 0 {
+		t.Errorf("expected epoch 0 got %d (%v)", epoch, err)
+	}
+}
@@ -0,0 +1,32 @@
+package snowflake
+
+import "hash/fnv"
+
+// NameHash returns the 64-bit FNV-1a hash of name that FieldFromName
+// reduces into the field range. Compare the hashes of known names at deploy
+// time to tell a collision of two fields from a duplicate name.
+func NameHash(name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return h.Sum64()
+}
+
+// FieldFromName hashes name into the field range: the 64-bit FNV-1a hash
+// of its bytes (offset basis 0xcbf29ce484222325, prime 0x100000001b3)
+// modulo 1024, i.e. its low 10 bits. The mapping is part of the API and
+// won't change, so the same name gives the same field across restarts and
+// is easy to reproduce in other languages, e.g. "ingest-eu-west-1a-blue"
+// gives 742.
+//
+// Like MachineIDFromMAC, different names can give the same field.
+func FieldFromName(name string) uint64 { return NameHash(name) & maxFieldBits }
+
+// NewFromName returns a new snowflake ID generator whose field is
+// FieldFromName(name), for workers that have a stable name but no stable
+// small integer. Returns ErrEmptyName if name is empty.
+func NewFromName(name string, opts ...Option) (*ID, error) {
+	if name == "" {
+		return nil, ErrEmptyName
+	}
+	return New(FieldFromName(name), opts...), nil
+}
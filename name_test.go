@@ -0,0 +1,49 @@
+package snowflake_test
+
+import (
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestFieldFromName(t *testing.T) {
+	// Pinned: these mappings are part of the API and must never change.
+	tc := []struct {
+		name  string
+		hash  uint64
+		field uint64
+	}{
+		{"ingest-eu-west-1a-blue", 0xd176b23daf21bee6, 742},
+		{"ingest-eu-west-1a-green", 0x0d77c0ab3dc08ba9, 937},
+		{"worker-1", 0x24913ec59027ebed, 1005},
+		{"worker-2", 0x24913bc59027e6d4, 724},
+		{"a", 0xaf63dc4c8601ec8c, 140},
+		{"", 0xcbf29ce484222325, 805},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if hash := snowflake.NameHash(tt.name); hash != tt.hash {
+				t.Errorf("expected hash %#x got %#x", tt.hash, hash)
+			}
+			if field := snowflake.FieldFromName(tt.name); field != tt.field {
+				t.Errorf("expected field %d got %d", tt.field, field)
+			}
+		})
+	}
+}
+
+func TestNewFromName(t *testing.T) {
+	sf, err := snowflake.NewFromName("ingest-eu-west-1a-blue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field := snowflake.Parse(sf.NextID()).MachineID; field != 742 {
+		t.Errorf("expected field %d got %d", 742, field)
+	}
+
+	_, err = snowflake.NewFromName("")
+	if err != snowflake.ErrEmptyName {
+		t.Errorf("expected error %v got %v", snowflake.ErrEmptyName, err)
+	}
+}
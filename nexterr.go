@@ -0,0 +1,134 @@
+package snowflake
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// FallibleGenerator is a Generator that can tell why it can't issue an ID
+// rather than block or carry on regardless, such as *ID and *ID2.
+type FallibleGenerator interface {
+	Generator
+	NextIDErr() (uint64, error)
+}
+
+var (
+	_ FallibleGenerator = (*ID)(nil)
+	_ FallibleGenerator = (*ID2)(nil)
+)
+
+// NextIDErr returns a new snowflake ID of g, or why it can't issue one if
+// g is a FallibleGenerator. Other generators never fail.
+func NextIDErr(g Generator) (uint64, error) {
+	if fg, ok := g.(FallibleGenerator); ok {
+		return fg.NextIDErr()
+	}
+	return g.NextID(), nil
+}
+
+// NextIDErr is like NextID but fails rather than issue an ID NextID would
+// have to block for or stretch its guarantees for. Returns an error
+// wrapping:
+//
+//   - ErrFieldUnassigned if the generator has no field to issue IDs of: its
+//     lease was lost, see NewLeased, or it was released, see NewRegistered
+//   - ErrBeforeEpoch if the clock is before the epoch
+//   - ErrClockMovedBackwards if the clock is behind where it was when the
+//     last ID was issued, until it catches up; NextID keeps issuing from
+//     the last millisecond instead
+//   - ErrTimestampOverflow if the timestamp no longer fits 41 bits
+//   - ErrSequenceExhausted if the sequence of the millisecond is used up
+//     and the next millisecond can't be borrowed, see WithMaxForwardDrift;
+//     NextID waits for the clock instead
+//
+// With WithRandomFallback, a random ID is issued rather than any of the
+// clock errors. Like NextID, it waits for the rate limiter if there is one.
+func (id *ID) NextIDErr() (uint64, error) {
+	if atomic.LoadUint32(&id.registered) == 2 {
+		return 0, fmt.Errorf("snowflake: field was released: %w", ErrFieldUnassigned)
+	}
+	return id.nextIDErr()
+}
+
+// NextIDErr is like (*ID).NextIDErr.
+func (id *ID2) NextIDErr() (uint64, error) { return id.nextIDErr() }
+
+// nextIDErr is like nextID but fails rather than block on the clock or the
+// lease. (internal-use only)
+func (g *generator) nextIDErr() (uint64, error) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if g.limiter != nil {
+		if wait := g.limiter.reserveN(g.wallNow(), 1); wait > 0 {
+			g.mtx.Unlock()
+			time.Sleep(wait)
+			g.mtx.Lock()
+		}
+	}
+
+	if g.leaseLost() {
+		return 0, fmt.Errorf("snowflake: field lease was lost: %w", ErrFieldUnassigned)
+	}
+	if g.sinceCheck > 0 && g.sinceCheck < g.checkEvery && g.sequence < maxSeqBits {
+		// the fast path of generate, which can't fail
+		return g.generate(), nil
+	}
+
+	now := g.now()
+	if err := g.checkClock(now); err != nil {
+		return 0, err
+	}
+	if g.checkEvery > 0 {
+		g.sinceCheck = 1
+	}
+	return g.generateAt(now), nil
+}
+
+// leaseLost reports whether the generator was created with NewLeased and
+// doesn't hold a lease. g.mtx must be held. (internal-use only)
+func (g *generator) leaseLost() bool {
+	if g.leaseHeld == nil {
+		return false
+	}
+	select {
+	case <-g.leaseDone:
+		return true
+	default:
+	}
+	select {
+	case <-g.leaseHeld:
+		return false
+	default:
+		return true
+	}
+}
+
+// checkClock returns why the generator can't issue an ID at now, in
+// milliseconds since the epoch, without waiting or moving back in time.
+// g.mtx must be held. (internal-use only)
+func (g *generator) checkClock(now int64) error {
+	if g.randomFallback {
+		// unusable clocks are for the fallback to handle
+		return nil
+	}
+
+	switch {
+	case now < 0:
+		return fmt.Errorf("snowflake: clock is %s before the epoch: %w", time.Duration(-now)*time.Millisecond, ErrBeforeEpoch)
+	case now < g.lastNow:
+		return fmt.Errorf("snowflake: clock moved backwards by %s: %w", time.Duration(g.lastNow-now)*time.Millisecond, ErrClockMovedBackwards)
+	case now > maxTimestamp:
+		return fmt.Errorf("snowflake: clock is %s past the last timestamp: %w", time.Duration(now-maxTimestamp)*time.Millisecond, ErrTimestampOverflow)
+	}
+
+	timestamp := now
+	if timestamp < g.observed {
+		timestamp = g.observed
+	}
+	if timestamp <= g.elapsedTime && g.sequence == maxSeqBits && g.elapsedTime+1-now > g.drift() {
+		return fmt.Errorf("snowflake: sequence of the last millisecond is exhausted: %w", ErrSequenceExhausted)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestNextIDErr(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+
+	tc := []struct {
+		name string
+		opts []snowflake.Option
+		// inject sets the condition up, after a first ID
+		inject func(sf *snowflake.ID, clock *fakeClock)
+		err    error
+	}{
+		{"Should issue an ID", nil, func(*snowflake.ID, *fakeClock) {}, nil},
+		{"Should return ErrClockMovedBackwards", nil, func(_ *snowflake.ID, clock *fakeClock) {
+			clock.Add(-5 * time.Millisecond)
+		}, snowflake.ErrClockMovedBackwards},
+		{"Should return ErrBeforeEpoch", nil, func(_ *snowflake.ID, clock *fakeClock) {
+			clock.Set(snowflake.Epoch().Add(-time.Millisecond))
+		}, snowflake.ErrBeforeEpoch},
+		{"Should return ErrTimestampOverflow", nil, func(_ *snowflake.ID, clock *fakeClock) {
+			clock.Set(snowflake.Epoch().Add(1 << 41 * time.Millisecond))
+		}, snowflake.ErrTimestampOverflow},
+		{"Should return ErrSequenceExhausted", nil, func(sf *snowflake.ID, _ *fakeClock) {
+			sf.AppendIDs(nil, 4095)
+		}, snowflake.ErrSequenceExhausted},
+		{"Should borrow the next millisecond with forward drift", []snowflake.Option{snowflake.WithMaxForwardDrift(time.Millisecond)}, func(sf *snowflake.ID, _ *fakeClock) {
+			sf.AppendIDs(nil, 4095)
+		}, nil},
+		{"Should fall back instead of failing", []snowflake.Option{snowflake.WithRandomFallback()}, func(_ *snowflake.ID, clock *fakeClock) {
+			clock.Set(snowflake.Epoch().Add(1 << 41 * time.Millisecond))
+		}, nil},
+		{"Should return ErrFieldUnassigned once released", nil, func(sf *snowflake.ID, _ *fakeClock) {
+			sf.Release()
+		}, snowflake.ErrFieldUnassigned},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock(start)
+			sf, err := snowflake.NewRegistered(120, append(tt.opts, snowflake.WithClock(clock))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sf.Release()
+
+			first, err := sf.NextIDErr()
+			if err != nil {
+				t.Fatalf("expected no error got %v", err)
+			}
+			tt.inject(sf, clock)
+
+			id, err := sf.NextIDErr()
+			if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+				t.Fatalf("expected error %v got %v", tt.err, err)
+			}
+			if err != nil && id != 0 {
+				t.Errorf("expected no ID with an error got %d", id)
+			}
+			if err == nil && id <= first && !snowflake.IsFallback(id) {
+				t.Errorf("expected ID %d to be greater than %d", id, first)
+			}
+		})
+	}
+}
+
+func TestNextIDErr_Recovers(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New2(1, 2, snowflake.WithClock(clock))
+	first := sf.NextID()
+
+	clock.Add(-5 * time.Millisecond)
+	if _, err := sf.NextIDErr(); !errors.Is(err, snowflake.ErrClockMovedBackwards) {
+		t.Fatalf("expected error %v got %v", snowflake.ErrClockMovedBackwards, err)
+	}
+	// NextID never fails, it issues from the last millisecond
+	if id := sf.NextID(); id <= first {
+		t.Errorf("expected ID %d to be greater than %d", id, first)
+	}
+
+	clock.Add(5 * time.Millisecond)
+	if _, err := sf.NextIDErr(); err != nil {
+		t.Errorf("expected no error once the clock caught up got %v", err)
+	}
+}
+
+func TestNextIDErr_LeaseLost(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var alloc snowflake.MemoryAllocator
+	sf, err := snowflake.NewLeased(ctx, &alloc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sf.NextIDErr(); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	alloc.Revoke(0)
+	if _, err := sf.NextIDErr(); !errors.Is(err, snowflake.ErrFieldUnassigned) {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldUnassigned, err)
+	}
+}
+
+func TestNextIDErr_Generator(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(1, snowflake.WithClock(clock))
+	sf.NextID()
+	clock.Add(-time.Millisecond)
+
+	if _, err := snowflake.NextIDErr(sf); !errors.Is(err, snowflake.ErrClockMovedBackwards) {
+		t.Errorf("expected error %v got %v", snowflake.ErrClockMovedBackwards, err)
+	}
+
+	// generators that can't fail don't
+	fake := snowflake.GeneratorFunc(func() uint64 { return 42 })
+	if id, err := snowflake.NextIDErr(fake); id != 42 || err != nil {
+		t.Errorf("expected 42 got %d %v", id, err)
+	}
+}
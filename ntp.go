@@ -0,0 +1,346 @@
+package snowflake
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// ntpPacketSize is the size of an SNTP packet. (internal-use only)
+	ntpPacketSize = 48
+	// ntpEpochOffset is the number of seconds from the NTP epoch, 1900, to
+	// the Unix epoch. (internal-use only)
+	ntpEpochOffset = 2208988800
+
+	defaultNTPInterval  = time.Minute
+	defaultNTPTimeout   = 5 * time.Second
+	defaultNTPMaxOffset = time.Second
+	// ntpSmoothing is the weight of the offset measured so far against a
+	// new sample: the smoothed offset moves by 1/ntpSmoothing of the
+	// difference. (internal-use only)
+	ntpSmoothing = 4
+)
+
+// NTPTransport sends an SNTP request to an NTP server and returns its
+// response, e.g. to swap UDP for a fake responder in tests.
+type NTPTransport interface {
+	Exchange(ctx context.Context, server string, req []byte) ([]byte, error)
+}
+
+// UDPTransport is the NTPTransport of NTPClock by default, exchanging
+// packets over UDP. A server with no port is reached on port 123.
+type UDPTransport struct{}
+
+var _ NTPTransport = UDPTransport{}
+
+// Exchange sends req to server and returns the first response.
+func (UDPTransport) Exchange(ctx context.Context, server string, req []byte) ([]byte, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, ntpPacketSize)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return nil, err
+	}
+	return resp[:n], nil
+}
+
+// NTPOption configures an NTPClock.
+type NTPOption func(*NTPClock)
+
+// WithNTPTransport makes the NTPClock query the servers through t instead
+// of UDP.
+func WithNTPTransport(t NTPTransport) NTPOption {
+	return func(c *NTPClock) {
+		c.transport = t
+	}
+}
+
+// WithNTPInterval sets how often the NTPClock queries the servers
+// (default: 1 minute). Panics if d isn't positive.
+func WithNTPInterval(d time.Duration) NTPOption {
+	if d <= 0 {
+		panic("snowflake: NTP interval must be positive")
+	}
+	return func(c *NTPClock) {
+		c.interval = d
+	}
+}
+
+// WithNTPTimeout bounds a query to a server (default: 5 seconds).
+func WithNTPTimeout(d time.Duration) NTPOption {
+	return func(c *NTPClock) {
+		c.timeout = d
+	}
+}
+
+// WithNTPMaxOffset sets how far the local clock may be off the servers
+// before the Health of generators using the NTPClock reports
+// ErrClockOffset (default: 1 second). 0 disables the check.
+func WithNTPMaxOffset(d time.Duration) NTPOption {
+	return func(c *NTPClock) {
+		c.maxOffset = d
+	}
+}
+
+// WithNTPLocalClock makes the NTPClock correct local instead of the system
+// clock.
+func WithNTPLocalClock(local Clock) NTPOption {
+	return func(c *NTPClock) {
+		c.local = local
+	}
+}
+
+// offsetClock is implemented by clocks that correct the local clock by a
+// measured offset, for Stats and Health. (internal-use only)
+type offsetClock interface {
+	Clock
+	offset() (offset time.Duration, synced bool)
+	maxClockOffset() time.Duration
+}
+
+// NTPClock is a Clock correcting the local clock by its offset to NTP
+// servers, for hosts whose clocks can't be trusted. It queries the servers
+// with SNTP in the background, keeps the sample with the least round trip
+// of every round, and smooths the offset over rounds so that one bad
+// sample doesn't jerk the time around.
+//
+// Now never goes backwards: a correction that would move it back holds it
+// at the last time it returned until the corrected time catches up, so
+// generators don't see their clock regress. Until a server answers, and
+// while none does, it serves the local time corrected by the last offset
+// measured, if any. Stats and Health of the generators using it report
+// the offset.
+//
+//	clock := snowflake.NewNTPClock([]string{"time.google.com", "pool.ntp.org"})
+//	defer clock.Close()
+//	sf := snowflake.New(1, snowflake.WithClock(clock))
+type NTPClock struct {
+	nanos  int64 // smoothed offset, keep first for 64-bit alignment of atomic operations
+	last   int64 // last time returned by Now, in Unix nanoseconds
+	synced int32
+
+	servers   []string
+	transport NTPTransport
+	local     Clock
+	interval  time.Duration
+	timeout   time.Duration
+	maxOffset time.Duration
+
+	mtx       sync.Mutex // serializes syncs
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+var _ offsetClock = (*NTPClock)(nil)
+
+// NewNTPClock returns a new snowflake.NTPClock querying servers, which
+// starts syncing in the background right away. Call Close to stop it.
+func NewNTPClock(servers []string, opts ...NTPOption) *NTPClock {
+	c := &NTPClock{
+		servers:   servers,
+		transport: UDPTransport{},
+		interval:  defaultNTPInterval,
+		timeout:   defaultNTPTimeout,
+		maxOffset: defaultNTPMaxOffset,
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.wg.Add(1)
+	go c.run()
+
+	return c
+}
+
+// Now returns the local time corrected by the offset, never less than the
+// last time it returned.
+func (c *NTPClock) Now() time.Time {
+	now := c.localNow().UnixNano() + atomic.LoadInt64(&c.nanos)
+	for {
+		last := atomic.LoadInt64(&c.last)
+		if now <= last {
+			return time.Unix(0, last)
+		}
+		if atomic.CompareAndSwapInt64(&c.last, last, now) {
+			return time.Unix(0, now)
+		}
+	}
+}
+
+// Offset returns the smoothed offset of the servers to the local clock:
+// positive if the local clock is behind. ok is false until a server
+// answered.
+func (c *NTPClock) Offset() (offset time.Duration, ok bool) {
+	return c.offset()
+}
+
+// Sync queries the servers now and updates the offset from the sample
+// with the least round trip. Returns an error wrapping the error of every
+// server if none answered, leaving the offset as it was.
+func (c *NTPClock) Sync(ctx context.Context) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var best ntpSample
+	var errs []string
+	for _, server := range c.servers {
+		s, err := c.query(ctx, server)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if best.server == "" || s.delay < best.delay {
+			best = s
+		}
+	}
+	if best.server == "" {
+		return fmt.Errorf("snowflake: no NTP server answered: %v: %w", errs, ErrNTPUnavailable)
+	}
+
+	offset := int64(best.offset)
+	if atomic.LoadInt32(&c.synced) == 1 {
+		current := atomic.LoadInt64(&c.nanos)
+		offset = current + (offset-current)/ntpSmoothing
+	}
+	atomic.StoreInt64(&c.nanos, offset)
+	atomic.StoreInt32(&c.synced, 1)
+	return nil
+}
+
+// Close stops the background syncs and waits for them to exit. Now keeps
+// correcting the local time by the last offset.
+func (c *NTPClock) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.wg.Wait()
+	})
+}
+
+// offset returns the smoothed offset. (internal-use only)
+func (c *NTPClock) offset() (time.Duration, bool) {
+	return time.Duration(atomic.LoadInt64(&c.nanos)), atomic.LoadInt32(&c.synced) == 1
+}
+
+// maxClockOffset returns the offset beyond which Health reports
+// ErrClockOffset. (internal-use only)
+func (c *NTPClock) maxClockOffset() time.Duration { return c.maxOffset }
+
+// localNow returns the local time. (internal-use only)
+func (c *NTPClock) localNow() time.Time {
+	if c.local != nil {
+		return c.local.Now()
+	}
+	return time.Now()
+}
+
+// run syncs every interval until Close is called. (internal-use only)
+func (c *NTPClock) run() {
+	defer c.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-c.done
+		cancel()
+	}()
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		// failures keep the last offset, the next round may do better
+		c.Sync(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// ntpSample is the outcome of an SNTP query. (internal-use only)
+type ntpSample struct {
+	server string
+	offset time.Duration
+	delay  time.Duration
+}
+
+// query measures the offset of server to the local clock. (internal-use only)
+func (c *NTPClock) query(ctx context.Context, server string) (ntpSample, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req := make([]byte, ntpPacketSize)
+	req[0] = 4<<3 | 3 // version 4, client mode
+	t1 := c.localNow()
+	putNTPTime(req[40:], t1)
+
+	resp, err := c.transport.Exchange(ctx, server, req)
+	t4 := c.localNow()
+	if err != nil {
+		return ntpSample{}, fmt.Errorf("%s: %v", server, err)
+	}
+
+	switch {
+	case len(resp) < ntpPacketSize:
+		return ntpSample{}, fmt.Errorf("%s: short response", server)
+	case resp[0]&7 != 4:
+		return ntpSample{}, fmt.Errorf("%s: not a server response", server)
+	case resp[1] == 0:
+		return ntpSample{}, fmt.Errorf("%s: kiss of death", server)
+	case !bytes.Equal(resp[24:32], req[40:48]):
+		return ntpSample{}, fmt.Errorf("%s: response to another request", server)
+	}
+
+	t2, t3 := ntpTime(resp[32:]), ntpTime(resp[40:])
+	return ntpSample{
+		server: server,
+		offset: (t2.Sub(t1) + t3.Sub(t4)) / 2,
+		delay:  t4.Sub(t1) - t3.Sub(t2),
+	}, nil
+}
+
+// putNTPTime writes t to b as a 64-bit NTP timestamp, whose seconds wrap
+// around in 2036. (internal-use only)
+func putNTPTime(b []byte, t time.Time) {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / 1e9
+	binary.BigEndian.PutUint64(b, secs<<32|frac)
+}
+
+// ntpTime reads a 64-bit NTP timestamp from b, taking seconds below 2^31,
+// before 1968, to be past the wrap around of 2036. (internal-use only)
+func ntpTime(b []byte) time.Time {
+	v := binary.BigEndian.Uint64(b)
+	secs, frac := int64(v>>32), (v&0xFFFFFFFF)*1e9>>32
+	if secs < 1<<31 {
+		secs += 1 << 32
+	}
+	return time.Unix(secs-ntpEpochOffset, int64(frac))
+}
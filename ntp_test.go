@@ -0,0 +1,304 @@
+package snowflake_test
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// putNTP writes t to b as a 64-bit NTP timestamp.
+func putNTP(b []byte, t time.Time) {
+	secs := uint64(t.Unix() + 2208988800)
+	binary.BigEndian.PutUint64(b, secs<<32|uint64(t.Nanosecond())<<32/1e9)
+}
+
+// ntpReply answers the SNTP request req as a server whose clock reads now.
+func ntpReply(req []byte, now time.Time) []byte {
+	resp := make([]byte, 48)
+	resp[0] = 4<<3 | 4 // version 4, server mode
+	resp[1] = 1        // stratum
+	copy(resp[24:32], req[40:48])
+	putNTP(resp[32:], now)
+	putNTP(resp[40:], now)
+	return resp
+}
+
+// fakeNTP is an NTPTransport answering for servers whose clocks are off
+// the local clock, taking delay to answer on the local clock.
+type fakeNTP struct {
+	mtx     sync.Mutex
+	local   *fakeClock
+	offsets map[string]time.Duration
+	delays  map[string]time.Duration
+	reply   func(req []byte, now time.Time) []byte
+}
+
+func newFakeNTP(local *fakeClock, offset time.Duration) *fakeNTP {
+	return &fakeNTP{
+		local:   local,
+		offsets: map[string]time.Duration{"ntp": offset},
+		delays:  map[string]time.Duration{},
+		reply:   ntpReply,
+	}
+}
+
+func (f *fakeNTP) Set(server string, offset time.Duration) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.offsets[server] = offset
+}
+
+func (f *fakeNTP) Exchange(ctx context.Context, server string, req []byte) ([]byte, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	offset, ok := f.offsets[server]
+	if !ok {
+		return nil, errors.New("unreachable")
+	}
+	delay := f.delays[server]
+	f.local.Add(delay / 2)
+	resp := f.reply(req, f.local.Now().Add(offset))
+	f.local.Add(delay / 2)
+	return resp, nil
+}
+
+// newSyncedNTPClock returns an NTPClock whose first sync, in the
+// background, is over.
+func newSyncedNTPClock(t *testing.T, servers []string, transport snowflake.NTPTransport, local *fakeClock, opts ...snowflake.NTPOption) *snowflake.NTPClock {
+	t.Helper()
+	clock := snowflake.NewNTPClock(servers, append([]snowflake.NTPOption{
+		snowflake.WithNTPTransport(transport),
+		snowflake.WithNTPLocalClock(local),
+		snowflake.WithNTPInterval(time.Hour),
+	}, opts...)...)
+	t.Cleanup(clock.Close)
+
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		if _, ok := clock.Offset(); ok {
+			return clock
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the NTPClock to sync")
+		}
+	}
+}
+
+var ntpStart = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestNTPClock_Offset(t *testing.T) {
+	local := newFakeClock(ntpStart)
+	ntp := newFakeNTP(local, 2*time.Second)
+	clock := newSyncedNTPClock(t, []string{"ntp"}, ntp, local)
+
+	if offset, _ := clock.Offset(); offset != 2*time.Second {
+		t.Errorf("expected offset %s got %s", 2*time.Second, offset)
+	}
+	if now := clock.Now(); !now.Equal(ntpStart.Add(2 * time.Second)) {
+		t.Errorf("expected %s got %s", ntpStart.Add(2*time.Second), now)
+	}
+
+	// later samples are smoothed in
+	ntp.Set("ntp", 6*time.Second)
+	if err := clock.Sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := clock.Offset(); offset != 3*time.Second {
+		t.Errorf("expected smoothed offset %s got %s", 3*time.Second, offset)
+	}
+}
+
+func TestNTPClock_LeastRoundTrip(t *testing.T) {
+	local := newFakeClock(ntpStart)
+	ntp := newFakeNTP(local, time.Second)
+	ntp.offsets["near"], ntp.delays["near"] = 5*time.Second, 10*time.Millisecond
+	ntp.delays["ntp"] = 200 * time.Millisecond
+
+	clock := newSyncedNTPClock(t, []string{"ntp", "near"}, ntp, local)
+	// NTP timestamps are precise to 2^-32 seconds
+	if offset, _ := clock.Offset(); offset < 5*time.Second-time.Microsecond || offset > 5*time.Second+time.Microsecond {
+		t.Errorf("expected the offset of the nearest server %s got %s", 5*time.Second, offset)
+	}
+}
+
+func TestNTPClock_RejectsBackwardsCorrection(t *testing.T) {
+	local := newFakeClock(ntpStart)
+	ntp := newFakeNTP(local, 2*time.Second)
+	clock := newSyncedNTPClock(t, []string{"ntp"}, ntp, local)
+
+	sf := snowflake.New(1, snowflake.WithClock(clock))
+	first := sf.NextID()
+	ahead := clock.Now()
+
+	// the servers now say the local clock is ahead
+	ntp.Set("ntp", -2*time.Second)
+	for i := 0; i < 20; i++ {
+		if err := clock.Sync(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if offset, _ := clock.Offset(); offset > 0 {
+		t.Fatalf("expected a negative offset got %s", offset)
+	}
+
+	if now := clock.Now(); now.Before(ahead) {
+		t.Errorf("expected the clock to hold at %s got %s", ahead, now)
+	}
+	if id := sf.NextID(); id <= first {
+		t.Errorf("expected ID %d to be greater than %d", id, first)
+	}
+	if stats := sf.Stats(); stats.ClockRegressions != 0 {
+		t.Errorf("expected no clock regressions got %d", stats.ClockRegressions)
+	}
+
+	// the clock moves again once the corrected time catches up
+	local.Add(5 * time.Second)
+	if now := clock.Now(); !now.After(ahead) {
+		t.Errorf("expected the clock to move past %s got %s", ahead, now)
+	}
+}
+
+func TestNTPClock_Unreachable(t *testing.T) {
+	local := newFakeClock(ntpStart)
+	ntp := newFakeNTP(local, 0)
+	clock := snowflake.NewNTPClock([]string{"down1", "down2"},
+		snowflake.WithNTPTransport(ntp), snowflake.WithNTPLocalClock(local), snowflake.WithNTPInterval(time.Hour))
+	defer clock.Close()
+
+	if err := clock.Sync(context.Background()); !errors.Is(err, snowflake.ErrNTPUnavailable) {
+		t.Errorf("expected error %v got %v", snowflake.ErrNTPUnavailable, err)
+	}
+	if _, ok := clock.Offset(); ok {
+		t.Error("expected no offset without a server")
+	}
+	if now := clock.Now(); !now.Equal(ntpStart) {
+		t.Errorf("expected the local time %s got %s", ntpStart, now)
+	}
+
+	sf := snowflake.New(1, snowflake.WithClock(clock))
+	if stats := sf.Stats(); stats.ClockSynced || stats.ClockOffset != 0 {
+		t.Errorf("expected no offset in stats got %s synced %t", stats.ClockOffset, stats.ClockSynced)
+	}
+}
+
+func TestNTPClock_KeepsOffsetWhenUnreachable(t *testing.T) {
+	local := newFakeClock(ntpStart)
+	ntp := newFakeNTP(local, 3*time.Second)
+	clock := newSyncedNTPClock(t, []string{"ntp"}, ntp, local)
+
+	ntp.mtx.Lock()
+	delete(ntp.offsets, "ntp")
+	ntp.mtx.Unlock()
+	if err := clock.Sync(context.Background()); !errors.Is(err, snowflake.ErrNTPUnavailable) {
+		t.Errorf("expected error %v got %v", snowflake.ErrNTPUnavailable, err)
+	}
+	if offset, ok := clock.Offset(); !ok || offset != 3*time.Second {
+		t.Errorf("expected the last offset %s got %s %t", 3*time.Second, offset, ok)
+	}
+}
+
+func TestNTPClock_InvalidResponses(t *testing.T) {
+	tc := []struct {
+		name  string
+		reply func(req []byte, now time.Time) []byte
+	}{
+		{"Should reject a short response", func(req []byte, now time.Time) []byte { return ntpReply(req, now)[:40] }},
+		{"Should reject a client packet", func(req []byte, now time.Time) []byte {
+			resp := ntpReply(req, now)
+			resp[0] = 4<<3 | 3
+			return resp
+		}},
+		{"Should reject a kiss of death", func(req []byte, now time.Time) []byte {
+			resp := ntpReply(req, now)
+			resp[1] = 0
+			return resp
+		}},
+		{"Should reject a response to another request", func(req []byte, now time.Time) []byte {
+			resp := ntpReply(req, now)
+			resp[31]++
+			return resp
+		}},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			local := newFakeClock(ntpStart)
+			ntp := newFakeNTP(local, time.Hour)
+			ntp.reply = tt.reply
+			clock := snowflake.NewNTPClock([]string{"ntp"},
+				snowflake.WithNTPTransport(ntp), snowflake.WithNTPLocalClock(local), snowflake.WithNTPInterval(time.Hour))
+			defer clock.Close()
+
+			if err := clock.Sync(context.Background()); !errors.Is(err, snowflake.ErrNTPUnavailable) {
+				t.Errorf("expected error %v got %v", snowflake.ErrNTPUnavailable, err)
+			}
+		})
+	}
+}
+
+func TestNTPClock_Health(t *testing.T) {
+	local := newFakeClock(ntpStart)
+	ntp := newFakeNTP(local, 2*time.Second)
+	clock := newSyncedNTPClock(t, []string{"ntp"}, ntp, local)
+
+	sf := snowflake.New(1, snowflake.WithClock(clock))
+	if err := sf.Health(); !errors.Is(err, snowflake.ErrClockOffset) {
+		t.Errorf("expected error %v got %v", snowflake.ErrClockOffset, err)
+	}
+	if stats := sf.Stats(); !stats.ClockSynced || stats.ClockOffset != 2*time.Second {
+		t.Errorf("expected offset %s in stats got %s synced %t", 2*time.Second, stats.ClockOffset, stats.ClockSynced)
+	}
+
+	tolerant := newSyncedNTPClock(t, []string{"ntp"}, ntp, local, snowflake.WithNTPMaxOffset(5*time.Second))
+	if err := snowflake.New(1, snowflake.WithClock(tolerant)).Health(); err != nil {
+		t.Errorf("expected no error within the max offset got %v", err)
+	}
+}
+
+func TestUDPTransport(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("no UDP:", err)
+	}
+	defer conn.Close()
+
+	serverNow := time.Now().Add(time.Hour)
+	go func() {
+		buf := make([]byte, 48)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil || n != 48 {
+			return
+		}
+		conn.WriteTo(ntpReply(buf, serverNow), addr)
+	}()
+
+	clock := snowflake.NewNTPClock([]string{conn.LocalAddr().String()}, snowflake.WithNTPInterval(time.Hour))
+	defer clock.Close()
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		if offset, ok := clock.Offset(); ok {
+			if offset < 59*time.Minute || offset > 61*time.Minute {
+				t.Errorf("expected an offset of about an hour got %s", offset)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the NTPClock to sync over UDP")
+		}
+	}
+}
+
+func TestWithNTPInterval_NotPositive(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected WithNTPInterval(0) to panic")
+		}
+	}()
+	snowflake.WithNTPInterval(0)
+}
@@ -0,0 +1,97 @@
+package snowflake
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	// minObfuscatorKey is the least size of an Obfuscator key.
+	// (internal-use only)
+	minObfuscatorKey = 16
+	// feistelRounds is the number of rounds of the Feistel network.
+	// (internal-use only)
+	feistelRounds = 4
+)
+
+// Obfuscator maps snowflake IDs to random-looking IDs and back, e.g. to
+// put them in public URLs without a lookup table. It's a 4-round Feistel
+// network keyed with AES, so the mapping is a bijection: distinct IDs
+// never map to the same obfuscated ID.
+//
+// IDs below 2^63, as all snowflake IDs are, map to IDs below 2^63, so they
+// still fit an int64. Obfuscated IDs don't sort like the IDs they come
+// from, by design: their order would leak when they were issued.
+//
+// An Obfuscator is safe for concurrent use.
+type Obfuscator struct {
+	block cipher.Block
+}
+
+// NewObfuscator returns a new snowflake.Obfuscator keyed with key.
+// Returns an error wrapping ErrKeyTooShort if key is shorter than 16 bytes.
+func NewObfuscator(key []byte) (*Obfuscator, error) {
+	if len(key) < minObfuscatorKey {
+		return nil, fmt.Errorf("snowflake: obfuscator key of %d bytes, expected at least %d: %w", len(key), minObfuscatorKey, ErrKeyTooShort)
+	}
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	return &Obfuscator{block: block}, nil
+}
+
+// Obfuscate returns the obfuscated ID of id.
+func (o *Obfuscator) Obfuscate(id uint64) uint64 {
+	// walk the cycle of id until back in its half of the 64-bit space
+	x := o.encrypt(id)
+	for x>>63 != id>>63 {
+		x = o.encrypt(x)
+	}
+	return x
+}
+
+// Deobfuscate returns the ID x is the obfuscated ID of. Returns an error
+// wrapping ErrInvalidID if x isn't the obfuscated ID of a snowflake ID.
+func (o *Obfuscator) Deobfuscate(x uint64) (uint64, error) {
+	if x>>63 != 0 {
+		return 0, fmt.Errorf("snowflake: %d overflows an obfuscated ID: %w", x, ErrInvalidID)
+	}
+	id := o.decrypt(x)
+	for id>>63 != 0 {
+		id = o.decrypt(id)
+	}
+	return id, nil
+}
+
+// encrypt runs the Feistel network forward over x. (internal-use only)
+func (o *Obfuscator) encrypt(x uint64) uint64 {
+	l, r := uint32(x>>32), uint32(x)
+	for i := 0; i < feistelRounds; i++ {
+		l, r = r, l^o.round(i, r)
+	}
+	return uint64(l)<<32 | uint64(r)
+}
+
+// decrypt runs the Feistel network backward over x. (internal-use only)
+func (o *Obfuscator) decrypt(x uint64) uint64 {
+	l, r := uint32(x>>32), uint32(x)
+	for i := feistelRounds - 1; i >= 0; i-- {
+		l, r = r^o.round(i, l), l
+	}
+	return uint64(l)<<32 | uint64(r)
+}
+
+// round is the round function of round i: the first 32 bits of the AES
+// encryption of i and half. (internal-use only)
+func (o *Obfuscator) round(i int, half uint32) uint32 {
+	var in, out [aes.BlockSize]byte
+	in[0] = byte(i)
+	binary.BigEndian.PutUint32(in[1:], half)
+	o.block.Encrypt(out[:], in[:])
+	return binary.BigEndian.Uint32(out[:])
+}
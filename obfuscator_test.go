@@ -0,0 +1,86 @@
+package snowflake_test
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func newObfuscator(t *testing.T) *snowflake.Obfuscator {
+	o, err := snowflake.NewObfuscator([]byte("0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	return o
+}
+
+func TestObfuscator_Bijection(t *testing.T) {
+	o := newObfuscator(t)
+	rng := rand.New(rand.NewSource(1))
+
+	seen := make(map[uint64]uint64, 100000)
+	for i := 0; i < 100000; i++ {
+		id := uint64(rng.Int63())
+		x := o.Obfuscate(id)
+		if x>>63 != 0 {
+			t.Fatalf("expected %d to map below 2^63 got %d", id, x)
+		}
+		if prev, ok := seen[x]; ok && prev != id {
+			t.Fatalf("expected no collision got %d and %d both mapping to %d", prev, id, x)
+		}
+		seen[x] = id
+
+		back, err := o.Deobfuscate(x)
+		if err != nil {
+			t.Fatalf("expected no error got %v", err)
+		}
+		if back != id {
+			t.Fatalf("expected %d got %d", id, back)
+		}
+	}
+}
+
+func TestObfuscator_Vectors(t *testing.T) {
+	o := newObfuscator(t)
+
+	// pinned, so that obfuscated IDs already out there keep their meaning
+	tc := []struct{ id, x uint64 }{
+		{0, 2033739822978224976},
+		{1, 6109134466235759395},
+		{42, 8360766408511997678},
+		{1292053924173320192, 2864542816675282853},
+		{1<<63 - 1, 1100062462061624885},
+	}
+
+	for _, tt := range tc {
+		if x := o.Obfuscate(tt.id); x != tt.x {
+			t.Errorf("expected %d to map to %d got %d", tt.id, tt.x, x)
+		}
+		if id, err := o.Deobfuscate(tt.x); err != nil || id != tt.id {
+			t.Errorf("expected %d got %d (%v)", tt.id, id, err)
+		}
+	}
+}
+
+func TestObfuscator_Errors(t *testing.T) {
+	if _, err := snowflake.NewObfuscator([]byte("too short")); !errors.Is(err, snowflake.ErrKeyTooShort) {
+		t.Errorf("expected error %v got %v", snowflake.ErrKeyTooShort, err)
+	}
+	if _, err := snowflake.NewObfuscator(nil); !errors.Is(err, snowflake.ErrKeyTooShort) {
+		t.Errorf("expected error %v got %v", snowflake.ErrKeyTooShort, err)
+	}
+
+	if _, err := newObfuscator(t).Deobfuscate(1 << 63); !errors.Is(err, snowflake.ErrInvalidID) {
+		t.Errorf("expected error %v got %v", snowflake.ErrInvalidID, err)
+	}
+
+	other, err := snowflake.NewObfuscator([]byte("fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newObfuscator(t).Obfuscate(42) == other.Obfuscate(42) {
+		t.Error("expected another key to map differently")
+	}
+}
@@ -0,0 +1,191 @@
+package snowflake
+
+import "time"
+
+// Option configures a generator created by New, New2 or NewSharded.
+type Option func(*generator)
+
+// apply applies the options to the generator and sets it up.
+// (internal-use only)
+func (g *generator) apply(opts []Option) {
+	g.applyOptions(opts)
+	g.setup()
+}
+
+// applyOptions applies the options to the generator, leaving the steps
+// that depend on its final field to setup. (internal-use only)
+func (g *generator) applyOptions(opts []Option) {
+	g.utilization = new(utilization)
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.tick == 0 && g.clock == nil {
+		g.tick = systemTick()
+	}
+}
+
+// setup tags the field of the generator and warms it up, once its field is
+// final. (internal-use only)
+func (g *generator) setup() {
+	if g.versionTag != 0 {
+		g.tagField()
+	}
+	if g.warmupStore != nil {
+		g.warmup()
+	}
+}
+
+// WithClock makes the generator read the current time from c instead of the
+// system clock.
+func WithClock(c Clock) Option {
+	return func(g *generator) {
+		g.clock = c
+	}
+}
+
+// WithRateLimit caps the generator at perSecond IDs per second using a token
+// bucket that holds up to perSecond tokens, so a burst of that size passes
+// immediately. NextID waits for a token, TryNextID returns ErrRateLimited.
+// A perSecond of 0 or less means no limit.
+func WithRateLimit(perSecond int) Option {
+	return func(g *generator) {
+		if perSecond <= 0 {
+			g.limiter = nil
+			return
+		}
+		g.limiter = newRateLimiter(perSecond)
+	}
+}
+
+// WithClockCheckEvery makes the generator read the clock only once every n
+// IDs while the sequence has headroom, reusing the last timestamp in between.
+// The clock is always read when the sequence is exhausted.
+//
+// IDs are never stamped ahead of the clock, but they can be stamped behind
+// it: by up to n-1 IDs during a burst, and by however long the generator sat
+// idle for the first IDs after a pause. Only use it for sustained, high
+// throughput workloads where that lag is acceptable.
+// An n of 1 or less reads the clock for every ID, which is the default.
+func WithClockCheckEvery(n int) Option {
+	return func(g *generator) {
+		if n <= 1 {
+			n = 0
+		}
+		g.checkEvery = n
+		g.sinceCheck = 0
+	}
+}
+
+// WithMaxForwardDrift lets the generator move its timestamp ahead of the
+// clock, by up to d, when the sequence of the current millisecond is
+// exhausted, instead of waiting for the clock to tick. The clock catches up
+// during quieter periods. Once the bound is reached the generator waits as
+// usual. Durations below a millisecond disable drift, which is the default.
+func WithMaxForwardDrift(d time.Duration) Option {
+	return func(g *generator) {
+		g.maxDrift = d.Milliseconds()
+	}
+}
+
+// WithWaitThresholds tunes how the generator waits for the next millisecond
+// once the sequence is exhausted: it busy-spins for up to spin, then yields
+// to the scheduler for up to yield, then sleeps for the estimated remaining
+// time. Spinning gives the lowest latency, sleeping the lowest CPU usage.
+// The defaults are 50µs and 200µs, or 0 on js and Windows, whose clock is
+// too coarse to spin waiting for. Negative values are treated as 0.
+// Generators using a Clock set with WithClock never sleep, since the clock
+// may not follow real time; they yield instead.
+func WithWaitThresholds(spin, yield time.Duration) Option {
+	return func(g *generator) {
+		if spin < 0 {
+			spin = 0
+		}
+		if yield < 0 {
+			yield = 0
+		}
+		g.waitTuned = true
+		g.spinWait = spin
+		g.yieldWait = yield
+	}
+}
+
+// WithRestoreTolerance sets how far ahead of the clock the last millisecond
+// of a state passed to RestoreState may be, to allow for small clock
+// corrections between saving and restoring. The default is 1s. Negative
+// values are treated as 0.
+func WithRestoreTolerance(d time.Duration) Option {
+	return func(g *generator) {
+		if d < 0 {
+			d = 0
+		}
+		g.toleranceSet = true
+		g.restoreTolerance = d
+	}
+}
+
+// WithStateStore makes the generator save its state to s every time it
+// moves on to a new millisecond, before issuing the first ID of it, so
+// saves happen at most once per millisecond rather than once per ID.
+// Saves happen while the generator is locked, so s should be fast; failed
+// saves are reported to the logger set with WithLogger. Use NewPersistent
+// to resume from the saved state.
+func WithStateStore(s StateStore) Option {
+	return func(g *generator) {
+		g.store = s
+	}
+}
+
+// WithOnSequenceExhausted makes the generator call f every time the
+// sequence of a millisecond runs out, with how long it then waited for the
+// next millisecond (0 when WithMaxForwardDrift let it move on right away).
+//
+// f runs on a goroutine of its own, so it can't hold up or deadlock the
+// generator, but only one call runs at a time: exhaustions happening while
+// f is still running are dropped. Keep f short to see them all.
+func WithOnSequenceExhausted(f func(waited time.Duration)) Option {
+	return func(g *generator) {
+		g.onExhausted = newHook(f)
+	}
+}
+
+// WithOnClockBackwards makes the generator call f every time it reads the
+// clock behind its previous reading, with how far behind. The generator
+// keeps issuing IDs from the last millisecond until the clock catches up,
+// so f is for alerting rather than recovering.
+//
+// Like the callback of WithOnSequenceExhausted, f runs on a goroutine of
+// its own and regressions happening while it's still running are dropped.
+func WithOnClockBackwards(f func(regression time.Duration)) Option {
+	return func(g *generator) {
+		g.onBackwards = newHook(f)
+	}
+}
+
+// WithHealthThresholds sets how far the clock may lag behind the last ID,
+// on top of the drift allowed with WithMaxForwardDrift, and how little
+// lifetime may be left before Health reports a problem. Both default to 0:
+// any lag beyond the drift is reported, and only an overflowed timestamp
+// is. Negative values are treated as 0.
+func WithHealthThresholds(clockTolerance, minRemaining time.Duration) Option {
+	return func(g *generator) {
+		if clockTolerance < 0 {
+			clockTolerance = 0
+		}
+		if minRemaining < 0 {
+			minRemaining = 0
+		}
+		g.clockTolerance = clockTolerance.Milliseconds()
+		g.minRemaining = minRemaining
+	}
+}
+
+// WithLease makes Health report ErrLeaseLost once done is closed, such as
+// the Done channel of a lease the field was acquired with.
+//
+//	lease, err := etcdalloc.Acquire(ctx, client, "/snowflake/fields", 10*time.Second)
+//	sf := snowflake.New(lease.Field(), snowflake.WithLease(lease.Done()))
+func WithLease(done <-chan struct{}) Option {
+	return func(g *generator) {
+		g.leaseDone = done
+	}
+}
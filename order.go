@@ -0,0 +1,123 @@
+package snowflake
+
+import "fmt"
+
+// SegmentOrder is the order of the field and the sequence below the
+// timestamp of an ID, see WithSegmentOrder.
+type SegmentOrder uint8
+
+const (
+	// SeqLow puts the sequence in the lowest 12 bits, below the field: the
+	// IDs of a millisecond sort by field, then by sequence. It's the
+	// default.
+	SeqLow SegmentOrder = iota
+	// FieldLow puts the field in the lowest 10 bits, below the sequence, as
+	// Sonyflake does: the IDs of a millisecond sort by sequence, the
+	// generators taking turns.
+	FieldLow
+)
+
+// WithSegmentOrder sets the order of the field and the sequence of the
+// generator's IDs, SeqLow by default. IDs don't tell which order they were
+// issued with: parse and compose FieldLow IDs with FieldLow.Parse and
+// FieldLow.Compose, Parse and SID.ToID being for SeqLow ones.
+//
+// The timestamp stays in the highest bits, so IDs still sort by time and
+// MinIDForTime and the other boundary helpers hold under both orders.
+// Random fallback IDs are marked the same under both, and Observe,
+// NextUUIDv7 and DescendingID follow the order. A BlockIssuer of a
+// FieldLow generator issues blocks of 1 ID, whose IDs aren't consecutive,
+// and version tags, read by VersionOf, are only supported by SeqLow. Parse
+// FieldLow descending IDs with FieldLow.ParseDescending.
+//
+// It panics if o is neither SeqLow nor FieldLow.
+func WithSegmentOrder(o SegmentOrder) Option {
+	if o > FieldLow {
+		panic(fmt.Sprintf("snowflake: unknown segment order %d", o))
+	}
+	return func(g *generator) {
+		g.fieldLow = o == FieldLow
+	}
+}
+
+// String returns the name of o.
+func (o SegmentOrder) String() string {
+	switch o {
+	case SeqLow:
+		return "SeqLow"
+	case FieldLow:
+		return "FieldLow"
+	}
+	return fmt.Sprintf("SegmentOrder(%d)", uint8(o))
+}
+
+// Parse is like Parse for IDs issued with WithSegmentOrder(o).
+func (o SegmentOrder) Parse(id uint64) SID {
+	if IsFallback(id) {
+		return SID{Fallback: true}
+	}
+	return Parse(o.toSeqLow(id))
+}
+
+// Parse2 is like Parse2 for IDs issued with WithSegmentOrder(o).
+func (o SegmentOrder) Parse2(id uint64) SID2 {
+	if IsFallback(id) {
+		return SID2{Fallback: true}
+	}
+	return Parse2(o.toSeqLow(id))
+}
+
+// Compose is like SID.ToID for IDs issued with WithSegmentOrder(o).
+func (o SegmentOrder) Compose(sid SID) (uint64, error) {
+	id, err := sid.ToID()
+	if err != nil {
+		return 0, err
+	}
+	return o.fromSeqLow(id), nil
+}
+
+// ParseDescending is like ParseDescending for IDs issued by a DescendingID
+// with WithSegmentOrder(o).
+func (o SegmentOrder) ParseDescending(sid uint64) SID {
+	return o.Parse(sid ^ o.descendingMask())
+}
+
+// descendingMask flips the timestamp and sequence of an ID of order o,
+// leaving the field. (internal-use only)
+func (o SegmentOrder) descendingMask() uint64 { return o.fromSeqLow(descendingMask) }
+
+// toSeqLow moves the field and the sequence of id, of order o, into the
+// SeqLow order. (internal-use only)
+func (o SegmentOrder) toSeqLow(id uint64) uint64 {
+	if o != FieldLow {
+		return id
+	}
+	return id&^lowBits | (id&maxFieldBits)<<sequenceBits | id>>fieldBits&maxSeqBits
+}
+
+// fromSeqLow moves the field and the sequence of id, of order SeqLow, into
+// the order o. (internal-use only)
+func (o SegmentOrder) fromSeqLow(id uint64) uint64 {
+	if o != FieldLow {
+		return id
+	}
+	return id&^lowBits | (id&maxSeqBits)<<fieldBits | id>>sequenceBits&maxFieldBits
+}
+
+// order returns the segment order of g. (internal-use only)
+func (g *generator) order() SegmentOrder {
+	if g.fieldLow {
+		return FieldLow
+	}
+	return SeqLow
+}
+
+// compose returns the ID of the last millisecond and sequence of g, in its
+// segment order. g.mtx must be held. (internal-use only)
+func (g *generator) compose() uint64 {
+	id := uint64(g.elapsedTime)<<(sequenceBits+fieldBits) | g.fieldSegment | g.sequence
+	if g.fieldLow {
+		id = FieldLow.fromSeqLow(id)
+	}
+	return id
+}
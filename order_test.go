@@ -0,0 +1,148 @@
+package snowflake_test
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestWithSegmentOrder(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	ms := clock.Now().UnixMilli()
+
+	tc := []struct {
+		name  string
+		order snowflake.SegmentOrder
+	}{
+		{"Should put the sequence low by default", snowflake.SeqLow},
+		{"Should put the field low", snowflake.FieldLow},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := snowflake.New(700, snowflake.WithClock(clock), snowflake.WithSegmentOrder(tt.order))
+			for seq, id := range sf.AppendIDs(nil, 100) {
+				want := snowflake.SID{Timestamp: ms, Sequence: uint64(seq), MachineID: 700, Field: 700}
+				if sid := tt.order.Parse(id); sid != want {
+					t.Fatalf("expected %+v got %+v", want, sid)
+				}
+
+				back, err := tt.order.Compose(want)
+				if err != nil || back != id {
+					t.Fatalf("expected %d got %d %v", id, back, err)
+				}
+				if id < snowflake.MinIDForTime(clock.Now()) {
+					t.Fatalf("expected ID %d to be at least MinIDForTime", id)
+				}
+			}
+			clock.Add(time.Millisecond)
+			ms++
+		})
+	}
+}
+
+func TestWithSegmentOrder_Mismatch(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+
+	tc := []struct {
+		name           string
+		issue, parseAs snowflake.SegmentOrder
+	}{
+		{"Should misparse FieldLow IDs as SeqLow", snowflake.FieldLow, snowflake.SeqLow},
+		{"Should misparse SeqLow IDs as FieldLow", snowflake.SeqLow, snowflake.FieldLow},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sf := snowflake.New(5, snowflake.WithClock(clock), snowflake.WithSegmentOrder(tt.issue))
+			sf.AppendIDs(nil, 2)
+			id := sf.NextID() // sequence 2
+
+			sid := tt.parseAs.Parse(id)
+			if sid.MachineID == 5 || sid.Sequence == 2 {
+				t.Errorf("expected field 5 and sequence 2 to be misparsed got %+v", sid)
+			}
+			if right := tt.issue.Parse(id); right.MachineID != 5 || right.Sequence != 2 {
+				t.Errorf("expected field 5 and sequence 2 got %+v", right)
+			}
+		})
+	}
+}
+
+func TestWithSegmentOrder_Sort(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+
+	tc := []struct {
+		name  string
+		order snowflake.SegmentOrder
+		want  []uint64 // fields of the IDs of a millisecond, in ID order
+	}{
+		{"Should sort by field with SeqLow", snowflake.SeqLow, []uint64{1, 1, 1, 2, 2, 2}},
+		{"Should interleave the fields with FieldLow", snowflake.FieldLow, []uint64{1, 2, 1, 2, 1, 2}},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			a := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithSegmentOrder(tt.order))
+			b := snowflake.New(2, snowflake.WithClock(clock), snowflake.WithSegmentOrder(tt.order))
+			ids := append(a.AppendIDs(nil, 3), b.AppendIDs(nil, 3)...)
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+			for i, id := range ids {
+				if field := tt.order.Parse(id).MachineID; field != tt.want[i] {
+					t.Fatalf("expected fields %v got %d at %d", tt.want, field, i)
+				}
+			}
+			clock.Add(time.Millisecond)
+		})
+	}
+}
+
+func TestWithSegmentOrder_Rollover(t *testing.T) {
+	clock := &tickingClock{t: snowflake.Epoch().Add(time.Hour), step: 100 * time.Nanosecond}
+	sf := snowflake.New(9, snowflake.WithClock(clock), snowflake.WithSegmentOrder(snowflake.FieldLow))
+
+	ids := sf.AppendIDs(nil, 20000)
+	for i, id := range ids {
+		if i > 0 && id <= ids[i-1] {
+			t.Fatalf("expected increasing IDs got %d after %d", id, ids[i-1])
+		}
+		if field := snowflake.FieldLow.Parse(id).MachineID; field != 9 {
+			t.Fatalf("expected field 9 got %d", field)
+		}
+	}
+	if last := sf.Stats().LastID; last != ids[len(ids)-1] {
+		t.Errorf("expected the last ID %d got %d", ids[len(ids)-1], last)
+	}
+}
+
+func TestWithSegmentOrder_Block(t *testing.T) {
+	issuer := snowflake.NewBlockIssuer(3, snowflake.WithSegmentOrder(snowflake.FieldLow))
+	if b := issuer.Issue(100); b.Count != 1 {
+		t.Errorf("expected a block of 1 ID got %d", b.Count)
+	}
+}
+
+func TestLayout_DecomposeOrder(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(42, snowflake.WithClock(clock), snowflake.WithSegmentOrder(snowflake.FieldLow))
+	sf.NextID()
+
+	l := snowflake.DefaultLayout()
+	l.Order = snowflake.FieldLow
+	d, ok := l.Decompose(sf.NextID())
+	if !ok || d.Field != 42 || d.Sequence != 1 || !d.Timestamp.Equal(clock.Now()) {
+		t.Errorf("expected field 42 and sequence 1 at %v got %+v", clock.Now(), d)
+	}
+}
+
+func TestWithSegmentOrder_Panics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected an unknown order to panic")
+		}
+	}()
+	snowflake.WithSegmentOrder(2)
+}
@@ -0,0 +1,17 @@
+module github.com/HotPotatoC/snowflake/pgxsnowflake
+
+go 1.21
+
+require (
+	github.com/HotPotatoC/snowflake v0.0.0-00010101000000-000000000000
+	github.com/jackc/pgx/v5 v5.7.1
+)
+
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20240606120523-5a60cdf6a761 // indirect
+	golang.org/x/crypto v0.27.0 // indirect
+	golang.org/x/text v0.18.0 // indirect
+)
+
+replace github.com/HotPotatoC/snowflake => ../
@@ -0,0 +1,141 @@
+// Package pgxsnowflake maps snowflake IDs to PostgreSQL bigint columns
+// for pgx v5, in both the text and binary protocols.
+//
+//	conn, err := pgx.Connect(ctx, os.Getenv("DATABASE_URL"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	pgxsnowflake.Register(conn)
+//
+//	var ids []pgxsnowflake.ID
+//	err = conn.QueryRow(ctx, "SELECT array_agg(id) FROM orders").Scan(&ids)
+//
+// With pgxpool, register the types on every connection:
+//
+//	config.AfterConnect = pgxsnowflake.AfterConnect
+package pgxsnowflake
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ID is a snowflake ID stored as a bigint. It can also be scanned from
+// and encoded to text columns holding the decimal representation.
+type ID uint64
+
+// NullID is an ID that may be NULL.
+type NullID struct {
+	ID    ID
+	Valid bool
+}
+
+var (
+	_ pgtype.Int64Scanner = (*ID)(nil)
+	_ pgtype.Int64Valuer  = ID(0)
+	_ pgtype.TextScanner  = (*ID)(nil)
+	_ pgtype.TextValuer   = ID(0)
+
+	_ pgtype.Int64Scanner = (*NullID)(nil)
+	_ pgtype.Int64Valuer  = NullID{}
+	_ pgtype.TextScanner  = (*NullID)(nil)
+	_ pgtype.TextValuer   = NullID{}
+)
+
+// Register makes ID, NullID and their slices the default Go types of
+// bigint and bigint[] parameters on conn.
+func Register(conn *pgx.Conn) { RegisterTypes(conn.TypeMap()) }
+
+// RegisterTypes is like Register but for a type map.
+func RegisterTypes(m *pgtype.Map) {
+	m.RegisterDefaultPgType(ID(0), "int8")
+	m.RegisterDefaultPgType(NullID{}, "int8")
+	m.RegisterDefaultPgType([]ID(nil), "_int8")
+	m.RegisterDefaultPgType([]NullID(nil), "_int8")
+}
+
+// AfterConnect calls Register, for pgxpool.Config.AfterConnect.
+func AfterConnect(ctx context.Context, conn *pgx.Conn) error {
+	Register(conn)
+	return nil
+}
+
+// ScanInt64 implements pgtype.Int64Scanner.
+func (id *ID) ScanInt64(v pgtype.Int8) error {
+	if !v.Valid {
+		return fmt.Errorf("pgxsnowflake: cannot scan NULL into ID")
+	}
+	if v.Int64 < 0 {
+		return fmt.Errorf("pgxsnowflake: cannot scan negative %d into ID", v.Int64)
+	}
+	*id = ID(v.Int64)
+	return nil
+}
+
+// Int64Value implements pgtype.Int64Valuer. Snowflake IDs fit in 63 bits,
+// so values beyond math.MaxInt64 are rejected rather than stored negative.
+func (id ID) Int64Value() (pgtype.Int8, error) {
+	if uint64(id) > math.MaxInt64 {
+		return pgtype.Int8{}, fmt.Errorf("pgxsnowflake: ID %d overflows bigint", uint64(id))
+	}
+	return pgtype.Int8{Int64: int64(id), Valid: true}, nil
+}
+
+// ScanText implements pgtype.TextScanner.
+func (id *ID) ScanText(v pgtype.Text) error {
+	if !v.Valid {
+		return fmt.Errorf("pgxsnowflake: cannot scan NULL into ID")
+	}
+	n, err := strconv.ParseUint(v.String, 10, 64)
+	if err != nil {
+		return fmt.Errorf("pgxsnowflake: cannot scan %q into ID", v.String)
+	}
+	*id = ID(n)
+	return nil
+}
+
+// TextValue implements pgtype.TextValuer.
+func (id ID) TextValue() (pgtype.Text, error) {
+	return pgtype.Text{String: strconv.FormatUint(uint64(id), 10), Valid: true}, nil
+}
+
+// ScanInt64 implements pgtype.Int64Scanner.
+func (n *NullID) ScanInt64(v pgtype.Int8) error {
+	if !v.Valid {
+		*n = NullID{}
+		return nil
+	}
+	n.Valid = true
+	return n.ID.ScanInt64(v)
+}
+
+// Int64Value implements pgtype.Int64Valuer.
+func (n NullID) Int64Value() (pgtype.Int8, error) {
+	if !n.Valid {
+		return pgtype.Int8{}, nil
+	}
+	return n.ID.Int64Value()
+}
+
+// ScanText implements pgtype.TextScanner.
+func (n *NullID) ScanText(v pgtype.Text) error {
+	if !v.Valid {
+		*n = NullID{}
+		return nil
+	}
+	n.Valid = true
+	return n.ID.ScanText(v)
+}
+
+// TextValue implements pgtype.TextValuer.
+func (n NullID) TextValue() (pgtype.Text, error) {
+	if !n.Valid {
+		return pgtype.Text{}, nil
+	}
+	return n.ID.TextValue()
+}
@@ -0,0 +1,133 @@
+package pgxsnowflake_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/pgxsnowflake"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var formats = []struct {
+	name string
+	code int16
+}{
+	{name: "text", code: pgtype.TextFormatCode},
+	{name: "binary", code: pgtype.BinaryFormatCode},
+}
+
+func newMap() *pgtype.Map {
+	m := pgtype.NewMap()
+	pgxsnowflake.RegisterTypes(m)
+	return m
+}
+
+// roundTrip encodes src as oid in format and scans it back into dst.
+func roundTrip(t *testing.T, m *pgtype.Map, oid uint32, format int16, src, dst interface{}) {
+	t.Helper()
+
+	buf, err := m.Encode(oid, format, src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Scan(oid, format, buf, dst); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestID(t *testing.T) {
+	m := newMap()
+	id := pgxsnowflake.ID(snowflake.New(1).NextID())
+
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			for _, oid := range []uint32{pgtype.Int8OID, pgtype.TextOID} {
+				var got pgxsnowflake.ID
+				roundTrip(t, m, oid, f.code, id, &got)
+				if got != id {
+					t.Errorf("expected %d got %d (oid %d)", id, got, oid)
+				}
+			}
+		})
+	}
+}
+
+func TestID_Array(t *testing.T) {
+	m := newMap()
+	sf := snowflake.New(1)
+	ids := []pgxsnowflake.ID{pgxsnowflake.ID(sf.NextID()), pgxsnowflake.ID(sf.NextID()), pgxsnowflake.ID(sf.NextID())}
+
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			var got []pgxsnowflake.ID
+			roundTrip(t, m, pgtype.Int8ArrayOID, f.code, ids, &got)
+			if !reflect.DeepEqual(got, ids) {
+				t.Errorf("expected %v got %v", ids, got)
+			}
+
+			// int8 arrays written by other code scan into IDs too
+			var fromInts []pgxsnowflake.ID
+			roundTrip(t, m, pgtype.Int8ArrayOID, f.code, []int64{int64(ids[0]), int64(ids[1])}, &fromInts)
+			if !reflect.DeepEqual(fromInts, ids[:2]) {
+				t.Errorf("expected %v got %v", ids[:2], fromInts)
+			}
+		})
+	}
+}
+
+func TestNullID(t *testing.T) {
+	m := newMap()
+	id := pgxsnowflake.ID(snowflake.New(1).NextID())
+
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			for _, src := range []pgxsnowflake.NullID{{ID: id, Valid: true}, {}} {
+				got := pgxsnowflake.NullID{ID: 1, Valid: true}
+				roundTrip(t, m, pgtype.Int8OID, f.code, src, &got)
+				if got != src {
+					t.Errorf("expected %+v got %+v", src, got)
+				}
+			}
+
+			src := []pgxsnowflake.NullID{{ID: id, Valid: true}, {}}
+			var got []pgxsnowflake.NullID
+			roundTrip(t, m, pgtype.Int8ArrayOID, f.code, src, &got)
+			if !reflect.DeepEqual(got, src) {
+				t.Errorf("expected %v got %v", src, got)
+			}
+		})
+	}
+}
+
+func TestID_Errors(t *testing.T) {
+	m := newMap()
+
+	tests := []struct {
+		name string
+		oid  uint32
+		src  interface{}
+	}{
+		{name: "Should return an error for NULL", oid: pgtype.Int8OID, src: nil},
+		{name: "Should return an error for negative values", oid: pgtype.Int8OID, src: int64(-1)},
+		{name: "Should return an error for non-numeric text", oid: pgtype.TextOID, src: "abc"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buf, err := m.Encode(tc.oid, pgtype.BinaryFormatCode, tc.src, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var id pgxsnowflake.ID
+			if err := m.Scan(tc.oid, pgtype.BinaryFormatCode, buf, &id); err == nil {
+				t.Errorf("expected an error got ID %d", id)
+			}
+		})
+	}
+
+	if _, err := m.Encode(pgtype.Int8OID, pgtype.BinaryFormatCode, pgxsnowflake.ID(1<<63), nil); err == nil {
+		t.Error("expected an error encoding an ID beyond bigint")
+	}
+}
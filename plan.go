@@ -0,0 +1,106 @@
+package snowflake
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// PlanInput describes a deployment for Plan. The layout defaults to that
+// of this package: 41 timestamp, 10 field and 12 sequence bits.
+type PlanInput struct {
+	// PeakRate is the expected peak number of IDs per second, across all
+	// generators.
+	PeakRate float64
+	// Generators is the number of generators sharing the load evenly.
+	// Defaults to 1.
+	Generators int
+
+	// TimestampBits is the number of bits of the timestamp.
+	TimestampBits int
+	// FieldBits is the number of bits of the field.
+	FieldBits int
+	// SequenceBits is the number of bits of the sequence.
+	SequenceBits int
+	// Epoch is the epoch of the timestamps. Defaults to the current epoch.
+	Epoch time.Time
+}
+
+// PlanReport is the capacity plan returned by Plan.
+type PlanReport struct {
+	// PerGenerator is the peak number of IDs per second of each generator.
+	PerGenerator float64
+	// Capacity is the number of IDs per second a generator can issue
+	// before its sequence runs out within a millisecond.
+	Capacity float64
+	// Headroom is the fraction of Capacity left at peak, negative when a
+	// generator can't keep up.
+	Headroom float64
+	// Fits reports whether each generator keeps up at peak, there are
+	// enough fields for the generators and the layout fits 63 bits.
+	Fits bool
+
+	// MinSequenceBits is the least number of sequence bits for each
+	// generator to keep up at peak.
+	MinSequenceBits int
+	// MinGenerators is the least number of generators to keep up at peak
+	// with the sequence bits of the layout.
+	MinGenerators int
+	// MaxGenerators is the number of distinct fields of the layout.
+	MaxGenerators int
+
+	// Exhaustion is when the timestamps of the layout overflow.
+	Exhaustion time.Time
+	// Problems explains why the plan doesn't fit, if it doesn't.
+	Problems []string
+}
+
+// Plan works out whether a deployment can issue IDs at its peak rate and
+// until when the layout lasts, suggesting the sequence bits or generators
+// it takes when it can't keep up.
+func Plan(in PlanInput) PlanReport {
+	if in.Generators < 1 {
+		in.Generators = 1
+	}
+	if in.TimestampBits == 0 && in.FieldBits == 0 && in.SequenceBits == 0 {
+		in.TimestampBits, in.FieldBits, in.SequenceBits = timestampBits, fieldBits, sequenceBits
+	}
+	if in.Epoch.IsZero() {
+		in.Epoch = Epoch()
+	}
+
+	r := PlanReport{
+		PerGenerator: in.PeakRate / float64(in.Generators),
+		Capacity:     math.Ldexp(1000, in.SequenceBits),
+		Fits:         true,
+	}
+	r.Headroom = 1 - r.PerGenerator/r.Capacity
+	r.MinGenerators = int(math.Ceil(in.PeakRate / r.Capacity))
+	for r.MinSequenceBits < 63 && math.Ldexp(1000, r.MinSequenceBits) < r.PerGenerator {
+		r.MinSequenceBits++
+	}
+
+	problem := func(format string, args ...interface{}) {
+		r.Fits = false
+		r.Problems = append(r.Problems, fmt.Sprintf(format, args...))
+	}
+
+	if bits := in.TimestampBits + in.FieldBits + in.SequenceBits; bits > 63 || in.TimestampBits < 1 || in.FieldBits < 0 || in.SequenceBits < 0 {
+		problem("layout of %d/%d/%d bits doesn't fit 63 bits", in.TimestampBits, in.FieldBits, in.SequenceBits)
+		return r
+	}
+	r.MaxGenerators = 1 << in.FieldBits
+	r.Exhaustion = time.UnixMilli(in.Epoch.UnixMilli() + 1<<in.TimestampBits).UTC()
+
+	if r.PerGenerator > r.Capacity {
+		problem("%.0f IDs/s per generator exceed the capacity of %.0f IDs/s: use %d sequence bits or %d generators",
+			r.PerGenerator, r.Capacity, r.MinSequenceBits, r.MinGenerators)
+	}
+	if in.Generators > r.MaxGenerators {
+		problem("%d generators exceed the %d fields of %d bits", in.Generators, r.MaxGenerators, in.FieldBits)
+	}
+	if r.MinGenerators > r.MaxGenerators {
+		problem("%d generators are needed at peak but there are only %d fields", r.MinGenerators, r.MaxGenerators)
+	}
+	return r
+}
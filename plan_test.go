@@ -0,0 +1,103 @@
+package snowflake_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestPlan(t *testing.T) {
+	epoch2024 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tc := []struct {
+		name            string
+		in              snowflake.PlanInput
+		fits            bool
+		headroom        float64
+		minSequenceBits int
+		minGenerators   int
+		exhaustion      time.Time
+		problem         string
+	}{
+		{
+			name:            "Should fit 1M IDs/s on one generator",
+			in:              snowflake.PlanInput{PeakRate: 1e6},
+			fits:            true,
+			headroom:        0.755859375,
+			minSequenceBits: 10,
+			minGenerators:   1,
+			exhaustion:      snowflake.Epoch().Add((1 << 41) * time.Millisecond),
+		},
+		{
+			name:            "Should not fit 10M IDs/s on one generator",
+			in:              snowflake.PlanInput{PeakRate: 1e7},
+			headroom:        1 - 1e7/4096000,
+			minSequenceBits: 14,
+			minGenerators:   3,
+			exhaustion:      snowflake.Epoch().Add((1 << 41) * time.Millisecond),
+			problem:         "use 14 sequence bits or 3 generators",
+		},
+		{
+			name:            "Should fit 10M IDs/s on 4 generators",
+			in:              snowflake.PlanInput{PeakRate: 1e7, Generators: 4},
+			fits:            true,
+			headroom:        1 - 2.5e6/4096000,
+			minSequenceBits: 12,
+			minGenerators:   3,
+			exhaustion:      snowflake.Epoch().Add((1 << 41) * time.Millisecond),
+		},
+		{
+			name:            "Should not fit more generators than fields",
+			in:              snowflake.PlanInput{PeakRate: 1e6, Generators: 2000},
+			headroom:        1 - 500.0/4096000,
+			minSequenceBits: 0,
+			minGenerators:   1,
+			exhaustion:      snowflake.Epoch().Add((1 << 41) * time.Millisecond),
+			problem:         "2000 generators exceed the 1024 fields",
+		},
+		{
+			name:            "Should plan a custom layout",
+			in:              snowflake.PlanInput{PeakRate: 3e7, Generators: 256, TimestampBits: 39, FieldBits: 8, SequenceBits: 16, Epoch: epoch2024},
+			fits:            true,
+			headroom:        1 - (3e7/256)/65536000,
+			minSequenceBits: 7,
+			minGenerators:   1,
+			exhaustion:      epoch2024.Add((1 << 39) * time.Millisecond),
+		},
+		{
+			name:            "Should not fit more bits than 63",
+			in:              snowflake.PlanInput{PeakRate: 1, TimestampBits: 42, FieldBits: 10, SequenceBits: 12},
+			headroom:        1 - 1.0/4096000,
+			minSequenceBits: 0,
+			minGenerators:   1,
+			problem:         "doesn't fit 63 bits",
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			r := snowflake.Plan(tt.in)
+
+			if r.Fits != tt.fits {
+				t.Errorf("expected fits %v got %v (%v)", tt.fits, r.Fits, r.Problems)
+			}
+			if r.Headroom != tt.headroom {
+				t.Errorf("expected headroom %v got %v", tt.headroom, r.Headroom)
+			}
+			if r.MinSequenceBits != tt.minSequenceBits || r.MinGenerators != tt.minGenerators {
+				t.Errorf("expected %d sequence bits or %d generators got %d and %d", tt.minSequenceBits, tt.minGenerators, r.MinSequenceBits, r.MinGenerators)
+			}
+			if !r.Exhaustion.Equal(tt.exhaustion) {
+				t.Errorf("expected exhaustion %v got %v", tt.exhaustion, r.Exhaustion)
+			}
+			if tt.problem != "" && (len(r.Problems) == 0 || !strings.Contains(strings.Join(r.Problems, "; "), tt.problem)) {
+				t.Errorf("expected problem %q got %v", tt.problem, r.Problems)
+			}
+			if tt.fits && len(r.Problems) != 0 {
+				t.Errorf("expected no problems got %v", r.Problems)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package snowflake
+
+import "sync"
+
+// Pool holds one generator per field for multi-tenant services, where each
+// tenant's IDs carry the tenant's own field. Generators are created on
+// first use and cached; memory is bounded by the field set given to
+// NewPool.
+type Pool struct {
+	opts    []Option
+	entries map[uint64]*poolEntry // read-only after NewPool
+}
+
+// poolEntry lazily creates the generator of one field. (internal-use only)
+type poolEntry struct {
+	once sync.Once
+	id   *ID
+}
+
+// NewPool returns a new snowflake.Pool serving the given fields.
+// Fields must be distinct and within range (max field value: 1023).
+// The options are applied to every generator.
+// Can return 3 errors: ErrNoFields, ErrFieldOutOfRange and ErrDuplicateField.
+func NewPool(fields []uint64, opts ...Option) (*Pool, error) {
+	if len(fields) == 0 {
+		return nil, ErrNoFields
+	}
+
+	entries := make(map[uint64]*poolEntry, len(fields))
+	for _, field := range fields {
+		if field > maxFieldBits {
+			return nil, ErrFieldOutOfRange
+		}
+
+		if _, ok := entries[field]; ok {
+			return nil, ErrDuplicateField
+		}
+		entries[field] = &poolEntry{}
+	}
+
+	return &Pool{opts: opts, entries: entries}, nil
+}
+
+// Get returns the generator of field, creating it on first use. Every call
+// for the same field returns the same generator.
+// Returns ErrUnknownField if field isn't in the pool's set.
+func (p *Pool) Get(field uint64) (Generator, error) {
+	e, ok := p.entries[field]
+	if !ok {
+		return nil, ErrUnknownField
+	}
+
+	e.once.Do(func() { e.id = New(field, p.opts...) })
+	return e.id, nil
+}
+
+// NextIDFor returns a new snowflake ID from the generator of field.
+// Returns ErrUnknownField if field isn't in the pool's set.
+func (p *Pool) NextIDFor(field uint64) (uint64, error) {
+	g, err := p.Get(field)
+	if err != nil {
+		return 0, err
+	}
+	return g.NextID(), nil
+}
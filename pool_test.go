@@ -0,0 +1,104 @@
+package snowflake_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestNewPool(t *testing.T) {
+	tc := []struct {
+		name   string
+		fields []uint64
+		err    error
+	}{
+		{"Should return ErrNoFields", nil, snowflake.ErrNoFields},
+		{"Should return ErrFieldOutOfRange", []uint64{1, 1024}, snowflake.ErrFieldOutOfRange},
+		{"Should return ErrDuplicateField", []uint64{1, 2, 1}, snowflake.ErrDuplicateField},
+		{"1,2,3", []uint64{1, 2, 3}, nil},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := snowflake.NewPool(tt.fields)
+			if err != tt.err {
+				t.Errorf("expected error %v got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestPool_Get(t *testing.T) {
+	pool, err := snowflake.NewPool([]uint64{5, 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := pool.Get(9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field := snowflake.Parse(g.NextID()).MachineID; field != 9 {
+		t.Errorf("expected field %d got %d", 9, field)
+	}
+
+	again, _ := pool.Get(9)
+	if again != g {
+		t.Error("expected the same generator for the same field")
+	}
+
+	if _, err := pool.Get(7); err != snowflake.ErrUnknownField {
+		t.Errorf("expected error %v got %v", snowflake.ErrUnknownField, err)
+	}
+	if _, err := pool.NextIDFor(7); err != snowflake.ErrUnknownField {
+		t.Errorf("expected error %v got %v", snowflake.ErrUnknownField, err)
+	}
+}
+
+func TestPool_Concurrent(t *testing.T) {
+	tenants := make([]uint64, 256)
+	for i := range tenants {
+		tenants[i] = uint64(i * 4)
+	}
+
+	pool, err := snowflake.NewPool(tenants)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	perWorker := 2000
+	workers := 32
+	ch := make(chan uint64, perWorker*workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				tenant := tenants[(w*perWorker+i)%len(tenants)]
+				id, err := pool.NextIDFor(tenant)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if field := snowflake.Parse(id).MachineID; field != tenant {
+					t.Errorf("expected field %d got %d", tenant, field)
+					return
+				}
+				ch <- id
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(ch)
+
+	ids := make(map[uint64]bool, perWorker*workers)
+	for id := range ch {
+		if ids[id] {
+			t.Fatalf("duplicate ID %d", id)
+		}
+		ids[id] = true
+	}
+}
@@ -0,0 +1,53 @@
+package snowflake
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+)
+
+// RandomMachineID returns a machine ID drawn uniformly from crypto/rand.
+//
+// Without coordination random machine IDs collide like any other 1024-slot
+// hash. With n processes the odds that at least two share a machine ID are
+// about 1 - e^(-n(n-1)/2048):
+//
+//	processes   collision odds
+//	        2   0.1%
+//	       10   4.3%
+//	       38   50%
+//	      100   99.2%
+//
+// Use NewWithRandomField with a verifier when that risk isn't acceptable.
+func RandomMachineID() uint64 {
+	var b [2]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("snowflake: reading crypto/rand: " + err.Error())
+	}
+	return uint64(binary.BigEndian.Uint16(b[:])) & maxFieldBits
+}
+
+// NewWithRandomField returns a new snowflake ID generator with a random
+// field (see RandomMachineID) that verify accepted. verify is where the
+// uniqueness check goes, e.g. a SETNX in Redis; it reports whether the
+// field is free to use. A rejected field is retried with a fresh random one
+// up to retries times, after which ErrNoUniqueField is returned.
+// An error from verify is returned as is. A nil verify accepts any field.
+func NewWithRandomField(verify func(field uint64) (bool, error), retries int, opts ...Option) (*ID, error) {
+	for attempt := 0; attempt <= retries; attempt++ {
+		field := RandomMachineID()
+		if verify == nil {
+			return New(field, opts...), nil
+		}
+
+		ok, err := verify(field)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return New(field, opts...), nil
+		}
+	}
+
+	return nil, fmt.Errorf("snowflake: %d random fields rejected: %w", retries+1, ErrNoUniqueField)
+}
@@ -0,0 +1,90 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestRandomMachineID(t *testing.T) {
+	seen := make(map[uint64]bool)
+	for i := 0; i < 10240; i++ {
+		field := snowflake.RandomMachineID()
+		if field > 1023 {
+			t.Fatalf("expected machine ID within range got %d", field)
+		}
+		seen[field] = true
+	}
+
+	if len(seen) < 1000 {
+		t.Errorf("expected random machine IDs to cover most of the 1024 values, covered %d", len(seen))
+	}
+}
+
+// rejectFirst returns a verifier rejecting the first n fields it is asked
+// about, recording every field.
+func rejectFirst(n int, asked *[]uint64) func(uint64) (bool, error) {
+	return func(field uint64) (bool, error) {
+		*asked = append(*asked, field)
+		return len(*asked) > n, nil
+	}
+}
+
+func TestNewWithRandomField(t *testing.T) {
+	var asked []uint64
+	sf, err := snowflake.NewWithRandomField(rejectFirst(3, &asked), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(asked) != 4 {
+		t.Fatalf("expected 4 fields to be verified got %d", len(asked))
+	}
+
+	accepted := asked[len(asked)-1]
+	if field := snowflake.Parse(sf.NextID()).MachineID; field != accepted {
+		t.Errorf("expected field %d got %d", accepted, field)
+	}
+}
+
+func TestNewWithRandomField_Errors(t *testing.T) {
+	verifyErr := errors.New("redis unavailable")
+
+	tests := []struct {
+		name   string
+		verify func(uint64) (bool, error)
+		asks   int
+		err    error
+	}{
+		{
+			name:   "Should return ErrNoUniqueField",
+			verify: func(uint64) (bool, error) { return false, nil },
+			asks:   4,
+			err:    snowflake.ErrNoUniqueField,
+		},
+		{
+			name:   "Should return the verifier's error",
+			verify: func(uint64) (bool, error) { return false, verifyErr },
+			asks:   1,
+			err:    verifyErr,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			asks := 0
+			_, err := snowflake.NewWithRandomField(func(field uint64) (bool, error) {
+				asks++
+				return tc.verify(field)
+			}, 3)
+
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected error %v got %v", tc.err, err)
+			}
+			if asks != tc.asks {
+				t.Errorf("expected %d fields to be verified got %d", tc.asks, asks)
+			}
+		})
+	}
+}
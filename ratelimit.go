@@ -0,0 +1,58 @@
+package snowflake
+
+import "time"
+
+// rateLimiter is a token bucket. It is not safe for concurrent use on its
+// own, generators only touch it while holding their lock. (internal-use only)
+type rateLimiter struct {
+	perNano float64 // tokens added per nanosecond
+	burst   float64
+	tokens  float64
+	last    time.Time
+}
+
+// newRateLimiter returns a full bucket refilling at perSecond tokens per
+// second. (internal-use only)
+func newRateLimiter(perSecond int) *rateLimiter {
+	return &rateLimiter{
+		perNano: float64(perSecond) / float64(time.Second),
+		burst:   float64(perSecond),
+		tokens:  float64(perSecond),
+	}
+}
+
+// refill adds the tokens earned since the last call. (internal-use only)
+func (r *rateLimiter) refill(now time.Time) {
+	if !r.last.IsZero() {
+		if elapsed := now.Sub(r.last); elapsed > 0 {
+			r.tokens += float64(elapsed) * r.perNano
+			if r.tokens > r.burst {
+				r.tokens = r.burst
+			}
+		}
+	}
+	if now.After(r.last) {
+		r.last = now
+	}
+}
+
+// allow takes a token if one is available. (internal-use only)
+func (r *rateLimiter) allow(now time.Time) bool {
+	r.refill(now)
+	if r.tokens < 1 {
+		return false
+	}
+	r.tokens--
+	return true
+}
+
+// reserveN takes n tokens, going into debt if needed, and returns how long
+// the caller must wait before the tokens are actually earned. (internal-use only)
+func (r *rateLimiter) reserveN(now time.Time, n int) time.Duration {
+	r.refill(now)
+	r.tokens -= float64(n)
+	if r.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-r.tokens / r.perNano)
+}
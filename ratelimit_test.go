@@ -0,0 +1,77 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestWithRateLimit_Burst(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithRateLimit(100))
+
+	for i := 0; i < 100; i++ {
+		if _, err := sf.TryNextID(); err != nil {
+			t.Fatalf("expected burst ID %d to pass got %v", i, err)
+		}
+	}
+
+	if _, err := sf.TryNextID(); err != snowflake.ErrRateLimited {
+		t.Errorf("expected error %v got %v", snowflake.ErrRateLimited, err)
+	}
+}
+
+func TestWithRateLimit_Sustained(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithRateLimit(1000))
+
+	// drain the initial burst
+	for {
+		if _, err := sf.TryNextID(); err != nil {
+			break
+		}
+	}
+
+	issued := 0
+	for step := 0; step < 1000; step++ { // one simulated second
+		clock.Add(time.Millisecond)
+		for {
+			if _, err := sf.TryNextID(); err != nil {
+				break
+			}
+			issued++
+		}
+	}
+
+	if issued < 999 || issued > 1001 {
+		t.Errorf("expected ~1000 IDs in a simulated second got %d", issued)
+	}
+}
+
+func TestWithRateLimit_NextIDWaits(t *testing.T) {
+	sf := snowflake.New(1, snowflake.WithRateLimit(200))
+
+	for i := 0; i < 200; i++ {
+		sf.NextID()
+	}
+
+	start := time.Now()
+	id := sf.NextID()
+	if elapsed := time.Since(start); elapsed < 2*time.Millisecond {
+		t.Errorf("expected NextID to wait for a token, returned after %s", elapsed)
+	}
+	if id == 0 {
+		t.Error("expected a non-zero ID")
+	}
+}
+
+func TestWithRateLimit_Disabled(t *testing.T) {
+	sf := snowflake.New(1, snowflake.WithRateLimit(0))
+
+	for i := 0; i < 10000; i++ {
+		if _, err := sf.TryNextID(); err != nil {
+			t.Fatalf("expected no rate limit got %v", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package snowflake
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// LineError is a line ParseReader failed to parse.
+type LineError struct {
+	// Line is the line number, starting at 1.
+	Line int
+	// Err is why it failed, as returned by ParseString.
+	Err error
+}
+
+func (e *LineError) Error() string { return fmt.Sprintf("line %d: %v", e.Line, e.Err) }
+
+// Unwrap returns e.Err.
+func (e *LineError) Unwrap() error { return e.Err }
+
+// ParseReader parses the newline-delimited decimal snowflake IDs read from
+// r, calling fn with each of them in order. Blank lines are skipped and
+// "\r\n" line endings are accepted, but IDs are otherwise validated like
+// ParseString. Lines can be of any length: a line too long to be an ID is
+// reported without being buffered whole.
+//
+// Stops at the first error: a line that isn't an ID is reported as a
+// *LineError wrapping ErrInvalidID, and errors of r and fn are returned,
+// the former wrapped and the latter as is.
+func ParseReader(r io.Reader, fn func(SID) error) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, err := br.ReadSlice('\n')
+		tooLong := err == bufio.ErrBufferFull
+		for err == bufio.ErrBufferFull {
+			_, err = br.ReadSlice('\n')
+		}
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("snowflake: reading line %d: %w", line, err)
+		}
+		if len(b) == 0 && err == io.EOF {
+			return nil
+		}
+
+		switch b = bytes.TrimSuffix(bytes.TrimSuffix(b, []byte("\n")), []byte("\r")); {
+		case tooLong:
+			return &LineError{Line: line, Err: fmt.Errorf("snowflake: line is too long: %w", ErrInvalidID)}
+		case len(bytes.TrimSpace(b)) == 0:
+		default:
+			sid, perr := ParseString(string(b))
+			if perr != nil {
+				return &LineError{Line: line, Err: perr}
+			}
+			if ferr := fn(sid); ferr != nil {
+				return ferr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
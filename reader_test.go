@@ -0,0 +1,149 @@
+package snowflake_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// collect parses s with ParseReader, returning the IDs it got.
+func collect(s string) ([]snowflake.SID, error) {
+	var sids []snowflake.SID
+	err := snowflake.ParseReader(strings.NewReader(s), func(sid snowflake.SID) error {
+		sids = append(sids, sid)
+		return nil
+	})
+	return sids, err
+}
+
+func TestParseReader(t *testing.T) {
+	ids := snowflake.New(5).AppendIDs(nil, 3)
+	a, b, c := strconv.FormatUint(ids[0], 10), strconv.FormatUint(ids[1], 10), strconv.FormatUint(ids[2], 10)
+
+	tc := []struct {
+		name  string
+		input string
+	}{
+		{"Should parse a trailing newline", a + "\n" + b + "\n" + c + "\n"},
+		{"Should parse without a trailing newline", a + "\n" + b + "\n" + c},
+		{"Should skip blank lines", "\n" + a + "\n\n  \n" + b + "\n\t\n" + c + "\n\n"},
+		{"Should parse CRLF line endings", a + "\r\n" + b + "\r\n\r\n" + c},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sids, err := collect(tt.input)
+			if err != nil {
+				t.Fatalf("expected no error got %v", err)
+			}
+			if len(sids) != len(ids) {
+				t.Fatalf("expected %d IDs got %d", len(ids), len(sids))
+			}
+			for i, id := range ids {
+				if sids[i] != snowflake.Parse(id) {
+					t.Errorf("expected %+v got %+v", snowflake.Parse(id), sids[i])
+				}
+			}
+		})
+	}
+
+	if sids, err := collect(""); err != nil || len(sids) != 0 {
+		t.Errorf("expected no IDs got %d (%v)", len(sids), err)
+	}
+}
+
+func TestParseReader_Invalid(t *testing.T) {
+	id := strconv.FormatUint(snowflake.New(5).NextID(), 10)
+
+	tc := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{"Should report a malformed line", id + "\n" + id + "x\n" + id, 2},
+		{"Should report surrounding spaces", id + "\n\n " + id + "\n", 3},
+		{"Should report leading zeros", "0" + id, 1},
+		{"Should report a malformed last line", id + "\n" + id + "\nabc", 3},
+		{"Should report a very long line", id + "\n" + strings.Repeat("1", 1<<20) + "\n" + id, 2},
+		{"Should report a very long last line", id + "\n" + strings.Repeat("1", 1<<20), 2},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := collect(tt.input)
+
+			var lerr *snowflake.LineError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("expected a LineError got %v", err)
+			}
+			if lerr.Line != tt.line {
+				t.Errorf("expected line %d got %d", tt.line, lerr.Line)
+			}
+			if !errors.Is(err, snowflake.ErrInvalidID) {
+				t.Errorf("expected error %v got %v", snowflake.ErrInvalidID, err)
+			}
+			if len(err.Error()) > 200 {
+				t.Errorf("expected a short error got %d bytes", len(err.Error()))
+			}
+		})
+	}
+}
+
+func TestParseReader_Abort(t *testing.T) {
+	errStop := errors.New("stop")
+	input := strings.Repeat(strconv.FormatUint(snowflake.New(5).NextID(), 10)+"\n", 10)
+
+	calls := 0
+	err := snowflake.ParseReader(strings.NewReader(input), func(snowflake.SID) error {
+		if calls++; calls == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("expected error %v got %v", errStop, err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls got %d", calls)
+	}
+
+	errRead := errors.New("read failed")
+	err = snowflake.ParseReader(iotest.DataErrReader(iotest.ErrReader(errRead)), func(snowflake.SID) error { return nil })
+	if !errors.Is(err, errRead) {
+		t.Errorf("expected error %v got %v", errRead, err)
+	}
+}
+
+func TestParseReader_Gzip(t *testing.T) {
+	ids := snowflake.New(5).AppendIDs(nil, 10000)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for _, id := range ids {
+		zw.Write(strconv.AppendUint(nil, id, 10))
+		zw.Write([]byte{'\n'})
+	}
+	zw.Close()
+
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	err = snowflake.ParseReader(zr, func(sid snowflake.SID) error {
+		if sid != snowflake.Parse(ids[n]) {
+			t.Errorf("expected %+v got %+v", snowflake.Parse(ids[n]), sid)
+		}
+		n++
+		return nil
+	})
+	if err != nil || n != len(ids) {
+		t.Errorf("expected %d IDs got %d (%v)", len(ids), n, err)
+	}
+}
@@ -0,0 +1,98 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RebaseEpoch translates an ID issued under the epoch from into the ID of
+// the same instant, field and sequence under the epoch to, e.g. to migrate
+// stored IDs to a new epoch. Returns an error wrapping ErrBeforeEpoch if
+// the instant of id is before to, and ErrTimestampOverflow if it's too far
+// after to for 41 bits.
+func RebaseEpoch(id uint64, from, to time.Time) (uint64, error) {
+	return rebase(id, from.UnixMilli()-to.UnixMilli())
+}
+
+// OffsetByDuration returns the ID of the same field and sequence as id,
+// issued d later, or earlier if d is negative. d is truncated to the
+// millisecond. Returns an error wrapping ErrBeforeEpoch if the timestamp
+// would precede the epoch, and ErrTimestampOverflow if it would overflow
+// 41 bits.
+func OffsetByDuration(id uint64, d time.Duration) (uint64, error) {
+	return rebase(id, d.Milliseconds())
+}
+
+// rebase adds shift milliseconds to the timestamp of id. (internal-use only)
+func rebase(id uint64, shift int64) (uint64, error) {
+	timestamp := int64(id>>(sequenceBits+fieldBits)) + shift
+	switch {
+	case timestamp < 0:
+		return 0, fmt.Errorf("snowflake: ID %d shifted by %s is %s before the epoch: %w",
+			id, time.Duration(shift)*time.Millisecond, time.Duration(-timestamp)*time.Millisecond, ErrBeforeEpoch)
+	case timestamp > maxTimestamp:
+		return 0, fmt.Errorf("snowflake: ID %d shifted by %s overflows 41 bits: %w",
+			id, time.Duration(shift)*time.Millisecond, ErrTimestampOverflow)
+	}
+	return uint64(timestamp)<<(sequenceBits+fieldBits) | id&(1<<(sequenceBits+fieldBits)-1), nil
+}
+
+// RebaseError is an ID RebaseAll failed to translate.
+type RebaseError struct {
+	// Index is the index of the ID.
+	Index int
+	// ID is the ID.
+	ID uint64
+	// Err is why it failed, as returned by RebaseEpoch.
+	Err error
+}
+
+func (e *RebaseError) Error() string { return fmt.Sprintf("index %d: %v", e.Index, e.Err) }
+
+// Unwrap returns e.Err.
+func (e *RebaseError) Unwrap() error { return e.Err }
+
+// RebaseErrors lists the IDs RebaseAll failed to translate, by increasing
+// index. errors.Is reports whether any of them matches.
+type RebaseErrors []*RebaseError
+
+func (e RebaseErrors) Error() string {
+	if len(e) == 1 {
+		return "snowflake: 1 ID failed to rebase: " + e[0].Error()
+	}
+	return fmt.Sprintf("snowflake: %d IDs failed to rebase, first at %v", len(e), e[0])
+}
+
+// Is reports whether any of the failures matches target.
+func (e RebaseErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// RebaseAll is RebaseEpoch for a batch of IDs. It returns the translated
+// IDs in the order of ids, with 0 in place of those that failed, which are
+// reported in a RebaseErrors.
+func RebaseAll(ids []uint64, from, to time.Time) ([]uint64, error) {
+	shift := from.UnixMilli() - to.UnixMilli()
+
+	out := make([]uint64, len(ids))
+	var failures RebaseErrors
+	for i, id := range ids {
+		rebased, err := rebase(id, shift)
+		if err != nil {
+			failures = append(failures, &RebaseError{Index: i, ID: id, Err: err})
+			continue
+		}
+		out[i] = rebased
+	}
+
+	if failures != nil {
+		return out, failures
+	}
+	return out, nil
+}
@@ -0,0 +1,153 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+var (
+	epoch2012 = time.Date(2012, 3, 28, 0, 0, 0, 0, time.UTC)
+	epoch2020 = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// decode returns the instant, field and sequence of id under epoch.
+func decode(id uint64, epoch time.Time) (int64, uint64, uint64) {
+	return int64(id>>22) + epoch.UnixMilli(), id >> 12 & 0x3FF, id & 0xFFF
+}
+
+func TestRebaseEpoch(t *testing.T) {
+	// instants from 2020 until 2012 + 69 years, any field and sequence
+	lo := uint64(epoch2020.Sub(epoch2012).Milliseconds())
+	property := func(ms uint64, low uint32) bool {
+		id := (lo+ms%(1<<41-lo))<<22 | uint64(low)&(1<<22-1)
+
+		rebased, err := snowflake.RebaseEpoch(id, epoch2012, epoch2020)
+		if err != nil {
+			t.Logf("%d: %v", id, err)
+			return false
+		}
+
+		instant, field, seq := decode(id, epoch2012)
+		rInstant, rField, rSeq := decode(rebased, epoch2020)
+		return instant == rInstant && field == rField && seq == rSeq
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRebaseEpoch_Errors(t *testing.T) {
+	tc := []struct {
+		name     string
+		id       uint64
+		from, to time.Time
+		expected error
+	}{
+		{"Should return ErrBeforeEpoch", snowflakeAt(epoch2020.Add(-time.Millisecond), epoch2012), epoch2012, epoch2020, snowflake.ErrBeforeEpoch},
+		{"Should return ErrTimestampOverflow", snowflakeAt(epoch2012.Add((1<<41)*time.Millisecond), epoch2020), epoch2020, epoch2012, snowflake.ErrTimestampOverflow},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := snowflake.RebaseEpoch(tt.id, tt.from, tt.to); !errors.Is(err, tt.expected) {
+				t.Errorf("expected error %v got %v", tt.expected, err)
+			}
+		})
+	}
+
+	// the instant of the epoch itself is fine
+	if id, err := snowflake.RebaseEpoch(snowflakeAt(epoch2020, epoch2012)|42, epoch2012, epoch2020); err != nil || id != 42 {
+		t.Errorf("expected ID 42 got %d (%v)", id, err)
+	}
+}
+
+func TestRebaseAll(t *testing.T) {
+	ids := []uint64{
+		snowflakeAt(epoch2020.Add(time.Hour), epoch2012) | 1,
+		snowflakeAt(epoch2020.Add(-time.Hour), epoch2012) | 2,
+		snowflakeAt(epoch2020.Add(2*time.Hour), epoch2012) | 3,
+		snowflakeAt(epoch2020.Add(-2*time.Hour), epoch2012) | 4,
+	}
+
+	out, err := snowflake.RebaseAll(ids, epoch2012, epoch2020)
+
+	expected := []uint64{snowflakeAt(epoch2020.Add(time.Hour), epoch2020) | 1, 0, snowflakeAt(epoch2020.Add(2*time.Hour), epoch2020) | 3, 0}
+	for i := range expected {
+		if out[i] != expected[i] {
+			t.Errorf("expected ID %d at %d got %d", expected[i], i, out[i])
+		}
+	}
+
+	var failures snowflake.RebaseErrors
+	if !errors.As(err, &failures) {
+		t.Fatalf("expected RebaseErrors got %v", err)
+	}
+	if len(failures) != 2 || failures[0].Index != 1 || failures[1].Index != 3 || failures[1].ID != ids[3] {
+		t.Errorf("expected failures at 1 and 3 got %v", failures)
+	}
+	if !errors.Is(err, snowflake.ErrBeforeEpoch) {
+		t.Errorf("expected error %v got %v", snowflake.ErrBeforeEpoch, err)
+	}
+
+	if _, err := snowflake.RebaseAll(ids[:1], epoch2012, epoch2020); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+}
+
+// snowflakeAt returns the ID of field 0 and sequence 0 at t under epoch.
+func snowflakeAt(t, epoch time.Time) uint64 {
+	return uint64(t.Sub(epoch).Milliseconds()) << 22
+}
+
+func TestOffsetByDuration(t *testing.T) {
+	// fields below 512, some IDs of field 1023 parse as random fallbacks
+	property := func(ms uint64, low uint32, offset int64) bool {
+		id := ms%(1<<41)<<22 | uint64(low)&(1<<21-1)
+		d := time.Duration(offset % int64(100*365*24*time.Hour))
+
+		shifted, err := snowflake.OffsetByDuration(id, d)
+		timestamp := snowflake.Parse(id).Timestamp + d.Milliseconds()
+		if timestamp < snowflake.Epoch().UnixMilli() {
+			return errors.Is(err, snowflake.ErrBeforeEpoch)
+		}
+		if timestamp-snowflake.Epoch().UnixMilli() >= 1<<41 {
+			return errors.Is(err, snowflake.ErrTimestampOverflow)
+		}
+		if err != nil {
+			t.Logf("%d by %v: %v", id, d, err)
+			return false
+		}
+
+		sid, shiftedSID := snowflake.Parse(id), snowflake.Parse(shifted)
+		return shiftedSID.Timestamp == timestamp && shiftedSID.MachineID == sid.MachineID && shiftedSID.Sequence == sid.Sequence
+	}
+
+	if err := quick.Check(property, &quick.Config{MaxCount: 1000}); err != nil {
+		t.Error(err)
+	}
+
+	id := snowflake.New(3, snowflake.WithClock(newFakeClock(snowflake.Epoch().Add(time.Hour)))).NextID()
+	tc := []struct {
+		name     string
+		d        time.Duration
+		expected error
+	}{
+		{"Should shift to just after the epoch", -time.Hour + time.Millisecond, nil},
+		{"Should shift to the epoch", -time.Hour, nil},
+		{"Should return ErrBeforeEpoch", -time.Hour - time.Millisecond, snowflake.ErrBeforeEpoch},
+		{"Should return ErrTimestampOverflow", (1<<41)*time.Millisecond - time.Hour, snowflake.ErrTimestampOverflow},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := snowflake.OffsetByDuration(id, tt.d); !errors.Is(err, tt.expected) {
+				t.Errorf("expected error %v got %v", tt.expected, err)
+			}
+		})
+	}
+}
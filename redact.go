@@ -0,0 +1,97 @@
+package snowflake
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lowBits masks the field and sequence of an ID. (internal-use only)
+const lowBits = 1<<(fieldBits+sequenceBits) - 1
+
+// RedactPrecision returns id with its timestamp rounded down to a multiple
+// of granularity since the Unix epoch (but not before the epoch), and its
+// field and sequence zeroed, so that it no longer reveals when it was
+// issued more precisely than granularity. A granularity of 0 or less
+// keeps the timestamp.
+//
+// All the IDs of a bucket redact to the same ID; use a Redactor to keep
+// them unique.
+func RedactPrecision(id uint64, granularity time.Duration) uint64 {
+	return redactTimestamp(id, granularity) << (sequenceBits + fieldBits)
+}
+
+// redactTimestamp returns the timestamp of id rounded down to granularity.
+// (internal-use only)
+func redactTimestamp(id uint64, granularity time.Duration) uint64 {
+	elapsed := int64(id >> (sequenceBits + fieldBits))
+	g := granularity.Milliseconds()
+	if g <= 1 {
+		return uint64(elapsed)
+	}
+
+	epoch := atomic.LoadInt64(&epochMillis)
+	unix := elapsed + epoch
+	mod := unix % g
+	if mod < 0 {
+		mod += g
+	}
+	if start := unix - mod - epoch; start > 0 {
+		return uint64(start)
+	}
+	return 0
+}
+
+// Redactor is like RedactPrecision, but keeps the redacted IDs unique: in
+// place of the field and sequence, each ID of a bucket gets a distinct one
+// of the 2^22 values left, in an order scrambled with a random key rather
+// than a plain count of the IDs of the bucket. Redacted IDs can't be traced
+// back to the original IDs, even by the Redactor.
+//
+// Redacted IDs are unique among those of a Redactor, not across Redactors,
+// and redacting an ID twice gives two IDs. A Redactor remembers a counter
+// for every bucket it has seen.
+//
+// A Redactor is safe for concurrent use.
+type Redactor struct {
+	granularity time.Duration
+	mul, add    uint64
+
+	mtx  sync.Mutex
+	used map[uint64]uint64 // IDs issued per bucket timestamp
+}
+
+// NewRedactor returns a new snowflake.Redactor rounding timestamps down to
+// granularity.
+func NewRedactor(granularity time.Duration) *Redactor {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("snowflake: reading crypto/rand: " + err.Error())
+	}
+	return &Redactor{
+		granularity: granularity,
+		mul:         binary.BigEndian.Uint64(b[:8]) | 1, // odd, so that it's a bijection
+		add:         binary.BigEndian.Uint64(b[8:]),
+		used:        make(map[uint64]uint64),
+	}
+}
+
+// Redact returns a redacted ID for id. Returns an error wrapping
+// ErrBucketFull once 2^22 IDs of the bucket of id were redacted.
+func (r *Redactor) Redact(id uint64) (uint64, error) {
+	timestamp := redactTimestamp(id, r.granularity)
+
+	r.mtx.Lock()
+	n := r.used[timestamp]
+	if n > lowBits {
+		r.mtx.Unlock()
+		return 0, fmt.Errorf("snowflake: %d IDs redacted in the bucket of ID %d: %w", n, id, ErrBucketFull)
+	}
+	r.used[timestamp] = n + 1
+	r.mtx.Unlock()
+
+	return timestamp<<(sequenceBits+fieldBits) | (n*r.mul+r.add)&lowBits, nil
+}
@@ -0,0 +1,97 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestRedactPrecision(t *testing.T) {
+	hour := snowflake.Epoch().Add(1000 * time.Hour)
+	id := snowflake.New(5, snowflake.WithClock(newFakeClock(hour.Add(42*time.Minute+1234*time.Millisecond)))).NextID()
+
+	tc := []struct {
+		name        string
+		granularity time.Duration
+		expected    time.Time
+	}{
+		{"Should round down to the hour", time.Hour, hour},
+		{"Should round down to the minute", time.Minute, hour.Add(42 * time.Minute)},
+		{"Should round down to the second", time.Second, hour.Add(42*time.Minute + time.Second)},
+		{"Should keep the timestamp", 0, hour.Add(42*time.Minute + 1234*time.Millisecond)},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sid := snowflake.Parse(snowflake.RedactPrecision(id, tt.granularity))
+			if sid.Timestamp != tt.expected.UnixMilli() {
+				t.Errorf("expected timestamp %d got %d", tt.expected.UnixMilli(), sid.Timestamp)
+			}
+			if sid.MachineID != 0 || sid.Sequence != 0 {
+				t.Errorf("expected field and sequence 0 got %d and %d", sid.MachineID, sid.Sequence)
+			}
+		})
+	}
+
+	// buckets that start before the epoch are clamped to it
+	if ts := snowflake.Parse(snowflake.RedactPrecision(id, 100000*time.Hour)).Timestamp; ts != snowflake.Epoch().UnixMilli() {
+		t.Errorf("expected timestamp %d got %d", snowflake.Epoch().UnixMilli(), ts)
+	}
+}
+
+func TestRedactor_Unique(t *testing.T) {
+	hour := snowflake.Epoch().Add(1000 * time.Hour)
+	clock := &tickingClock{t: hour, step: 300 * time.Millisecond}
+	gens := []*snowflake.ID{
+		snowflake.New(1, snowflake.WithClock(clock)),
+		snowflake.New(2, snowflake.WithClock(clock)),
+	}
+
+	// 10k IDs of 2 generators within an hour, some in the same millisecond
+	var ids []uint64
+	for i := 0; i < 5000; i++ {
+		for _, sf := range gens {
+			ids = append(ids, sf.AppendIDs(nil, 1+i%2)...)
+		}
+	}
+	ids = ids[:10000]
+	if last := snowflake.Parse(ids[len(ids)-1]).Timestamp; last >= hour.Add(time.Hour).UnixMilli() {
+		t.Fatalf("expected all IDs within the hour got %d", last)
+	}
+
+	r := snowflake.NewRedactor(time.Hour)
+	seen := make(map[uint64]bool, len(ids))
+	for _, id := range append(ids, ids[:100]...) { // redacting twice still gives unique IDs
+		redacted, err := r.Redact(id)
+		if err != nil {
+			t.Fatalf("expected no error got %v", err)
+		}
+		if seen[redacted] {
+			t.Fatalf("expected unique IDs got %d twice", redacted)
+		}
+		seen[redacted] = true
+
+		if ts := snowflake.Parse(redacted).Timestamp; ts != hour.UnixMilli() {
+			t.Fatalf("expected timestamp %d got %d", hour.UnixMilli(), ts)
+		}
+	}
+}
+
+func TestRedactor_BucketFull(t *testing.T) {
+	r := snowflake.NewRedactor(time.Hour)
+	id := snowflake.New(1).NextID()
+
+	for i := 0; i < 1<<22; i++ {
+		if _, err := r.Redact(id); err != nil {
+			t.Fatalf("expected no error at %d got %v", i, err)
+		}
+	}
+	if _, err := r.Redact(id); !errors.Is(err, snowflake.ErrBucketFull) {
+		t.Errorf("expected error %v got %v", snowflake.ErrBucketFull, err)
+	}
+	if _, err := r.Redact(id + 2*uint64(time.Hour/time.Millisecond)<<22); err != nil {
+		t.Errorf("expected another bucket to be free got %v", err)
+	}
+}
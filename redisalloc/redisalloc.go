@@ -0,0 +1,229 @@
+// Package redisalloc leases snowflake field values from Redis, so that every
+// member of an elastic fleet gets a distinct machine ID.
+//
+//	lease, err := redisalloc.Acquire(ctx, rdb, "snowflake:fields", 30*time.Second)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer lease.Close()
+//
+//	sf := snowflake.New(lease.Field())
+//
+//	go func() {
+//		<-lease.Done() // the field may now be handed to someone else
+//		log.Fatal("lost snowflake field lease: ", lease.Err())
+//	}()
+//
+// Runtimes too short-lived to hold a lease can share the sequence of every
+// millisecond in Redis instead, see NewRedisSequenced.
+package redisalloc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// ErrNoFreeField is returned when every field value is leased. It wraps
+	// snowflake.ErrNoFreeField.
+	ErrNoFreeField = fmt.Errorf("redisalloc: %w", snowflake.ErrNoFreeField)
+	// ErrLeaseLost is reported by Lease.Err when the lease key was taken over
+	// or expired before it could be renewed.
+	ErrLeaseLost = errors.New("redisalloc: lease lost")
+)
+
+// claimScript sets the first free KEYS[1]:<n> to ARGV[1] with a TTL of ARGV[2]
+// milliseconds and returns n, or -1 when every field is taken.
+var claimScript = redis.NewScript(`
+for i = 0, tonumber(ARGV[3]) do
+	if redis.call("SET", KEYS[1] .. ":" .. i, ARGV[1], "NX", "PX", ARGV[2]) then
+		return i
+	end
+end
+return -1
+`)
+
+// renewScript extends the TTL of KEYS[1] if it still holds ARGV[1].
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseScript deletes KEYS[1] if it still holds ARGV[1].
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Lease is a field value held in Redis. It is renewed in the background
+// every third of its TTL until Close is called or the lease is lost.
+type Lease struct {
+	client redis.Scripter
+	key    string
+	token  string
+	field  uint64
+	ttl    time.Duration
+
+	done      chan struct{}
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	lostOnce  sync.Once
+
+	mtx sync.Mutex
+	err error
+}
+
+// Acquire atomically claims the lowest free field value under keyspace
+// (keys are keyspace:0 to keyspace:1023) for ttl, and starts renewing it.
+// Returns ErrNoFreeField when every field value is taken.
+func Acquire(ctx context.Context, client redis.Scripter, keyspace string, ttl time.Duration) (*Lease, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := time.Now()
+	field, err := claimScript.Run(ctx, client, []string{keyspace},
+		token, ttl.Milliseconds(), snowflake.MaxField()).Int64()
+	if err != nil {
+		return nil, err
+	}
+	if field < 0 {
+		return nil, ErrNoFreeField
+	}
+
+	l := &Lease{
+		client: client,
+		key:    keyspace + ":" + strconv.FormatInt(field, 10),
+		token:  token,
+		field:  uint64(field),
+		ttl:    ttl,
+		done:   make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+
+	l.wg.Add(1)
+	go l.renew(claimed)
+
+	return l, nil
+}
+
+// Allocator is a snowflake.FieldAllocator acquiring leases under Keyspace
+// with Acquire, for snowflake.NewLeased.
+//
+//	sf, err := snowflake.NewLeased(ctx, redisalloc.Allocator{Client: rdb, Keyspace: "snowflake:fields", TTL: 30 * time.Second})
+type Allocator struct {
+	Client   redis.Scripter
+	Keyspace string
+	TTL      time.Duration
+}
+
+var _ snowflake.FieldAllocator = Allocator{}
+
+// Acquire is like the package-level Acquire.
+func (a Allocator) Acquire(ctx context.Context) (snowflake.Lease, error) {
+	l, err := Acquire(ctx, a.Client, a.Keyspace, a.TTL)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Field returns the leased field value.
+func (l *Lease) Field() uint64 { return l.field }
+
+// Done is closed when the lease is lost, after which another process may
+// be handed the same field value. It is not closed by Close.
+func (l *Lease) Done() <-chan struct{} { return l.done }
+
+// Err returns why the lease was lost, or nil while it is held.
+func (l *Lease) Err() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.err
+}
+
+// Close stops renewing the lease and releases the field value.
+func (l *Lease) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.stop)
+		l.wg.Wait()
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.ttl)
+		defer cancel()
+		err = releaseScript.Run(ctx, l.client, []string{l.key}, l.token).Err()
+	})
+	return err
+}
+
+// renew extends the lease every third of its TTL, from lastRenewed on. If
+// the key no longer holds our token, or renewals keep failing until the key
+// would expire before the next one, the lease is lost: the key may still
+// be held then, but another process must not be handed the field while
+// the lease reports it as held. (internal-use only)
+func (l *Lease) renew(lastRenewed time.Time) {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		// the TTL is counted on the server from about when the request is
+		// sent, not from when the reply comes back
+		sent := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), l.ttl/3)
+		renewed, err := renewScript.Run(ctx, l.client, []string{l.key}, l.token, l.ttl.Milliseconds()).Int64()
+		cancel()
+
+		switch {
+		case err == nil && renewed == 1:
+			lastRenewed = sent
+		case err == nil:
+			l.lose(ErrLeaseLost)
+			return
+		case time.Since(lastRenewed)+l.ttl/3 >= l.ttl:
+			l.lose(err)
+			return
+		}
+	}
+}
+
+// lose marks the lease as lost. (internal-use only)
+func (l *Lease) lose(err error) {
+	l.lostOnce.Do(func() {
+		l.mtx.Lock()
+		l.err = err
+		l.mtx.Unlock()
+		close(l.done)
+	})
+}
+
+// newToken returns a random token identifying a lease holder. (internal-use only)
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,281 @@
+package redisalloc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/redisalloc"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	return mr, rdb
+}
+
+func TestAcquire(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newRedis(t)
+
+	first, err := redisalloc.Acquire(ctx, rdb, "fields", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := redisalloc.Acquire(ctx, rdb, "fields", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Field() != 0 || second.Field() != 1 {
+		t.Errorf("expected fields 0 and 1 got %d and %d", first.Field(), second.Field())
+	}
+	if ttl := mr.TTL("fields:0"); ttl != time.Minute {
+		t.Errorf("expected TTL %s got %s", time.Minute, ttl)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("fields:0") {
+		t.Error("expected Close to release the field")
+	}
+
+	// the lowest free field is handed out again
+	third, err := redisalloc.Acquire(ctx, rdb, "fields", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Field() != 0 {
+		t.Errorf("expected field 0 to be reused got %d", third.Field())
+	}
+
+	second.Close()
+	third.Close()
+}
+
+func TestAcquire_ContentionForLastSlot(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newRedis(t)
+
+	for i := 0; i < 1023; i++ {
+		mr.Set(fmt.Sprintf("fields:%d", i), "someone-else")
+	}
+
+	var wg sync.WaitGroup
+	leases := make(chan *redisalloc.Lease, 8)
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lease, err := redisalloc.Acquire(ctx, rdb, "fields", time.Minute)
+			if err != nil {
+				errs <- err
+				return
+			}
+			leases <- lease
+		}()
+	}
+	wg.Wait()
+	close(leases)
+	close(errs)
+
+	if len(leases) != 1 {
+		t.Fatalf("expected exactly one winner got %d", len(leases))
+	}
+	lease := <-leases
+	defer lease.Close()
+	if lease.Field() != 1023 {
+		t.Errorf("expected field 1023 got %d", lease.Field())
+	}
+
+	for err := range errs {
+		if err != redisalloc.ErrNoFreeField {
+			t.Errorf("expected error %v got %v", redisalloc.ErrNoFreeField, err)
+		}
+		if !errors.Is(err, snowflake.ErrNoFreeField) {
+			t.Errorf("expected error %v to wrap %v", err, snowflake.ErrNoFreeField)
+		}
+	}
+}
+
+func TestAcquire_ExpiredLeaseIsReclaimed(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newRedis(t)
+
+	// a holder that crashed without releasing
+	mr.Set("fields:0", "crashed")
+	mr.SetTTL("fields:0", time.Second)
+
+	lease, err := redisalloc.Acquire(ctx, rdb, "fields", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Field() != 1 {
+		t.Errorf("expected field 1 while 0 is held got %d", lease.Field())
+	}
+	lease.Close()
+
+	mr.FastForward(2 * time.Second)
+
+	lease, err = redisalloc.Acquire(ctx, rdb, "fields", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+	if lease.Field() != 0 {
+		t.Errorf("expected the expired field 0 to be reclaimed got %d", lease.Field())
+	}
+}
+
+func TestLease_Renewal(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newRedis(t)
+
+	ttl := 300 * time.Millisecond
+	lease, err := redisalloc.Acquire(ctx, rdb, "fields", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	// let a renewal happen after the TTL went down
+	mr.FastForward(200 * time.Millisecond)
+	time.Sleep(ttl)
+
+	if got := mr.TTL("fields:0"); got != ttl {
+		t.Errorf("expected the TTL to be renewed to %s got %s", ttl, got)
+	}
+
+	select {
+	case <-lease.Done():
+		t.Errorf("expected the lease to be held got %v", lease.Err())
+	default:
+	}
+}
+
+func TestLease_Lost(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newRedis(t)
+
+	lease, err := redisalloc.Acquire(ctx, rdb, "fields", 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	// someone else took the field over
+	mr.Set("fields:0", "someone-else")
+
+	select {
+	case <-lease.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the lease to be lost")
+	}
+
+	if lease.Err() != redisalloc.ErrLeaseLost {
+		t.Errorf("expected error %v got %v", redisalloc.ErrLeaseLost, lease.Err())
+	}
+
+	// Close must not release a key that isn't ours anymore
+	lease.Close()
+	if v, _ := mr.Get("fields:0"); v != "someone-else" {
+		t.Errorf("expected the new holder to keep the field got %q", v)
+	}
+}
+
+func TestLease_RenewalFailure(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newRedis(t)
+
+	lease, err := redisalloc.Acquire(ctx, rdb, "fields", 300*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	mr.Close() // Redis becomes unreachable
+
+	select {
+	case <-lease.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected the lease to be lost once renewals failed for a whole TTL")
+	}
+
+	if lease.Err() == nil {
+		t.Error("expected Err to report the renewal failure")
+	}
+}
+
+func TestLease_LostBeforeExpiry(t *testing.T) {
+	ctx := context.Background()
+	mr, rdb := newRedis(t)
+
+	ttl := 600 * time.Millisecond
+	start := time.Now()
+	lease, err := redisalloc.Acquire(ctx, rdb, "fields", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	mr.SetError("LOADING Redis is loading the dataset in memory") // renewals fail
+
+	select {
+	case <-lease.Done():
+	case <-time.After(3 * time.Second):
+		t.Fatal("expected the lease to be lost")
+	}
+	if elapsed := time.Since(start); elapsed >= ttl {
+		t.Errorf("expected the lease to be lost before its key expires after %s got %s", ttl, elapsed)
+	}
+
+	// only then may another process claim the field
+	mr.SetError("")
+	mr.FastForward(ttl)
+	other, err := redisalloc.Acquire(ctx, rdb, "fields", ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if other.Field() != 0 {
+		t.Errorf("expected the expired field 0 to be claimed again got %d", other.Field())
+	}
+}
+
+func TestAllocator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mr, rdb := newRedis(t)
+
+	sf, err := snowflake.NewLeased(ctx, redisalloc.Allocator{Client: rdb, Keyspace: "fields", TTL: 300 * time.Millisecond}, snowflake.WithLeasePolicy(snowflake.ReacquireOnLeaseLoss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field := snowflake.Parse(sf.NextID()).MachineID; field != 0 {
+		t.Fatalf("expected field 0 got %d", field)
+	}
+
+	// someone else took field 0 over
+	mr.Set("fields:0", "someone-else")
+
+	// the generator carries on with the next lease
+	deadline := time.Now().Add(5 * time.Second)
+	for snowflake.Parse(sf.NextID()).MachineID != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected field 1 to be leased once field 0 was lost")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
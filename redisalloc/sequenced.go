@@ -0,0 +1,241 @@
+package redisalloc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// slotBits is the width of the field and sequence of an ID, shared by
+	// every instance as one sequence. (internal-use only)
+	slotBits = 22
+	maxSlots = 1 << slotBits
+
+	// DefaultBatch is the number of slots claimed per round trip by default.
+	DefaultBatch = 32
+	// DefaultSequenceTTL is the TTL of the key of a millisecond by default.
+	DefaultSequenceTTL = 10 * time.Second
+	// DefaultTimeout bounds a round trip to Redis by default.
+	DefaultTimeout = time.Second
+)
+
+// ErrRedisUnavailable is wrapped by the errors of RedisSequenced.NextIDErr
+// when Redis can't be reached, or fails.
+var ErrRedisUnavailable = errors.New("redisalloc: redis unavailable")
+
+// incrScript adds ARGV[1] to KEYS[1], setting its TTL to ARGV[2]
+// milliseconds when it's created, and returns the sum.
+var incrScript = redis.NewScript(`
+local n = redis.call("INCRBY", KEYS[1], ARGV[1])
+if n == tonumber(ARGV[1]) then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return n
+`)
+
+// SequencedOption configures a RedisSequenced.
+type SequencedOption func(*RedisSequenced)
+
+// WithKeyPrefix sets the prefix of the keys of the milliseconds (default:
+// "snowflake:seq"). Instances sharing a prefix share a sequence.
+func WithKeyPrefix(prefix string) SequencedOption {
+	return func(s *RedisSequenced) {
+		s.prefix = prefix
+	}
+}
+
+// WithBatch sets the number of slots claimed per round trip (default:
+// DefaultBatch). Panics if n is 0 or over 2^22, the slots of a
+// millisecond.
+func WithBatch(n uint64) SequencedOption {
+	if n == 0 || n > maxSlots {
+		panic("redisalloc: batch must be between 1 and 2^22")
+	}
+	return func(s *RedisSequenced) {
+		s.batch = n
+	}
+}
+
+// WithSequenceTTL sets the TTL of the key of a millisecond (default:
+// DefaultSequenceTTL). It must exceed the clock skew across instances: an
+// instance whose clock is behind by more than the TTL reuses the expired
+// key of a millisecond, and issues the same IDs again.
+func WithSequenceTTL(ttl time.Duration) SequencedOption {
+	return func(s *RedisSequenced) {
+		s.ttl = ttl
+	}
+}
+
+// WithTimeout bounds a round trip to Redis (default: DefaultTimeout).
+func WithTimeout(d time.Duration) SequencedOption {
+	return func(s *RedisSequenced) {
+		s.timeout = d
+	}
+}
+
+// WithSequencedClock makes the RedisSequenced read the time from c instead
+// of the system clock.
+func WithSequencedClock(c snowflake.Clock) SequencedOption {
+	return func(s *RedisSequenced) {
+		s.clock = c
+	}
+}
+
+// WithFallback makes the RedisSequenced issue the IDs of g while Redis is
+// unavailable, rather than fail. The IDs of g must not collide with the
+// ones claimed from Redis, e.g. those of a generator with
+// snowflake.WithRandomFallback set apart by snowflake.IsFallback.
+func WithFallback(g snowflake.Generator) SequencedOption {
+	return func(s *RedisSequenced) {
+		s.fallback = g
+	}
+}
+
+// RedisSequenced is a snowflake.Generator for stateless runtimes, such as
+// functions, with no machine ID to claim: the timestamp of its IDs is
+// local, but their field and sequence, 22 bits together, come from INCRBY
+// on a key per millisecond in Redis, so that any number of instances share
+// the slots of every millisecond. It claims slots in batches to save round
+// trips; the slots of a batch left when the millisecond moves on are
+// dropped, so that IDs carry the time they're issued at.
+//
+// Like snowflake.ID, it never moves back in time: while the clock is
+// behind the last ID, it claims slots of the millisecond of the last ID.
+// It's safe for concurrent use.
+type RedisSequenced struct {
+	client   redis.Scripter
+	prefix   string
+	batch    uint64
+	ttl      time.Duration
+	timeout  time.Duration
+	clock    snowflake.Clock
+	fallback snowflake.Generator
+
+	mtx sync.Mutex
+	// ms is the millisecond, since the epoch, of the batch from next,
+	// included, to end, excluded.
+	ms, next, end uint64
+}
+
+var (
+	_ snowflake.Generator         = (*RedisSequenced)(nil)
+	_ snowflake.FallibleGenerator = (*RedisSequenced)(nil)
+)
+
+// NewRedisSequenced returns a new redisalloc.RedisSequenced claiming
+// slots from client.
+func NewRedisSequenced(client redis.Scripter, opts ...SequencedOption) *RedisSequenced {
+	s := &RedisSequenced{
+		client:  client,
+		prefix:  "snowflake:seq",
+		batch:   DefaultBatch,
+		ttl:     DefaultSequenceTTL,
+		timeout: DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NextID returns a new snowflake ID. Panics if Redis is unavailable and no
+// fallback is configured, see WithFallback and NextIDErr.
+func (s *RedisSequenced) NextID() uint64 {
+	id, err := s.NextIDErr()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// NextIDErr returns a new snowflake ID, or an error wrapping
+// ErrRedisUnavailable if Redis is unavailable and no fallback is
+// configured. Returns an error wrapping snowflake.ErrBeforeEpoch or
+// snowflake.ErrTimestampOverflow if the clock is out of the lifetime of
+// the epoch.
+func (s *RedisSequenced) NextIDErr() (uint64, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for {
+		ms, err := s.now()
+		if err != nil {
+			return 0, err
+		}
+		if ms < s.ms {
+			ms = s.ms
+		}
+		if ms == s.ms && s.next < s.end {
+			slot := s.next
+			s.next++
+			return ms<<slotBits | slot, nil
+		}
+
+		end, err := s.claim(ms)
+		if err != nil {
+			if s.fallback != nil {
+				return s.fallback.NextID(), nil
+			}
+			return 0, err
+		}
+		if end > maxSlots {
+			// the slots of the millisecond are used up, wait for the next
+			s.ms, s.next, s.end = ms+1, 0, 0
+			s.sleepUntil(ms + 1)
+			continue
+		}
+		s.ms, s.next, s.end = ms, end-s.batch, end
+	}
+}
+
+// claim claims a batch of slots of ms and returns the end of the batch.
+// (internal-use only)
+func (s *RedisSequenced) claim(ms uint64) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	key := s.prefix + ":" + strconv.FormatUint(ms, 10)
+	end, err := incrScript.Run(ctx, s.client, []string{key}, s.batch, s.ttl.Milliseconds()).Uint64()
+	if err != nil {
+		return 0, fmt.Errorf("redisalloc: claiming %d slots of %s: %v: %w", s.batch, key, err, ErrRedisUnavailable)
+	}
+	return end, nil
+}
+
+// now returns the time, in milliseconds since the epoch. (internal-use only)
+func (s *RedisSequenced) now() (uint64, error) {
+	elapsed := s.wallNow().Sub(snowflake.Epoch()).Milliseconds()
+	switch {
+	case elapsed < 0:
+		return 0, fmt.Errorf("redisalloc: clock is before the epoch: %w", snowflake.ErrBeforeEpoch)
+	case elapsed >= 1<<(64-slotBits-1):
+		return 0, fmt.Errorf("redisalloc: clock is past the last timestamp: %w", snowflake.ErrTimestampOverflow)
+	}
+	return uint64(elapsed), nil
+}
+
+// wallNow returns the time of the clock. (internal-use only)
+func (s *RedisSequenced) wallNow() time.Time {
+	if s.clock != nil {
+		return s.clock.Now()
+	}
+	return time.Now()
+}
+
+// sleepUntil sleeps until the clock reaches ms, in milliseconds since the
+// epoch, or for a millisecond if the clock doesn't move. (internal-use only)
+func (s *RedisSequenced) sleepUntil(ms uint64) {
+	for i := 0; i < 2; i++ {
+		if now, err := s.now(); err != nil || now >= ms {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
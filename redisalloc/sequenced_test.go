@@ -0,0 +1,199 @@
+package redisalloc_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/redisalloc"
+)
+
+type fixedClock struct{ t time.Time }
+
+func (c fixedClock) Now() time.Time { return c.t }
+
+var sequencedNow = snowflake.Epoch().Add(time.Hour)
+
+func TestRedisSequenced(t *testing.T) {
+	mr, rdb := newRedis(t)
+	sf := redisalloc.NewRedisSequenced(rdb, redisalloc.WithBatch(4),
+		redisalloc.WithSequencedClock(fixedClock{sequencedNow}))
+
+	for i := uint64(0); i < 10; i++ {
+		id, err := sf.NextIDErr()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sid := snowflake.Parse(id)
+		if want := sequencedNow.UnixMilli(); sid.Timestamp != want {
+			t.Errorf("expected timestamp %d got %d", want, sid.Timestamp)
+		}
+		if slot := id & (1<<22 - 1); slot != i {
+			t.Errorf("expected slot %d got %d", i, slot)
+		}
+	}
+
+	// 10 IDs take 3 batches of 4
+	key := "snowflake:seq:3600000"
+	if got, err := mr.Get(key); err != nil || got != "12" {
+		t.Errorf("expected %s to be 12 got %q %v", key, got, err)
+	}
+	if ttl := mr.TTL(key); ttl != redisalloc.DefaultSequenceTTL {
+		t.Errorf("expected TTL %s got %s", redisalloc.DefaultSequenceTTL, ttl)
+	}
+}
+
+func TestRedisSequenced_Interleaving(t *testing.T) {
+	_, rdb := newRedis(t)
+	clock := fixedClock{sequencedNow}
+	a := redisalloc.NewRedisSequenced(rdb, redisalloc.WithBatch(3), redisalloc.WithSequencedClock(clock))
+	b := redisalloc.NewRedisSequenced(rdb, redisalloc.WithBatch(3), redisalloc.WithSequencedClock(clock))
+
+	// two instances within one millisecond take turns claiming batches
+	seen := make(map[uint64]string)
+	var lastA, lastB uint64
+	for i := 0; i < 30; i++ {
+		for _, inst := range []struct {
+			name string
+			sf   *redisalloc.RedisSequenced
+			last *uint64
+		}{{"a", a, &lastA}, {"b", b, &lastB}} {
+			id := inst.sf.NextID()
+			if other, ok := seen[id]; ok {
+				t.Fatalf("expected unique IDs got %d from %s and %s", id, other, inst.name)
+			}
+			if id <= *inst.last && *inst.last != 0 {
+				t.Errorf("expected ID %d of %s to be greater than %d", id, inst.name, *inst.last)
+			}
+			seen[id] = inst.name
+			*inst.last = id
+		}
+	}
+
+	for id := range seen {
+		if ts := snowflake.Parse(id).Timestamp; ts != sequencedNow.UnixMilli() {
+			t.Fatalf("expected every ID in the same millisecond got timestamp %d", ts)
+		}
+	}
+}
+
+func TestRedisSequenced_Concurrent(t *testing.T) {
+	_, rdb := newRedis(t)
+
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[uint64]struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// one instance per goroutine, as separate functions would be
+			sf := redisalloc.NewRedisSequenced(rdb, redisalloc.WithBatch(16), redisalloc.WithSequencedClock(fixedClock{sequencedNow}))
+			ids := make([]uint64, 500)
+			for j := range ids {
+				ids[j] = sf.NextID()
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			for _, id := range ids {
+				if _, ok := seen[id]; ok {
+					t.Errorf("expected unique IDs got %d twice", id)
+				}
+				seen[id] = struct{}{}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRedisSequenced_NextMillisecond(t *testing.T) {
+	mr, rdb := newRedis(t)
+	sf := redisalloc.NewRedisSequenced(rdb, redisalloc.WithSequencedClock(fixedClock{sequencedNow}))
+
+	// the slots of the millisecond are used up by other instances
+	mr.Set("snowflake:seq:3600000", "4194304")
+
+	id, err := sf.NextIDErr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := sequencedNow.UnixMilli() + 1; snowflake.Parse(id).Timestamp != want {
+		t.Errorf("expected the next millisecond %d got %d", want, snowflake.Parse(id).Timestamp)
+	}
+}
+
+func TestRedisSequenced_ClockBackwards(t *testing.T) {
+	_, rdb := newRedis(t)
+	clock := &fixedClock{sequencedNow}
+	sf := redisalloc.NewRedisSequenced(rdb, redisalloc.WithBatch(1), redisalloc.WithSequencedClock(clock))
+
+	first := sf.NextID()
+	clock.t = clock.t.Add(-time.Second)
+	if id := sf.NextID(); id <= first {
+		t.Errorf("expected ID %d to be greater than %d", id, first)
+	}
+}
+
+func TestRedisSequenced_Unavailable(t *testing.T) {
+	mr, rdb := newRedis(t)
+	sf := redisalloc.NewRedisSequenced(rdb, redisalloc.WithBatch(2), redisalloc.WithTimeout(100*time.Millisecond),
+		redisalloc.WithSequencedClock(fixedClock{sequencedNow}))
+	if _, err := sf.NextIDErr(); err != nil {
+		t.Fatal(err)
+	}
+	mr.Close()
+
+	// the batch is still served, then Redis is needed
+	if _, err := sf.NextIDErr(); err != nil {
+		t.Errorf("expected the rest of the batch got %v", err)
+	}
+	if _, err := sf.NextIDErr(); !errors.Is(err, redisalloc.ErrRedisUnavailable) {
+		t.Errorf("expected error %v got %v", redisalloc.ErrRedisUnavailable, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NextID to panic without a fallback")
+		}
+	}()
+	sf.NextID()
+}
+
+func TestRedisSequenced_Fallback(t *testing.T) {
+	mr, rdb := newRedis(t)
+	mr.Close()
+
+	fallback := snowflake.New(0, snowflake.WithRandomFallback(), snowflake.WithClock(fixedClock{snowflake.Epoch().Add(-time.Hour)}))
+	sf := redisalloc.NewRedisSequenced(rdb, redisalloc.WithTimeout(100*time.Millisecond), redisalloc.WithFallback(fallback))
+
+	id, err := sf.NextIDErr()
+	if err != nil {
+		t.Fatalf("expected the fallback to issue an ID got %v", err)
+	}
+	if !snowflake.IsFallback(id) {
+		t.Errorf("expected ID %d from the fallback", id)
+	}
+}
+
+func TestRedisSequenced_BeforeEpoch(t *testing.T) {
+	_, rdb := newRedis(t)
+	sf := redisalloc.NewRedisSequenced(rdb, redisalloc.WithSequencedClock(fixedClock{snowflake.Epoch().Add(-time.Second)}))
+	if _, err := sf.NextIDErr(); !errors.Is(err, snowflake.ErrBeforeEpoch) {
+		t.Errorf("expected error %v got %v", snowflake.ErrBeforeEpoch, err)
+	}
+}
+
+func TestWithBatch_OutOfRange(t *testing.T) {
+	for _, n := range []uint64{0, 1<<22 + 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected WithBatch(%d) to panic", n)
+				}
+			}()
+			redisalloc.WithBatch(n)
+		}()
+	}
+}
@@ -0,0 +1,65 @@
+package snowflake
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// registry holds the fields claimed by NewRegistered in this process.
+// (internal-use only)
+var registry = struct {
+	mtx    sync.Mutex
+	fields map[uint64]bool
+}{fields: make(map[uint64]bool)}
+
+// NewRegistered is like New but claims field in a process-wide registry
+// first, so that two packages of the same binary can't unknowingly share a
+// field and generate colliding IDs. Call Release once the generator is
+// discarded to free the field again. Generators created with New are not
+// registered and never conflict.
+// Can return 2 errors: ErrFieldOutOfRange and ErrFieldInUse.
+func NewRegistered(field uint64, opts ...Option) (*ID, error) {
+	if field > maxFieldBits {
+		return nil, ErrFieldOutOfRange
+	}
+
+	registry.mtx.Lock()
+	defer registry.mtx.Unlock()
+
+	if registry.fields[field] {
+		return nil, ErrFieldInUse
+	}
+	registry.fields[field] = true
+
+	id := New(field, opts...)
+	id.registered = 1
+	return id, nil
+}
+
+// Release frees the field of a generator created with NewRegistered so it
+// can be registered again. The generator must not be used afterwards.
+// Releasing twice, or releasing a generator created with New, does nothing.
+func (id *ID) Release() {
+	if !atomic.CompareAndSwapUint32(&id.registered, 1, 2) {
+		return
+	}
+
+	registry.mtx.Lock()
+	delete(registry.fields, id.field)
+	registry.mtx.Unlock()
+}
+
+// RegisteredFields returns the fields currently held by generators created
+// with NewRegistered, in increasing order.
+func RegisteredFields() []uint64 {
+	registry.mtx.Lock()
+	fields := make([]uint64, 0, len(registry.fields))
+	for field := range registry.fields {
+		fields = append(fields, field)
+	}
+	registry.mtx.Unlock()
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i] < fields[j] })
+	return fields
+}
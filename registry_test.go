@@ -0,0 +1,116 @@
+package snowflake_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestNewRegistered(t *testing.T) {
+	sf, err := snowflake.NewRegistered(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sf.Release()
+
+	if field := snowflake.Parse(sf.NextID()).MachineID; field != 100 {
+		t.Errorf("expected field %d got %d", 100, field)
+	}
+
+	tc := []struct {
+		name  string
+		field uint64
+		err   error
+	}{
+		{"Should return ErrFieldInUse", 100, snowflake.ErrFieldInUse},
+		{"Should return ErrFieldOutOfRange", 1024, snowflake.ErrFieldOutOfRange},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := snowflake.NewRegistered(tt.field)
+			if err != tt.err {
+				t.Errorf("expected error %v got %v", tt.err, err)
+			}
+		})
+	}
+
+	// plain generators are not registered
+	snowflake.New(100)
+	if fields := snowflake.RegisteredFields(); !reflect.DeepEqual(fields, []uint64{100}) {
+		t.Errorf("expected registered fields [100] got %v", fields)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	sf, err := snowflake.NewRegistered(101)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sf.Release()
+	sf.Release() // no-op
+
+	if fields := snowflake.RegisteredFields(); len(fields) != 0 {
+		t.Errorf("expected no registered fields got %v", fields)
+	}
+
+	again, err := snowflake.NewRegistered(101)
+	if err != nil {
+		t.Fatalf("expected a released field to be registrable got %v", err)
+	}
+	defer again.Release()
+
+	// releasing the stale generator must not free the new registration
+	sf.Release()
+	if _, err := snowflake.NewRegistered(101); err != snowflake.ErrFieldInUse {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldInUse, err)
+	}
+
+	// nor does releasing a plain generator
+	snowflake.New(101).Release()
+	if _, err := snowflake.NewRegistered(101); err != snowflake.ErrFieldInUse {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldInUse, err)
+	}
+}
+
+func TestNewRegistered_Concurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+	winners := make(map[uint64][]*snowflake.ID)
+
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			field := uint64(200 + i%4)
+			sf, err := snowflake.NewRegistered(field)
+			if err == snowflake.ErrFieldInUse {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mtx.Lock()
+			winners[field] = append(winners[field], sf)
+			mtx.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	for field := uint64(200); field < 204; field++ {
+		if len(winners[field]) != 1 {
+			t.Errorf("expected exactly one generator to register field %d got %d", field, len(winners[field]))
+		}
+		for _, sf := range winners[field] {
+			sf.Release()
+		}
+	}
+
+	if fields := snowflake.RegisteredFields(); len(fields) != 0 {
+		t.Errorf("expected no registered fields got %v", fields)
+	}
+}
@@ -0,0 +1,269 @@
+// Package segment issues IDs from ranges, or segments, reserved in a shared
+// store, in the manner of Meituan's Leaf: an alternative to snowflake IDs
+// with no dependency on the clock, for when IDs don't need to sort by time.
+//
+//	store := segment.NewSQLStore(db, "snowflake_segments")
+//	sf, err := segment.New(ctx, store, "orders")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer sf.Close()
+//
+//	id := sf.NextID()
+//
+// Every generator sharing a store and a key issues unique IDs, increasing
+// within a generator but not across them. The IDs left in the segments of
+// a generator when it stops are never issued, so restarts leave gaps.
+package segment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// DefaultStep is the number of IDs in a segment by default.
+const DefaultStep = 10000
+
+const (
+	// prefetchAt is the share of a segment, in quarters, consumed before
+	// the next one is fetched in the background. (internal-use only)
+	prefetchAt = 3
+	// minBackoff and maxBackoff bound the time between retries after the
+	// store failed. (internal-use only)
+	minBackoff = 10 * time.Millisecond
+	maxBackoff = 10 * time.Second
+)
+
+// ErrClosed is returned once the SegmentGenerator is closed and its
+// segments are used up.
+var ErrClosed = errors.New("segment: generator is closed")
+
+// Option configures a SegmentGenerator.
+type Option func(*SegmentGenerator)
+
+// WithStep sets the number of IDs reserved at once (default: DefaultStep).
+// Bigger steps call the store less often but leave bigger gaps on
+// restarts. Panics if step is 0.
+func WithStep(step uint64) Option {
+	if step == 0 {
+		panic("segment: step must be positive")
+	}
+	return func(g *SegmentGenerator) {
+		g.step = step
+	}
+}
+
+// span is a segment of IDs, from start, included, to end, excluded, of
+// which next is the next to issue. (internal-use only)
+type span struct {
+	start, next, end uint64
+}
+
+// remaining returns the number of IDs left in s. (internal-use only)
+func (s span) remaining() uint64 { return s.end - s.next }
+
+// consumed reports whether prefetchAt quarters of s were issued.
+// (internal-use only)
+func (s span) consumed() bool { return (s.next-s.start)*4 >= (s.end-s.start)*prefetchAt }
+
+// SegmentGenerator issues the IDs of a key from segments reserved in a
+// SegmentStore, serving them from memory. Once 75% of its segment is
+// issued, it reserves the next one in the background, so callers only wait
+// for the store when it can't keep up, or failed. It's safe for concurrent
+// use.
+type SegmentGenerator struct {
+	store SegmentStore
+	key   string
+	step  uint64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mtx  sync.Mutex
+	cond *sync.Cond
+	cur  span
+	next span
+	// fetching is set while a segment is being reserved, by a prefetch or
+	// a caller out of IDs, so only one is reserved at a time.
+	fetching bool
+	// retryAt holds prefetches back after the store failed.
+	retryAt time.Time
+	backoff time.Duration
+	err     error
+}
+
+var (
+	_ snowflake.Generator         = (*SegmentGenerator)(nil)
+	_ snowflake.FallibleGenerator = (*SegmentGenerator)(nil)
+)
+
+// New returns a new segment.SegmentGenerator issuing the IDs of key in
+// store, reserving its first segment before returning. Call Close to stop
+// the background prefetches.
+func New(ctx context.Context, store SegmentStore, key string, opts ...Option) (*SegmentGenerator, error) {
+	g := &SegmentGenerator{
+		store: store,
+		key:   key,
+		step:  DefaultStep,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.cond = sync.NewCond(&g.mtx)
+	g.ctx, g.cancel = context.WithCancel(context.Background())
+
+	s, err := g.reserve(ctx)
+	if err != nil {
+		g.cancel()
+		return nil, err
+	}
+	g.cur = s
+	return g, nil
+}
+
+// NextID returns the next ID, waiting for the store, and retrying with a
+// backoff, while it fails. Panics once the generator is closed and its
+// segments are used up.
+func (g *SegmentGenerator) NextID() uint64 {
+	for backoff := minBackoff; ; {
+		id, err := g.NextIDErr()
+		if err == nil {
+			return id
+		}
+		if errors.Is(err, ErrClosed) {
+			panic("segment: NextID on a closed generator")
+		}
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// NextIDErr is like NextID but returns the error of the store rather than
+// retry, if the generator is out of IDs and the store fails, or ErrClosed
+// once the generator is closed and its segments are used up.
+func (g *SegmentGenerator) NextIDErr() (uint64, error) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	for {
+		if g.cur.remaining() > 0 {
+			id := g.cur.next
+			g.cur.next++
+			g.prefetch()
+			return id, nil
+		}
+		if g.next.remaining() > 0 {
+			g.cur, g.next = g.next, span{}
+			continue
+		}
+		if g.fetching {
+			// a prefetch is on its way, or another caller is fetching
+			g.cond.Wait()
+			continue
+		}
+
+		s, err := g.fetch()
+		if err != nil {
+			return 0, err
+		}
+		g.cur = s
+	}
+}
+
+// Err returns the error of the last failed reservation, or nil if the last
+// one succeeded.
+func (g *SegmentGenerator) Err() error {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	return g.err
+}
+
+// Close stops the background prefetches. IDs left in the segments of the
+// generator are still issued after Close, but no new segment is reserved.
+func (g *SegmentGenerator) Close() {
+	g.cancel()
+}
+
+// prefetch reserves the next segment in the background if the current one
+// is mostly consumed. g.mtx must be held. (internal-use only)
+func (g *SegmentGenerator) prefetch() {
+	if g.fetching || g.next.remaining() > 0 || !g.cur.consumed() || time.Now().Before(g.retryAt) {
+		return
+	}
+
+	g.fetching = true
+	go func() {
+		s, err := g.reserve(g.ctx)
+
+		g.mtx.Lock()
+		defer g.mtx.Unlock()
+		g.fetching = false
+		g.cond.Broadcast()
+		if g.failed(err) {
+			return
+		}
+		g.next = s
+	}()
+}
+
+// fetch reserves a segment for a caller out of IDs, releasing g.mtx while
+// the store is called. g.mtx must be held. (internal-use only)
+func (g *SegmentGenerator) fetch() (span, error) {
+	g.fetching = true
+	g.mtx.Unlock()
+	s, err := g.reserve(g.ctx)
+	g.mtx.Lock()
+	g.fetching = false
+	g.cond.Broadcast()
+
+	if g.failed(err) {
+		return span{}, err
+	}
+	return s, nil
+}
+
+// failed records the outcome of a reservation, backing prefetches off
+// while the store fails. g.mtx must be held. (internal-use only)
+func (g *SegmentGenerator) failed(err error) bool {
+	g.err = err
+	if err == nil {
+		g.backoff, g.retryAt = 0, time.Time{}
+		return false
+	}
+
+	if g.backoff *= 2; g.backoff < minBackoff {
+		g.backoff = minBackoff
+	} else if g.backoff > maxBackoff {
+		g.backoff = maxBackoff
+	}
+	g.retryAt = time.Now().Add(g.backoff)
+	return true
+}
+
+// reserve reserves the next segment of the key in the store.
+// (internal-use only)
+func (g *SegmentGenerator) reserve(ctx context.Context) (span, error) {
+	if g.ctx.Err() != nil {
+		return span{}, ErrClosed
+	}
+
+	maxID, err := g.store.Reserve(ctx, g.key, g.step)
+	if err != nil {
+		if g.ctx.Err() != nil {
+			return span{}, ErrClosed
+		}
+		return span{}, fmt.Errorf("segment: reserving %d IDs of %q: %w", g.step, g.key, err)
+	}
+	if maxID < g.step {
+		return span{}, fmt.Errorf("segment: max ID %d of %q is below the step %d", maxID, g.key, g.step)
+	}
+	return span{start: maxID - g.step, next: maxID - g.step, end: maxID}, nil
+}
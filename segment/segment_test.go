@@ -0,0 +1,301 @@
+package segment_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake/segment"
+)
+
+var errStore = errors.New("store is down")
+
+// testStore is a MemoryStore counting reservations, which can be made to
+// fail.
+type testStore struct {
+	segment.MemoryStore
+	calls int32
+	fail  int32
+}
+
+func (s *testStore) Reserve(ctx context.Context, key string, step uint64) (uint64, error) {
+	atomic.AddInt32(&s.calls, 1)
+	if atomic.LoadInt32(&s.fail) == 1 {
+		return 0, errStore
+	}
+	return s.MemoryStore.Reserve(ctx, key, step)
+}
+
+func (s *testStore) Calls() int32 { return atomic.LoadInt32(&s.calls) }
+
+func (s *testStore) SetFail(fail bool) {
+	var v int32
+	if fail {
+		v = 1
+	}
+	atomic.StoreInt32(&s.fail, v)
+}
+
+// waitFor polls cond until it holds or a second passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newGenerator(t *testing.T, store segment.SegmentStore, opts ...segment.Option) *segment.SegmentGenerator {
+	t.Helper()
+	sf, err := segment.New(context.Background(), store, "orders", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sf.Close)
+	return sf
+}
+
+func TestSegmentGenerator_Sequential(t *testing.T) {
+	var store segment.MemoryStore
+	sf := newGenerator(t, &store, segment.WithStep(10))
+
+	for want := uint64(0); want < 25; want++ {
+		if id := sf.NextID(); id != want {
+			t.Fatalf("expected ID %d got %d", want, id)
+		}
+	}
+}
+
+func TestSegmentGenerator_Concurrent(t *testing.T) {
+	store := &testStore{}
+	sf := newGenerator(t, store, segment.WithStep(100))
+
+	const goroutines, perGoroutine = 8, 10000
+	var mtx sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[uint64]struct{})
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ids := make([]uint64, perGoroutine)
+			for j := range ids {
+				ids[j] = sf.NextID()
+				if j > 0 && ids[j] <= ids[j-1] {
+					t.Errorf("expected ID %d to be greater than %d", ids[j], ids[j-1])
+					return
+				}
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			for _, id := range ids {
+				if _, ok := seen[id]; ok {
+					t.Errorf("expected unique IDs got %d twice", id)
+				}
+				seen[id] = struct{}{}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// one reservation per segment, plus at most one prefetched ahead
+	if calls, max := store.Calls(), int32(goroutines*perGoroutine/100+1); calls > max {
+		t.Errorf("expected at most %d reservations got %d", max, calls)
+	}
+}
+
+func TestSegmentGenerator_Prefetch(t *testing.T) {
+	store := &testStore{}
+	sf := newGenerator(t, store, segment.WithStep(100))
+
+	for i := 0; i < 74; i++ {
+		sf.NextID()
+	}
+	if calls := store.Calls(); calls != 1 {
+		t.Fatalf("expected no prefetch before 75%% got %d reservations", calls)
+	}
+
+	sf.NextID()
+	waitFor(t, func() bool { return store.MaxID("orders") == 200 })
+
+	// the next segment is served from memory, without reserving again
+	store.SetFail(true)
+	for want := uint64(75); want < 175; want++ {
+		if id, err := sf.NextIDErr(); err != nil || id != want {
+			t.Fatalf("expected ID %d got %d %v", want, id, err)
+		}
+	}
+}
+
+// gatedStore is a MemoryStore whose reservations, once armed, wait to be
+// let through from reserves, so that tests can tell which call made them.
+type gatedStore struct {
+	segment.MemoryStore
+	armed    int32
+	reserves chan chan struct{}
+}
+
+func (s *gatedStore) Reserve(ctx context.Context, key string, step uint64) (uint64, error) {
+	if atomic.LoadInt32(&s.armed) == 1 {
+		done := make(chan struct{})
+		s.reserves <- done
+		<-done
+	}
+	return s.MemoryStore.Reserve(ctx, key, step)
+}
+
+func TestSegmentGenerator_PrefetchOnly(t *testing.T) {
+	store := &gatedStore{reserves: make(chan chan struct{})}
+	sf := newGenerator(t, store, segment.WithStep(100))
+	atomic.StoreInt32(&store.armed, 1)
+
+	// every segment switch is served by a prefetch started by the ID at
+	// 75% of the segment, never by NextID calling the store itself
+	for want := uint64(0); want < 2000; want++ {
+		ids := make(chan uint64, 1)
+		go func() { ids <- sf.NextID() }()
+
+		var id uint64
+		select {
+		case id = <-ids:
+		case done := <-store.reserves:
+			close(done)
+			t.Fatalf("expected NextID of ID %d not to wait for the store", want)
+		}
+		if id != want {
+			t.Fatalf("expected ID %d got %d", want, id)
+		}
+
+		if id%100 == 74 {
+			select {
+			case done := <-store.reserves:
+				close(done)
+			case <-time.After(time.Second):
+				t.Fatalf("expected ID %d to start a prefetch", id)
+			}
+		}
+	}
+}
+
+func TestSegmentGenerator_StoreFailure(t *testing.T) {
+	store := &testStore{}
+	sf := newGenerator(t, store, segment.WithStep(100))
+
+	// the store fails mid segment: the prefetch fails, the segment is
+	// still served
+	store.SetFail(true)
+	for want := uint64(0); want < 100; want++ {
+		if id, err := sf.NextIDErr(); err != nil || id != want {
+			t.Fatalf("expected ID %d got %d %v", want, id, err)
+		}
+	}
+	waitFor(t, func() bool { return sf.Err() != nil })
+	if err := sf.Err(); !errors.Is(err, errStore) {
+		t.Errorf("expected error %v got %v", errStore, err)
+	}
+
+	if _, err := sf.NextIDErr(); !errors.Is(err, errStore) {
+		t.Fatalf("expected error %v got %v", errStore, err)
+	}
+
+	// NextID waits for the store to come back
+	done := make(chan uint64)
+	go func() { done <- sf.NextID() }()
+	time.Sleep(20 * time.Millisecond)
+	store.SetFail(false)
+
+	select {
+	case id := <-done:
+		if id != 100 {
+			t.Errorf("expected ID 100 got %d", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected NextID to return once the store is back")
+	}
+	if err := sf.Err(); err != nil {
+		t.Errorf("expected no error once the store is back got %v", err)
+	}
+}
+
+func TestSegmentGenerator_Restart(t *testing.T) {
+	var store segment.MemoryStore
+
+	seen := make(map[uint64]struct{})
+	var last uint64
+	for restart := 0; restart < 5; restart++ {
+		sf, err := segment.New(context.Background(), &store, "orders", segment.WithStep(100))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 150; i++ {
+			id := sf.NextID()
+			if _, ok := seen[id]; ok {
+				t.Fatalf("expected unique IDs across restarts got %d twice", id)
+			}
+			if i == 0 && restart > 0 && id <= last {
+				t.Errorf("expected the first ID %d after a restart to be greater than %d", id, last)
+			}
+			seen[id] = struct{}{}
+			last = id
+		}
+		sf.Close()
+	}
+}
+
+func TestSegmentGenerator_Keys(t *testing.T) {
+	var store segment.MemoryStore
+	orders := newGenerator(t, &store, segment.WithStep(10))
+	users, err := segment.New(context.Background(), &store, "users", segment.WithStep(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer users.Close()
+
+	if a, b := orders.NextID(), users.NextID(); a != 0 || b != 0 {
+		t.Errorf("expected keys to have their own IDs got %d and %d", a, b)
+	}
+}
+
+func TestSegmentGenerator_Close(t *testing.T) {
+	var store segment.MemoryStore
+	sf := newGenerator(t, &store, segment.WithStep(10))
+	sf.NextID()
+	sf.Close()
+
+	for want := uint64(1); want < 10; want++ {
+		if id, err := sf.NextIDErr(); err != nil || id != want {
+			t.Fatalf("expected ID %d got %d %v", want, id, err)
+		}
+	}
+	if _, err := sf.NextIDErr(); !errors.Is(err, segment.ErrClosed) {
+		t.Errorf("expected error %v got %v", segment.ErrClosed, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected NextID to panic once closed")
+		}
+	}()
+	sf.NextID()
+}
+
+func TestNew_StoreFailure(t *testing.T) {
+	store := &testStore{fail: 1}
+	if _, err := segment.New(context.Background(), store, "orders"); !errors.Is(err, errStore) {
+		t.Errorf("expected error %v got %v", errStore, err)
+	}
+}
+
+func TestWithStep_Zero(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected WithStep(0) to panic")
+		}
+	}()
+	segment.WithStep(0)
+}
@@ -0,0 +1,101 @@
+package segment
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownKey is returned by SQLStore when the table has no row for a
+// key.
+var ErrUnknownKey = errors.New("segment: unknown key")
+
+// SegmentStore reserves segments of IDs shared by every generator of a key.
+type SegmentStore interface {
+	// Reserve atomically adds step to the max ID of key and returns the
+	// new max ID. The step IDs below it are the caller's alone.
+	Reserve(ctx context.Context, key string, step uint64) (maxID uint64, err error)
+}
+
+var (
+	_ SegmentStore = (*SQLStore)(nil)
+	_ SegmentStore = (*MemoryStore)(nil)
+)
+
+// SQLStore is a SegmentStore keeping the max IDs in a SQL table with one
+// row per key, for databases supporting UPDATE ... RETURNING, such as
+// PostgreSQL, SQLite and MariaDB:
+//
+//	CREATE TABLE snowflake_segments (
+//		name   VARCHAR(128) PRIMARY KEY,
+//		max_id BIGINT NOT NULL
+//	);
+//	INSERT INTO snowflake_segments (name, max_id) VALUES ('orders', 1);
+//
+// The max ID of a row is the first ID of the next segment, so starting it
+// at 1 keeps 0 from being issued.
+type SQLStore struct {
+	db    *sql.DB
+	table string
+	// QuestionMarks makes the query use ? placeholders, as MariaDB does,
+	// instead of PostgreSQL's $1 and $2. It is off by default.
+	QuestionMarks bool
+}
+
+// NewSQLStore returns a new segment.SQLStore keeping the max IDs in table
+// of db. The table name is used as is in the query.
+func NewSQLStore(db *sql.DB, table string) *SQLStore {
+	return &SQLStore{db: db, table: table}
+}
+
+// Query returns the query Reserve runs, with the step and the key as
+// arguments.
+func (s *SQLStore) Query() string {
+	if s.QuestionMarks {
+		return fmt.Sprintf("UPDATE %s SET max_id = max_id + ? WHERE name = ? RETURNING max_id", s.table)
+	}
+	return fmt.Sprintf("UPDATE %s SET max_id = max_id + $1 WHERE name = $2 RETURNING max_id", s.table)
+}
+
+// Reserve adds step to the max ID of the row of key. Returns an error
+// wrapping ErrUnknownKey if there is no row for key.
+func (s *SQLStore) Reserve(ctx context.Context, key string, step uint64) (uint64, error) {
+	var maxID uint64
+	err := s.db.QueryRowContext(ctx, s.Query(), int64(step), key).Scan(&maxID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("segment: no row for %q in %s: %w", key, s.table, ErrUnknownKey)
+	}
+	return maxID, err
+}
+
+// MemoryStore is a SegmentStore keeping the max IDs in memory, for tests
+// and single processes. Keys start at a max ID of 0. The zero value is
+// ready to use.
+type MemoryStore struct {
+	mtx    sync.Mutex
+	maxIDs map[string]uint64
+}
+
+// Reserve adds step to the max ID of key.
+func (s *MemoryStore) Reserve(ctx context.Context, key string, step uint64) (uint64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.maxIDs == nil {
+		s.maxIDs = make(map[string]uint64)
+	}
+	s.maxIDs[key] += step
+	return s.maxIDs[key], nil
+}
+
+// MaxID returns the max ID of key.
+func (s *MemoryStore) MaxID(key string) uint64 {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.maxIDs[key]
+}
@@ -0,0 +1,154 @@
+package segment_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake/segment"
+)
+
+// fakeDriver is a database/sql driver answering the query of SQLStore from
+// a map, recording the queries it ran.
+type fakeDriver struct {
+	mtx     sync.Mutex
+	maxIDs  map[string]int64
+	queries []string
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.d, query}, nil }
+func (c fakeConn) Close() error                              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return 2 }
+func (s fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mtx.Lock()
+	defer s.d.mtx.Unlock()
+	s.d.queries = append(s.d.queries, s.query)
+
+	key := args[1].(string)
+	maxID, ok := s.d.maxIDs[key]
+	if !ok {
+		return &fakeRows{}, nil
+	}
+	maxID += args[0].(int64)
+	s.d.maxIDs[key] = maxID
+	return &fakeRows{values: []int64{maxID}}, nil
+}
+
+type fakeRows struct{ values []int64 }
+
+func (r *fakeRows) Columns() []string { return []string{"max_id"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+var (
+	fakeDB     = &fakeDriver{maxIDs: map[string]int64{"orders": 1}}
+	registerDB sync.Once
+)
+
+func openFakeDB(t *testing.T) *sql.DB {
+	t.Helper()
+	registerDB.Do(func() { sql.Register("segmenttest", fakeDB) })
+	db, err := sql.Open("segmenttest", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLStore(t *testing.T) {
+	tc := []struct {
+		name          string
+		questionMarks bool
+		query         string
+	}{
+		{"Should use dollar placeholders", false, "UPDATE snowflake_segments SET max_id = max_id + $1 WHERE name = $2 RETURNING max_id"},
+		{"Should use question mark placeholders", true, "UPDATE snowflake_segments SET max_id = max_id + ? WHERE name = ? RETURNING max_id"},
+	}
+
+	db := openFakeDB(t)
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			store := segment.NewSQLStore(db, "snowflake_segments")
+			store.QuestionMarks = tt.questionMarks
+			if query := store.Query(); query != tt.query {
+				t.Errorf("expected query %q got %q", tt.query, query)
+			}
+
+			before := fakeDB.maxIDs["orders"]
+			maxID, err := store.Reserve(context.Background(), "orders", 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := uint64(before + 100); maxID != want {
+				t.Errorf("expected max ID %d got %d", want, maxID)
+			}
+			if query := fakeDB.queries[len(fakeDB.queries)-1]; query != tt.query {
+				t.Errorf("expected to run %q got %q", tt.query, query)
+			}
+		})
+	}
+}
+
+func TestSQLStore_UnknownKey(t *testing.T) {
+	store := segment.NewSQLStore(openFakeDB(t), "snowflake_segments")
+	if _, err := store.Reserve(context.Background(), "missing", 100); !errors.Is(err, segment.ErrUnknownKey) {
+		t.Errorf("expected error %v got %v", segment.ErrUnknownKey, err)
+	}
+}
+
+func TestSQLStore_Generator(t *testing.T) {
+	store := segment.NewSQLStore(openFakeDB(t), "snowflake_segments")
+	sf := newGenerator(t, store, segment.WithStep(10))
+
+	first := sf.NextID()
+	for i := 1; i < 25; i++ {
+		if id := sf.NextID(); id != first+uint64(i) {
+			t.Fatalf("expected ID %d got %d", first+uint64(i), id)
+		}
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	var store segment.MemoryStore
+	for i, want := range []uint64{10, 20, 30} {
+		if maxID, err := store.Reserve(context.Background(), "orders", 10); err != nil || maxID != want {
+			t.Errorf("reservation %d: expected max ID %d got %d %v", i, want, maxID, err)
+		}
+	}
+	if maxID := store.MaxID("users"); maxID != 0 {
+		t.Errorf("expected max ID 0 for a new key got %d", maxID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.Reserve(ctx, "orders", 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error %v got %v", context.Canceled, err)
+	}
+}
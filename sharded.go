@@ -0,0 +1,52 @@
+package snowflake
+
+import "sync/atomic"
+
+// ShardedGenerator spreads NextID calls across several generators, each
+// owning a distinct field value. With K shards, K*4096 IDs are available per
+// millisecond and lock contention is divided by K.
+//
+// IDs are unique across shards but only roughly time-ordered: two IDs issued
+// in the same millisecond by different shards are ordered by field, not by
+// call order.
+type ShardedGenerator struct {
+	next   uint64 // keep first for 64-bit alignment of atomic operations
+	shards []*ID
+}
+
+// NewSharded returns a new snowflake.ShardedGenerator with one shard per field.
+// Fields must be distinct and within range (max field value: 1023).
+// The options are applied to every shard.
+// Can return 3 errors: ErrNoFields, ErrFieldOutOfRange and ErrDuplicateField.
+func NewSharded(fields []uint64, opts ...Option) (*ShardedGenerator, error) {
+	if len(fields) == 0 {
+		return nil, ErrNoFields
+	}
+
+	seen := make(map[uint64]struct{}, len(fields))
+	shards := make([]*ID, len(fields))
+	for i, field := range fields {
+		if field > maxFieldBits {
+			return nil, ErrFieldOutOfRange
+		}
+
+		if _, ok := seen[field]; ok {
+			return nil, ErrDuplicateField
+		}
+		seen[field] = struct{}{}
+
+		shards[i] = New(field, opts...)
+	}
+
+	return &ShardedGenerator{shards: shards}, nil
+}
+
+// NextID returns a new snowflake ID from the next shard in round-robin order.
+// Only the selected shard is locked.
+func (s *ShardedGenerator) NextID() uint64 {
+	n := atomic.AddUint64(&s.next, 1) - 1
+	return s.shards[n%uint64(len(s.shards))].NextID()
+}
+
+// Len returns the number of shards.
+func (s *ShardedGenerator) Len() int { return len(s.shards) }
@@ -0,0 +1,81 @@
+package snowflake_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestNewSharded(t *testing.T) {
+	tc := []struct {
+		name   string
+		fields []uint64
+		err    error
+	}{
+		{"Should return ErrNoFields", nil, snowflake.ErrNoFields},
+		{"Should return ErrFieldOutOfRange", []uint64{1, 1024}, snowflake.ErrFieldOutOfRange},
+		{"Should return ErrDuplicateField", []uint64{1, 2, 1}, snowflake.ErrDuplicateField},
+		{"1,2,3", []uint64{1, 2, 3}, nil},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sg, err := snowflake.NewSharded(tt.fields)
+			if err != tt.err {
+				t.Errorf("expected error %v got %v", tt.err, err)
+			}
+
+			if err == nil && sg.Len() != len(tt.fields) {
+				t.Errorf("expected %d shards got %d", len(tt.fields), sg.Len())
+			}
+		})
+	}
+}
+
+func TestShardedGenerator_RoundRobin(t *testing.T) {
+	fields := []uint64{3, 7, 11}
+	sg, err := snowflake.NewSharded(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 30; i++ {
+		expected := fields[i%len(fields)]
+		if field := snowflake.Parse(sg.NextID()).MachineID; field != expected {
+			t.Errorf("expected field %d got %d", expected, field)
+		}
+	}
+}
+
+func TestShardedGenerator_Concurrent(t *testing.T) {
+	n := 100000
+	ch := make(chan uint64, n)
+	sg, err := snowflake.NewSharded([]uint64{1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ch <- sg.NextID()
+		}()
+	}
+	wg.Wait()
+	close(ch)
+
+	ids := make(map[uint64]bool)
+	for id := range ch {
+		if _, ok := ids[id]; ok {
+			t.Error("expected to be unique, but got a repeated ID")
+			break
+		}
+		ids[id] = true
+	}
+	if len(ids) != n {
+		t.Errorf("expected map length %d got %d", n, len(ids))
+	}
+}
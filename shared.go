@@ -0,0 +1,106 @@
+package snowflake
+
+import (
+	"encoding/binary"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+const (
+	// sharedSize is the size of the file of a SharedID: a magic number
+	// followed by the state. (internal-use only)
+	sharedSize = 16
+	// sharedStateOffset is where the state is in the file of a SharedID.
+	// (internal-use only)
+	sharedStateOffset = 8
+)
+
+// sharedMagic identifies the files of SharedID, version 1. (internal-use only)
+var sharedMagic = binary.LittleEndian.Uint64([]byte("sfshare1"))
+
+// SharedID is a snowflake ID generator whose state lives in a file mapped
+// into memory, so that several processes on one host, such as the workers
+// of a prefork server, issue IDs from a single sequence with the same field
+// and never issue the same ID twice.
+//
+// The state is a single word holding the Unix millisecond and sequence of
+// the last ID, updated with compare-and-swap: there are no locks to be left
+// held by a process dying while issuing an ID, and the file is valid at any
+// time, so processes can come and go. The file should be on a local file
+// system, ideally in memory such as /dev/shm or /run; it may be removed
+// once no process uses it anymore.
+//
+//	sf, err := snowflake.NewShared("/dev/shm/snowflake-orders", 1)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer sf.Close()
+//	id := sf.NextID()
+type SharedID struct {
+	state        *uint64
+	fieldSegment uint64
+	unmap        func() error
+}
+
+// NewShared returns a new snowflake.SharedID with the state in the file at
+// path, creating it if needed. Returns an error wrapping ErrFieldOutOfRange
+// if field is bigger than 1023, ErrInvalidState if path isn't the file of a
+// SharedID, and ErrSharedUnsupported on platforms without shared memory
+// mappings.
+//
+// The processes sharing the file must use the same epoch.
+func NewShared(path string, field uint64) (*SharedID, error) {
+	if field > maxFieldBits {
+		return nil, fmt.Errorf("snowflake: field %d: %w (max %d)", field, ErrFieldOutOfRange, maxFieldBits)
+	}
+
+	mem, unmap, err := mapShared(path)
+	if err != nil {
+		return nil, fmt.Errorf("snowflake: mapping %s: %w", path, err)
+	}
+
+	// the first process to map a new file, which is all zeros, marks it
+	magic := (*uint64)(unsafe.Pointer(&mem[0]))
+	if !atomic.CompareAndSwapUint64(magic, 0, sharedMagic) && atomic.LoadUint64(magic) != sharedMagic {
+		unmap()
+		return nil, fmt.Errorf("snowflake: %s isn't a shared generator file: %w", path, ErrInvalidState)
+	}
+
+	return &SharedID{
+		state:        (*uint64)(unsafe.Pointer(&mem[sharedStateOffset])),
+		fieldSegment: field << sequenceBits,
+		unmap:        unmap,
+	}, nil
+}
+
+// NextID returns a new snowflake ID, unique among all the processes sharing
+// the file of id. When the sequence of a millisecond is exhausted it waits
+// for the next one, yielding to the scheduler.
+func (id *SharedID) NextID() uint64 {
+	for {
+		last := atomic.LoadUint64(id.state)
+		ms, seq := int64(last>>sequenceBits), last&maxSeqBits
+
+		next := last + 1
+		if now := time.Now().UnixMilli(); now > ms {
+			next = uint64(now) << sequenceBits
+		} else if seq == maxSeqBits {
+			// never go back in time, wait for the clock to move on
+			runtime.Gosched()
+			continue
+		}
+
+		if atomic.CompareAndSwapUint64(id.state, last, next) {
+			elapsed := int64(next>>sequenceBits) - atomic.LoadInt64(&epochMillis)
+			return uint64(elapsed)<<(sequenceBits+fieldBits) | id.fieldSegment | next&maxSeqBits
+		}
+	}
+}
+
+// Close unmaps the file of id. id must not be used afterwards.
+func (id *SharedID) Close() error {
+	return id.unmap()
+}
@@ -0,0 +1,9 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package snowflake
+
+// mapShared always fails with ErrSharedUnsupported. (internal-use only)
+func mapShared(path string) ([]byte, func() error, error) {
+	return nil, nil, ErrSharedUnsupported
+}
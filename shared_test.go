@@ -0,0 +1,167 @@
+package snowflake_test
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// When set, the test binary acts as a separate process that issues
+// sharedChildIDs IDs from the shared generator at the path it holds and
+// prints them.
+const sharedChildEnv = "SNOWFLAKE_TEST_SHARED_PATH"
+
+const sharedChildIDs = 50000
+
+func TestMain(m *testing.M) {
+	if path := os.Getenv(sharedChildEnv); path != "" {
+		sf, err := snowflake.NewShared(path, 1)
+		if err != nil {
+			fmt.Println("error:", err)
+			os.Exit(1)
+		}
+		w := bufio.NewWriter(os.Stdout)
+		for i := 0; i < sharedChildIDs; i++ {
+			fmt.Fprintln(w, sf.NextID())
+		}
+		w.Flush()
+		sf.Close()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// newShared returns a shared generator in dir, skipping the test on
+// platforms that don't support them.
+func newShared(t *testing.T, path string) *snowflake.SharedID {
+	t.Helper()
+
+	sf, err := snowflake.NewShared(path, 1)
+	if errors.Is(err, snowflake.ErrSharedUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sf.Close() })
+	return sf
+}
+
+func TestNewShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snowflake")
+	a, b := newShared(t, path), newShared(t, path)
+
+	seen := make(map[uint64]bool)
+	var prev uint64
+	for i := 0; i < 3*4096; i++ {
+		sf := a
+		if i%3 == 0 {
+			sf = b
+		}
+		id := sf.NextID()
+		if seen[id] || id <= prev {
+			t.Fatalf("expected a new increasing ID, got %d after %d", id, prev)
+		}
+		seen[id] = true
+		prev = id
+
+		if sid := snowflake.Parse(id); sid.MachineID != 1 {
+			t.Fatalf("expected field 1 got %d", sid.MachineID)
+		}
+	}
+}
+
+func TestNewShared_Errors(t *testing.T) {
+	newShared(t, filepath.Join(t.TempDir(), "snowflake"))
+
+	if _, err := snowflake.NewShared(filepath.Join(t.TempDir(), "snowflake"), 1024); !errors.Is(err, snowflake.ErrFieldOutOfRange) {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldOutOfRange, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "other")
+	if err := os.WriteFile(path, []byte("not a shared generator"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snowflake.NewShared(path, 1); !errors.Is(err, snowflake.ErrInvalidState) {
+		t.Errorf("expected error %v got %v", snowflake.ErrInvalidState, err)
+	}
+}
+
+func TestNewShared_Processes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snowflake")
+	sf := newShared(t, path)
+
+	// processes and goroutines of this one racing to create the file and
+	// issue IDs
+	var mtx sync.Mutex
+	var issued [][]uint64
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+
+			cmd := exec.Command(os.Args[0], "-test.run=^$")
+			cmd.Env = append(os.Environ(), sharedChildEnv+"="+path)
+			out, err := cmd.Output()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			var ids []uint64
+			for _, line := range strings.Fields(string(out)) {
+				id, err := strconv.ParseUint(line, 10, 64)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				ids = append(ids, id)
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			issued = append(issued, ids)
+		}()
+		go func() {
+			defer wg.Done()
+
+			ids := make([]uint64, sharedChildIDs)
+			for i := range ids {
+				ids[i] = sf.NextID()
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			issued = append(issued, ids)
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[uint64]bool)
+	for _, ids := range issued {
+		if len(ids) != sharedChildIDs {
+			t.Fatalf("expected %d IDs got %d", sharedChildIDs, len(ids))
+		}
+		for i, id := range ids {
+			if seen[id] {
+				t.Fatalf("expected unique IDs, got %d twice", id)
+			}
+			seen[id] = true
+			if i > 0 && id <= ids[i-1] {
+				t.Fatalf("expected IDs to increase, got %d after %d", id, ids[i-1])
+			}
+		}
+	}
+}
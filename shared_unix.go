@@ -0,0 +1,39 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package snowflake
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapShared maps the file of a SharedID at path into memory, creating it
+// if needed, and returns the mapping with a function unmapping it.
+// (internal-use only)
+func mapShared(path string) ([]byte, func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the mapping outlives the file descriptor
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	// processes racing to create the file all grow it to the same size,
+	// which keeps what the first one may have written already
+	if info.Size() < sharedSize {
+		if err := f.Truncate(sharedSize); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	mem, err := syscall.Mmap(int(f.Fd()), 0, sharedSize, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mem, func() error { return syscall.Munmap(mem) }, nil
+}
@@ -0,0 +1,149 @@
+package snowflake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// sidJSON is the JSON form of SID and SID2, with a timestamp in either
+// milliseconds or RFC 3339. (internal-use only)
+type sidJSON struct {
+	Timestamp json.RawMessage
+	Sequence  uint64
+	MachineID *uint64
+	Field     uint64
+	Field1    uint64
+	Field2    uint64
+	Fallback  bool
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts the JSON encoding
+// of a SID, {"Timestamp":1640942460724,"Sequence":0,"MachineID":1}, with
+// the timestamp either in Unix milliseconds or an RFC 3339 string, and the
+// deprecated "Field" in place of "MachineID". Returns an error wrapping
+// ErrFieldOutOfRange if the machine ID is bigger than 1023, and
+// ErrInvalidID if the timestamp or sequence is invalid.
+func (sid *SID) UnmarshalJSON(data []byte) error {
+	var v sidJSON
+	timestamp, err := v.unmarshal(data)
+	if err != nil {
+		return err
+	}
+	if v.Fallback {
+		*sid = SID{Fallback: true}
+		return nil
+	}
+	machineID := v.Field
+	if v.MachineID != nil {
+		machineID = *v.MachineID
+	}
+	if machineID > maxFieldBits {
+		return fmt.Errorf("snowflake: machine ID %d is bigger than %d: %w", machineID, maxFieldBits, ErrFieldOutOfRange)
+	}
+	*sid = SID{Timestamp: timestamp, Sequence: v.Sequence, MachineID: machineID, Field: machineID}
+	return nil
+}
+
+// UnmarshalJSON is like (*SID).UnmarshalJSON, for the JSON encoding of a
+// SID2, {"Timestamp":1640942460724,"Sequence":0,"Field1":1,"Field2":0}.
+// Fields must be up to 31.
+func (sid *SID2) UnmarshalJSON(data []byte) error {
+	var v sidJSON
+	timestamp, err := v.unmarshal(data)
+	if err != nil {
+		return err
+	}
+	if v.Fallback {
+		*sid = SID2{Fallback: true}
+		return nil
+	}
+	if v.Field1 > maxFieldHalfBits || v.Field2 > maxFieldHalfBits {
+		return fmt.Errorf("snowflake: fields %d and %d, max %d: %w", v.Field1, v.Field2, maxFieldHalfBits, ErrFieldOutOfRange)
+	}
+	*sid = SID2{Timestamp: timestamp, Sequence: v.Sequence, Field1: v.Field1, Field2: v.Field2}
+	return nil
+}
+
+// unmarshal decodes data into v, returning its timestamp in Unix
+// milliseconds, or 0 for a fallback ID. (internal-use only)
+func (v *sidJSON) unmarshal(data []byte) (int64, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return 0, err
+	}
+	if v.Fallback {
+		return 0, nil
+	}
+	if v.Sequence > maxSeqBits {
+		return 0, fmt.Errorf("snowflake: sequence %d is bigger than %d: %w", v.Sequence, maxSeqBits, ErrInvalidID)
+	}
+
+	if len(v.Timestamp) == 0 || bytes.Equal(v.Timestamp, []byte("null")) {
+		return 0, fmt.Errorf("snowflake: missing timestamp: %w", ErrInvalidID)
+	}
+	if v.Timestamp[0] != '"' {
+		var ms int64
+		if err := json.Unmarshal(v.Timestamp, &ms); err != nil {
+			return 0, fmt.Errorf("snowflake: timestamp %s: %w", v.Timestamp, ErrInvalidID)
+		}
+		return ms, nil
+	}
+
+	var t time.Time
+	if err := json.Unmarshal(v.Timestamp, &t); err != nil {
+		return 0, fmt.Errorf("snowflake: timestamp %s: %w", v.Timestamp, ErrInvalidID)
+	}
+	if t.Nanosecond()%int(time.Millisecond) != 0 {
+		return 0, fmt.Errorf("snowflake: timestamp %s is finer than milliseconds: %w", v.Timestamp, ErrInvalidID)
+	}
+	return t.UnixMilli(), nil
+}
+
+// ToID returns the snowflake ID sid is the parsed representation of, under
+// the current epoch. Returns an error wrapping ErrBeforeEpoch or
+// ErrTimestampOverflow if the timestamp doesn't fit the layout,
+// ErrFieldOutOfRange if the machine ID is bigger than 1023 and
+// ErrInvalidID if the sequence is bigger than 4095 or sid is a fallback,
+// whose random bits Parse drops. A SID with only the deprecated Field set
+// uses it as the machine ID.
+func (sid SID) ToID() (uint64, error) {
+	if sid.Fallback {
+		return 0, fmt.Errorf("snowflake: can't rebuild a fallback ID: %w", ErrInvalidID)
+	}
+	machineID := sid.MachineID
+	if machineID == 0 {
+		machineID = sid.Field
+	}
+	if machineID > maxFieldBits {
+		return 0, fmt.Errorf("snowflake: machine ID %d is bigger than %d: %w", machineID, maxFieldBits, ErrFieldOutOfRange)
+	}
+	return toID(sid.Timestamp, machineID, sid.Sequence)
+}
+
+// ToID is like SID.ToID. Fields must be up to 31.
+func (sid SID2) ToID() (uint64, error) {
+	if sid.Fallback {
+		return 0, fmt.Errorf("snowflake: can't rebuild a fallback ID: %w", ErrInvalidID)
+	}
+	if sid.Field1 > maxFieldHalfBits || sid.Field2 > maxFieldHalfBits {
+		return 0, fmt.Errorf("snowflake: fields %d and %d, max %d: %w", sid.Field1, sid.Field2, maxFieldHalfBits, ErrFieldOutOfRange)
+	}
+	return toID(sid.Timestamp, sid.Field2<<(fieldBits/2)|sid.Field1, sid.Sequence)
+}
+
+// toID encodes the ID of a timestamp in Unix milliseconds, field and
+// sequence. (internal-use only)
+func toID(timestamp int64, field, sequence uint64) (uint64, error) {
+	elapsed := timestamp - atomic.LoadInt64(&epochMillis)
+	switch {
+	case elapsed < 0:
+		return 0, fmt.Errorf("snowflake: timestamp %d: %w", timestamp, ErrBeforeEpoch)
+	case elapsed > maxTimestamp:
+		return 0, fmt.Errorf("snowflake: timestamp %d: %w", timestamp, ErrTimestampOverflow)
+	case sequence > maxSeqBits:
+		return 0, fmt.Errorf("snowflake: sequence %d is bigger than %d: %w", sequence, maxSeqBits, ErrInvalidID)
+	}
+	return uint64(elapsed)<<(sequenceBits+fieldBits) | field<<sequenceBits | sequence, nil
+}
@@ -0,0 +1,160 @@
+package snowflake_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestSID_JSONRoundTrip(t *testing.T) {
+	// instants within the lifetime of the epoch, short of those of random
+	// fallback IDs, any field and sequence
+	property := func(ms uint64, low uint32) bool {
+		id := ms%(15<<37)<<22 | uint64(low)&(1<<22-1)
+
+		data, err := json.Marshal(snowflake.Parse(id))
+		if err != nil {
+			t.Logf("%d: %v", id, err)
+			return false
+		}
+		var sid snowflake.SID
+		if err := json.Unmarshal(data, &sid); err != nil {
+			t.Logf("%d: %s: %v", id, data, err)
+			return false
+		}
+		got, err := sid.ToID()
+		if err != nil {
+			t.Logf("%d: %v", id, err)
+			return false
+		}
+		return got == id
+	}
+
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSID2_JSONRoundTrip(t *testing.T) {
+	sf := snowflake.New2(7, 19)
+	ids := sf.AppendIDs(nil, 1000)
+
+	for _, i := range rand.Perm(len(ids))[:100] {
+		id := ids[i]
+		data, err := json.Marshal(snowflake.Parse2(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sid snowflake.SID2
+		if err := json.Unmarshal(data, &sid); err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		if got, err := sid.ToID(); err != nil || got != id {
+			t.Errorf("expected %d got %d (%v)", id, got, err)
+		}
+	}
+}
+
+func TestSID_UnmarshalJSON(t *testing.T) {
+	tc := []struct {
+		name     string
+		data     string
+		expected snowflake.SID
+	}{
+		{
+			"Should accept milliseconds",
+			`{"Timestamp":1640942460724,"Sequence":42,"MachineID":5}`,
+			snowflake.SID{Timestamp: 1640942460724, Sequence: 42, MachineID: 5, Field: 5},
+		},
+		{
+			"Should accept RFC 3339",
+			`{"Timestamp":"2021-12-31T09:21:00.724Z","Sequence":42,"MachineID":5}`,
+			snowflake.SID{Timestamp: 1640942460724, Sequence: 42, MachineID: 5, Field: 5},
+		},
+		{
+			"Should accept RFC 3339 with an offset",
+			`{"timestamp":"2021-12-31T10:21:00.724+01:00","sequence":42,"machineID":5}`,
+			snowflake.SID{Timestamp: 1640942460724, Sequence: 42, MachineID: 5, Field: 5},
+		},
+		{
+			"Should accept the deprecated field",
+			`{"Timestamp":1640942460724,"Sequence":42,"Field":5}`,
+			snowflake.SID{Timestamp: 1640942460724, Sequence: 42, MachineID: 5, Field: 5},
+		},
+		{
+			"Should prefer the machine ID to the deprecated field",
+			`{"Timestamp":1640942460724,"Sequence":42,"MachineID":0,"Field":5}`,
+			snowflake.SID{Timestamp: 1640942460724, Sequence: 42},
+		},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var sid snowflake.SID
+			if err := json.Unmarshal([]byte(tt.data), &sid); err != nil {
+				t.Fatal(err)
+			}
+			if sid != tt.expected {
+				t.Errorf("expected %+v got %+v", tt.expected, sid)
+			}
+		})
+	}
+}
+
+func TestSID_UnmarshalJSON_Errors(t *testing.T) {
+	tc := []struct {
+		name     string
+		data     string
+		expected error
+	}{
+		{"Should reject a machine ID above 1023", `{"Timestamp":1640942460724,"MachineID":1024}`, snowflake.ErrFieldOutOfRange},
+		{"Should reject a deprecated field above 1023", `{"Timestamp":1640942460724,"Field":1024}`, snowflake.ErrFieldOutOfRange},
+		{"Should reject a sequence above 4095", `{"Timestamp":1640942460724,"Sequence":4096}`, snowflake.ErrInvalidID},
+		{"Should reject a missing timestamp", `{"MachineID":1}`, snowflake.ErrInvalidID},
+		{"Should reject a malformed time", `{"Timestamp":"yesterday"}`, snowflake.ErrInvalidID},
+		{"Should reject a sub-millisecond time", `{"Timestamp":"2021-12-31T09:21:00.7241Z"}`, snowflake.ErrInvalidID},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			var sid snowflake.SID
+			if err := json.Unmarshal([]byte(tt.data), &sid); !errors.Is(err, tt.expected) {
+				t.Errorf("expected error %v got %v", tt.expected, err)
+			}
+		})
+	}
+
+	var sid snowflake.SID2
+	if err := json.Unmarshal([]byte(`{"Timestamp":1640942460724,"Field1":1,"Field2":32}`), &sid); !errors.Is(err, snowflake.ErrFieldOutOfRange) {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldOutOfRange, err)
+	}
+}
+
+func TestSID_ToID_Errors(t *testing.T) {
+	epoch := snowflake.Epoch().UnixMilli()
+
+	tc := []struct {
+		name     string
+		sid      snowflake.SID
+		expected error
+	}{
+		{"Should return ErrBeforeEpoch", snowflake.SID{Timestamp: epoch - 1}, snowflake.ErrBeforeEpoch},
+		{"Should return ErrTimestampOverflow", snowflake.SID{Timestamp: epoch + 1<<41}, snowflake.ErrTimestampOverflow},
+		{"Should return ErrFieldOutOfRange", snowflake.SID{Timestamp: epoch, MachineID: 1024}, snowflake.ErrFieldOutOfRange},
+		{"Should return ErrFieldOutOfRange for the deprecated field", snowflake.SID{Timestamp: epoch, Field: 1024}, snowflake.ErrFieldOutOfRange},
+		{"Should return ErrInvalidID", snowflake.SID{Timestamp: epoch, Sequence: 4096}, snowflake.ErrInvalidID},
+		{"Should return ErrInvalidID for a fallback", snowflake.SID{Fallback: true}, snowflake.ErrInvalidID},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.sid.ToID(); !errors.Is(err, tt.expected) {
+				t.Errorf("expected error %v got %v", tt.expected, err)
+			}
+		})
+	}
+}
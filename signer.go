@@ -0,0 +1,99 @@
+package snowflake
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// macSize is the size of the truncated HMAC-SHA256 of a token.
+// (internal-use only)
+const macSize = 16
+
+// tokenLen is the length of a token: the base64url of an ID and its MAC.
+// (internal-use only)
+var tokenLen = base64.RawURLEncoding.EncodedLen(8 + macSize)
+
+// Signer turns snowflake IDs into opaque tokens safe to expose publicly,
+// and back. A token is the unpadded base64url of the ID followed by its
+// HMAC-SHA256 truncated to 128 bits, so it can't be forged or guessed from
+// other tokens. It still reveals the ID to whoever decodes it.
+//
+// A Signer signs with its first key and verifies with any of them, so keys
+// can be rotated by putting the new key first and dropping the old one
+// once its tokens are no longer in use.
+type Signer struct {
+	keys [][]byte
+
+	// MaxAge, if positive, is the age after which the ID of a token is
+	// rejected by Verify.
+	MaxAge time.Duration
+	// Clock is the clock MaxAge is measured on. Defaults to the system
+	// clock.
+	Clock Clock
+}
+
+// NewSigner returns a new snowflake.Signer signing with key and verifying
+// with key and the retired keys old. Panics if a key is empty.
+func NewSigner(key []byte, old ...[]byte) *Signer {
+	keys := append([][]byte{key}, old...)
+	for _, k := range keys {
+		if len(k) == 0 {
+			panic("snowflake: empty signing key")
+		}
+	}
+	return &Signer{keys: keys}
+}
+
+// Sign returns the token of id.
+func (s *Signer) Sign(id uint64) string {
+	b := make([]byte, 8, 8+macSize)
+	binary.BigEndian.PutUint64(b, id)
+	return base64.RawURLEncoding.EncodeToString(mac(s.keys[0], b))
+}
+
+// Verify returns the ID of token. Returns an error wrapping
+// ErrInvalidToken if the token is malformed or wasn't signed by any of the
+// keys of s, and ErrTokenExpired if its ID is older than s.MaxAge.
+func (s *Signer) Verify(token string) (uint64, error) {
+	if len(token) != tokenLen {
+		return 0, fmt.Errorf("snowflake: token is %d characters, expected %d: %w", len(token), tokenLen, ErrInvalidToken)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, fmt.Errorf("snowflake: malformed token: %w", ErrInvalidToken)
+	}
+
+	valid := false
+	for _, key := range s.keys {
+		if hmac.Equal(mac(key, b[:8:8])[8:], b[8:]) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return 0, fmt.Errorf("snowflake: token signature mismatch: %w", ErrInvalidToken)
+	}
+
+	id := binary.BigEndian.Uint64(b)
+	if s.MaxAge > 0 {
+		now := time.Now()
+		if s.Clock != nil {
+			now = s.Clock.Now()
+		}
+		if age := now.Sub(time.UnixMilli(getTimestamp(id))); age > s.MaxAge {
+			return 0, fmt.Errorf("snowflake: token is %s old: %w", age, ErrTokenExpired)
+		}
+	}
+	return id, nil
+}
+
+// mac appends the truncated HMAC of b under key to b. (internal-use only)
+func mac(key, b []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(b)
+	return append(b, h.Sum(nil)[:macSize]...)
+}
@@ -0,0 +1,106 @@
+package snowflake_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestSigner(t *testing.T) {
+	s := snowflake.NewSigner([]byte("secret"))
+	id := snowflake.New(1).NextID()
+
+	token := s.Sign(id)
+	if len(token) != 32 || strings.ContainsAny(token, "+/=") {
+		t.Errorf("expected 32 base64url characters got %q", token)
+	}
+
+	got, err := s.Verify(token)
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	if got != id {
+		t.Errorf("expected %d got %d", id, got)
+	}
+
+	if other := snowflake.NewSigner([]byte("other")).Sign(id); other == token {
+		t.Errorf("expected tokens of another key to differ got %q", other)
+	}
+}
+
+func TestSigner_Invalid(t *testing.T) {
+	s := snowflake.NewSigner([]byte("secret"))
+	token := s.Sign(snowflake.New(1).NextID())
+
+	tamper := func(i int) string {
+		b, _ := base64.RawURLEncoding.DecodeString(token)
+		b[i] ^= 1
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	tc := []struct {
+		name  string
+		token string
+	}{
+		{"Should reject a tampered payload", tamper(7)},
+		{"Should reject a tampered MAC", tamper(20)},
+		{"Should reject another key", snowflake.NewSigner([]byte("other")).Sign(42)},
+		{"Should reject a short token", token[:31]},
+		{"Should reject a long token", token + "A"},
+		{"Should reject an empty token", ""},
+		{"Should reject padding", token[:30] + "=="},
+		{"Should reject standard base64", strings.Repeat("+", 32)},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Verify(tt.token); !errors.Is(err, snowflake.ErrInvalidToken) {
+				t.Errorf("expected error %v got %v", snowflake.ErrInvalidToken, err)
+			}
+		})
+	}
+}
+
+func TestSigner_Rotation(t *testing.T) {
+	id := snowflake.New(1).NextID()
+	oldToken := snowflake.NewSigner([]byte("v1")).Sign(id)
+
+	s := snowflake.NewSigner([]byte("v2"), []byte("v1"))
+	if got, err := s.Verify(oldToken); err != nil || got != id {
+		t.Errorf("expected %d got %d (%v)", id, got, err)
+	}
+
+	newToken := s.Sign(id)
+	if newToken != snowflake.NewSigner([]byte("v2")).Sign(id) {
+		t.Error("expected to sign with the first key")
+	}
+
+	retired := snowflake.NewSigner([]byte("v2"))
+	if _, err := retired.Verify(oldToken); !errors.Is(err, snowflake.ErrInvalidToken) {
+		t.Errorf("expected error %v got %v", snowflake.ErrInvalidToken, err)
+	}
+}
+
+func TestSigner_MaxAge(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start)
+	s := snowflake.NewSigner([]byte("secret"))
+	s.MaxAge = time.Minute
+	s.Clock = clock
+
+	token := s.Sign(snowflake.New(1, snowflake.WithClock(newFakeClock(start))).NextID())
+
+	clock.Set(start.Add(time.Minute))
+	if _, err := s.Verify(token); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+
+	clock.Set(start.Add(time.Minute + time.Millisecond))
+	if _, err := s.Verify(token); !errors.Is(err, snowflake.ErrTokenExpired) {
+		t.Errorf("expected error %v got %v", snowflake.ErrTokenExpired, err)
+	}
+}
@@ -0,0 +1,71 @@
+package snowflake
+
+import (
+	"sync"
+	"time"
+)
+
+// SkewEstimator estimates the clock offset of a remote generator from the
+// IDs it sends, e.g. to alert when a partner's clock drifts.
+//
+// For each ID, the delay between its timestamp and its receipt is the
+// network latency minus the offset of the remote clock. Latency is never
+// negative, so the smallest delay over a window of recent IDs approximates
+// the offset, give or take the least latency in the window. The more IDs
+// are observed, the closer that least latency gets to the true minimum.
+//
+// A SkewEstimator is safe for concurrent use.
+type SkewEstimator struct {
+	mtx    sync.Mutex
+	delays []int64 // ring buffer of the last delays, in ms
+	next   int
+	full   bool
+}
+
+// NewSkewEstimator returns a new snowflake.SkewEstimator over the last
+// window observations. A window less than 1 is treated as 1.
+func NewSkewEstimator(window int) *SkewEstimator {
+	if window < 1 {
+		window = 1
+	}
+	return &SkewEstimator{delays: make([]int64, window)}
+}
+
+// Observe records that the snowflake ID id, issued under the current
+// epoch, was received at receivedAt by the local clock.
+func (s *SkewEstimator) Observe(id uint64, receivedAt time.Time) {
+	delay := receivedAt.UnixMilli() - getTimestamp(id)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.delays[s.next] = delay
+	if s.next++; s.next == len(s.delays) {
+		s.next, s.full = 0, true
+	}
+}
+
+// Estimate returns the estimated offset of the remote clock: positive if
+// it's ahead of the local clock, negative if it's behind. It's accurate to
+// the millisecond at best and overestimates how far behind the remote
+// clock is by the least latency observed.
+// ok is false until an ID is observed.
+func (s *SkewEstimator) Estimate() (offset time.Duration, ok bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	n := s.next
+	if s.full {
+		n = len(s.delays)
+	}
+	if n == 0 {
+		return 0, false
+	}
+
+	least := s.delays[0]
+	for _, delay := range s.delays[1:n] {
+		if delay < least {
+			least = delay
+		}
+	}
+	return -time.Duration(least) * time.Millisecond, true
+}
@@ -0,0 +1,86 @@
+package snowflake_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestSkewEstimator(t *testing.T) {
+	tc := []struct {
+		name   string
+		offset time.Duration
+	}{
+		{"Should estimate a remote clock ahead", 1500 * time.Millisecond},
+		{"Should estimate a remote clock behind", -800 * time.Millisecond},
+		{"Should estimate a synchronized clock", 0},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			rng := rand.New(rand.NewSource(1))
+			start := snowflake.Epoch().Add(time.Hour)
+			remote := &tickingClock{t: start.Add(tt.offset), step: 10 * time.Millisecond}
+			sf := snowflake.New(1, snowflake.WithClock(remote))
+			est := snowflake.NewSkewEstimator(100)
+
+			if _, ok := est.Estimate(); ok {
+				t.Fatal("expected no estimate before any observation")
+			}
+
+			// latencies of 5 to 55ms, 1 in 10 of at most 6ms
+			var errAfter10, errAfter500 time.Duration
+			for i := 0; i < 500; i++ {
+				id := sf.NextID()
+				latency := time.Duration(5+rng.Intn(50)) * time.Millisecond
+				if i%10 == 0 {
+					latency = time.Duration(5+rng.Intn(2)) * time.Millisecond
+				}
+				sent := time.UnixMilli(snowflake.Parse(id).Timestamp).Add(-tt.offset)
+				est.Observe(id, sent.Add(latency))
+
+				estimate, ok := est.Estimate()
+				if !ok {
+					t.Fatal("expected an estimate")
+				}
+				if i == 10 {
+					errAfter10 = tt.offset - estimate
+				}
+				errAfter500 = tt.offset - estimate
+			}
+
+			if errAfter500 < 5*time.Millisecond || errAfter500 > 6*time.Millisecond {
+				t.Errorf("expected an error of 5 to 6ms (the least latency) got %v", errAfter500)
+			}
+			if errAfter10 < errAfter500 {
+				t.Errorf("expected the error to shrink from %v got %v", errAfter10, errAfter500)
+			}
+		})
+	}
+}
+
+func TestSkewEstimator_Window(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	est := snowflake.NewSkewEstimator(3)
+
+	// a remote clock 1s ahead, then synchronized
+	observe := func(at time.Time, offset time.Duration) {
+		id := uint64(at.Add(offset).Sub(snowflake.Epoch()).Milliseconds()) << 22
+		est.Observe(id, at)
+	}
+	for i := 0; i < 3; i++ {
+		observe(start.Add(time.Duration(i)*time.Second), time.Second)
+	}
+	if offset, _ := est.Estimate(); offset != time.Second {
+		t.Errorf("expected offset %v got %v", time.Second, offset)
+	}
+
+	for i := 3; i < 6; i++ {
+		observe(start.Add(time.Duration(i)*time.Second), 0)
+	}
+	if offset, _ := est.Estimate(); offset != 0 {
+		t.Errorf("expected the old observations to roll out of the window got offset %v", offset)
+	}
+}
@@ -2,25 +2,169 @@ package snowflake
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"os"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const (
+	cacheLineSize    = 64
 	fieldBits        = 10
 	sequenceBits     = 12
-	maxFieldBits     = 0x3FF // 0x3FF shorthand for (1 << fieldBits) - 1 or 1023
-	maxFieldHalfBits = 0x1F  // 0x1F shorthand for (1 << (fieldBits / 2)) - 1 or 31
-	maxSeqBits       = 0xFFF // 0xFFF shorthand for (1 << sequenceBits) - 1 or 4095
+	maxFieldBits     = 0x3FF         // 0x3FF shorthand for (1 << fieldBits) - 1 or 1023
+	maxFieldHalfBits = 0x1F          // 0x1F shorthand for (1 << (fieldBits / 2)) - 1 or 31
+	maxSeqBits       = 0xFFF         // 0xFFF shorthand for (1 << sequenceBits) - 1 or 4095
+	maxTimestamp     = 0x1FFFFFFFFFF // 0x1FFFFFFFFFF shorthand for (1 << 41) - 1, the last millisecond whose IDs fit an int64
 )
 
 var (
 	epoch = time.Date(2012, 3, 28, 0, 0, 0, 0, time.UTC)
+	// epochMillis caches epoch in milliseconds since the Unix epoch so the
+	// hot path doesn't need to do time.Time arithmetic. Accessed atomically.
+	epochMillis = epoch.UnixMilli()
 
 	// ErrEpochIsZero is returned when the epoch is set to the zero time.
 	ErrEpochIsZero = errors.New("epoch is zero")
 	// ErrEpochFuture is returned when the epoch is in the future.
 	ErrEpochFuture = errors.New("epoch is in the future")
+	// ErrNoFields is returned when a generator is given an empty set of fields.
+	ErrNoFields = errors.New("no fields provided")
+	// ErrFieldOutOfRange is returned when a field value exceeds the max field value.
+	ErrFieldOutOfRange = errors.New("field is out of range")
+	// ErrDuplicateField is returned when the same field value is provided more than once.
+	ErrDuplicateField = errors.New("duplicate field")
+	// ErrRateLimited is returned when a generator's rate limit is exhausted.
+	ErrRateLimited = errors.New("rate limit exceeded")
+	// ErrNoSuitableInterface is returned when no network interface has an
+	// address a machine ID can be derived from.
+	ErrNoSuitableInterface = errors.New("no suitable network interface")
+	// ErrNoNumericSuffix is returned when a name doesn't end with a number.
+	ErrNoNumericSuffix = errors.New("name has no numeric suffix")
+	// ErrMachineIDUnset is returned when the machine ID environment variable is not set.
+	ErrMachineIDUnset = errors.New("machine ID is not set")
+	// ErrMachineIDNotNumeric is returned when a machine ID is not a decimal number.
+	ErrMachineIDNotNumeric = errors.New("machine ID is not a decimal number")
+	// ErrNotOnAWS is returned when the AWS instance metadata can't be reached.
+	ErrNotOnAWS = errors.New("not running on AWS")
+	// ErrNotOnGCP is returned when the GCP metadata server can't be reached.
+	ErrNotOnGCP = errors.New("not running on GCP")
+	// ErrNoUniqueField is returned when every random field tried was rejected.
+	ErrNoUniqueField = errors.New("no unique field found")
+	// ErrNotInContainer is returned when no container ID can be found.
+	ErrNotInContainer = errors.New("not running in a container")
+	// ErrEmptyName is returned when a generator is named with an empty string.
+	ErrEmptyName = errors.New("name is empty")
+	// ErrFieldInUse is returned when a field is already registered by a live
+	// generator in this process.
+	ErrFieldInUse = errors.New("field is already in use")
+	// ErrUnknownField is returned when a pool is asked for a field outside its set.
+	ErrUnknownField = errors.New("field is not in the pool")
+	// ErrEmptyBlock is returned when a block fetched by a BlockConsumer holds no IDs.
+	ErrEmptyBlock = errors.New("block is empty")
+	// ErrUnknownEncoding is returned when an encoding name isn't recognized.
+	ErrUnknownEncoding = errors.New("unknown encoding")
+	// ErrInvalidID is returned when a string isn't a valid encoded snowflake ID.
+	ErrInvalidID = errors.New("invalid snowflake ID")
+	// ErrInvalidState is returned when a saved generator state is corrupted
+	// or of an unknown version.
+	ErrInvalidState = errors.New("invalid generator state")
+	// ErrEpochMismatch is returned when a saved generator state was saved
+	// under another epoch.
+	ErrEpochMismatch = errors.New("epoch mismatch")
+	// ErrStateInFuture is returned when a saved generator state is ahead of
+	// the clock.
+	ErrStateInFuture = errors.New("generator state is in the future")
+	// ErrFieldMismatch is returned when a stored generator state is for
+	// another field.
+	ErrFieldMismatch = errors.New("field mismatch")
+	// ErrLayoutMismatch is returned when an encoded generator has another
+	// bit layout than this package's.
+	ErrLayoutMismatch = errors.New("layout mismatch")
+	// ErrBeforeEpoch is returned when an instant is before the epoch it's
+	// encoded under.
+	ErrBeforeEpoch = errors.New("instant is before the epoch")
+	// ErrClockBehind is reported by Health when the clock is behind the
+	// last ID issued.
+	ErrClockBehind = errors.New("clock is behind the last ID")
+	// ErrTimestampOverflow is reported by Health, and returned by
+	// NextIDErr, when the timestamp no longer fits its 41 bits.
+	ErrTimestampOverflow = errors.New("timestamp overflows")
+	// ErrLifetimeLow is reported by Health when the time left until the
+	// timestamp overflows is below the floor set with WithHealthThresholds.
+	ErrLifetimeLow = errors.New("lifetime is running out")
+	// ErrFieldReleased is reported by Health when the field of a generator
+	// was released.
+	ErrFieldReleased = errors.New("field was released")
+	// ErrLeaseLost is reported by Health when the field lease of a
+	// generator was lost.
+	ErrLeaseLost = errors.New("field lease was lost")
+	// ErrInvalidToken is returned when a token fails verification by a
+	// Signer.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrTokenExpired is returned when the ID of a token is older than the
+	// MaxAge of a Signer.
+	ErrTokenExpired = errors.New("token expired")
+	// ErrKeyTooShort is returned when a key is too short to be secure.
+	ErrKeyTooShort = errors.New("key is too short")
+	// ErrBucketFull is returned when a Redactor has issued all the IDs of
+	// a timestamp bucket.
+	ErrBucketFull = errors.New("redaction bucket is full")
+	// ErrNoPlausibleEpoch is returned when an ID has no plausible timestamp
+	// under any of the epochs of a MultiEpochParser.
+	ErrNoPlausibleEpoch = errors.New("no plausible epoch")
+	// ErrAmbiguousEpoch is returned when an ID has a plausible timestamp
+	// under several of the epochs of a MultiEpochParser.
+	ErrAmbiguousEpoch = errors.New("ambiguous epoch")
+	// ErrVersionMismatch is returned when an ID is tagged with another
+	// layout version than expected.
+	ErrVersionMismatch = errors.New("layout version mismatch")
+	// ErrNoPlausibleLayout is returned when an ID is implausible under all
+	// the layouts it's parsed with.
+	ErrNoPlausibleLayout = errors.New("no plausible layout")
+	// ErrAmbiguousLayout is returned when an ID is as plausible under
+	// several of the layouts it's parsed with.
+	ErrAmbiguousLayout = errors.New("ambiguous layout")
+	// ErrClockSkew is returned when an observed ID is further ahead of the
+	// clock than a generator may drift.
+	ErrClockSkew = errors.New("observed ID is too far ahead of the clock")
+	// ErrSharedUnsupported is returned by NewShared on platforms without
+	// shared memory mappings.
+	ErrSharedUnsupported = errors.New("shared generators are not supported on this platform")
+	// ErrInvalidRange is returned when a time range is empty or its step
+	// is too small.
+	ErrInvalidRange = errors.New("invalid time range")
+	// ErrNotSorted is returned when IDs expected in ascending order aren't.
+	ErrNotSorted = errors.New("IDs are not sorted")
+	// ErrCorruptData is returned when compressed IDs are truncated or
+	// corrupted.
+	ErrCorruptData = errors.New("corrupt compressed IDs")
+	// ErrNoFreeField is returned when every field is leased.
+	ErrNoFreeField = errors.New("no free field")
+	// ErrUnknownMachine is returned when an ID is of a machine ID that
+	// isn't allowed.
+	ErrUnknownMachine = errors.New("unknown machine ID")
+	// ErrImplausibleTime is returned when an ID couldn't have been issued
+	// at its timestamp.
+	ErrImplausibleTime = errors.New("implausible timestamp")
+	// ErrClockMovedBackwards is returned by NextIDErr when the clock is
+	// behind where it was when the last ID was issued.
+	ErrClockMovedBackwards = errors.New("clock moved backwards")
+	// ErrSequenceExhausted is returned by NextIDErr when the sequence of
+	// the millisecond is used up.
+	ErrSequenceExhausted = errors.New("sequence is exhausted")
+	// ErrFieldUnassigned is returned by NextIDErr when the generator has
+	// no field to issue IDs of.
+	ErrFieldUnassigned = errors.New("field is unassigned")
+	// ErrNTPUnavailable is returned by NTPClock.Sync when no NTP server
+	// answered.
+	ErrNTPUnavailable = errors.New("NTP servers unavailable")
+	// ErrClockOffset is reported by Health when the local clock is further
+	// off the NTP servers of an NTPClock than allowed.
+	ErrClockOffset = errors.New("clock is off the NTP servers")
 )
 
 // Epoch returns the current configured epoch.
@@ -32,6 +176,9 @@ var (
 //	err := snowflake.SetEpoch(time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC))
 func Epoch() time.Time { return epoch }
 
+// MaxField returns the max field value of an ID (1023).
+func MaxField() uint64 { return maxFieldBits }
+
 // SetEpoch changes the epoch / starting time to a custom time.
 // Can return 2 errors: ErrEpochIsZero and ErrEpochFuture.
 // If the epoch is set to the zero time, ErrEpochIsZero is returned.
@@ -48,93 +195,363 @@ func SetEpoch(e time.Time) error {
 	}
 
 	epoch = e
+	atomic.StoreInt64(&epochMillis, e.UnixMilli())
 
 	return nil
 }
 
-// ID is a custom type for a snowflake ID.
-type ID struct {
-	mtx         sync.Mutex
-	field       uint64
-	sequence    uint64
-	elapsedTime int64
-	lastID      uint64
+// generator holds the sequence state shared by ID and ID2. (internal-use only)
+type generator struct {
+	mtx              sync.Mutex
+	fieldSegment     uint64 // precomputed at construction, only changed by UnmarshalBinary, rollField and setField
+	sequence         uint64
+	elapsedTime      int64
+	clock            Clock        // nil means the system clock
+	limiter          *rateLimiter // nil means unlimited
+	checkEvery       int          // 0 means read the clock for every ID
+	sinceCheck       int          // IDs issued since the clock was last read
+	maxDrift         int64        // how many milliseconds timestamps may lead the clock
+	tick             int64        // clock granularity in milliseconds, 0 means 1
+	waitTuned        bool         // whether spinWait and yieldWait replace the defaults
+	spinWait         time.Duration
+	yieldWait        time.Duration
+	lastNow          int64 // last clock reading, to detect regressions
+	counters         Stats
+	anomalies        *anomalyLog // nil means anomalies aren't logged
+	toleranceSet     bool        // whether restoreTolerance replaces the default
+	restoreTolerance time.Duration
+	store            StateStore // nil means the state isn't persisted
+	onExhausted      *hook      // nil means no callback
+	onBackwards      *hook      // nil means no callback
+	clockTolerance   int64      // how many milliseconds the clock may lag behind in Health
+	minRemaining     time.Duration
+	leaseDone        <-chan struct{}  // nil means no lease
+	warmupStore      StateStore       // nil means no warmup
+	ready            chan struct{}    // closed once warm, nil means no warmup
+	versionTag       uint64           // 0 means untagged
+	utilization      *utilization     // nil means untracked
+	capacityWarning  *capacityWarning // nil means no warning
+	observed         int64            // millisecond to issue from at the earliest, set by Observe
+	randomFallback   bool             // whether to issue random IDs while the clock is unusable
+	entropy          bool             // whether the field is random bits drawn every millisecond
+	leasePolicy      LeasePolicy      // what to do once the lease of NewLeased is lost
+	leaseHeld        chan struct{}    // closed while the lease of NewLeased is held, nil means no lease
+	fieldLow         bool             // whether the field is below the sequence, see WithSegmentOrder
+	foldFields       bool             // whether New folds a field out of range, see WithFieldFolding
+
+	// keep the fields above on their own cache line when several
+	// generators sit next to each other in memory (e.g. a []ID)
+	_ [cacheLineSize]byte
 }
 
-// New returns a new snowflake.ID (max field value: 1023)
-func New(field uint64) *ID {
-	return &ID{field: field, lastID: 0}
+// nextID returns a new snowflake ID using the precomputed field segment,
+// waiting for the rate limiter if there is one. (internal-use only)
+func (g *generator) nextID() uint64 {
+	g.mtx.Lock()
+
+	if g.limiter != nil {
+		if wait := g.limiter.reserveN(g.wallNow(), 1); wait > 0 {
+			g.mtx.Unlock()
+			time.Sleep(wait)
+			g.mtx.Lock()
+		}
+	}
+
+	id := g.generate()
+
+	g.mtx.Unlock()
+
+	return id
 }
 
-// NextID returns a new snowflake ID.
-//
-//	Format:
-//	1011001001101101011001010111100000001011111111111000000000001
-//	|--------------timestamp--------------|--disc---|----seq----|
-func (id *ID) NextID() uint64 {
-	id.mtx.Lock()
-	defer id.mtx.Unlock()
+// appendIDs appends n new snowflake IDs to dst under a single lock.
+// (internal-use only)
+func (g *generator) appendIDs(dst []uint64, n int) []uint64 {
+	if n <= 0 {
+		return dst
+	}
+
+	g.mtx.Lock()
+
+	if g.limiter != nil {
+		if wait := g.limiter.reserveN(g.wallNow(), n); wait > 0 {
+			g.mtx.Unlock()
+			time.Sleep(wait)
+			g.mtx.Lock()
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		dst = append(dst, g.generate())
+	}
+
+	g.mtx.Unlock()
 
-	nowSinceEpoch := msSinceEpoch()
+	return dst
+}
+
+// tryNextID is like nextID but fails with ErrRateLimited instead of waiting
+// for the rate limiter. (internal-use only)
+func (g *generator) tryNextID() (uint64, error) {
+	g.mtx.Lock()
+
+	if g.limiter != nil && !g.limiter.allow(g.wallNow()) {
+		g.mtx.Unlock()
+		return 0, ErrRateLimited
+	}
+
+	id := g.generate()
+
+	g.mtx.Unlock()
+
+	return id, nil
+}
+
+// generate returns a new snowflake ID. g.mtx must be held. (internal-use only)
+func (g *generator) generate() uint64 {
+	g.awaitLease()
+
+	if g.sinceCheck > 0 && g.sinceCheck < g.checkEvery && g.sequence < maxSeqBits {
+		// the sequence has headroom, reuse the last timestamp without
+		// reading the clock
+		g.sinceCheck++
+		g.sequence++
+		g.counters.IDs++
+		g.trackSequence()
+		return g.compose()
+	}
+
+	if g.checkEvery > 0 {
+		g.sinceCheck = 1
+	}
+
+	return g.generateAt(g.now())
+}
+
+// generateAt returns a new snowflake ID for a time already read from the
+// clock, in milliseconds since the epoch. g.mtx must be held. (internal-use only)
+func (g *generator) generateAt(nowSinceEpoch int64) uint64 {
+	if g.randomFallback {
+		if reason := g.unusableClock(nowSinceEpoch); reason != "" {
+			return g.fallbackID(reason)
+		}
+	}
+
+	timestamp := nowSinceEpoch
+	prev := g.elapsedTime
+	last := g.sequence
+
+	g.counters.IDs++
+	if nowSinceEpoch < g.lastNow {
+		regression := time.Duration(g.lastNow-nowSinceEpoch) * time.Millisecond
+		g.counters.ClockRegressions++
+		g.report(anomalyClockBackwards, "snowflake: clock moved backwards", g.fieldSegment>>sequenceBits,
+			"regression", regression)
+		g.onBackwards.fire(regression)
+	}
+	g.lastNow = nowSinceEpoch
+	if timestamp < g.observed {
+		// an ID observed from another node is ahead of the clock, count
+		// from its millisecond instead
+		timestamp = g.observed
+	}
 
 	// reference: https://github.com/twitter-archive/snowflake/blob/snowflake-2010/src/main/scala/com/twitter/service/snowflake/IdWorker.scala#L81
-	if nowSinceEpoch == id.elapsedTime { // same millisecond as last time
-		id.sequence = (id.sequence + 1) & maxSeqBits // increment sequence number
+	if timestamp <= g.elapsedTime { // same millisecond as last time, or the clock lags behind it
+		// never go back in time, keep issuing from the last millisecond instead
+		timestamp = g.elapsedTime
+		g.sequence = (g.sequence + 1) & maxSeqBits // increment sequence number
 
-		if id.sequence == 0 {
+		if g.sequence == 0 {
 			// if we've used up all the bits in the sequence number,
 			// we need to change the timestamp
-			nowSinceEpoch = waitUntilNextMs(id.elapsedTime) // wait until next millisecond
+			g.counters.Rollovers++
+			waited := g.counters.Waited
+			timestamp = g.nextMs(nowSinceEpoch)
+			g.onExhausted.fire(g.counters.Waited - waited)
 		}
 	} else {
-		id.sequence = 0
+		g.sequence = 0
 	}
 
-	id.elapsedTime = nowSinceEpoch
+	g.elapsedTime = timestamp
+	if g.entropy && timestamp != prev {
+		g.rollField()
+	}
+	g.trackSequence()
+	if timestamp != prev && g.counters.IDs > 1 {
+		g.endMillisecond(last)
+	}
+	if g.store != nil && timestamp != prev {
+		g.saveState()
+	}
 
-	timestampSegment := uint64(id.elapsedTime << (sequenceBits + fieldBits))
-	fieldSegment := uint64(id.field) << sequenceBits
-	sequenceSegment := uint64(id.sequence)
+	return g.compose()
+}
 
-	// if the field is bigger than the max, we need to reset it
-	if id.field > maxFieldBits {
-		fieldSegment = 0
-	}
+// ID is a custom type for a snowflake ID.
+type ID struct {
+	generator
+	field      uint64
+	registered uint32 // 1 while the field is held in the process registry, 2 once released
+}
 
-	return timestampSegment | fieldSegment | sequenceSegment
+// New returns a new snowflake.ID issuing IDs of machineID, the 10-bit
+// field that tells apart the generators of a fleet (max machine ID: 1023).
+// A machine ID bigger than the max is reset to 0, unless folded into range
+// with WithFieldFolding.
+func New(machineID uint64, opts ...Option) *ID {
+	id := &ID{field: machineID}
+	if machineID <= maxFieldBits {
+		id.fieldSegment = machineID << sequenceBits
+	}
+	id.applyOptions(opts)
+	// fold before the warmup, which compares the saved field with ours
+	if id.foldFields {
+		id.foldField(machineID)
+	}
+	id.setup()
+	if !id.foldFields {
+		id.checkField(machineID, maxFieldBits)
+	}
+	return id
 }
 
-// SID is the parsed representation of a snowflake ID.
+// NextID returns a new snowflake ID.
+//
+//	Format:
+//	1011001001101101011001010111100000001011111111111000000000001
+//	|--------------timestamp--------------|--disc---|----seq----|
+func (id *ID) NextID() uint64 { return id.nextID() }
+
+// TryNextID is like NextID but returns ErrRateLimited instead of waiting
+// when the generator's rate limit is exhausted.
+func (id *ID) TryNextID() (uint64, error) { return id.tryNextID() }
+
+// AppendIDs appends n new snowflake IDs to dst and returns the extended slice.
+// The IDs are generated under a single lock, so they are contiguous and
+// increasing. When dst has enough capacity no allocation happens, which
+// makes it a good fit for pooled buffers:
+//
+//	var pool = sync.Pool{New: func() interface{} { return make([]uint64, 0, 8192) }}
+//
+//	buf := pool.Get().([]uint64)
+//	buf = sf.AppendIDs(buf[:0], 8192)
+//	// use buf
+//	pool.Put(buf)
+func (id *ID) AppendIDs(dst []uint64, n int) []uint64 { return id.appendIDs(dst, n) }
+
+// SID is the parsed representation of a snowflake ID. It marshals to JSON
+// as {"Timestamp":1640942460724,"Sequence":0,"MachineID":1,"Field":1},
+// with the timestamp in Unix milliseconds, and "Fallback":true added for
+// fallback IDs.
 type SID struct {
 	// Timestamp is the timestamp of the snowflake ID.
 	Timestamp int64
 	// Sequence is the sequence number of the snowflake ID.
 	Sequence uint64
-	// Field is the field value of the snowflake ID.
+	// MachineID is the machine ID (field) of the snowflake ID.
+	MachineID uint64
+	// Field holds the same value as MachineID.
+	//
+	// Deprecated: Use MachineID. Field will be removed in a future release.
 	Field uint64
+	// Fallback reports whether the snowflake ID is a random one issued
+	// while the clock was unusable, see WithRandomFallback. The other
+	// fields are then zero.
+	Fallback bool `json:",omitempty"`
 }
 
 // Parse parses an existing snowflake ID
 func Parse(sid uint64) SID {
+	if IsFallback(sid) {
+		return SID{Fallback: true}
+	}
 	return SID{
 		Timestamp: getTimestamp(sid),
 		Sequence:  getSequence(sid),
-		Field:     getDiscriminant(sid),
+		MachineID: getMachineID(sid),
+		Field:     getMachineID(sid),
+	}
+}
+
+// ParseAll parses a batch of existing snowflake IDs, in order.
+// It's equivalent to calling Parse on each of them, only faster.
+func ParseAll(ids []uint64) []SID { return AppendSIDs(make([]SID, 0, len(ids)), ids) }
+
+// AppendSIDs parses a batch of existing snowflake IDs and appends them to
+// dst, in order, returning the extended slice. Like AppendIDs, it lets the
+// caller reuse a buffer between batches.
+func AppendSIDs(dst []SID, ids []uint64) []SID {
+	epoch := atomic.LoadInt64(&epochMillis)
+	for _, id := range ids {
+		if IsFallback(id) {
+			dst = append(dst, SID{Fallback: true})
+			continue
+		}
+		dst = append(dst, SID{
+			Timestamp: int64(id>>(sequenceBits+fieldBits)) + epoch,
+			Sequence:  getSequence(id),
+			MachineID: getMachineID(id),
+			Field:     getMachineID(id),
+		})
 	}
+	return dst
+}
+
+// ParseString strictly parses the decimal form of a snowflake ID, as
+// printed by strconv.FormatUint. Unlike Decimal.Parse, it rejects leading
+// zeros and IDs whose timestamp overflows 41 bits, neither of which a
+// generator ever produces. Returns an error wrapping ErrInvalidID if s
+// isn't such an ID.
+func ParseString(s string) (SID, error) {
+	id, err := Decimal.Parse(s)
+	if err != nil {
+		return SID{}, err
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return SID{}, fmt.Errorf("snowflake: %q has leading zeros: %w", s, ErrInvalidID)
+	}
+	if id > math.MaxInt64 {
+		return SID{}, fmt.Errorf("snowflake: %q overflows the timestamp: %w", s, ErrInvalidID)
+	}
+	return Parse(id), nil
 }
 
 // ID2 is a snowflake ID with 2 field fields.
 type ID2 struct {
-	mtx         sync.Mutex
-	field1      uint64
-	field2      uint64
-	sequence    uint64
-	elapsedTime int64
+	generator
+	field1 uint64
+	field2 uint64
 }
 
 // New2 returns a new snowflake.ID2 (max field value: 31)
-func New2(field1 uint64, field2 uint64) *ID2 {
-	return &ID2{field1: field1, field2: field2}
+// A field bigger than the max is reset to 0.
+func New2(field1 uint64, field2 uint64, opts ...Option) *ID2 {
+	id := &ID2{field1: field1, field2: field2}
+	if field1 <= maxFieldHalfBits {
+		id.fieldSegment |= field1 << sequenceBits
+	}
+	if field2 <= maxFieldHalfBits {
+		id.fieldSegment |= field2 << (sequenceBits + fieldBits/2)
+	}
+	id.apply(opts)
+	id.checkField(field1, maxFieldHalfBits)
+	id.checkField(field2, maxFieldHalfBits)
+	return id
+}
+
+// New2WithPID returns a new snowflake.ID2 with machineID as the first field
+// and the process ID masked into 5 bits (os.Getpid() & 0x1F) as the second.
+// Processes on the same machine whose PIDs are 32 apart share a second
+// field, so this suits a handful of processes per machine, not a fleet of
+// short-lived ones.
+// Returns ErrFieldOutOfRange if machineID is bigger than 31.
+func New2WithPID(machineID uint64, opts ...Option) (*ID2, error) {
+	if machineID > maxFieldHalfBits {
+		return nil, ErrFieldOutOfRange
+	}
+	return New2(machineID, uint64(os.Getpid())&maxFieldHalfBits, opts...), nil
 }
 
 // NextID returns a new snowflake ID with 2 field fields.
@@ -143,45 +560,18 @@ func New2(field1 uint64, field2 uint64) *ID2 {
 //	Format:
 //	1011001001101101011001010111100000001011111111111000000000001
 //	|--------------timestamp--------------|-d2-|-d1-|----seq----|
-func (id *ID2) NextID() uint64 {
-	id.mtx.Lock()
-	defer id.mtx.Unlock()
-
-	nowSinceEpoch := msSinceEpoch()
+func (id *ID2) NextID() uint64 { return id.nextID() }
 
-	// reference: https://github.com/twitter-archive/snowflake/blob/snowflake-2010/src/main/scala/com/twitter/service/snowflake/IdWorker.scala#L81
-	if nowSinceEpoch == id.elapsedTime { // same millisecond as last time
-		id.sequence = (id.sequence + 1) & maxSeqBits // increment sequence number
+// TryNextID is like NextID but returns ErrRateLimited instead of waiting
+// when the generator's rate limit is exhausted.
+func (id *ID2) TryNextID() (uint64, error) { return id.tryNextID() }
 
-		if id.sequence == 0 {
-			// if we've used up all the bits in the sequence number,
-			// we need to change the timestamp
-			nowSinceEpoch = waitUntilNextMs(id.elapsedTime) // wait until next millisecond
-		}
-	} else {
-		id.sequence = 0
-	}
-
-	id.elapsedTime = nowSinceEpoch
-
-	timestampSegment := uint64(id.elapsedTime << (sequenceBits + fieldBits))
-	field1Segment := id.field1 << uint64(sequenceBits)
-	field2Segment := id.field2 << uint64(sequenceBits+fieldBits/2)
-	sequenceSegment := uint64(id.sequence)
-
-	// if the field is bigger than the max, we need to reset it
-	if id.field1 > uint64(maxFieldHalfBits) {
-		field1Segment = 0
-	}
-
-	if id.field2 > uint64(maxFieldHalfBits) {
-		field2Segment = 0
-	}
-
-	return timestampSegment | field2Segment | field1Segment | sequenceSegment
-}
+// AppendIDs appends n new snowflake IDs to dst and returns the extended slice.
+// See ID.AppendIDs.
+func (id *ID2) AppendIDs(dst []uint64, n int) []uint64 { return id.appendIDs(dst, n) }
 
 // SID2 is the parsed representation of a snowflake ID with 2 field fields.
+// It marshals to JSON like SID, with fields Field1 and Field2.
 type SID2 struct {
 	// Timestamp is the timestamp of the snowflake ID.
 	Timestamp int64
@@ -191,50 +581,192 @@ type SID2 struct {
 	Field1 uint64
 	// Field2 is the second field value of the snowflake ID.
 	Field2 uint64
+	// Fallback is like SID.Fallback.
+	Fallback bool `json:",omitempty"`
 }
 
 // Parse2 parses an existing snowflake ID with 2 field fields.
 func Parse2(sid uint64) SID2 {
+	if IsFallback(sid) {
+		return SID2{Fallback: true}
+	}
 	return SID2{
 		Timestamp: getTimestamp(sid),
 		Sequence:  getSequence(sid),
-		Field1:    getFirstDiscriminant(sid),
-		Field2:    getSecondDiscriminant(sid),
+		Field1:    getWorkerID(sid),
+		Field2:    getDatacenterID(sid),
+	}
+}
+
+// Parse2All is ParseAll for snowflake IDs with 2 field fields.
+func Parse2All(ids []uint64) []SID2 { return AppendSID2s(make([]SID2, 0, len(ids)), ids) }
+
+// AppendSID2s is AppendSIDs for snowflake IDs with 2 field fields.
+func AppendSID2s(dst []SID2, ids []uint64) []SID2 {
+	epoch := atomic.LoadInt64(&epochMillis)
+	for _, id := range ids {
+		if IsFallback(id) {
+			dst = append(dst, SID2{Fallback: true})
+			continue
+		}
+		dst = append(dst, SID2{
+			Timestamp: int64(id>>(sequenceBits+fieldBits)) + epoch,
+			Sequence:  getSequence(id),
+			Field1:    getWorkerID(id),
+			Field2:    getDatacenterID(id),
+		})
+	}
+	return dst
+}
+
+// NewDatacenterWorker returns a new snowflake.ID2 laid out like Twitter's
+// original scheme: the datacenter ID in the upper 5 field bits and the
+// worker ID in the lower 5 (New2(workerID, datacenterID)).
+// Returns ErrFieldOutOfRange if either ID is bigger than 31.
+//
+//	Format:
+//	1011001001101101011001010111100000001011111111111000000000001
+//	|--------------timestamp--------------|-dc-|-wk-|----seq----|
+func NewDatacenterWorker(datacenterID, workerID uint64, opts ...Option) (*ID2, error) {
+	if datacenterID > maxFieldHalfBits || workerID > maxFieldHalfBits {
+		return nil, ErrFieldOutOfRange
 	}
+	return New2(workerID, datacenterID, opts...), nil
+}
+
+// DatacenterWorkerSID is the parsed representation of a snowflake ID
+// generated by NewDatacenterWorker.
+type DatacenterWorkerSID struct {
+	// Timestamp is the timestamp of the snowflake ID.
+	Timestamp int64
+	// Sequence is the sequence number of the snowflake ID.
+	Sequence uint64
+	// DatacenterID is the datacenter ID of the snowflake ID.
+	DatacenterID uint64
+	// WorkerID is the worker ID of the snowflake ID.
+	WorkerID uint64
+}
+
+// ParseDatacenterWorker parses an existing snowflake ID generated by
+// NewDatacenterWorker.
+func ParseDatacenterWorker(sid uint64) DatacenterWorkerSID {
+	return DatacenterWorkerSID{
+		Timestamp:    getTimestamp(sid),
+		Sequence:     getSequence(sid),
+		DatacenterID: getDatacenterID(sid),
+		WorkerID:     getWorkerID(sid),
+	}
+}
+
+// now returns the number of milliseconds since the epoch according to the
+// generator's clock. (internal-use only)
+func (g *generator) now() int64 {
+	if g.clock == nil {
+		return msSinceEpoch()
+	}
+	return g.clock.Now().UnixMilli() - atomic.LoadInt64(&epochMillis)
+}
+
+// wallNow returns the current time according to the generator's clock.
+// (internal-use only)
+func (g *generator) wallNow() time.Time {
+	if g.clock == nil {
+		return time.Now()
+	}
+	return g.clock.Now()
+}
+
+// nextMs returns the millisecond to move to once the sequence of the current
+// one is exhausted, borrowing from the future when forward drift is allowed
+// and waiting otherwise. (internal-use only)
+func (g *generator) nextMs(nowSinceEpoch int64) int64 {
+	next := g.elapsedTime + 1
+	drift := g.drift()
+	if drift == 0 {
+		return g.waitUntilNextMs(g.elapsedTime) // wait until next millisecond
+	}
+
+	if next-nowSinceEpoch <= drift {
+		return next
+	}
+
+	// too far ahead of the clock, wait until next is within the bound again
+	if ms := g.waitUntilNextMs(next - drift - 1); ms > next {
+		return ms
+	}
+	return next
 }
 
 // waitUntilNextMs waits until the next millisecond to return. (internal-use only)
-func waitUntilNextMs(last int64) int64 {
-	ms := msSinceEpoch()
-	for ms <= last {
-		ms = msSinceEpoch()
+// It busy-spins for a short while, then yields to the scheduler, then
+// sleeps for the estimated remaining time, see WithWaitThresholds.
+func (g *generator) waitUntilNextMs(last int64) int64 {
+	read := g.wallNow
+	realTime := g.clock == nil
+	if pc, ok := g.clock.(preciseClock); ok {
+		// don't wait on a cached time, it may lag behind the real one
+		read = pc.precise
+		realTime = true
+	}
+
+	spin, yield := defaultSpinWait, defaultYieldWait
+	if g.waitTuned {
+		spin, yield = g.spinWait, g.yieldWait
+	}
+	if !realTime {
+		// an injected clock needn't follow real time, so there's no
+		// telling how long to sleep for
+		yield = math.MaxInt64 - spin
+	}
+
+	next := time.UnixMilli(last + 1 + atomic.LoadInt64(&epochMillis))
+	now := read()
+	start := now
+	for now.Before(next) {
+		switch waited := now.Sub(start); {
+		case waited < spin:
+		case waited < spin+yield:
+			runtime.Gosched()
+		default:
+			time.Sleep(next.Sub(now))
+		}
+		now = read()
+	}
+	waited := now.Sub(start)
+	g.counters.Waited += waited
+	if waited > slowWait {
+		g.report(anomalySlowWait, "snowflake: slow wait for the next millisecond", g.fieldSegment>>sequenceBits,
+			"waited", waited)
 	}
-	return ms
+	g.lastNow = now.UnixMilli() - atomic.LoadInt64(&epochMillis)
+	return g.lastNow
 }
 
 // msSinceEpoch returns the number of milliseconds since the epoch. (internal-use only)
 func msSinceEpoch() int64 {
-	return time.Since(epoch).Nanoseconds() / 1e6
+	return time.Now().UnixMilli() - atomic.LoadInt64(&epochMillis)
 }
 
-// getDiscriminant returns the discriminant value of a snowflake ID. (internal-use only)
-func getDiscriminant(id uint64) uint64 {
+// getMachineID returns the machine ID (field) of a snowflake ID. (internal-use only)
+func getMachineID(id uint64) uint64 {
 	return (id >> sequenceBits) & maxFieldBits
 }
 
-// getFirstDiscriminant returns the first discriminant value of a snowflake ID. (internal-use only)
-func getFirstDiscriminant(id uint64) uint64 {
+// getWorkerID returns the first field, the worker ID, of a snowflake ID
+// with 2 fields. (internal-use only)
+func getWorkerID(id uint64) uint64 {
 	return (id >> sequenceBits) & maxFieldHalfBits
 }
 
-// getSecondDiscriminant returns the second discriminant value of a snowflake ID. (internal-use only)
-func getSecondDiscriminant(id uint64) uint64 {
+// getDatacenterID returns the second field, the datacenter ID, of a
+// snowflake ID with 2 fields. (internal-use only)
+func getDatacenterID(id uint64) uint64 {
 	return (id >> (sequenceBits + fieldBits/2)) & maxFieldHalfBits
 }
 
 // getTimestamp returns the timestamp of a snowflake ID. (internal-use only)
 func getTimestamp(id uint64) int64 {
-	return int64(id>>(sequenceBits+fieldBits)) + epoch.UnixNano()/1e6
+	return int64(id>>(sequenceBits+fieldBits)) + atomic.LoadInt64(&epochMillis)
 }
 
 // getSequence returns the sequence number of a snowflake ID. (internal-use only)
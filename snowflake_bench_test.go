@@ -1,6 +1,9 @@
 package snowflake_test
 
 import (
+	"fmt"
+	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/HotPotatoC/snowflake"
@@ -8,41 +11,363 @@ import (
 	gosnowflake "github.com/godruoyi/go-snowflake"
 )
 
+// generators lists the implementations compared by the benchmarks.
+// Each entry returns a fresh "next ID" function.
+var generators = []struct {
+	name string
+	new  func() func()
+}{
+	{"github.com/HotPotatoC/snowflake", func() func() {
+		sf := snowflake.New(1)
+		return func() { sf.NextID() }
+	}},
+	{"github.com/HotPotatoC/snowflake/ID2", func() func() {
+		sf := snowflake.New2(1, 1)
+		return func() { sf.NextID() }
+	}},
+	{"github.com/bwmarrin/snowflake", func() func() {
+		node, _ := bwmarrinsnowflake.NewNode(1)
+		return func() { node.Generate() }
+	}},
+	{"github.com/godruoyi/go-snowflake", func() func() {
+		gosnowflake.SetMachineID(1)
+		return func() { gosnowflake.ID() }
+	}},
+}
+
 func BenchmarkNewID(b *testing.B) {
-	benchmarks := []struct {
-		name string
-		fn   func(b *testing.B)
-	}{
-		{"github.com/HotPotatoC/snowflake", benchmarkHotPotatoCSnowflake},
-		{"github.com/bwmarrin/snowflake", benchmarkBwmarrinSnowflake},
-		{"github.com/godruoyi/go-snowflake", benchmarkGoSnowflake},
+	for _, g := range generators {
+		b.Run(g.name, func(b *testing.B) {
+			next := g.new()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				next()
+			}
+		})
 	}
+}
+
+func BenchmarkNewID_Parallel(b *testing.B) {
+	for _, g := range generators {
+		b.Run(g.name, func(b *testing.B) {
+			next := g.new()
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					next()
+				}
+			})
+		})
+	}
+
+	b.Run("github.com/HotPotatoC/snowflake/Sharded-4", func(b *testing.B) {
+		sg, _ := snowflake.NewSharded([]uint64{1, 2, 3, 4})
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				sg.NextID()
+			}
+		})
+	})
+}
+
+// BenchmarkNewID_Goroutines splits b.N across a fixed number of workers,
+// independent of GOMAXPROCS, to show how each implementation behaves as
+// contention grows.
+func BenchmarkNewID_Goroutines(b *testing.B) {
+	for _, workers := range []int{4, 16, 64} {
+		for _, g := range generators {
+			b.Run(fmt.Sprintf("%s/%d", g.name, workers), func(b *testing.B) {
+				next := g.new()
+				b.ReportAllocs()
+				b.ResetTimer()
+				runWorkers(b.N, workers, next)
+			})
+		}
+	}
+}
+
+// runWorkers calls fn n times in total spread across a pool of workers.
+func runWorkers(n, workers int, fn func()) {
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		count := n / workers
+		if w < n%workers {
+			count++
+		}
+
+		go func(count int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				fn()
+			}
+		}(count)
+	}
+	wg.Wait()
+}
+
+// BenchmarkNextID_Unbounded measures the per-call cost of NextID without
+// hitting the 4096 IDs/ms sequence limit, by rotating across every field.
+func BenchmarkNextID_Unbounded(b *testing.B) {
+	b.Run("ID", func(b *testing.B) {
+		gens := make([]*snowflake.ID, 1024)
+		for i := range gens {
+			gens[i] = snowflake.New(uint64(i))
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			gens[i&1023].NextID()
+		}
+	})
+
+	b.Run("UnsafeID", func(b *testing.B) {
+		gens := make([]*snowflake.UnsafeID, 1024)
+		for i := range gens {
+			gens[i] = snowflake.NewUnsafe(uint64(i))
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			gens[i&1023].NextID()
+		}
+	})
+
+	b.Run("UnsafeID/CoarseClock", func(b *testing.B) {
+		clock := snowflake.NewCoarseClock(0)
+		defer clock.Close()
+
+		gens := make([]*snowflake.UnsafeID, 1024)
+		for i := range gens {
+			gens[i] = snowflake.NewUnsafe(uint64(i), snowflake.WithClock(clock))
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			gens[i&1023].NextID()
+		}
+	})
+
+	b.Run("ID/CoarseClock", func(b *testing.B) {
+		clock := snowflake.NewCoarseClock(0)
+		defer clock.Close()
+
+		gens := make([]*snowflake.ID, 1024)
+		for i := range gens {
+			gens[i] = snowflake.New(uint64(i), snowflake.WithClock(clock))
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			gens[i&1023].NextID()
+		}
+	})
+
+	b.Run("ID2", func(b *testing.B) {
+		gens := make([]*snowflake.ID2, 1024)
+		for i := range gens {
+			gens[i] = snowflake.New2(uint64(i&31), uint64(i>>5))
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			gens[i&1023].NextID()
+		}
+	})
+}
 
-	for _, bb := range benchmarks {
-		b.Run(bb.name, bb.fn)
+// BenchmarkNextID_Adjacent hammers generators stored next to each other in
+// one slice, one goroutine per generator, to expose false sharing.
+func BenchmarkNextID_Adjacent(b *testing.B) {
+	for _, n := range []int{2, 4, 8} {
+		b.Run(fmt.Sprintf("ID/%d", n), func(b *testing.B) {
+			gens := make([]snowflake.ID, n)
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			var wg sync.WaitGroup
+			wg.Add(n)
+			for g := range gens {
+				go func(sf *snowflake.ID) {
+					defer wg.Done()
+					for i := 0; i < b.N/n; i++ {
+						sf.NextID()
+					}
+				}(&gens[g])
+			}
+			wg.Wait()
+		})
 	}
 }
 
-func benchmarkHotPotatoCSnowflake(b *testing.B) {
-	sf := snowflake.New(1)
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		sf.NextID()
+// batchSink keeps benchmark batches from being stack allocated.
+var batchSink []uint64
+
+func BenchmarkAppendIDs(b *testing.B) {
+	const batch = 8192
+
+	b.Run("NextID", func(b *testing.B) {
+		sf := snowflake.New(1)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			ids := make([]uint64, 0, batch)
+			for j := 0; j < batch; j++ {
+				ids = append(ids, sf.NextID())
+			}
+			batchSink = ids
+		}
+	})
+
+	b.Run("AppendIDs", func(b *testing.B) {
+		sf := snowflake.New(1)
+		buf := make([]uint64, 0, batch)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			buf = sf.AppendIDs(buf[:0], batch)
+		}
+		batchSink = buf
+	})
+}
+
+// sidSink keeps benchmark results from being optimized away.
+var sidSink []snowflake.SID
+
+func BenchmarkParseAll(b *testing.B) {
+	const batch = 8192
+	ids := snowflake.New(1).AppendIDs(nil, batch)
+
+	b.Run("Parse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sids := make([]snowflake.SID, 0, batch)
+			for _, id := range ids {
+				sids = append(sids, snowflake.Parse(id))
+			}
+			sidSink = sids
+		}
+	})
+
+	b.Run("ParseAll", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sidSink = snowflake.ParseAll(ids)
+		}
+	})
+
+	b.Run("AppendSIDs", func(b *testing.B) {
+		buf := make([]snowflake.SID, 0, batch)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			buf = snowflake.AppendSIDs(buf[:0], ids)
+		}
+		sidSink = buf
+	})
+}
+
+// stringSink keeps benchmark results from being optimized away.
+var stringSink string
+
+func BenchmarkNextStringID(b *testing.B) {
+	b.Run("FormatUint", func(b *testing.B) {
+		sf := snowflake.New(1)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			stringSink = strconv.FormatUint(sf.NextID(), 10)
+		}
+	})
+
+	b.Run("NextStringID", func(b *testing.B) {
+		sf := snowflake.New(1)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			stringSink = sf.NextStringID()
+		}
+	})
+
+	b.Run("NextEncodedID/base58", func(b *testing.B) {
+		sf := snowflake.New(1)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			stringSink = sf.NextEncodedID(snowflake.Base58)
+		}
+	})
+}
+
+func BenchmarkActorGen(b *testing.B) {
+	for _, workers := range []int{4, 16, 64, 256} {
+		b.Run(fmt.Sprintf("mutex/%d", workers), func(b *testing.B) {
+			sf := snowflake.New(1)
+			b.ReportAllocs()
+			b.ResetTimer()
+			runWorkers(b.N, workers, func() { sf.NextID() })
+		})
+
+		b.Run(fmt.Sprintf("actor/%d", workers), func(b *testing.B) {
+			sf := snowflake.NewActor(1)
+			defer sf.Close()
+			b.ReportAllocs()
+			b.ResetTimer()
+			runWorkers(b.N, workers, func() { sf.NextID() })
+		})
 	}
 }
 
-func benchmarkBwmarrinSnowflake(b *testing.B) {
-	node, _ := bwmarrinsnowflake.NewNode(1)
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		node.Generate()
+func BenchmarkWithClockCheckEvery(b *testing.B) {
+	for _, n := range []int{1, 64} {
+		b.Run(fmt.Sprintf("every-%d", n), func(b *testing.B) {
+			clock := &countingClock{Clock: systemClock{}}
+			gens := make([]*snowflake.ID, 1024)
+			for i := range gens {
+				gens[i] = snowflake.New(uint64(i), snowflake.WithClock(clock), snowflake.WithClockCheckEvery(n))
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				gens[(i>>6)&1023].NextID()
+			}
+			b.ReportMetric(float64(clock.reads)/float64(b.N), "clock-reads/op")
+		})
 	}
 }
 
-func benchmarkGoSnowflake(b *testing.B) {
-	gosnowflake.SetMachineID(1)
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		gosnowflake.ID()
+// BenchmarkChain measures the overhead of middlewares over a plain
+// generator, rotating across every field as BenchmarkNextID_Unbounded does.
+func BenchmarkChain(b *testing.B) {
+	gens := make([]snowflake.Generator, 1024)
+	for i := range gens {
+		gens[i] = snowflake.New(uint64(i))
+	}
+	rotating := snowflake.GeneratorFunc(func() func() uint64 {
+		var i int
+		return func() uint64 {
+			i++
+			return gens[i&1023].NextID()
+		}
+	}())
+
+	var issued snowflake.Counter
+	benchmarks := []struct {
+		name string
+		g    snowflake.Generator
+	}{
+		{"None", rotating},
+		{"Counter", snowflake.Chain(rotating, issued.Middleware())},
+		{"Counter+RateLimited", snowflake.Chain(rotating, issued.Middleware(), snowflake.RateLimited(1<<40))},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bm.g.NextID()
+			}
+		})
 	}
 }
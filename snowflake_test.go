@@ -1,6 +1,9 @@
 package snowflake_test
 
 import (
+	"encoding/json"
+	"errors"
+	"os"
 	"sync"
 	"testing"
 	"time"
@@ -197,9 +200,9 @@ func TestField(t *testing.T) {
 
 	for _, tt := range tc {
 		t.Run(tt.name, func(t *testing.T) {
-			if snowflake.Parse(tt.sf.NextID()).Field != tt.expected {
+			if snowflake.Parse(tt.sf.NextID()).MachineID != tt.expected {
 				t.Errorf("expected field %d got %d",
-					tt.expected, snowflake.Parse(tt.sf.NextID()).Field)
+					tt.expected, snowflake.Parse(tt.sf.NextID()).MachineID)
 			}
 		})
 	}
@@ -232,9 +235,72 @@ func Test2Fields(t *testing.T) {
 	}
 }
 
+func TestNew2WithPID(t *testing.T) {
+	sf, err := snowflake.New2WithPID(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed := snowflake.Parse2(sf.NextID())
+	if parsed.Field1 != 7 {
+		t.Errorf("expected machine ID %d got %d", 7, parsed.Field1)
+	}
+	if pid := uint64(os.Getpid()) & 0x1F; parsed.Field2 != pid {
+		t.Errorf("expected masked PID %d got %d", pid, parsed.Field2)
+	}
+
+	_, err = snowflake.New2WithPID(32)
+	if err != snowflake.ErrFieldOutOfRange {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldOutOfRange, err)
+	}
+}
+
+func TestNewDatacenterWorker(t *testing.T) {
+	sf, err := snowflake.NewDatacenterWorker(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := sf.NextID()
+
+	// Pinned: the worker ID occupies the lower 5 field bits, the datacenter
+	// ID the upper 5, as in Twitter's layout.
+	if worker := id >> 12 & 0x1F; worker != 5 {
+		t.Errorf("expected worker ID %d in bits 12-16 got %d", 5, worker)
+	}
+	if datacenter := id >> 17 & 0x1F; datacenter != 3 {
+		t.Errorf("expected datacenter ID %d in bits 17-21 got %d", 3, datacenter)
+	}
+
+	parsed := snowflake.ParseDatacenterWorker(id)
+	if parsed.DatacenterID != 3 || parsed.WorkerID != 5 {
+		t.Errorf("expected datacenter ID 3 and worker ID 5 got %d and %d", parsed.DatacenterID, parsed.WorkerID)
+	}
+	if generic := snowflake.Parse2(id); generic.Field1 != parsed.WorkerID || generic.Field2 != parsed.DatacenterID {
+		t.Errorf("expected Parse2 fields %d,%d got %d,%d", parsed.WorkerID, parsed.DatacenterID, generic.Field1, generic.Field2)
+	}
+
+	tc := []struct {
+		name       string
+		datacenter uint64
+		worker     uint64
+	}{
+		{"Should return ErrFieldOutOfRange for the datacenter ID", 32, 0},
+		{"Should return ErrFieldOutOfRange for the worker ID", 0, 32},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := snowflake.NewDatacenterWorker(tt.datacenter, tt.worker)
+			if err != snowflake.ErrFieldOutOfRange {
+				t.Errorf("expected error %v got %v", snowflake.ErrFieldOutOfRange, err)
+			}
+		})
+	}
+}
+
 func TestParse(t *testing.T) {
 	// timestamp: 1640942460724
-	// Field: 1
+	// MachineID: 1
 	// Sequence: 0
 	id := uint64(1292053924173320192)
 
@@ -243,8 +309,8 @@ func TestParse(t *testing.T) {
 		t.Errorf("expected sequence %d got %d", 0, sid.Sequence)
 	}
 
-	if sid.Field != 1 {
-		t.Errorf("expected field %d got %d", 1, sid.Field)
+	if sid.MachineID != 1 {
+		t.Errorf("expected machine ID %d got %d", 1, sid.MachineID)
 	}
 
 	if sid.Timestamp != 1640942460724 {
@@ -252,6 +318,42 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestSID_DeprecatedField(t *testing.T) {
+	id := snowflake.New(742).NextID()
+	tagged := snowflake.New(42, snowflake.WithVersionTag(3)).NextID()
+	fromEpochs, _, _ := snowflake.NewMultiEpochParser(snowflake.Epoch()).Parse(id)
+	fromTagged, _ := snowflake.ParseTagged(tagged, 3)
+
+	var fromJSON snowflake.SID
+	json.Unmarshal([]byte(`{"Timestamp":1640942460724,"Field":742}`), &fromJSON)
+
+	tc := []struct {
+		name     string
+		sid      snowflake.SID
+		expected uint64
+	}{
+		{"Should set both in Parse", snowflake.Parse(id), 742},
+		{"Should set both in ParseAll", snowflake.ParseAll([]uint64{id})[0], 742},
+		{"Should set both in MultiEpochParser.Parse", fromEpochs, 742},
+		{"Should set both in ParseTagged", fromTagged, 42},
+		{"Should set both from the JSON of the deprecated field", fromJSON, 742},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.sid.MachineID != tt.expected || tt.sid.Field != tt.sid.MachineID {
+				t.Errorf("expected machine ID and field %d got %+v", tt.expected, tt.sid)
+			}
+		})
+	}
+
+	// code setting only the deprecated field keeps rebuilding the same ID
+	sid := snowflake.Parse(id)
+	if rebuilt, err := (snowflake.SID{Timestamp: sid.Timestamp, Sequence: sid.Sequence, Field: 742}).ToID(); err != nil || rebuilt != id {
+		t.Errorf("expected %d got %d (%v)", id, rebuilt, err)
+	}
+}
+
 func TestParse2Fields(t *testing.T) {
 	// timestamp: 1640945127245
 	// Field1: 1
@@ -277,6 +379,61 @@ func TestParse2Fields(t *testing.T) {
 	}
 }
 
+func TestParseString(t *testing.T) {
+	sid, err := snowflake.ParseString("1292053924173320192")
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	if sid != snowflake.Parse(1292053924173320192) {
+		t.Errorf("expected %+v got %+v", snowflake.Parse(1292053924173320192), sid)
+	}
+
+	tc := []struct {
+		name string
+		s    string
+	}{
+		{"Should return ErrInvalidID for an empty string", ""},
+		{"Should return ErrInvalidID for a non-digit", "12920539241733201x2"},
+		{"Should return ErrInvalidID for a sign", "+1292053924173320192"},
+		{"Should return ErrInvalidID for surrounding spaces", " 1292053924173320192"},
+		{"Should return ErrInvalidID for leading zeros", "01292053924173320192"},
+		{"Should return ErrInvalidID for a timestamp overflow", "9223372036854775808"},
+		{"Should return ErrInvalidID for a 64-bit overflow", "18446744073709551616"},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := snowflake.ParseString(tt.s)
+			if !errors.Is(err, snowflake.ErrInvalidID) {
+				t.Errorf("expected error %v got %v", snowflake.ErrInvalidID, err)
+			}
+		})
+	}
+}
+
+func TestParseAll(t *testing.T) {
+	ids := append(snowflake.New(700).AppendIDs(nil, 5000), 0, 1<<63-1, 1<<64-1)
+
+	sids := snowflake.ParseAll(ids)
+	sids2 := snowflake.Parse2All(ids)
+	if len(sids) != len(ids) || len(sids2) != len(ids) {
+		t.Fatalf("expected %d IDs got %d and %d", len(ids), len(sids), len(sids2))
+	}
+	for i, id := range ids {
+		if sids[i] != snowflake.Parse(id) {
+			t.Errorf("expected %+v at %d got %+v", snowflake.Parse(id), i, sids[i])
+		}
+		if sids2[i] != snowflake.Parse2(id) {
+			t.Errorf("expected %+v at %d got %+v", snowflake.Parse2(id), i, sids2[i])
+		}
+	}
+
+	buf := snowflake.AppendSIDs(make([]snowflake.SID, 1, 16), ids[:3])
+	if len(buf) != 4 || buf[0] != (snowflake.SID{}) || buf[3] != sids[2] {
+		t.Errorf("expected the SIDs appended after the first got %+v", buf)
+	}
+}
+
 func TestEpoch(t *testing.T) {
 	epoch := time.Date(2012, 3, 28, 0, 0, 0, 0, time.UTC)
 
@@ -0,0 +1,59 @@
+// Package snowflakeexpvar publishes the stats of snowflake generators
+// with expvar, for services that don't run Prometheus.
+//
+//	sf := snowflake.New(1)
+//	snowflakeexpvar.Publish("snowflake", sf)
+//
+// The stats are then served on /debug/vars with the other expvars:
+//
+//	"snowflake": {"last_id": "1292053924173320192", "sequence": 0, "ids": 1,
+//	              "rollovers": 0, "waited_ns": 0, "clock_regressions": 0,
+//	              "max_sequence": 0, "remaining_seconds": 1477316123}
+//
+// It's a separate package since importing expvar registers /debug/vars on
+// http.DefaultServeMux.
+package snowflakeexpvar
+
+import (
+	"expvar"
+	"strconv"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// Statser is implemented by generators that keep stats, such as
+// *snowflake.ID and *snowflake.ID2.
+type Statser interface {
+	Stats() snowflake.Stats
+}
+
+// vars is the JSON object published for a generator.
+type vars struct {
+	LastID           string  `json:"last_id"` // a string since JavaScript numbers can't hold it
+	Sequence         uint64  `json:"sequence"`
+	IDs              uint64  `json:"ids"`
+	Rollovers        uint64  `json:"rollovers"`
+	WaitedNs         int64   `json:"waited_ns"`
+	ClockRegressions uint64  `json:"clock_regressions"`
+	MaxSequence      uint64  `json:"max_sequence"`
+	RemainingSeconds float64 `json:"remaining_seconds"`
+}
+
+// Publish publishes the stats of g as the expvar name, read each time the
+// variables are served. Like expvar.Publish, it panics if name is already
+// published.
+func Publish(name string, g Statser) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		stats := g.Stats()
+		return vars{
+			LastID:           strconv.FormatUint(stats.LastID, 10),
+			Sequence:         stats.Sequence,
+			IDs:              stats.IDs,
+			Rollovers:        stats.Rollovers,
+			WaitedNs:         int64(stats.Waited),
+			ClockRegressions: stats.ClockRegressions,
+			MaxSequence:      stats.MaxSequence,
+			RemainingSeconds: stats.Remaining.Seconds(),
+		}
+	}))
+}
@@ -0,0 +1,67 @@
+package snowflakeexpvar_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/snowflakeexpvar"
+)
+
+func TestPublish(t *testing.T) {
+	sf := snowflake.New(1)
+	snowflakeexpvar.Publish("snowflake_test", sf)
+
+	var last uint64
+	for i := 0; i < 5; i++ {
+		last = sf.NextID()
+	}
+
+	rec := httptest.NewRecorder()
+	expvar.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/vars", nil))
+
+	var body struct {
+		Snowflake struct {
+			LastID           string  `json:"last_id"`
+			Sequence         uint64  `json:"sequence"`
+			IDs              uint64  `json:"ids"`
+			Rollovers        uint64  `json:"rollovers"`
+			MaxSequence      uint64  `json:"max_sequence"`
+			RemainingSeconds float64 `json:"remaining_seconds"`
+		} `json:"snowflake_test"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	got := body.Snowflake
+	if got.LastID != strconv.FormatUint(last, 10) {
+		t.Errorf("expected last ID %d got %s", last, got.LastID)
+	}
+	if got.Sequence != snowflake.Parse(last).Sequence {
+		t.Errorf("expected sequence %d got %d", snowflake.Parse(last).Sequence, got.Sequence)
+	}
+	if got.MaxSequence < got.Sequence {
+		t.Errorf("expected max sequence of at least %d got %d", got.Sequence, got.MaxSequence)
+	}
+	if got.IDs != 5 {
+		t.Errorf("expected 5 IDs got %d", got.IDs)
+	}
+	if got.RemainingSeconds <= 0 {
+		t.Errorf("expected remaining lifetime got %v", got.RemainingSeconds)
+	}
+}
+
+func TestPublish_Duplicate(t *testing.T) {
+	snowflakeexpvar.Publish("snowflake_dup", snowflake.New(1))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected publishing a name twice to panic")
+		}
+	}()
+	snowflakeexpvar.Publish("snowflake_dup", snowflake.New2(1, 2))
+}
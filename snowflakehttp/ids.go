@@ -0,0 +1,76 @@
+package snowflakehttp
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// DefaultMaxCount is the largest count of GET /ids, unless changed with
+// WithMaxCount.
+const DefaultMaxCount = 1000
+
+// idsChunk is the number of IDs GET /ids generates at once. (internal-use only)
+const idsChunk = 512
+
+// ids serves GET /ids?count=N.
+func (h *handler) ids(w http.ResponseWriter, r *http.Request) {
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	switch {
+	case err != nil:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("count %q is not a number", r.URL.Query().Get("count")))
+		return
+	case count < 1 || count > h.maxCount:
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("count %d is not between 1 and %d", count, h.maxCount))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// the body is written as it is generated, a chunk of IDs at a time, so
+	// that large counts aren't held in memory
+	bw := bufio.NewWriterSize(w, 32<<10)
+	bw.WriteByte('[')
+	buf := make([]uint64, 0, idsChunk)
+	num := make([]byte, 0, 20)
+	for written := 0; written < count; {
+		if r.Context().Err() != nil {
+			return
+		}
+
+		n := count - written
+		if n > idsChunk {
+			n = idsChunk
+		}
+		buf = appendIDs(h.g, buf[:0], n)
+		for i, id := range buf {
+			if written+i > 0 {
+				bw.WriteByte(',')
+			}
+			bw.WriteByte('"')
+			bw.Write(strconv.AppendUint(num[:0], id, 10))
+			bw.WriteByte('"')
+		}
+		written += n
+	}
+	bw.WriteString("]\n")
+	bw.Flush()
+}
+
+// appendIDs appends n IDs of g to dst, in one go for generators with an
+// AppendIDs method such as *snowflake.ID.
+func appendIDs(g snowflake.Generator, dst []uint64, n int) []uint64 {
+	if g, ok := g.(interface {
+		AppendIDs(dst []uint64, n int) []uint64
+	}); ok {
+		return g.AppendIDs(dst, n)
+	}
+	for i := 0; i < n; i++ {
+		dst = append(dst, g.NextID())
+	}
+	return dst
+}
@@ -0,0 +1,165 @@
+package snowflakehttp_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/snowflakehttp"
+)
+
+func TestHandler_IDs(t *testing.T) {
+	srv := httptest.NewServer(snowflakehttp.Handler(snowflake.New(7), snowflakehttp.WithMaxCount(100000)))
+	defer srv.Close()
+
+	for _, count := range []int{1, 500, 100000} {
+		t.Run(strconv.Itoa(count), func(t *testing.T) {
+			resp, err := srv.Client().Get(srv.URL + "/ids?count=" + strconv.Itoa(count))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d got %d", http.StatusOK, resp.StatusCode)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("expected content type application/json got %q", ct)
+			}
+
+			var body []string
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if len(body) != count {
+				t.Fatalf("expected %d IDs got %d", count, len(body))
+			}
+
+			var last uint64
+			for i, s := range body {
+				id, err := strconv.ParseUint(s, 10, 64)
+				if err != nil {
+					t.Fatalf("expected a decimal ID got %q", s)
+				}
+				if i > 0 && id <= last {
+					t.Fatalf("expected ID %d to be greater than %d got %d", i, last, id)
+				}
+				if field := snowflake.Parse(id).MachineID; field != 7 {
+					t.Fatalf("expected field 7 got %d", field)
+				}
+				last = id
+			}
+		})
+	}
+}
+
+// writeCounter is a ResponseWriter counting the writes to it.
+type writeCounter struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (w *writeCounter) Write(b []byte) (int, error) {
+	w.writes++
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestHandler_IDsStreams(t *testing.T) {
+	h := snowflakehttp.Handler(counter{}, snowflakehttp.WithMaxCount(100000))
+	w := &writeCounter{ResponseRecorder: httptest.NewRecorder()}
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ids?count=100000", nil))
+
+	// 100000 IDs of 4 bytes each, written 32KiB at a time
+	if w.writes < 10 {
+		t.Errorf("expected the IDs to be written in chunks got %d writes", w.writes)
+	}
+}
+
+func TestHandler_IDsCount(t *testing.T) {
+	h := snowflakehttp.Handler(snowflake.New(1), snowflakehttp.WithMaxCount(500))
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{name: "Should accept the max count", query: "?count=500", status: http.StatusOK},
+		{name: "Should reject a count over the max", query: "?count=501", status: http.StatusBadRequest},
+		{name: "Should reject a zero count", query: "?count=0", status: http.StatusBadRequest},
+		{name: "Should reject a negative count", query: "?count=-1", status: http.StatusBadRequest},
+		{name: "Should reject a malformed count", query: "?count=ten", status: http.StatusBadRequest},
+		{name: "Should reject a missing count", query: "", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ids"+tc.query, nil))
+
+			if rec.Code != tc.status {
+				t.Fatalf("expected status %d got %d", tc.status, rec.Code)
+			}
+			if tc.status == http.StatusOK {
+				return
+			}
+			var body struct{ Error string }
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+				t.Errorf("expected a JSON error body got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandler_RateLimit(t *testing.T) {
+	// a burst of 2, then a request every 100s
+	h := snowflakehttp.Handler(snowflake.New(1), snowflakehttp.WithRateLimit("X-API-Key", 0.01, 2))
+
+	serve := func(path, key, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		req.RemoteAddr = addr
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		addr   string
+		status int
+	}{
+		{name: "Should allow the burst", path: "/ids?count=10", key: "a", addr: "10.0.0.1:1000", status: http.StatusOK},
+		{name: "Should count GET /id too", path: "/id", key: "a", addr: "10.0.0.2:1000", status: http.StatusOK},
+		{name: "Should reject a client over its limit", path: "/ids?count=10", key: "a", addr: "10.0.0.3:1000", status: http.StatusTooManyRequests},
+		{name: "Should not limit other clients", path: "/ids?count=10", key: "b", addr: "10.0.0.1:1000", status: http.StatusOK},
+		{name: "Should not limit GET /healthz", path: "/healthz", key: "a", addr: "10.0.0.1:1000", status: http.StatusOK},
+		{name: "Should key requests without header by host", path: "/id", addr: "10.0.0.9:1000", status: http.StatusOK},
+		{name: "Should ignore the port", path: "/id", addr: "10.0.0.9:2000", status: http.StatusOK},
+		{name: "Should reject a host over its limit", path: "/id", addr: "10.0.0.9:3000", status: http.StatusTooManyRequests},
+	}
+
+	for _, tc := range tests {
+		rec := serve(tc.path, tc.key, tc.addr)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d got %d", tc.name, tc.status, rec.Code)
+		}
+		if tc.status != http.StatusTooManyRequests {
+			continue
+		}
+
+		if retry := rec.Header().Get("Retry-After"); retry != "100" {
+			t.Errorf("%s: expected Retry-After 100 got %q", tc.name, retry)
+		}
+		var body struct{ Error string }
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+			t.Errorf("%s: expected a JSON error body got %s", tc.name, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package snowflakehttp
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// limiter is a token bucket per client, the client of a request being the
+// value of a header or, without one, its remote host.
+type limiter struct {
+	header string
+	rate   float64 // tokens per second
+	burst  float64
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// client returns the key of the bucket of r.
+func (l *limiter) client(r *http.Request) string {
+	if key := r.Header.Get(l.header); key != "" {
+		return "h:" + key
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	// distinct from any header value
+	return "a:" + host
+}
+
+// allow takes a token from the bucket of r if there is one, else returns
+// how long until there is.
+func (l *limiter) allow(r *http.Request) (bool, time.Duration) {
+	key := l.client(r)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep forgets the buckets that have refilled, as good as new ones, at
+// most once per refill period so that clients don't pile up forever.
+func (l *limiter) sweep(now time.Time) {
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	if now.Sub(l.swept) < refill {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
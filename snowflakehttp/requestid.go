@@ -0,0 +1,64 @@
+package snowflakehttp
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// DefaultRequestIDHeader is the header RequestIDMiddleware uses when none
+// is given.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// RequestIDMiddleware returns a middleware tagging every request with a
+// snowflake ID, set on the response header (DefaultRequestIDHeader if
+// header is empty) and in the request context, see RequestIDFrom.
+//
+// A request carrying a valid snowflake ID in the same header keeps it, so
+// the ID follows a request across services. Anything snowflake.ParseString
+// rejects, or a header given more than once, is replaced by a new ID of g.
+//
+//	mux := http.NewServeMux()
+//	http.ListenAndServe(":8080", snowflakehttp.RequestIDMiddleware(sf, "")(mux))
+func RequestIDMiddleware(g snowflake.Generator, header string) func(http.Handler) http.Handler {
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, ok := inboundRequestID(r.Header.Values(header))
+			if !ok {
+				id = g.NextID()
+			}
+
+			w.Header().Set(header, strconv.FormatUint(id, 10))
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFrom returns the request ID RequestIDMiddleware put in ctx, and
+// whether there is one.
+func RequestIDFrom(ctx context.Context) (uint64, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(uint64)
+	return id, ok
+}
+
+// inboundRequestID returns the ID of a request ID header, if it holds
+// exactly one valid snowflake ID.
+func inboundRequestID(values []string) (uint64, bool) {
+	if len(values) != 1 {
+		return 0, false
+	}
+	if _, err := snowflake.ParseString(values[0]); err != nil {
+		return 0, false
+	}
+	id, _ := strconv.ParseUint(values[0], 10, 64)
+	return id, true
+}
@@ -0,0 +1,118 @@
+package snowflakehttp_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/snowflakehttp"
+)
+
+// serveRequestID runs req through the middleware and returns the response
+// header value and the ID the handler saw in its context.
+func serveRequestID(t *testing.T, g snowflake.Generator, header string, req *http.Request) (string, uint64) {
+	t.Helper()
+
+	var seen uint64
+	h := snowflakehttp.RequestIDMiddleware(g, header)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := snowflakehttp.RequestIDFrom(r.Context())
+		if !ok {
+			t.Fatal("expected a request ID in the context")
+		}
+		seen = id
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if header == "" {
+		header = snowflakehttp.DefaultRequestIDHeader
+	}
+	return rec.Header().Get(header), seen
+}
+
+func TestRequestIDMiddleware_Generates(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	got, seen := serveRequestID(t, snowflake.New(7), "", req)
+
+	id, err := strconv.ParseUint(got, 10, 64)
+	if err != nil {
+		t.Fatalf("expected a decimal ID got %q", got)
+	}
+	if id != seen {
+		t.Errorf("expected context ID %d got %d", id, seen)
+	}
+	if snowflake.Parse(id).MachineID != 7 {
+		t.Errorf("expected field 7 got %d", snowflake.Parse(id).MachineID)
+	}
+}
+
+func TestRequestIDMiddleware_Reuses(t *testing.T) {
+	inbound := snowflake.New(3).NextID()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", strconv.FormatUint(inbound, 10))
+	got, seen := serveRequestID(t, snowflake.New(7), "", req)
+
+	if got != strconv.FormatUint(inbound, 10) {
+		t.Errorf("expected header %d got %s", inbound, got)
+	}
+	if seen != inbound {
+		t.Errorf("expected context ID %d got %d", inbound, seen)
+	}
+}
+
+func TestRequestIDMiddleware_ReplacesInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+	}{
+		{name: "Should replace an empty header", values: []string{""}},
+		{name: "Should replace garbage", values: []string{"not-an-id"}},
+		{name: "Should replace a UUID", values: []string{"6f1c2a7e-8d2b-4c1e-9a0f-3b5d7e9c1a2b"}},
+		{name: "Should replace a negative number", values: []string{"-1292053924173320192"}},
+		{name: "Should replace leading zeros", values: []string{"01292053924173320192"}},
+		{name: "Should replace a 64-bit overflow", values: []string{"18446744073709551616"}},
+		{name: "Should replace a timestamp overflow", values: []string{"9223372036854775808"}},
+		{name: "Should replace a repeated header", values: []string{"1292053924173320192", "1292053924173320193"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, v := range tc.values {
+				req.Header.Add("X-Request-ID", v)
+			}
+			got, seen := serveRequestID(t, snowflake.New(7), "", req)
+
+			if got != strconv.FormatUint(seen, 10) {
+				t.Fatalf("expected header %d got %s", seen, got)
+			}
+			if snowflake.Parse(seen).MachineID != 7 {
+				t.Errorf("expected a new ID with field 7 got %d", seen)
+			}
+		})
+	}
+}
+
+func TestRequestIDMiddleware_Header(t *testing.T) {
+	inbound := snowflake.New(3).NextID()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Trace-ID", strconv.FormatUint(inbound, 10))
+	req.Header.Set("X-Request-ID", "1292053924173320192")
+	got, seen := serveRequestID(t, snowflake.New(7), "X-Trace-ID", req)
+
+	if seen != inbound || got != strconv.FormatUint(inbound, 10) {
+		t.Errorf("expected ID %d from X-Trace-ID got %s (context %d)", inbound, got, seen)
+	}
+}
+
+func TestRequestIDFrom_Missing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if id, ok := snowflakehttp.RequestIDFrom(req.Context()); ok {
+		t.Errorf("expected no request ID got %d", id)
+	}
+}
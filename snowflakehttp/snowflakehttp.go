@@ -0,0 +1,226 @@
+// Package snowflakehttp serves snowflake IDs over HTTP.
+//
+//	sf := snowflake.New(1)
+//	http.Handle("/snowflake/", http.StripPrefix("/snowflake", snowflakehttp.Handler(sf)))
+//
+// The handler answers four routes, relative to where it is mounted:
+//
+//	GET /id          {"id":"1292053924173320192"}
+//	GET /ids?count=3 ["1292053924173320192","1292053924173320193","1292053924173320194"]
+//	GET /parse/{id}  {"id":"1292053924173320192","timestamp":1640942460724,
+//	                  "time":"2021-12-31T09:21:00.724Z","sequence":0,"field":1}
+//	GET /healthz     {"status":"ok"}
+//
+// IDs are rendered as JSON strings since JavaScript numbers can't hold
+// them exactly. A GET /id accepting text/plain before application/json
+// gets the bare number instead, which is handy with curl:
+//
+//	curl -H 'Accept: text/plain' localhost:8080/snowflake/id
+//
+// Errors are answered with a JSON body, with the status telling them apart:
+//
+//	{"error":"count 5000 is not between 1 and 1000"}
+//
+//	400  a malformed ID, with leading zeros or above 2^63-1, or a count
+//	     of GET /ids that isn't between 1 and the maximum (see WithMaxCount)
+//	404  an unknown path
+//	405  a method other than GET and HEAD
+//	429  a client over its rate limit (see WithRateLimit), with a
+//	     Retry-After header in seconds
+//	503  an unhealthy generator
+package snowflakehttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// parseResponse is the body of GET /parse/{id}.
+type parseResponse struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Time      string `json:"time"`
+	Sequence  uint64 `json:"sequence"`
+	Field     uint64 `json:"field"`
+}
+
+// errorResponse is the body of every error response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler returns a handler serving IDs of g on GET /id and GET /ids,
+// decomposing decimal IDs on GET /parse/{id} and reporting the health of g
+// on GET /healthz, for generators with a Health method such as
+// *snowflake.ID. Errors are described in the package documentation.
+func Handler(g snowflake.Generator, opts ...Option) http.Handler {
+	h := &handler{g: g, maxCount: DefaultMaxCount}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Option configures a Handler.
+type Option func(*handler)
+
+// WithMaxCount sets the largest count of GET /ids, DefaultMaxCount by
+// default. Larger counts are rejected with 400. Panics if max is less
+// than 1.
+func WithMaxCount(max int) Option {
+	if max < 1 {
+		panic("snowflakehttp: max count " + strconv.Itoa(max) + " is less than 1")
+	}
+	return func(h *handler) { h.maxCount = max }
+}
+
+// WithRateLimit limits each client to perSecond requests to GET /id and
+// GET /ids per second on average, and burst requests at once. A client is
+// identified by the value of header, e.g. an API key, or by its remote
+// host for requests without it. Requests over the limit are rejected with
+// 429. Panics if perSecond isn't positive or burst is less than 1.
+func WithRateLimit(header string, perSecond float64, burst int) Option {
+	if !(perSecond > 0) || burst < 1 {
+		panic(fmt.Sprintf("snowflakehttp: rate limit of %v per second, burst %d", perSecond, burst))
+	}
+	return func(h *handler) {
+		h.limiter = &limiter{
+			header:  header,
+			rate:    perSecond,
+			burst:   float64(burst),
+			now:     time.Now,
+			buckets: make(map[string]*tokenBucket),
+		}
+	}
+}
+
+type handler struct {
+	g        snowflake.Generator
+	maxCount int
+	limiter  *limiter // nil without a rate limit
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var serve func(http.ResponseWriter, *http.Request)
+	switch {
+	case r.URL.Path == "/id":
+		serve = h.limit(h.id)
+	case r.URL.Path == "/ids":
+		serve = h.limit(h.ids)
+	case strings.HasPrefix(r.URL.Path, "/parse/"):
+		serve = h.parse
+	case r.URL.Path == "/healthz":
+		serve = h.healthz
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	serve(w, r)
+}
+
+// limit returns serve behind the rate limit, if any.
+func (h *handler) limit(serve func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	if h.limiter == nil {
+		return serve
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok, retry := h.limiter.allow(r); !ok {
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(retry.Seconds())), 10))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+		serve(w, r)
+	}
+}
+
+// id serves GET /id.
+func (h *handler) id(w http.ResponseWriter, r *http.Request) {
+	id := strconv.FormatUint(h.g.NextID(), 10)
+
+	if acceptsText(r) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(id + "\n"))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		ID string `json:"id"`
+	}{id})
+}
+
+// parse serves GET /parse/{id}.
+func (h *handler) parse(w http.ResponseWriter, r *http.Request) {
+	// ParseString rejects what Parse would misread: IDs with leading
+	// zeros or above 2^63-1
+	s := strings.TrimPrefix(r.URL.Path, "/parse/")
+	sid, err := snowflake.ParseString(s)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, parseResponse{
+		ID:        s,
+		Timestamp: sid.Timestamp,
+		Time:      time.UnixMilli(sid.Timestamp).UTC().Format(time.RFC3339Nano),
+		Sequence:  sid.Sequence,
+		Field:     sid.MachineID,
+	})
+}
+
+// healthz serves GET /healthz.
+func (h *handler) healthz(w http.ResponseWriter, r *http.Request) {
+	if g, ok := h.g.(interface{ Health() error }); ok {
+		if err := g.Health(); err != nil {
+			writeError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Status string `json:"status"`
+	}{"ok"})
+}
+
+// acceptsText reports whether the Accept header of r lists text/plain
+// before application/json. Quality values are ignored.
+func acceptsText(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+				mediaType = mediaType[:i]
+			}
+			switch strings.TrimSpace(mediaType) {
+			case "text/plain":
+				return true
+			case "application/json":
+				return false
+			}
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
@@ -0,0 +1,175 @@
+package snowflakehttp_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/snowflakehttp"
+)
+
+func TestHandler_ID(t *testing.T) {
+	srv := httptest.NewServer(snowflakehttp.Handler(snowflake.New(7)))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+	}{
+		{name: "Should default to JSON", contentType: "application/json"},
+		{name: "Should serve JSON to browsers", accept: "text/html,application/json;q=0.9,*/*;q=0.8", contentType: "application/json"},
+		{name: "Should serve text/plain when preferred", accept: "text/plain, application/json", contentType: "text/plain; charset=utf-8"},
+		{name: "Should serve JSON when preferred", accept: "application/json, text/plain", contentType: "application/json"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/id", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status %d got %d", http.StatusOK, resp.StatusCode)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != tc.contentType {
+				t.Fatalf("expected content type %q got %q", tc.contentType, ct)
+			}
+
+			var s string
+			if tc.contentType == "application/json" {
+				var body map[string]interface{}
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+					t.Fatal(err)
+				}
+				str, ok := body["id"].(string)
+				if !ok {
+					t.Fatalf("expected the ID as a JSON string got %T", body["id"])
+				}
+				s = str
+			} else {
+				b, err := io.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				s = strings.TrimSuffix(string(b), "\n")
+			}
+
+			id, err := strconv.ParseUint(s, 10, 64)
+			if err != nil {
+				t.Fatalf("expected a decimal ID got %q", s)
+			}
+			if field := snowflake.Parse(id).MachineID; field != 7 {
+				t.Errorf("expected field 7 got %d", field)
+			}
+		})
+	}
+}
+
+func TestHandler_Parse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	snowflakehttp.Handler(snowflake.New(1)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/parse/1292053924173320192", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d got %d", http.StatusOK, rec.Code)
+	}
+
+	expected := `{"id":"1292053924173320192","timestamp":1640942460724,"time":"2021-12-31T09:21:00.724Z","sequence":0,"field":1}`
+	if body := strings.TrimSpace(rec.Body.String()); body != expected {
+		t.Errorf("expected body %s got %s", expected, body)
+	}
+}
+
+func TestHandler_Errors(t *testing.T) {
+	h := snowflakehttp.Handler(snowflake.New(1))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+	}{
+		{name: "Should return 400 for a malformed ID", method: http.MethodGet, path: "/parse/12ab", status: http.StatusBadRequest},
+		{name: "Should return 400 for a negative ID", method: http.MethodGet, path: "/parse/-1", status: http.StatusBadRequest},
+		{name: "Should return 400 for an overflowing ID", method: http.MethodGet, path: "/parse/18446744073709551616", status: http.StatusBadRequest},
+		{name: "Should return 400 for an ID above the max int64", method: http.MethodGet, path: "/parse/9223372036854775808", status: http.StatusBadRequest},
+		{name: "Should return 400 for the max uint64", method: http.MethodGet, path: "/parse/18446744073709551615", status: http.StatusBadRequest},
+		{name: "Should return 400 for leading zeros", method: http.MethodGet, path: "/parse/00042", status: http.StatusBadRequest},
+		{name: "Should return 400 for a missing ID", method: http.MethodGet, path: "/parse/", status: http.StatusBadRequest},
+		{name: "Should return 404 for unknown paths", method: http.MethodGet, path: "/uuid", status: http.StatusNotFound},
+		{name: "Should return 405 for POST", method: http.MethodPost, path: "/id", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+
+			if rec.Code != tc.status {
+				t.Errorf("expected status %d got %d", tc.status, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected content type application/json got %q", ct)
+			}
+
+			var body struct{ Error string }
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+				t.Errorf("expected a JSON error body got %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+// counter is a generator without health checks.
+type counter struct{}
+
+func (counter) NextID() uint64 { return 1 }
+
+func TestHandler_Healthz(t *testing.T) {
+	lost := make(chan struct{})
+	close(lost)
+
+	tests := []struct {
+		name   string
+		g      snowflake.Generator
+		status int
+	}{
+		{name: "Should return 200 for a healthy generator", g: snowflake.New(1), status: http.StatusOK},
+		{name: "Should return 503 for an unhealthy generator", g: snowflake.New(1, snowflake.WithLease(lost)), status: http.StatusServiceUnavailable},
+		{name: "Should return 200 for a generator without health checks", g: counter{}, status: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			snowflakehttp.Handler(tc.g).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			if rec.Code != tc.status {
+				t.Errorf("expected status %d got %d", tc.status, rec.Code)
+			}
+
+			var body struct{ Status, Error string }
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("expected a JSON body got %s", rec.Body.String())
+			}
+			if tc.status == http.StatusOK && body.Status != "ok" {
+				t.Errorf("expected status ok got %s", rec.Body.String())
+			}
+			if tc.status != http.StatusOK && !strings.Contains(body.Error, snowflake.ErrLeaseLost.Error()) {
+				t.Errorf("expected the error to mention %q got %q", snowflake.ErrLeaseLost, body.Error)
+			}
+		})
+	}
+}
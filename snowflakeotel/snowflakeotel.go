@@ -0,0 +1,124 @@
+// Package snowflakeotel instruments snowflake generators with
+// OpenTelemetry, so that time spent waiting for the next millisecond once
+// a sequence is exhausted shows up in traces and metrics instead of as
+// unexplained gaps.
+//
+//	sf, err := snowflakeotel.Wrap(snowflake.New(1), otel.Meter("orders"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	id := sf.NextIDContext(ctx)
+//	span.SetAttributes(snowflakeotel.AttributeID(id))
+//
+// Each wait is recorded in the snowflake.wait.duration histogram and, for
+// NextIDContext, as a snowflake.wait event on the span of ctx.
+package snowflakeotel
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// timestampShift is the position of the timestamp of an ID, above the
+// field and the sequence in either segment order.
+const timestampShift = 22
+
+// statser is implemented by generators that keep stats, such as
+// *snowflake.ID and *snowflake.ID2.
+type statser interface {
+	Stats() snowflake.Stats
+}
+
+// AttributeID returns a snowflake.id attribute holding id as a decimal
+// string, since attribute values are int64 and readers of traces may
+// handle large numbers poorly.
+func AttributeID(id uint64) attribute.KeyValue {
+	return attribute.String("snowflake.id", strconv.FormatUint(id, 10))
+}
+
+var _ snowflake.Generator = (*Generator)(nil)
+
+// Generator is a snowflake.Generator recording the waits of the generator
+// it wraps.
+type Generator struct {
+	g     snowflake.Generator
+	stats func() snowflake.Stats // nil if the generator keeps no stats
+
+	hist  metric.Float64Histogram
+	attrs metric.MeasurementOption
+
+	mtx    sync.Mutex
+	waited time.Duration // Stats().Waited as of the last check
+
+	lastMs atomic.Uint64 // timestamp of the last ID issued, to spot new milliseconds
+}
+
+// Wrap returns a Generator issuing the IDs of g and recording its waits
+// with meter, along with attrs. Waits are read from the Stats of g, so
+// generators other than *snowflake.ID and *snowflake.ID2 are passed
+// through unrecorded.
+func Wrap(g snowflake.Generator, meter metric.Meter, attrs ...attribute.KeyValue) (*Generator, error) {
+	hist, err := meter.Float64Histogram("snowflake.wait.duration",
+		metric.WithDescription("Time spent waiting for the next millisecond after a sequence was exhausted."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Generator{g: g, hist: hist, attrs: metric.WithAttributes(attrs...)}
+	if s, ok := g.(statser); ok {
+		w.stats = s.Stats
+		w.waited = s.Stats().Waited
+	}
+	return w, nil
+}
+
+// NextID returns the next ID of the wrapped generator.
+func (g *Generator) NextID() uint64 { return g.NextIDContext(context.Background()) }
+
+// NextIDContext is like NextID but also adds a snowflake.wait event to the
+// span of ctx when the call had to wait.
+func (g *Generator) NextIDContext(ctx context.Context) uint64 {
+	id := g.g.NextID()
+
+	// a wait always ends with the first ID of a millisecond, so the stats
+	// only need to be checked then. The sequence of that ID isn't read, as
+	// it sits above the field under FieldLow
+	if g.stats != nil && g.lastMs.Swap(id>>timestampShift) != id>>timestampShift {
+		if waited := g.sinceLastCheck(); waited > 0 {
+			g.hist.Record(ctx, waited.Seconds(), g.attrs)
+			trace.SpanFromContext(ctx).AddEvent("snowflake.wait", trace.WithAttributes(
+				attribute.Int64("snowflake.wait.ns", int64(waited)),
+				AttributeID(id),
+			))
+		}
+	}
+
+	return id
+}
+
+// sinceLastCheck returns how long the generator waited since the last
+// call. Concurrent waits are attributed to whichever call checks first.
+func (g *Generator) sinceLastCheck() time.Duration {
+	waited := g.stats().Waited
+
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	if waited <= g.waited {
+		return 0
+	}
+	d := waited - g.waited
+	g.waited = waited
+	return d
+}
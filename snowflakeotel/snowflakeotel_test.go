@@ -0,0 +1,149 @@
+package snowflakeotel_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/snowflakeotel"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// tickingClock moves forward by step on every reading.
+type tickingClock struct {
+	mtx  sync.Mutex
+	t    time.Time
+	step time.Duration
+}
+
+func (c *tickingClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.t = c.t.Add(c.step)
+	return c.t
+}
+
+func TestWrap(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	spans := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test")
+
+	// 10000 clock readings per millisecond, so the generator exhausts the
+	// sequence of every millisecond and waits for the next one
+	sf := snowflake.New(1, snowflake.WithClock(&tickingClock{t: snowflake.Epoch().Add(time.Hour), step: 100 * time.Nanosecond}))
+	w, err := snowflakeotel.Wrap(sf, meter, attribute.String("generator", "orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, span := tracer.Start(context.Background(), "burst")
+	for i := 0; i < 3*4096+10; i++ {
+		w.NextIDContext(ctx)
+	}
+	span.End()
+
+	stats := sf.Stats()
+	if stats.Rollovers != 3 {
+		t.Fatalf("expected 3 rollovers got %d", stats.Rollovers)
+	}
+
+	var events int
+	for _, ev := range spans.Ended()[0].Events() {
+		if ev.Name == "snowflake.wait" {
+			events++
+		}
+	}
+	if events != 3 {
+		t.Errorf("expected 3 wait events got %d", events)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	hist := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Histogram[float64])
+	point := hist.DataPoints[0]
+	if point.Count != 3 {
+		t.Errorf("expected 3 recorded waits got %d", point.Count)
+	}
+	if diff := point.Sum - stats.Waited.Seconds(); diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("expected a total of %v got %vs", stats.Waited, point.Sum)
+	}
+	if v, ok := point.Attributes.Value("generator"); !ok || v.AsString() != "orders" {
+		t.Errorf("expected the generator attribute got %v", point.Attributes)
+	}
+}
+
+func TestWrap_FieldLow(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	// the first ID of a FieldLow millisecond has the field in its low bits
+	sf := snowflake.New(1, snowflake.WithSegmentOrder(snowflake.FieldLow),
+		snowflake.WithClock(&tickingClock{t: snowflake.Epoch().Add(time.Hour), step: 100 * time.Nanosecond}))
+	w, err := snowflakeotel.Wrap(sf, meter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3*4096+10; i++ {
+		w.NextID()
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	var count uint64
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if hist, ok := m.Data.(metricdata.Histogram[float64]); ok {
+				for _, point := range hist.DataPoints {
+					count += point.Count
+				}
+			}
+		}
+	}
+	if count != 3 {
+		t.Errorf("expected 3 recorded waits got %d", count)
+	}
+}
+
+func TestWrap_NoWaits(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test")
+
+	w, err := snowflakeotel.Wrap(snowflake.New(1), meter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		w.NextID()
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if hist, ok := m.Data.(metricdata.Histogram[float64]); ok && len(hist.DataPoints) > 0 {
+				t.Errorf("expected no recorded waits got %+v", hist.DataPoints)
+			}
+		}
+	}
+}
+
+func TestAttributeID(t *testing.T) {
+	kv := snowflakeotel.AttributeID(1292053924173320192)
+	if kv.Key != "snowflake.id" || kv.Value.AsString() != "1292053924173320192" {
+		t.Errorf("expected snowflake.id=1292053924173320192 got %s=%s", kv.Key, kv.Value.Emit())
+	}
+}
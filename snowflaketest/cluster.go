@@ -0,0 +1,311 @@
+package snowflaketest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// LoadPattern scales the rate of a node at elapsed time since its Cluster
+// started: 1 is the rate given to Run, 0 is idle.
+type LoadPattern func(elapsed time.Duration) float64
+
+// Steady is the LoadPattern of nodes by default, issuing at the rate given
+// to Run throughout.
+func Steady() LoadPattern {
+	return func(time.Duration) float64 { return 1 }
+}
+
+// Burst returns a LoadPattern issuing at factor times the rate for length
+// at the start of every period, and at the rate otherwise.
+func Burst(period, length time.Duration, factor float64) LoadPattern {
+	if period <= 0 {
+		panic("snowflaketest: burst period must be positive")
+	}
+	return func(elapsed time.Duration) float64 {
+		if elapsed%period < length {
+			return factor
+		}
+		return 1
+	}
+}
+
+// ClusterOption configures a Cluster.
+type ClusterOption func(*Cluster)
+
+// WithFields sets the fields of the nodes, one per node, instead of 0 to
+// n-1, e.g. to check a proposed assignment or simulate a misconfigured
+// node. Panics if there isn't one field per node.
+func WithFields(fields ...uint64) ClusterOption {
+	return func(c *Cluster) {
+		if len(fields) != len(c.nodes) {
+			panic(fmt.Sprintf("snowflaketest: %d fields for %d nodes", len(fields), len(c.nodes)))
+		}
+		for i, field := range fields {
+			c.nodes[i].field = field
+		}
+	}
+}
+
+// WithNodeOptions configures the generator of every node with opts too,
+// e.g. to check a proposed WithMaxForwardDrift. Options moving the
+// sequence, such as WithSegmentOrder, aren't supported.
+func WithNodeOptions(opts ...snowflake.Option) ClusterOption {
+	return func(c *Cluster) {
+		c.opts = append(c.opts, opts...)
+	}
+}
+
+// WithSkew sets the clock of node ahead of the shared clock by skew, or
+// behind it if skew is negative.
+func WithSkew(node int, skew time.Duration) ClusterOption {
+	return func(c *Cluster) {
+		c.node(node).skew = skew
+	}
+}
+
+// WithBackwardsStep steps the clock of node back by step at elapsed time
+// at since the Cluster started, as an NTP correction would. The steps of
+// a node add up.
+func WithBackwardsStep(node int, at, step time.Duration) ClusterOption {
+	return func(c *Cluster) {
+		n := c.node(node)
+		n.steps = append(n.steps, clockStep{at: at, step: step})
+		sort.Slice(n.steps, func(i, j int) bool { return n.steps[i].at < n.steps[j].at })
+	}
+}
+
+// WithLoad sets the load pattern of node (default: Steady).
+func WithLoad(node int, load LoadPattern) ClusterOption {
+	return func(c *Cluster) {
+		c.node(node).load = load
+	}
+}
+
+// WithOrderTolerance makes Run report the IDs below an ID another node
+// issued more than tolerance before them on the shared clock, to check how
+// well IDs sort across the fleet. Without it, only IDs out of order within
+// a node are reported.
+func WithOrderTolerance(tolerance time.Duration) ClusterOption {
+	return func(c *Cluster) {
+		c.tolerance = tolerance
+		c.crossNode = true
+	}
+}
+
+// clockStep is a backwards step of the clock of a node. (internal-use only)
+type clockStep struct {
+	at, step time.Duration
+}
+
+// clusterNode is a node of a Cluster. (internal-use only)
+type clusterNode struct {
+	field uint64
+	skew  time.Duration
+	steps []clockStep
+	load  LoadPattern
+	fake  *Fake
+}
+
+// clock returns the time of the node at elapsed time since the Cluster
+// started, from the shared time now. (internal-use only)
+func (n *clusterNode) clock(now time.Time, elapsed time.Duration) time.Time {
+	now = now.Add(n.skew)
+	for _, s := range n.steps {
+		if s.at > elapsed {
+			break
+		}
+		now = now.Add(-s.step)
+	}
+	return now
+}
+
+// Collision is an ID issued more than once.
+type Collision struct {
+	ID uint64
+	// Nodes are the nodes that issued it, in the order they did.
+	Nodes []int
+}
+
+// OrderViolation is an ID issued below an ID issued before it.
+type OrderViolation struct {
+	// Node issued ID at elapsed time At since the Cluster started.
+	Node int
+	ID   uint64
+	At   time.Duration
+	// PrevNode issued Prev, the ID that ID should be above: the last ID of
+	// Node itself, or one of another node issued more than the tolerance
+	// set with WithOrderTolerance before.
+	PrevNode int
+	Prev     uint64
+}
+
+// NodeStats are the counters of a node over a Run.
+type NodeStats struct {
+	Field uint64
+	// IDs is the number of IDs issued.
+	IDs int
+	// MaxLead is how far the timestamps of the node ran ahead of its clock
+	// at most, from sequences used up or clocks stepped back.
+	MaxLead time.Duration
+	// Steps is the number of backwards steps of its clock.
+	Steps int
+}
+
+// ClusterReport is the outcome of a Run.
+type ClusterReport struct {
+	// IDs is the number of IDs issued by every node.
+	IDs             int
+	Collisions      []Collision
+	OrderViolations []OrderViolation
+	Nodes           []NodeStats
+}
+
+// OK reports whether the Run found no collision nor order violation.
+func (r ClusterReport) OK() bool { return len(r.Collisions) == 0 && len(r.OrderViolations) == 0 }
+
+// Cluster simulates a fleet of nodes, each a Fake, driven by a shared
+// clock that only moves in Run, to check a configuration of fields,
+// epoch and options against clock skew, clock steps and load before
+// rolling it out.
+//
+//	c := snowflaketest.NewCluster(3, snowflaketest.WithSkew(1, -50*time.Millisecond))
+//	report := c.Run(time.Second, 10000)
+//	if !report.OK() {
+//		t.Errorf("%d collisions, %d order violations", len(report.Collisions), len(report.OrderViolations))
+//	}
+//
+// A Cluster isn't safe for concurrent use.
+type Cluster struct {
+	nodes     []*clusterNode
+	opts      []snowflake.Option
+	now       time.Time
+	tolerance time.Duration
+	crossNode bool
+}
+
+// NewCluster returns a new snowflaketest.Cluster of n nodes, with fields 0
+// to n-1, starting at Start. Panics if n is less than 1 or more than the
+// fields available.
+func NewCluster(n int, opts ...ClusterOption) *Cluster {
+	if n < 1 || uint64(n) > snowflake.MaxField()+1 {
+		panic(fmt.Sprintf("snowflaketest: can't simulate %d nodes", n))
+	}
+
+	c := &Cluster{nodes: make([]*clusterNode, n), now: Start}
+	for i := range c.nodes {
+		c.nodes[i] = &clusterNode{field: uint64(i), load: Steady()}
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	for _, n := range c.nodes {
+		n.fake = newFake(n.field, c.opts)
+		n.fake.SetTime(n.clock(c.now, 0))
+	}
+	return c
+}
+
+// Node returns the generator of node i, e.g. to issue IDs between Runs.
+func (c *Cluster) Node(i int) *Fake { return c.node(i).fake }
+
+// Now returns the time of the shared clock.
+func (c *Cluster) Now() time.Time { return c.now }
+
+// node returns node i, panicking if there is none. (internal-use only)
+func (c *Cluster) node(i int) *clusterNode {
+	if i < 0 || i >= len(c.nodes) {
+		panic(fmt.Sprintf("snowflaketest: no node %d in a cluster of %d", i, len(c.nodes)))
+	}
+	return c.nodes[i]
+}
+
+// issued is an ID issued by a node. (internal-use only)
+type issued struct {
+	id   uint64
+	node int
+}
+
+// Run moves the shared clock on by duration, a millisecond at a time,
+// with every node issuing ratePerNode IDs per second scaled by its load
+// pattern, and reports what went wrong. Runs carry on from the time and
+// IDs of the previous one, but only report what happened during theirs.
+func (c *Cluster) Run(duration time.Duration, ratePerNode float64) ClusterReport {
+	report := ClusterReport{Nodes: make([]NodeStats, len(c.nodes))}
+	for i, n := range c.nodes {
+		report.Nodes[i].Field = n.field
+	}
+
+	seen := make(map[uint64][]int)
+	last := make([]issued, len(c.nodes))
+	for i := range last {
+		last[i] = issued{node: -1}
+	}
+	// maxBefore[t] is the greatest ID issued up to millisecond t, for
+	// order violations across nodes
+	var maxBefore []issued
+	tolerance := int(c.tolerance / time.Millisecond)
+
+	start := c.now.Sub(Start)
+	owed := make([]float64, len(c.nodes))
+	for ms := 0; time.Duration(ms)*time.Millisecond < duration; ms++ {
+		elapsed := start + time.Duration(ms)*time.Millisecond
+		now := Start.Add(elapsed)
+
+		greatest := issued{node: -1}
+		if ms > 0 {
+			greatest = maxBefore[ms-1]
+		}
+		bound := issued{node: -1}
+		if c.crossNode && ms-tolerance-1 >= 0 {
+			bound = maxBefore[ms-tolerance-1]
+		}
+
+		for i, n := range c.nodes {
+			for _, s := range n.steps {
+				if s.at > elapsed-time.Millisecond && s.at <= elapsed {
+					report.Nodes[i].Steps++
+				}
+			}
+			clock := n.clock(now, elapsed)
+			n.fake.SetTime(clock)
+
+			owed[i] += ratePerNode * n.load(elapsed) / 1000
+			for ; owed[i] >= 1; owed[i]-- {
+				id := n.fake.NextID()
+				report.IDs++
+				report.Nodes[i].IDs++
+
+				if len(seen[id]) == 1 {
+					report.Collisions = append(report.Collisions, Collision{ID: id})
+				}
+				seen[id] = append(seen[id], i)
+
+				if lead := timeOf(id).Sub(clock); lead > report.Nodes[i].MaxLead {
+					report.Nodes[i].MaxLead = lead
+				}
+
+				switch prev := last[i]; {
+				case prev.node >= 0 && id <= prev.id:
+					report.OrderViolations = append(report.OrderViolations, OrderViolation{Node: i, ID: id, At: elapsed, PrevNode: i, Prev: prev.id})
+				case bound.node >= 0 && bound.node != i && id < bound.id:
+					report.OrderViolations = append(report.OrderViolations, OrderViolation{Node: i, ID: id, At: elapsed, PrevNode: bound.node, Prev: bound.id})
+				}
+				last[i] = issued{id: id, node: i}
+
+				if greatest.node < 0 || id > greatest.id {
+					greatest = issued{id: id, node: i}
+				}
+			}
+		}
+		maxBefore = append(maxBefore, greatest)
+	}
+	c.now = c.now.Add(duration)
+
+	for i := range report.Collisions {
+		report.Collisions[i].Nodes = seen[report.Collisions[i].ID]
+	}
+	return report
+}
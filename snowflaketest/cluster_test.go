@@ -0,0 +1,141 @@
+package snowflaketest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/snowflaketest"
+)
+
+func TestCluster(t *testing.T) {
+	c := snowflaketest.NewCluster(3)
+	report := c.Run(time.Second, 10000)
+
+	if !report.OK() {
+		t.Errorf("expected no collision nor order violation got %d and %d", len(report.Collisions), len(report.OrderViolations))
+	}
+	if report.IDs != 30000 {
+		t.Errorf("expected 30000 IDs got %d", report.IDs)
+	}
+	for i, stats := range report.Nodes {
+		if stats.Field != uint64(i) {
+			t.Errorf("expected node %d to have field %d got %d", i, i, stats.Field)
+		}
+		if stats.IDs != 10000 {
+			t.Errorf("expected node %d to issue 10000 IDs got %d", i, stats.IDs)
+		}
+	}
+
+	// the next run carries on from the shared time
+	c.Run(time.Second, 10)
+	if now, want := c.Now(), snowflaketest.Start.Add(2*time.Second); !now.Equal(want) {
+		t.Errorf("expected time %s got %s", want, now)
+	}
+	if got, want := snowflake.Parse(c.Node(0).NextID()).Timestamp, c.Now().Add(-time.Millisecond).UnixMilli(); got != want {
+		t.Errorf("expected the timestamp of the last tick %d got %d", want, got)
+	}
+}
+
+func TestCluster_SharedField(t *testing.T) {
+	c := snowflaketest.NewCluster(3, snowflaketest.WithFields(7, 7, 8))
+	report := c.Run(100*time.Millisecond, 1000)
+
+	if len(report.Collisions) != 100 {
+		t.Fatalf("expected 100 collisions got %d", len(report.Collisions))
+	}
+	for _, collision := range report.Collisions {
+		if len(collision.Nodes) != 2 || collision.Nodes[0] != 0 || collision.Nodes[1] != 1 {
+			t.Errorf("expected ID %d to be issued by nodes [0 1] got %v", collision.ID, collision.Nodes)
+		}
+	}
+	if report.Nodes[0].Field != 7 || report.Nodes[1].Field != 7 {
+		t.Errorf("expected nodes 0 and 1 to have field 7 got %d and %d", report.Nodes[0].Field, report.Nodes[1].Field)
+	}
+}
+
+func TestCluster_BackwardsStep(t *testing.T) {
+	c := snowflaketest.NewCluster(2, snowflaketest.WithBackwardsStep(1, 100*time.Millisecond, 50*time.Millisecond))
+	report := c.Run(time.Second, 1000)
+
+	if !report.OK() {
+		t.Errorf("expected no collision nor order violation got %d and %d", len(report.Collisions), len(report.OrderViolations))
+	}
+	if steps := report.Nodes[1].Steps; steps != 1 {
+		t.Errorf("expected 1 step got %d", steps)
+	}
+	if lead := report.Nodes[1].MaxLead; lead <= 0 || lead > 50*time.Millisecond {
+		t.Errorf("expected a lead of up to %s got %s", 50*time.Millisecond, lead)
+	}
+	if lead := report.Nodes[0].MaxLead; lead != 0 {
+		t.Errorf("expected no lead got %s", lead)
+	}
+}
+
+func TestCluster_OrderTolerance(t *testing.T) {
+	tc := []struct {
+		name       string
+		tolerance  time.Duration
+		violations bool
+	}{
+		{"Should report IDs sorting before those of another node", 10 * time.Millisecond, true},
+		{"Should tolerate skew within the tolerance", 100 * time.Millisecond, false},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			c := snowflaketest.NewCluster(2,
+				snowflaketest.WithSkew(1, -50*time.Millisecond),
+				snowflaketest.WithOrderTolerance(tt.tolerance))
+			report := c.Run(time.Second, 1000)
+
+			if got := len(report.OrderViolations) > 0; got != tt.violations {
+				t.Fatalf("expected order violations %t got %d", tt.violations, len(report.OrderViolations))
+			}
+			for _, v := range report.OrderViolations {
+				if v.Node != 1 || v.PrevNode != 0 || v.ID >= v.Prev {
+					t.Errorf("expected node 1 to issue below node 0 got %+v", v)
+				}
+			}
+		})
+	}
+
+	// only order within a node is checked by default
+	c := snowflaketest.NewCluster(2, snowflaketest.WithSkew(1, -50*time.Millisecond))
+	if report := c.Run(time.Second, 1000); !report.OK() {
+		t.Errorf("expected no order violation got %d", len(report.OrderViolations))
+	}
+}
+
+func TestCluster_Load(t *testing.T) {
+	c := snowflaketest.NewCluster(2, snowflaketest.WithLoad(0, snowflaketest.Burst(100*time.Millisecond, 10*time.Millisecond, 5)))
+	report := c.Run(time.Second, 1000)
+
+	if got := report.Nodes[0].IDs; got != 1400 {
+		t.Errorf("expected 1400 IDs from the bursting node got %d", got)
+	}
+	if got := report.Nodes[1].IDs; got != 1000 {
+		t.Errorf("expected 1000 IDs from the steady node got %d", got)
+	}
+}
+
+func TestCluster_SequenceExhausted(t *testing.T) {
+	c := snowflaketest.NewCluster(1)
+	report := c.Run(10*time.Millisecond, 1e7)
+
+	if !report.OK() {
+		t.Errorf("expected no collision nor order violation got %d and %d", len(report.Collisions), len(report.OrderViolations))
+	}
+	if lead := report.Nodes[0].MaxLead; lead <= 0 {
+		t.Errorf("expected IDs to run ahead of the clock got %s", lead)
+	}
+}
+
+func TestWithFields_Mismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected WithFields with too few fields to panic")
+		}
+	}()
+	snowflaketest.NewCluster(3, snowflaketest.WithFields(1, 2))
+}
@@ -0,0 +1,124 @@
+// Package snowflaketest provides deterministic snowflake generators for
+// testing code that consumes snowflake IDs, without sleeping.
+//
+//	f := snowflaketest.NewFake(1)
+//	a := f.NextID()
+//	f.Advance(time.Second)
+//	b := f.NextID()
+//	// a == snowflaketest.IDAt(snowflaketest.Start, 1, 0)
+//	// b == snowflaketest.IDAt(snowflaketest.Start.Add(time.Second), 1, 0)
+//
+// Cluster runs a fleet of Fakes against a shared clock, to find colliding
+// or misordered IDs under clock skew, clock steps and load.
+package snowflaketest
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// Start is the time a Fake starts at.
+var Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const (
+	fieldShift     = 12
+	timestampShift = 22
+	maxSequence    = 0xFFF
+)
+
+// Fake is a snowflake generator whose time only moves when told to. IDs
+// issued at the same time get increasing sequence numbers, starting at 0.
+//
+// Like a real generator, a Fake never issues IDs going backwards: after
+// SetTime to an earlier time it keeps issuing from the latest time it has
+// seen, and once the sequence of a millisecond is used up it moves its
+// time on by a millisecond instead of waiting.
+type Fake struct {
+	mtx  sync.Mutex
+	now  time.Time
+	id   *snowflake.ID
+	last uint64
+}
+
+var _ snowflake.Generator = (*Fake)(nil)
+
+// NewFake returns a new snowflaketest.Fake issuing IDs with the given
+// field (max field value: 1023), starting at Start.
+func NewFake(field uint64) *Fake { return newFake(field, nil) }
+
+// newFake returns a new Fake whose generator is also configured with
+// opts. (internal-use only)
+func newFake(field uint64, opts []snowflake.Option) *Fake {
+	f := &Fake{now: Start}
+	f.id = snowflake.New(field, append(opts[:len(opts):len(opts)], snowflake.WithClock(fakeClock{f}))...)
+	return f
+}
+
+// NextID returns the next ID.
+func (f *Fake) NextID() uint64 {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.last&maxSequence == maxSequence {
+		// the generator would wait for a clock that never moves
+		if next := timeOf(f.last).Add(time.Millisecond); f.now.Before(next) {
+			f.now = next
+		}
+	}
+
+	f.last = f.id.NextID()
+	return f.last
+}
+
+// Advance moves the time of f on by d.
+func (f *Fake) Advance(d time.Duration) {
+	f.mtx.Lock()
+	f.now = f.now.Add(d)
+	f.mtx.Unlock()
+}
+
+// SetTime sets the time of f to t, which must not be before the epoch.
+func (f *Fake) SetTime(t time.Time) {
+	f.mtx.Lock()
+	f.now = t
+	f.mtx.Unlock()
+}
+
+// Now returns the time of f.
+func (f *Fake) Now() time.Time {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	return f.now
+}
+
+// fakeClock reads the time of a Fake. It's only read by the generator of
+// the Fake, with f.mtx held.
+type fakeClock struct {
+	f *Fake
+}
+
+func (c fakeClock) Now() time.Time { return c.f.now }
+
+// IDAt returns the ID issued at t, truncated to the millisecond, with the
+// given field and sequence number, for building exact fixtures. It panics
+// if t is before the epoch or field or seq are out of range.
+func IDAt(t time.Time, field, seq uint64) uint64 {
+	ms := t.UnixMilli() - snowflake.Epoch().UnixMilli()
+	switch {
+	case ms < 0:
+		panic(fmt.Sprintf("snowflaketest: %s is before the epoch", t))
+	case field > snowflake.MaxField():
+		panic(fmt.Sprintf("snowflaketest: field %d out of range", field))
+	case seq > maxSequence:
+		panic(fmt.Sprintf("snowflaketest: sequence %d out of range", seq))
+	}
+	return uint64(ms)<<timestampShift | field<<fieldShift | seq
+}
+
+// timeOf returns the time of id.
+func timeOf(id uint64) time.Time {
+	return time.UnixMilli(snowflake.Parse(id).Timestamp)
+}
@@ -0,0 +1,103 @@
+package snowflaketest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/snowflaketest"
+)
+
+func TestFake(t *testing.T) {
+	f := snowflaketest.NewFake(1)
+
+	want := []uint64{
+		snowflaketest.IDAt(snowflaketest.Start, 1, 0),
+		snowflaketest.IDAt(snowflaketest.Start, 1, 1),
+		snowflaketest.IDAt(snowflaketest.Start, 1, 2),
+	}
+	for i, w := range want {
+		if got := f.NextID(); got != w {
+			t.Errorf("expected ID %d to be %d got %d", i, w, got)
+		}
+	}
+
+	f.Advance(time.Second)
+	if got, w := f.NextID(), snowflaketest.IDAt(snowflaketest.Start.Add(time.Second), 1, 0); got != w {
+		t.Errorf("expected %d after Advance got %d", w, got)
+	}
+
+	at := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+	f.SetTime(at)
+	if got, w := f.NextID(), snowflaketest.IDAt(at, 1, 0); got != w {
+		t.Errorf("expected %d after SetTime got %d", w, got)
+	}
+	if !f.Now().Equal(at) {
+		t.Errorf("expected time %s got %s", at, f.Now())
+	}
+}
+
+func TestFake_Backwards(t *testing.T) {
+	f := snowflaketest.NewFake(1)
+	f.NextID()
+
+	f.SetTime(snowflaketest.Start.Add(-time.Hour))
+	if got, w := f.NextID(), snowflaketest.IDAt(snowflaketest.Start, 1, 1); got != w {
+		t.Errorf("expected %d got %d", w, got)
+	}
+}
+
+func TestFake_SequenceExhausted(t *testing.T) {
+	f := snowflaketest.NewFake(1)
+	for i := 0; i < 4096; i++ {
+		f.NextID()
+	}
+
+	next := snowflaketest.Start.Add(time.Millisecond)
+	if got, w := f.NextID(), snowflaketest.IDAt(next, 1, 0); got != w {
+		t.Errorf("expected %d got %d", w, got)
+	}
+	if !f.Now().Equal(next) {
+		t.Errorf("expected time %s got %s", next, f.Now())
+	}
+}
+
+func TestIDAt(t *testing.T) {
+	at := time.Date(2021, 12, 31, 9, 21, 0, 724e6, time.UTC)
+	id := snowflaketest.IDAt(at, 24, 7)
+
+	sid := snowflake.Parse(id)
+	if sid.Timestamp != at.UnixMilli() {
+		t.Errorf("expected timestamp %d got %d", at.UnixMilli(), sid.Timestamp)
+	}
+	if sid.MachineID != 24 {
+		t.Errorf("expected field %d got %d", 24, sid.MachineID)
+	}
+	if sid.Sequence != 7 {
+		t.Errorf("expected sequence %d got %d", 7, sid.Sequence)
+	}
+}
+
+func TestIDAt_Panics(t *testing.T) {
+	tc := []struct {
+		name  string
+		t     time.Time
+		field uint64
+		seq   uint64
+	}{
+		{"Should panic before the epoch", snowflake.Epoch().Add(-time.Millisecond), 0, 0},
+		{"Should panic for a field out of range", snowflaketest.Start, 1024, 0},
+		{"Should panic for a sequence out of range", snowflaketest.Start, 0, 4096},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			snowflaketest.IDAt(tt.t, tt.field, tt.seq)
+		})
+	}
+}
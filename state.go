@@ -0,0 +1,121 @@
+package snowflake
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// stateVersion is the version of the format written by SaveState.
+	stateVersion = 1
+	// stateSize is the size of a saved state: version, epoch, elapsed
+	// time, sequence, field and a CRC-32 of all of them.
+	stateSize = 1 + 8 + 8 + 2 + 2 + 4
+
+	defaultRestoreTolerance = time.Second
+)
+
+// state is the position of a generator. (internal-use only)
+type state struct {
+	epochMillis int64
+	elapsedTime int64
+	sequence    uint64
+	field       uint64
+}
+
+// SaveState returns the position of the generator (epoch, field and the
+// last millisecond and sequence it issued) in a small versioned binary
+// format, for checkpointing. A generator restored from it with
+// RestoreState never reissues an ID this one issued before SaveState.
+func (id *ID) SaveState() ([]byte, error) {
+	id.mtx.Lock()
+	s := id.state()
+	id.mtx.Unlock()
+
+	return s.marshal(), nil
+}
+
+// state returns the position of g. g.mtx must be held. (internal-use only)
+func (g *generator) state() state {
+	return state{
+		epochMillis: atomic.LoadInt64(&epochMillis),
+		elapsedTime: g.elapsedTime,
+		sequence:    g.sequence,
+		field:       g.fieldSegment >> sequenceBits,
+	}
+}
+
+// RestoreState returns a new snowflake.ID resuming from a state returned
+// by SaveState, configured with opts.
+//
+// Returns an error wrapping ErrInvalidState if data is corrupted or of an
+// unknown version, ErrEpochMismatch if it was saved under another epoch,
+// and ErrStateInFuture if its last millisecond is ahead of the clock by
+// more than the tolerance set with WithRestoreTolerance (1s by default),
+// which points at a clock gone wrong.
+func RestoreState(data []byte, opts ...Option) (*ID, error) {
+	s, err := unmarshalState(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if current := atomic.LoadInt64(&epochMillis); s.epochMillis != current {
+		return nil, fmt.Errorf("snowflake: state saved under epoch %s, not %s: %w",
+			time.UnixMilli(s.epochMillis).UTC(), time.UnixMilli(current).UTC(), ErrEpochMismatch)
+	}
+
+	id := New(s.field, opts...)
+	tolerance := defaultRestoreTolerance
+	if id.toleranceSet {
+		tolerance = id.restoreTolerance
+	}
+	if ahead := time.Duration(s.elapsedTime-id.now()) * time.Millisecond; ahead > tolerance {
+		return nil, fmt.Errorf("snowflake: state is %s ahead of the clock: %w", ahead, ErrStateInFuture)
+	}
+
+	id.elapsedTime = s.elapsedTime
+	id.sequence = s.sequence
+	return id, nil
+}
+
+// marshal encodes s. (internal-use only)
+func (s state) marshal() []byte {
+	b := make([]byte, stateSize)
+	b[0] = stateVersion
+	binary.BigEndian.PutUint64(b[1:], uint64(s.epochMillis))
+	binary.BigEndian.PutUint64(b[9:], uint64(s.elapsedTime))
+	binary.BigEndian.PutUint16(b[17:], uint16(s.sequence))
+	binary.BigEndian.PutUint16(b[19:], uint16(s.field))
+	binary.BigEndian.PutUint32(b[21:], crc32.ChecksumIEEE(b[:21]))
+	return b
+}
+
+// unmarshalState decodes a state encoded by marshal. (internal-use only)
+func unmarshalState(b []byte) (state, error) {
+	if len(b) == 0 {
+		return state{}, fmt.Errorf("snowflake: empty state: %w", ErrInvalidState)
+	}
+	if b[0] != stateVersion {
+		return state{}, fmt.Errorf("snowflake: unknown state version %d: %w", b[0], ErrInvalidState)
+	}
+	if len(b) != stateSize {
+		return state{}, fmt.Errorf("snowflake: state is %d bytes, expected %d: %w", len(b), stateSize, ErrInvalidState)
+	}
+	if crc32.ChecksumIEEE(b[:21]) != binary.BigEndian.Uint32(b[21:]) {
+		return state{}, fmt.Errorf("snowflake: state checksum mismatch: %w", ErrInvalidState)
+	}
+
+	s := state{
+		epochMillis: int64(binary.BigEndian.Uint64(b[1:])),
+		elapsedTime: int64(binary.BigEndian.Uint64(b[9:])),
+		sequence:    uint64(binary.BigEndian.Uint16(b[17:])),
+		field:       uint64(binary.BigEndian.Uint16(b[19:])),
+	}
+	if s.elapsedTime < 0 || s.elapsedTime > maxTimestamp || s.sequence > maxSeqBits || s.field > maxFieldBits {
+		return state{}, fmt.Errorf("snowflake: state out of range: %w", ErrInvalidState)
+	}
+	return s, nil
+}
@@ -0,0 +1,115 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestSaveRestoreState(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(5, snowflake.WithClock(clock))
+
+	var last uint64
+	for i := 0; i < 10; i++ {
+		last = sf.NextID()
+	}
+
+	data, err := sf.SaveState()
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	restored, err := snowflake.RestoreState(data, snowflake.WithClock(clock))
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	// same millisecond: the sequence carries on
+	id := restored.NextID()
+	if id != last+1 {
+		t.Errorf("expected %d got %d", last+1, id)
+	}
+	if snowflake.Parse(id).MachineID != 5 {
+		t.Errorf("expected field %d got %d", 5, snowflake.Parse(id).MachineID)
+	}
+
+	// the clock is behind the state: IDs keep coming from the saved millisecond
+	clock.Set(snowflake.Epoch().Add(time.Hour - 500*time.Millisecond))
+	restored, err = snowflake.RestoreState(data, snowflake.WithClock(clock))
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	if id := restored.NextID(); id <= last {
+		t.Errorf("expected an ID greater than %d got %d", last, id)
+	}
+}
+
+func TestRestoreState_InFuture(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New(5, snowflake.WithClock(clock))
+	sf.NextID()
+
+	data, err := sf.SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Set(snowflake.Epoch().Add(time.Hour - 2*time.Second))
+	if _, err := snowflake.RestoreState(data, snowflake.WithClock(clock)); !errors.Is(err, snowflake.ErrStateInFuture) {
+		t.Errorf("expected error %v got %v", snowflake.ErrStateInFuture, err)
+	}
+
+	if _, err := snowflake.RestoreState(data, snowflake.WithClock(clock), snowflake.WithRestoreTolerance(5*time.Second)); err != nil {
+		t.Errorf("expected no error within the tolerance got %v", err)
+	}
+}
+
+func TestRestoreState_Invalid(t *testing.T) {
+	data, err := snowflake.New(5).SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt := func(f func(b []byte) []byte) []byte {
+		return f(append([]byte(nil), data...))
+	}
+
+	tc := []struct {
+		name string
+		data []byte
+	}{
+		{"Should return ErrInvalidState for an empty state", nil},
+		{"Should return ErrInvalidState for an unknown version", corrupt(func(b []byte) []byte { b[0] = 2; return b })},
+		{"Should return ErrInvalidState for a truncated state", data[:len(data)-1]},
+		{"Should return ErrInvalidState for trailing bytes", corrupt(func(b []byte) []byte { return append(b, 0) })},
+		{"Should return ErrInvalidState for a flipped bit", corrupt(func(b []byte) []byte { b[12] ^= 1; return b })},
+		{"Should return ErrInvalidState for a bad checksum", corrupt(func(b []byte) []byte { b[len(b)-1]++; return b })},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := snowflake.RestoreState(tt.data); !errors.Is(err, snowflake.ErrInvalidState) {
+				t.Errorf("expected error %v got %v", snowflake.ErrInvalidState, err)
+			}
+		})
+	}
+}
+
+func TestRestoreState_EpochMismatch(t *testing.T) {
+	defer snowflake.SetEpoch(snowflake.Epoch())
+
+	data, err := snowflake.New(5).SaveState()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := snowflake.SetEpoch(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snowflake.RestoreState(data); !errors.Is(err, snowflake.ErrEpochMismatch) {
+		t.Errorf("expected error %v got %v", snowflake.ErrEpochMismatch, err)
+	}
+}
@@ -0,0 +1,80 @@
+package snowflake
+
+import "time"
+
+// Stats is a snapshot of the counters of a generator, for monitoring.
+type Stats struct {
+	// IDs is the number of IDs issued.
+	IDs uint64
+	// Rollovers is the number of times the sequence of a millisecond ran
+	// out, so that the generator had to move on to the next millisecond.
+	Rollovers uint64
+	// Waited is the total time spent waiting for the clock to tick after a
+	// rollover. Generators using an injected Clock measure it on that clock.
+	Waited time.Duration
+	// ClockRegressions is the number of times the clock was read behind
+	// its previous reading.
+	ClockRegressions uint64
+	// Fallbacks is the number of random IDs issued while the clock was
+	// unusable, see WithRandomFallback. They're counted in IDs too.
+	Fallbacks uint64
+	// MaxSequence is the highest sequence number issued in any
+	// millisecond, a measure of how close bursts come to rolling over.
+	MaxSequence uint64
+	// Utilization is a histogram of how much of the sequence was used in
+	// each of the last 1024 milliseconds IDs were issued in, not counting
+	// the current one: Utilization[i] is the number of milliseconds whose
+	// highest sequence number was in the i-th tenth of 0 to 4095, so
+	// Utilization[9] counts those that came within 10% of rolling over.
+	Utilization [utilizationBuckets]uint64
+
+	// LastID is the last ID issued, or 0 if none was.
+	LastID uint64
+	// Sequence is the sequence number of LastID.
+	Sequence uint64
+	// Remaining is the time left until the timestamps of the generator
+	// overflow 41 bits, making IDs overflow int64 (about 69 years after the
+	// epoch).
+	Remaining time.Duration
+	// ClockOffset is the offset of the NTP servers to the local clock,
+	// positive if the local clock is behind, when the generator's clock is
+	// an NTPClock that synced, see ClockSynced.
+	ClockOffset time.Duration
+	// ClockSynced is whether the generator's clock is an NTPClock a server
+	// answered.
+	ClockSynced bool
+}
+
+// Stats returns a snapshot of the counters of the generator.
+func (id *ID) Stats() Stats { return id.stats() }
+
+// Stats returns a snapshot of the counters of the generator.
+func (id *ID2) Stats() Stats { return id.stats() }
+
+// stats returns a snapshot of the counters of g. (internal-use only)
+func (g *generator) stats() Stats {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+
+	stats := g.counters
+	if g.utilization != nil {
+		stats.Utilization = g.utilization.histogram
+	}
+	if stats.IDs > 0 {
+		stats.LastID = g.compose()
+		stats.Sequence = g.sequence
+	}
+	stats.Remaining = time.Duration(maxTimestamp-g.now()) * time.Millisecond
+	if oc, ok := g.clock.(offsetClock); ok {
+		stats.ClockOffset, stats.ClockSynced = oc.offset()
+	}
+	return stats
+}
+
+// trackSequence records the sequence number just issued. g.mtx must be
+// held. (internal-use only)
+func (g *generator) trackSequence() {
+	if g.sequence > g.counters.MaxSequence {
+		g.counters.MaxSequence = g.sequence
+	}
+}
@@ -0,0 +1,124 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// remaining is the lifetime left at now.
+func remaining(now time.Time) time.Duration {
+	return snowflake.Epoch().Add((1<<41 - 1) * time.Millisecond).Sub(now)
+}
+
+func TestStats(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start)
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithMaxForwardDrift(10*time.Millisecond))
+
+	expected := snowflake.Stats{Remaining: remaining(start)}
+	if stats := sf.Stats(); stats != expected {
+		t.Errorf("expected %+v got %+v", expected, stats)
+	}
+
+	var last uint64
+	for i := 0; i < 10; i++ {
+		last = sf.NextID()
+	}
+	expected = snowflake.Stats{IDs: 10, MaxSequence: 9, LastID: last, Sequence: 9, Remaining: remaining(start)}
+	if stats := sf.Stats(); stats != expected {
+		t.Errorf("expected %+v got %+v", expected, stats)
+	}
+
+	// three milliseconds worth of IDs roll over twice, drifting ahead
+	// without waiting
+	for i := 0; i < 3*4096-10; i++ {
+		last = sf.NextID()
+	}
+	expected = snowflake.Stats{
+		IDs:         3 * 4096,
+		Rollovers:   2,
+		MaxSequence: 4095,
+		Utilization: [10]uint64{9: 2},
+		LastID:      last,
+		Sequence:    4095,
+		Remaining:   remaining(start),
+	}
+	if stats := sf.Stats(); stats != expected {
+		t.Errorf("expected %+v got %+v", expected, stats)
+	}
+
+	// stepping the clock back is one regression, however many IDs are
+	// issued before it recovers; the first of them rolls over since the
+	// last millisecond is exhausted
+	clock.Set(start.Add(-time.Millisecond))
+	sf.NextID()
+	last = sf.NextID()
+	expected = snowflake.Stats{
+		IDs:              3*4096 + 2,
+		Rollovers:        3,
+		ClockRegressions: 1,
+		MaxSequence:      4095,
+		Utilization:      [10]uint64{9: 3},
+		LastID:           last,
+		Sequence:         1,
+		Remaining:        remaining(start.Add(-time.Millisecond)),
+	}
+	if stats := sf.Stats(); stats != expected {
+		t.Errorf("expected %+v got %+v", expected, stats)
+	}
+}
+
+func TestStats_MaxSequence(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(start)
+	sf := snowflake.New2(1, 1, snowflake.WithClock(clock))
+
+	// bursts of 3, 7 and 2 IDs in consecutive milliseconds
+	var last uint64
+	for _, burst := range []int{3, 7, 2} {
+		for i := 0; i < burst; i++ {
+			last = sf.NextID()
+		}
+		clock.Add(time.Millisecond)
+	}
+
+	expected := snowflake.Stats{
+		IDs:         12,
+		MaxSequence: 6,
+		Utilization: [10]uint64{0: 2},
+		LastID:      last,
+		Sequence:    1,
+		Remaining:   remaining(start.Add(3 * time.Millisecond)),
+	}
+	if stats := sf.Stats(); stats != expected {
+		t.Errorf("expected %+v got %+v", expected, stats)
+	}
+}
+
+func TestStats_Waited(t *testing.T) {
+	clock := &tickingClock{t: snowflake.Epoch().Add(time.Hour), step: 100 * time.Nanosecond}
+	sf := snowflake.New2(1, 1, snowflake.WithClock(clock))
+
+	for i := 0; i < 3*4096; i++ {
+		sf.NextID()
+	}
+
+	stats := sf.Stats()
+	if stats.IDs != 3*4096 {
+		t.Errorf("expected %d IDs got %d", 3*4096, stats.IDs)
+	}
+	if stats.Rollovers == 0 || stats.Waited == 0 {
+		t.Errorf("expected rollovers and waits got %+v", stats)
+	}
+	if max := time.Duration(stats.Rollovers) * time.Millisecond; stats.Waited > max {
+		t.Errorf("expected to wait at most %v got %v", max, stats.Waited)
+	}
+}
+
+func TestStats_Remaining(t *testing.T) {
+	if r := snowflake.New(1).Stats().Remaining; r < 50*365*24*time.Hour || r > 70*365*24*time.Hour {
+		t.Errorf("expected 50 to 70 years of lifetime left got %v", r)
+	}
+}
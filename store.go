@@ -0,0 +1,138 @@
+package snowflake
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// StateStore persists the state of a generator, as encoded by SaveState,
+// across restarts. See WithStateStore and NewPersistent.
+type StateStore interface {
+	// Save replaces the stored state with state.
+	Save(state []byte) error
+	// Load returns the stored state, or an error wrapping fs.ErrNotExist
+	// if none was saved yet.
+	Load() ([]byte, error)
+}
+
+// FileStore is a StateStore keeping the state in a file. Saves write a
+// temporary file next to it, named after it with a ".tmp" suffix, and
+// rename it over the file, so a crash mid save leaves the previous state
+// in place.
+type FileStore struct {
+	path string
+	// Sync makes saves fsync the file and its directory before returning,
+	// so the state survives a power loss too, at the cost of much slower
+	// saves. It is off by default.
+	Sync bool
+}
+
+var _ StateStore = (*FileStore)(nil)
+
+// FileStateStore returns a new snowflake.FileStore keeping the state in
+// the file at path.
+func FileStateStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Path returns the path of the state file.
+func (s *FileStore) Path() string { return s.path }
+
+// tempPath returns the path of the file saves are written to before being
+// renamed over the state file. (internal-use only)
+func (s *FileStore) tempPath() string { return s.path + ".tmp" }
+
+// Save writes state to a temporary file and renames it over the state
+// file.
+func (s *FileStore) Save(state []byte) error {
+	f, err := os.OpenFile(s.tempPath(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(state)
+	if err == nil && s.Sync {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(s.tempPath(), s.path); err != nil {
+		return err
+	}
+
+	if s.Sync {
+		return syncDir(filepath.Dir(s.path))
+	}
+	return nil
+}
+
+// Load reads the state file. A leftover temporary file from an
+// interrupted save is ignored.
+func (s *FileStore) Load() ([]byte, error) {
+	return os.ReadFile(s.path)
+}
+
+// syncDir fsyncs the directory dir, persisting renames in it.
+// (internal-use only)
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// NewPersistent returns a new snowflake.ID (max field value: 1023) saving
+// its state to store, see WithStateStore, and resuming from the state
+// already in it, if any.
+//
+// Since the stored state can be behind the IDs issued before a crash by up
+// to a millisecond, the generator resumes after the stored millisecond
+// rather than within it. A store holding no state yet starts a fresh
+// generator. Returns the errors of RestoreState, and an error wrapping
+// ErrFieldMismatch if the stored state is for another field.
+func NewPersistent(field uint64, store StateStore, opts ...Option) (*ID, error) {
+	opts = append(opts[:len(opts):len(opts)], WithStateStore(store))
+
+	data, err := store.Load()
+	if errors.Is(err, fs.ErrNotExist) {
+		return New(field, opts...), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("snowflake: loading state: %w", err)
+	}
+
+	id, err := RestoreState(data, opts...)
+	if err != nil {
+		return nil, err
+	}
+	stored := id.fieldSegment >> sequenceBits
+	if id.versionTag != 0 {
+		stored &= maxTaggedField
+	}
+	if stored != field {
+		return nil, fmt.Errorf("snowflake: state is for field %d, not %d: %w", stored, field, ErrFieldMismatch)
+	}
+
+	// IDs of the stored millisecond may have been issued after the save
+	id.sequence = maxSeqBits
+	return id, nil
+}
+
+// saveState saves the state of g to its store, reporting failures.
+// g.mtx must be held. (internal-use only)
+func (g *generator) saveState() {
+	s := g.state()
+	if err := g.store.Save(s.marshal()); err != nil {
+		g.report(anomalyStateSave, "snowflake: saving state failed", s.field, "error", err)
+	}
+}
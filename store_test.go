@@ -0,0 +1,139 @@
+package snowflake_test
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// countingStore counts the saves passed on to a snowflake.StateStore.
+type countingStore struct {
+	snowflake.StateStore
+	saves int
+}
+
+func (s *countingStore) Save(state []byte) error {
+	s.saves++
+	return s.StateStore.Save(state)
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snowflake.state")
+
+	for _, sync := range []bool{false, true} {
+		store := snowflake.FileStateStore(path)
+		store.Sync = sync
+
+		state := []byte{1, 2, 3}
+		if err := store.Save(state); err != nil {
+			t.Fatalf("expected no error got %v", err)
+		}
+		got, err := store.Load()
+		if err != nil {
+			t.Fatalf("expected no error got %v", err)
+		}
+		if !bytes.Equal(got, state) {
+			t.Errorf("expected %v got %v", state, got)
+		}
+	}
+}
+
+func TestFileStore_Missing(t *testing.T) {
+	store := snowflake.FileStateStore(filepath.Join(t.TempDir(), "snowflake.state"))
+	if _, err := store.Load(); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected error %v got %v", fs.ErrNotExist, err)
+	}
+}
+
+func TestFileStore_CrashBeforeRename(t *testing.T) {
+	store := snowflake.FileStateStore(filepath.Join(t.TempDir(), "snowflake.state"))
+
+	for _, state := range [][]byte{[]byte("first"), []byte("second")} {
+		if err := store.Save(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a save interrupted after writing part of the temporary file
+	if err := os.WriteFile(store.Path()+".tmp", []byte("thi"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	if string(got) != "second" {
+		t.Errorf("expected the last complete state %q got %q", "second", got)
+	}
+
+	// the next save replaces the leftover
+	if err := store.Save([]byte("third")); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	if got, _ := store.Load(); string(got) != "third" {
+		t.Errorf("expected %q got %q", "third", got)
+	}
+}
+
+func TestWithStateStore(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	store := &countingStore{StateStore: snowflake.FileStateStore(filepath.Join(t.TempDir(), "snowflake.state"))}
+	sf := snowflake.New(5, snowflake.WithClock(clock), snowflake.WithStateStore(store))
+
+	for i := 0; i < 100; i++ {
+		sf.NextID()
+	}
+	if store.saves != 1 {
+		t.Errorf("expected %d saves got %d", 1, store.saves)
+	}
+
+	clock.Add(time.Millisecond)
+	for i := 0; i < 100; i++ {
+		sf.NextID()
+	}
+	if store.saves != 2 {
+		t.Errorf("expected %d saves got %d", 2, store.saves)
+	}
+}
+
+func TestNewPersistent(t *testing.T) {
+	clock := &tickingClock{t: snowflake.Epoch().Add(time.Hour), step: time.Microsecond}
+	store := snowflake.FileStateStore(filepath.Join(t.TempDir(), "snowflake.state"))
+
+	sf, err := snowflake.NewPersistent(5, store, snowflake.WithClock(clock))
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	var last uint64
+	for i := 0; i < 10; i++ {
+		last = sf.NextID()
+	}
+
+	// crash and restart within the same millisecond
+	sf, err = snowflake.NewPersistent(5, store, snowflake.WithClock(clock))
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	id := sf.NextID()
+	if snowflake.Parse(id).Timestamp <= snowflake.Parse(last).Timestamp {
+		t.Errorf("expected a millisecond after %d got %d", snowflake.Parse(last).Timestamp, snowflake.Parse(id).Timestamp)
+	}
+
+	if _, err := snowflake.NewPersistent(6, store, snowflake.WithClock(clock)); !errors.Is(err, snowflake.ErrFieldMismatch) {
+		t.Errorf("expected error %v got %v", snowflake.ErrFieldMismatch, err)
+	}
+
+	if err := store.Save([]byte("garbage")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := snowflake.NewPersistent(5, store, snowflake.WithClock(clock)); !errors.Is(err, snowflake.ErrInvalidState) {
+		t.Errorf("expected error %v got %v", snowflake.ErrInvalidState, err)
+	}
+}
@@ -0,0 +1,33 @@
+package snowflake
+
+import "time"
+
+// UnsafeID is a snowflake ID generator without any locking.
+// It must only ever be used from a single goroutine, e.g. in a CLI batch
+// importer where the mutex of ID is pure overhead. Sharing it between
+// goroutines produces duplicate IDs; the race detector reports such misuse.
+type UnsafeID struct {
+	g generator
+}
+
+// NewUnsafe returns a new snowflake.UnsafeID (max field value: 1023)
+// A field bigger than the max is reset to 0.
+func NewUnsafe(field uint64, opts ...Option) *UnsafeID {
+	id := &UnsafeID{}
+	if field <= maxFieldBits {
+		id.g.fieldSegment = field << sequenceBits
+	}
+	id.g.apply(opts)
+	id.g.checkField(field, maxFieldBits)
+	return id
+}
+
+// NextID returns a new snowflake ID. Not safe for concurrent use.
+func (id *UnsafeID) NextID() uint64 {
+	if id.g.limiter != nil {
+		if wait := id.g.limiter.reserveN(id.g.wallNow(), 1); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	return id.g.generate()
+}
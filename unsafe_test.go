@@ -0,0 +1,32 @@
+package snowflake_test
+
+import (
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestUnsafeID(t *testing.T) {
+	n := 100000
+	sf := snowflake.NewUnsafe(7)
+
+	var last uint64
+	for i := 0; i < n; i++ {
+		id := sf.NextID()
+		if id <= last {
+			t.Errorf("expected to be increasing, but got %d after %d", id, last)
+			break
+		}
+		last = id
+	}
+
+	if field := snowflake.Parse(last).MachineID; field != 7 {
+		t.Errorf("expected field %d got %d", 7, field)
+	}
+}
+
+func TestUnsafeID_FieldOverflow(t *testing.T) {
+	if field := snowflake.Parse(snowflake.NewUnsafe(1024).NextID()).MachineID; field != 0 {
+		t.Errorf("expected field %d got %d", 0, field)
+	}
+}
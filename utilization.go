@@ -0,0 +1,84 @@
+package snowflake
+
+const (
+	// utilizationBuckets is the number of buckets of Stats.Utilization,
+	// each a tenth of the sequence capacity.
+	utilizationBuckets = 10
+	// utilizationWindow is how many milliseconds Stats.Utilization covers.
+	utilizationWindow = 1024
+)
+
+// utilization is a rolling histogram of the highest sequence number
+// reached in each of the last utilizationWindow milliseconds the generator
+// issued IDs in. (internal-use only)
+type utilization struct {
+	window    [utilizationWindow]uint8 // bucket of each millisecond, a ring
+	next      int                      // where the next millisecond goes in window
+	full      bool                     // whether window wrapped around
+	histogram [utilizationBuckets]uint64
+}
+
+// fold adds a millisecond that ended at sequence seq to the histogram,
+// evicting the oldest one if the window is full. (internal-use only)
+func (u *utilization) fold(seq uint64) {
+	bucket := uint8(seq * utilizationBuckets >> sequenceBits)
+	if u.full {
+		u.histogram[u.window[u.next]]--
+	}
+	u.window[u.next] = bucket
+	u.histogram[bucket]++
+	u.next++
+	if u.next == utilizationWindow {
+		u.next = 0
+		u.full = true
+	}
+}
+
+// capacityWarning calls a callback when a millisecond uses more of the
+// sequence than a threshold. Like hook, only one call runs at a time.
+// (internal-use only)
+type capacityWarning struct {
+	gate
+	threshold float64
+	f         func(util float64)
+}
+
+// fire calls the callback if a millisecond that ended at sequence seq is
+// above the threshold, unless a call is still running. (internal-use only)
+func (w *capacityWarning) fire(seq uint64) {
+	if w == nil {
+		return
+	}
+	if util := float64(seq+1) / (maxSeqBits + 1); util > w.threshold {
+		w.run(func() { w.f(util) })
+	}
+}
+
+// WithCapacityWarning makes the generator call fn whenever a millisecond
+// it issued IDs in used more than threshold of the 4096 sequence numbers
+// available, with the fraction it used, e.g. 0.8 to be warned ahead of
+// bursts that would make it roll over and wait. A millisecond is checked
+// once the generator moves on to the next one, so the last millisecond of
+// a burst is only checked when the next ID is issued.
+//
+// Like the callback of WithOnSequenceExhausted, fn runs on a goroutine of
+// its own and warnings happening while it's still running are dropped.
+func WithCapacityWarning(threshold float64, fn func(util float64)) Option {
+	return func(g *generator) {
+		if fn == nil {
+			g.capacityWarning = nil
+			return
+		}
+		g.capacityWarning = &capacityWarning{threshold: threshold, f: fn}
+	}
+}
+
+// endMillisecond records a millisecond that ended at sequence seq, as the
+// generator moves on to the next one. g.mtx must be held.
+// (internal-use only)
+func (g *generator) endMillisecond(seq uint64) {
+	if g.utilization != nil {
+		g.utilization.fold(seq)
+	}
+	g.capacityWarning.fire(seq)
+}
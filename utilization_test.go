@@ -0,0 +1,73 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestStats_Utilization(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	warnings := make(chan float64, 1)
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithCapacityWarning(0.8, func(util float64) {
+		warnings <- util
+	}))
+
+	// 85%, 2% and 50% of the capacity in consecutive milliseconds
+	for _, burst := range []int{3482, 82, 2048} {
+		for i := 0; i < burst; i++ {
+			sf.NextID()
+		}
+		clock.Add(time.Millisecond)
+	}
+
+	select {
+	case util := <-warnings:
+		if expected := 3482.0 / 4096; util != expected {
+			t.Errorf("expected utilization %v got %v", expected, util)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a capacity warning")
+	}
+
+	// the last millisecond is only counted once the next one starts
+	expected := [10]uint64{0: 1, 8: 1}
+	if u := sf.Stats().Utilization; u != expected {
+		t.Errorf("expected %v got %v", expected, u)
+	}
+	sf.NextID()
+	expected[4]++
+	if u := sf.Stats().Utilization; u != expected {
+		t.Errorf("expected %v got %v", expected, u)
+	}
+
+	select {
+	case util := <-warnings:
+		t.Errorf("expected no other warning got %v", util)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestStats_UtilizationWindow(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(time.Hour))
+	sf := snowflake.New2(1, 1, snowflake.WithClock(clock))
+
+	// the 10 fullest milliseconds fall out of the window of 1024
+	for ms := 0; ms < 1035; ms++ {
+		burst := 1
+		if ms < 10 {
+			burst = 4096
+		}
+		for i := 0; i < burst; i++ {
+			sf.NextID()
+		}
+		clock.Add(time.Millisecond)
+	}
+	sf.NextID()
+
+	expected := [10]uint64{0: 1024}
+	if u := sf.Stats().Utilization; u != expected {
+		t.Errorf("expected %v got %v", expected, u)
+	}
+}
@@ -0,0 +1,44 @@
+package snowflake
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+)
+
+// NextUUIDv7 returns a new UUIDv7 (RFC 9562) from the same timestamp and
+// sequence as the snowflake IDs of the generator, so that the UUIDs and
+// IDs it issues sort alike.
+//
+//	Format:
+//	|-----unix_ts_ms (48)-----|ver (4)|-seq (12)-|var (2)|---random (62)---|
+//
+// The sequence goes in rand_a, keeping the UUIDs of a millisecond ordered,
+// and rand_b is drawn from crypto/rand, so UUIDs of different generators
+// don't collide even if they share a field.
+func (id *ID) NextUUIDv7() [16]byte { return newUUIDv7(id.order().toSeqLow(id.nextID())) }
+
+// NextUUIDv7 is like (*ID).NextUUIDv7.
+func (id *ID2) NextUUIDv7() [16]byte { return newUUIDv7(id.order().toSeqLow(id.nextID())) }
+
+// UUIDv7Time returns the timestamp of the UUIDv7 u, to the millisecond.
+func UUIDv7Time(u [16]byte) time.Time {
+	var b [8]byte
+	copy(b[2:], u[:6])
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(b[:])))
+}
+
+// newUUIDv7 returns the UUIDv7 of the timestamp and sequence of the
+// snowflake ID sid, of order SeqLow. (internal-use only)
+func newUUIDv7(sid uint64) [16]byte {
+	var u [16]byte
+	if _, err := rand.Read(u[8:]); err != nil {
+		panic("snowflake: reading crypto/rand: " + err.Error())
+	}
+
+	ms := uint64(int64(sid>>(sequenceBits+fieldBits)) + atomic.LoadInt64(&epochMillis))
+	binary.BigEndian.PutUint64(u[:8], ms<<16|0x7<<12|getSequence(sid))
+	u[8] = u[8]&0x3F | 0x80
+	return u
+}
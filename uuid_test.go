@@ -0,0 +1,76 @@
+package snowflake_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestNextUUIDv7(t *testing.T) {
+	now := snowflake.Epoch().Add(time.Hour)
+	clock := newFakeClock(now)
+	sf := snowflake.New(1, snowflake.WithClock(clock))
+
+	var prev [16]byte
+	for i := 0; i < 100; i++ {
+		u := sf.NextUUIDv7()
+
+		if v := u[6] >> 4; v != 7 {
+			t.Errorf("expected version 7 got %d", v)
+		}
+		if v := u[8] >> 6; v != 0b10 {
+			t.Errorf("expected variant 0b10 got %b", v)
+		}
+		if ts := snowflake.UUIDv7Time(u); !ts.Equal(now) {
+			t.Errorf("expected time %v got %v", now, ts)
+		}
+		if seq := uint64(u[6]&0x0F)<<8 | uint64(u[7]); seq != uint64(i) {
+			t.Errorf("expected sequence %d got %d", i, seq)
+		}
+		if i > 0 && bytes.Compare(u[:], prev[:]) <= 0 {
+			t.Errorf("expected %x after %x", u, prev)
+		}
+		prev = u
+	}
+}
+
+func TestNextUUIDv7_FieldLow(t *testing.T) {
+	sf := snowflake.New(1, snowflake.WithSegmentOrder(snowflake.FieldLow),
+		snowflake.WithClock(newFakeClock(snowflake.Epoch().Add(time.Hour))))
+
+	var prev [16]byte
+	for i := 0; i < 100; i++ {
+		u := sf.NextUUIDv7()
+		if seq := uint64(u[6]&0x0F)<<8 | uint64(u[7]); seq != uint64(i) {
+			t.Errorf("expected sequence %d got %d", i, seq)
+		}
+		if i > 0 && bytes.Compare(u[:], prev[:]) <= 0 {
+			t.Errorf("expected %x after %x", u, prev)
+		}
+		prev = u
+	}
+}
+
+func TestNextUUIDv7_SharedOrder(t *testing.T) {
+	clock := &tickingClock{t: snowflake.Epoch().Add(time.Hour), step: 300 * time.Microsecond}
+	sf := snowflake.New2(1, 2, snowflake.WithClock(clock))
+
+	// UUIDs and IDs of the same generator interleave in one order
+	id := sf.NextID()
+	u := sf.NextUUIDv7()
+	next := sf.NextID()
+
+	ts := snowflake.UUIDv7Time(u).UnixMilli()
+	if ts < snowflake.Parse2(id).Timestamp || ts > snowflake.Parse2(next).Timestamp {
+		t.Errorf("expected the UUID timestamp between %d and %d got %d", snowflake.Parse2(id).Timestamp, snowflake.Parse2(next).Timestamp, ts)
+	}
+	if next <= id+1 && snowflake.Parse2(next).Timestamp == snowflake.Parse2(id).Timestamp {
+		t.Errorf("expected the UUID to take a sequence number between %d and %d", id, next)
+	}
+
+	if u2 := sf.NextUUIDv7(); bytes.Equal(u2[8:], u[8:]) {
+		t.Errorf("expected random tails got %x twice", u[8:])
+	}
+}
@@ -0,0 +1,130 @@
+package snowflake
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// defaultMaxSkew is how far ahead of the clock a Validator finds
+// timestamps plausible by default. (internal-use only)
+const defaultMaxSkew = time.Minute
+
+// FieldRange is a range of fields, From and To included.
+type FieldRange struct {
+	From, To uint64
+}
+
+// ValidatorOption configures a Validator.
+type ValidatorOption func(*Validator)
+
+// Validator checks IDs of unknown provenance, such as IDs sent by clients,
+// against the machine IDs known to issue IDs and the times they could have
+// been issued at. It's safe for concurrent use, including SetAllowed.
+type Validator struct {
+	allowed   atomic.Value // *fieldSet, replaced whole by SetAllowed
+	ranges    []FieldRange // set by WithAllowedRanges, for NewValidator
+	notBefore int64        // in Unix milliseconds, 0 means any time since the epoch
+	maxSkew   time.Duration
+	clock     Clock // nil means the system clock
+}
+
+// fieldSet is a set of fields, a bit per field. (internal-use only)
+type fieldSet [(maxFieldBits + 1) / 64]uint64
+
+func (s *fieldSet) has(field uint64) bool { return s[field/64]&(1<<(field%64)) != 0 }
+func (s *fieldSet) add(field uint64)      { s[field/64] |= 1 << (field % 64) }
+
+// WithAllowedRanges allows the fields of ranges too, e.g. FieldRange{0, 63}
+// for the first 64 machine IDs.
+func WithAllowedRanges(ranges ...FieldRange) ValidatorOption {
+	return func(v *Validator) {
+		v.ranges = append(v.ranges, ranges...)
+	}
+}
+
+// WithNotBefore makes IDs issued before t implausible, e.g. the time the
+// first generator was deployed.
+func WithNotBefore(t time.Time) ValidatorOption {
+	return func(v *Validator) {
+		v.notBefore = t.UnixMilli()
+	}
+}
+
+// WithMaxSkew sets how far ahead of the clock an ID may be to be
+// plausible, to allow for the drift between the clocks of the generators
+// and the validator, 1 minute by default. It panics if d is negative.
+func WithMaxSkew(d time.Duration) ValidatorOption {
+	if d < 0 {
+		panic("snowflake: negative max skew")
+	}
+	return func(v *Validator) {
+		v.maxSkew = d
+	}
+}
+
+// WithValidatorClock makes the Validator read the time from c instead of
+// the system clock.
+func WithValidatorClock(c Clock) ValidatorOption {
+	return func(v *Validator) {
+		v.clock = c
+	}
+}
+
+// NewValidator returns a new snowflake.Validator allowing the machine IDs
+// in allowed, and in the ranges of WithAllowedRanges. It panics if one of
+// them is bigger than 1023.
+func NewValidator(allowed []uint64, opts ...ValidatorOption) *Validator {
+	v := &Validator{maxSkew: defaultMaxSkew}
+	for _, opt := range opts {
+		opt(v)
+	}
+	v.SetAllowed(allowed, v.ranges...)
+	return v
+}
+
+// SetAllowed replaces the allowed machine IDs with allowed and the fields
+// of ranges, e.g. after a deployment. Validations in progress complete with
+// the previous allowlist. It panics if a machine ID is bigger than 1023.
+func (v *Validator) SetAllowed(allowed []uint64, ranges ...FieldRange) {
+	set := new(fieldSet)
+	for _, field := range allowed {
+		if field > maxFieldBits {
+			panic(fmt.Sprintf("snowflake: allowed machine ID %d is bigger than %d", field, maxFieldBits))
+		}
+		set.add(field)
+	}
+	for _, r := range ranges {
+		if r.To > maxFieldBits {
+			panic(fmt.Sprintf("snowflake: allowed machine IDs %d to %d go beyond %d", r.From, r.To, maxFieldBits))
+		}
+		for field := r.From; field <= r.To; field++ {
+			set.add(field)
+		}
+	}
+	v.allowed.Store(set)
+}
+
+// Validate checks that id was plausibly issued by an allowed machine.
+// Returns an error wrapping ErrImplausibleTime if its timestamp is before
+// the time set with WithNotBefore, or ahead of the clock by more than the
+// max skew, and ErrUnknownMachine if its machine ID isn't allowed.
+func (v *Validator) Validate(id uint64) error {
+	ts := getTimestamp(id)
+	if ts < v.notBefore {
+		return fmt.Errorf("snowflake: ID %d is from before %s: %w", id, time.UnixMilli(v.notBefore).UTC(), ErrImplausibleTime)
+	}
+
+	now := time.Now()
+	if v.clock != nil {
+		now = v.clock.Now()
+	}
+	if ahead := time.Duration(ts-now.UnixMilli()) * time.Millisecond; ahead > v.maxSkew {
+		return fmt.Errorf("snowflake: ID %d is %s ahead of the clock: %w", id, ahead, ErrImplausibleTime)
+	}
+
+	if field := getMachineID(id); !v.allowed.Load().(*fieldSet).has(field) {
+		return fmt.Errorf("snowflake: ID %d is of machine ID %d: %w", id, field, ErrUnknownMachine)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package snowflake_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestValidator(t *testing.T) {
+	clock := newFakeClock(snowflake.Epoch().Add(100 * day))
+	v := snowflake.NewValidator([]uint64{100, 200},
+		snowflake.WithAllowedRanges(snowflake.FieldRange{From: 0, To: 63}),
+		snowflake.WithNotBefore(snowflake.Epoch().Add(day)),
+		snowflake.WithValidatorClock(clock))
+
+	idAt := func(t time.Time, field uint64) uint64 {
+		return snowflake.New(field, snowflake.WithClock(newFakeClock(t))).NextID()
+	}
+	now := clock.Now()
+
+	tc := []struct {
+		name string
+		id   uint64
+		err  error
+	}{
+		{"Should allow a listed machine ID", idAt(now, 100), nil},
+		{"Should allow the other listed machine ID", idAt(now, 200), nil},
+		{"Should allow the start of a range", idAt(now, 0), nil},
+		{"Should allow the end of a range", idAt(now, 63), nil},
+		{"Should reject a machine ID past a range", idAt(now, 64), snowflake.ErrUnknownMachine},
+		{"Should reject an unlisted machine ID", idAt(now, 101), snowflake.ErrUnknownMachine},
+		{"Should allow a timestamp within the max skew", idAt(now.Add(time.Minute), 100), nil},
+		{"Should reject a timestamp past the max skew", idAt(now.Add(time.Minute+time.Millisecond), 100), snowflake.ErrImplausibleTime},
+		{"Should allow the not before time", idAt(snowflake.Epoch().Add(day), 100), nil},
+		{"Should reject a timestamp before the not before time", idAt(snowflake.Epoch().Add(day-time.Millisecond), 100), snowflake.ErrImplausibleTime},
+		{"Should check the time before the machine ID", idAt(now.Add(time.Hour), 101), snowflake.ErrImplausibleTime},
+		{"Should reject a fallback ID", 0xF<<59 | 0x3FF<<12, snowflake.ErrImplausibleTime},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(tt.id)
+			if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+				t.Errorf("expected error %v got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidator_SetAllowed(t *testing.T) {
+	id := snowflake.New(5).NextID()
+	v := snowflake.NewValidator(nil)
+	if err := v.Validate(id); !errors.Is(err, snowflake.ErrUnknownMachine) {
+		t.Fatalf("expected error %v got %v", snowflake.ErrUnknownMachine, err)
+	}
+
+	v.SetAllowed(nil, snowflake.FieldRange{From: 4, To: 6})
+	if err := v.Validate(id); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	v.SetAllowed([]uint64{7})
+	if err := v.Validate(id); !errors.Is(err, snowflake.ErrUnknownMachine) {
+		t.Errorf("expected the range to be replaced got %v", err)
+	}
+}
+
+func TestValidator_ConcurrentUpdate(t *testing.T) {
+	even, odd := make([]uint64, 0, 512), make([]uint64, 0, 512)
+	for field := uint64(0); field < 1024; field += 2 {
+		even, odd = append(even, field), append(odd, field+1)
+	}
+	v := snowflake.NewValidator(even)
+	id := snowflake.New(10).NextID()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			if i%2 == 0 {
+				v.SetAllowed(odd)
+			} else {
+				v.SetAllowed(even)
+			}
+		}
+	}()
+
+	// every validation sees one allowlist or the other
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if err := v.Validate(id); err != nil && !errors.Is(err, snowflake.ErrUnknownMachine) {
+					t.Errorf("expected no error or %v got %v", snowflake.ErrUnknownMachine, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestNewValidator_Panics(t *testing.T) {
+	tc := []struct {
+		name string
+		fn   func()
+	}{
+		{"Should panic on a machine ID out of range", func() { snowflake.NewValidator([]uint64{1024}) }},
+		{"Should panic on a range out of range", func() {
+			snowflake.NewValidator(nil, snowflake.WithAllowedRanges(snowflake.FieldRange{From: 1000, To: 1024}))
+		}},
+		{"Should panic on a negative max skew", func() { snowflake.WithMaxSkew(-time.Second) }},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			tt.fn()
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package snowflake
+
+import "fmt"
+
+const (
+	// versionShift is the position of the version tag, in the 2 highest
+	// field bits.
+	versionShift = sequenceBits + fieldBits - 2
+	// versionMask masks the version tag of an ID.
+	versionMask = 3 << versionShift
+	// maxVersionTag is the highest version tag.
+	maxVersionTag = 3
+	// maxTaggedField is the max field value of a tagged ID.
+	maxTaggedField = 0xFF
+)
+
+// WithVersionTag reserves the 2 highest field bits of the generator's IDs
+// for the layout version v, from 1 to 3, so that IDs tell which layout
+// they were issued with, e.g. 1 for ID and 2 for ID2. See VersionOf and
+// ParseTagged.
+//
+// It leaves 8 field bits: fields up to 255 for New, and up to 31 and 7 for
+// New2. A bigger field resets the whole field to 0, unless its high bits
+// already hold v. A v of 0 or above 3 leaves the IDs untagged, which is
+// the default.
+func WithVersionTag(v uint8) Option {
+	return func(g *generator) {
+		if v > maxVersionTag {
+			v = 0
+		}
+		g.versionTag = uint64(v)
+	}
+}
+
+// tagField stamps the version tag into the field segment of g.
+// (internal-use only)
+func (g *generator) tagField() {
+	tag := g.versionTag << versionShift
+	if high := g.fieldSegment & versionMask; high != 0 && high != tag {
+		if g.foldFields {
+			// New folds it into range instead
+			g.fieldSegment = tag
+			return
+		}
+		g.report(anomalyFieldReset, "snowflake: field out of range of a tagged layout, reset to 0",
+			g.fieldSegment>>sequenceBits, "max", maxTaggedField)
+		g.fieldSegment = 0
+	}
+	g.fieldSegment |= tag
+}
+
+// VersionOf returns the layout version tag of id, as set by
+// WithVersionTag. ok is false for untagged IDs, whose version reads 0.
+//
+// Tags can't be told apart from field bits: an untagged ID with a field of
+// 256 or more reads as tagged. Only rely on tags where no such field was
+// ever used.
+func VersionOf(id uint64) (v uint8, ok bool) {
+	v = uint8(id & versionMask >> versionShift)
+	return v, v != 0
+}
+
+// ParseTagged is like Parse for IDs issued with WithVersionTag(v), leaving
+// the tag out of the field. Untagged IDs are parsed as they are. Returns
+// an error wrapping ErrVersionMismatch if id is tagged with another
+// version.
+func ParseTagged(id uint64, v uint8) (SID, error) {
+	if err := checkVersion(id, v); err != nil {
+		return SID{}, err
+	}
+	sid := Parse(id)
+	sid.MachineID &= maxTaggedField
+	sid.Field = sid.MachineID
+	return sid, nil
+}
+
+// Parse2Tagged is ParseTagged for IDs with 2 field fields.
+func Parse2Tagged(id uint64, v uint8) (SID2, error) {
+	if err := checkVersion(id, v); err != nil {
+		return SID2{}, err
+	}
+	sid := Parse2(id)
+	sid.Field2 &= maxTaggedField >> (fieldBits / 2)
+	return sid, nil
+}
+
+// checkVersion checks that id is tagged with v or untagged.
+// (internal-use only)
+func checkVersion(id uint64, v uint8) error {
+	if tag, ok := VersionOf(id); ok && tag != v {
+		return fmt.Errorf("snowflake: ID %d is tagged with version %d, not %d: %w", id, tag, v, ErrVersionMismatch)
+	}
+	return nil
+}
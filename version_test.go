@@ -0,0 +1,109 @@
+package snowflake_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestWithVersionTag(t *testing.T) {
+	id := snowflake.New(200, snowflake.WithVersionTag(1)).NextID()
+	if v, ok := snowflake.VersionOf(id); !ok || v != 1 {
+		t.Errorf("expected version 1 got %d (%v)", v, ok)
+	}
+	sid, err := snowflake.ParseTagged(id, 1)
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	if sid.MachineID != 200 {
+		t.Errorf("expected field 200 got %d", sid.MachineID)
+	}
+
+	id2 := snowflake.New2(3, 5, snowflake.WithVersionTag(2)).NextID()
+	if v, ok := snowflake.VersionOf(id2); !ok || v != 2 {
+		t.Errorf("expected version 2 got %d (%v)", v, ok)
+	}
+	sid2, err := snowflake.Parse2Tagged(id2, 2)
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	if sid2.Field1 != 3 || sid2.Field2 != 5 {
+		t.Errorf("expected fields 3 and 5 got %d and %d", sid2.Field1, sid2.Field2)
+	}
+}
+
+func TestWithVersionTag_Mismatch(t *testing.T) {
+	id := snowflake.New(200, snowflake.WithVersionTag(1)).NextID()
+	id2 := snowflake.New2(3, 5, snowflake.WithVersionTag(2)).NextID()
+
+	if _, err := snowflake.ParseTagged(id2, 1); !errors.Is(err, snowflake.ErrVersionMismatch) {
+		t.Errorf("expected error %v got %v", snowflake.ErrVersionMismatch, err)
+	}
+	if _, err := snowflake.Parse2Tagged(id, 2); !errors.Is(err, snowflake.ErrVersionMismatch) {
+		t.Errorf("expected error %v got %v", snowflake.ErrVersionMismatch, err)
+	}
+}
+
+func TestWithVersionTag_Legacy(t *testing.T) {
+	id := snowflake.New(42).NextID()
+	if v, ok := snowflake.VersionOf(id); ok || v != 0 {
+		t.Errorf("expected no version got %d (%v)", v, ok)
+	}
+	for _, v := range []uint8{1, 2, 3} {
+		sid, err := snowflake.ParseTagged(id, v)
+		if err != nil {
+			t.Fatalf("expected no error got %v", err)
+		}
+		if sid != snowflake.Parse(id) {
+			t.Errorf("expected %+v got %+v", snowflake.Parse(id), sid)
+		}
+	}
+
+	// out of range versions leave IDs untagged
+	if _, ok := snowflake.VersionOf(snowflake.New(42, snowflake.WithVersionTag(4)).NextID()); ok {
+		t.Error("expected version 4 to leave the ID untagged")
+	}
+}
+
+func TestWithVersionTag_FieldOutOfRange(t *testing.T) {
+	tc := []struct {
+		name  string
+		id    uint64
+		field uint64
+	}{
+		{"Should reset a field above 255", snowflake.New(2<<8|9, snowflake.WithVersionTag(1)).NextID(), 0},
+		{"Should reset a second field above 7", snowflake.New2(3, 16, snowflake.WithVersionTag(1)).NextID(), 0},
+		{"Should keep a field already tagged", snowflake.New(1<<8|9, snowflake.WithVersionTag(1)).NextID(), 9},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			sid, err := snowflake.ParseTagged(tt.id, 1)
+			if err != nil {
+				t.Fatalf("expected no error got %v", err)
+			}
+			if sid.MachineID != tt.field {
+				t.Errorf("expected field %d got %d", tt.field, sid.MachineID)
+			}
+		})
+	}
+}
+
+func TestWithVersionTag_Persistent(t *testing.T) {
+	store := &memStore{}
+	first, err := snowflake.NewPersistent(7, store, snowflake.WithVersionTag(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.NextID()
+
+	restored, err := snowflake.NewPersistent(7, store, snowflake.WithVersionTag(3))
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	sid, err := snowflake.ParseTagged(restored.NextID(), 3)
+	if err != nil || sid.MachineID != 7 {
+		t.Errorf("expected field 7 got %d (%v)", sid.MachineID, err)
+	}
+}
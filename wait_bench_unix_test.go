@@ -0,0 +1,47 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package snowflake_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// cpuTime returns the user+system CPU time used by the process so far.
+func cpuTime(b *testing.B) time.Duration {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		b.Fatal(err)
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+}
+
+// BenchmarkWaitUntilNextMs reports how much CPU each strategy burns per
+// exhaustion wait.
+func BenchmarkWaitUntilNextMs(b *testing.B) {
+	tc := []struct {
+		name string
+		opt  snowflake.Option
+	}{
+		{"spin", snowflake.WithWaitThresholds(time.Hour, 0)},
+		{"adaptive", snowflake.WithWaitThresholds(50*time.Microsecond, 200*time.Microsecond)},
+		{"sleep", snowflake.WithWaitThresholds(0, 0)},
+	}
+
+	for _, tt := range tc {
+		b.Run(tt.name, func(b *testing.B) {
+			sf := snowflake.New(1, tt.opt)
+			buf := make([]uint64, 0, 4096)
+			b.ResetTimer()
+			start := cpuTime(b)
+			for i := 0; i < b.N; i++ {
+				buf = sf.AppendIDs(buf[:0], 4096) // at least one full millisecond
+			}
+			b.ReportMetric(float64(cpuTime(b)-start)/float64(b.N), "cpu-ns/op")
+		})
+	}
+}
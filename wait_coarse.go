@@ -0,0 +1,31 @@
+//go:build js || windows
+// +build js windows
+
+package snowflake
+
+import (
+	"sync"
+	"time"
+)
+
+// The system clock may tick every 15ms or so, which isn't worth spinning
+// through: waits for the next tick sleep right away, leaving the CPU to
+// other goroutines (and, on js, to the event loop). (internal-use only)
+const (
+	defaultSpinWait  time.Duration = 0
+	defaultYieldWait time.Duration = 0
+)
+
+var (
+	systemTickOnce sync.Once
+	systemTickMs   int64
+)
+
+// systemTick returns the granularity of the system clock in milliseconds,
+// measured the first time it's called. (internal-use only)
+func systemTick() int64 {
+	systemTickOnce.Do(func() {
+		systemTickMs = measureGranularity(time.Now)
+	})
+	return systemTickMs
+}
@@ -0,0 +1,35 @@
+//go:build js || windows
+// +build js windows
+
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestSystemClockGranularity(t *testing.T) {
+	tick := snowflake.SystemClockGranularity()
+	if tick < time.Millisecond || tick > time.Second {
+		t.Fatalf("expected a granularity of 1ms to 1s got %v", tick)
+	}
+
+	sf := snowflake.New(1)
+	seen := make(map[uint64]bool)
+	var prev uint64
+	for i := 0; i < 200000; i++ {
+		id := sf.NextID()
+		if id <= prev || seen[id] {
+			t.Fatalf("expected unique increasing IDs got %d after %d", id, prev)
+		}
+		seen[id], prev = true, id
+
+		// the system clock reads up to a tick behind real time, and so
+		// may lead it by up to a tick too
+		if lead := time.UnixMilli(snowflake.Parse(id).Timestamp).Sub(time.Now()); lead >= tick {
+			t.Fatalf("expected timestamps to lead the clock by less than %v, led by %v", tick, lead)
+		}
+	}
+}
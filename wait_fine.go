@@ -0,0 +1,17 @@
+//go:build !js && !windows
+// +build !js,!windows
+
+package snowflake
+
+import "time"
+
+// The system clock ticks every millisecond or faster, so waits for the
+// next millisecond are short enough to spin through. (internal-use only)
+const (
+	defaultSpinWait  = 50 * time.Microsecond
+	defaultYieldWait = 200 * time.Microsecond
+)
+
+// systemTick returns the granularity of the system clock in milliseconds.
+// (internal-use only)
+func systemTick() int64 { return 1 }
@@ -0,0 +1,31 @@
+//go:build !js && !windows
+// +build !js,!windows
+
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+func TestSystemClockGranularity(t *testing.T) {
+	if tick := snowflake.SystemClockGranularity(); tick != time.Millisecond {
+		t.Errorf("expected a granularity of 1ms got %v", tick)
+	}
+
+	sf := snowflake.New(1)
+	var prev uint64
+	for i := 0; i < 100000; i++ {
+		id := sf.NextID()
+		if id <= prev {
+			t.Fatalf("expected increasing IDs got %d after %d", id, prev)
+		}
+		prev = id
+
+		if lead := time.UnixMilli(snowflake.Parse(id).Timestamp).Sub(time.Now()); lead > 0 {
+			t.Fatalf("expected timestamps not to lead the clock, led by %v", lead)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package snowflake_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// floorClock reads the real time, but never earlier than floor.
+type floorClock struct {
+	floor time.Time
+}
+
+func (c floorClock) Now() time.Time {
+	if now := time.Now(); now.After(c.floor) {
+		return now
+	}
+	return c.floor
+}
+
+// exhaust generates IDs until the sequence rolls over into a new millisecond
+// and returns the first ID of that millisecond and when it was returned.
+func exhaust(sf *snowflake.ID) (uint64, time.Time) {
+	prev := snowflake.Parse(sf.NextID())
+	for {
+		id := sf.NextID()
+		returned := time.Now()
+		sid := snowflake.Parse(id)
+		if sid.Timestamp != prev.Timestamp {
+			return id, returned
+		}
+		prev = sid
+	}
+}
+
+func TestWaitUntilNextMs_WakeUp(t *testing.T) {
+	tc := []struct {
+		name string
+		opts []snowflake.Option
+	}{
+		{"default", nil},
+		{"spin only", []snowflake.Option{snowflake.WithWaitThresholds(time.Hour, 0)}},
+		{"yield only", []snowflake.Option{snowflake.WithWaitThresholds(0, time.Hour)}},
+	}
+
+	tolerance := 5 * time.Millisecond
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			// hold the clock on one millisecond long enough to exhaust it
+			floor := time.Now().Add(50 * time.Millisecond).Truncate(time.Millisecond)
+			opts := append([]snowflake.Option{snowflake.WithClock(floorClock{floor})}, tt.opts...)
+			sf := snowflake.New(1, opts...)
+
+			id, returned := exhaust(sf)
+			tick := time.UnixMilli(snowflake.Parse(id).Timestamp)
+			if !tick.Equal(floor.Add(time.Millisecond)) {
+				t.Fatalf("expected to roll over to %s got %s", floor.Add(time.Millisecond), tick)
+			}
+			if returned.Before(tick) {
+				t.Errorf("expected to wake up after the next tick %s, woke up at %s", tick, returned)
+			}
+			if late := returned.Sub(tick); late > tolerance {
+				t.Errorf("expected to wake up within %s of the next tick, was %s late", tolerance, late)
+			}
+		})
+	}
+}
+
+func TestWaitUntilNextMs_Sleep(t *testing.T) {
+	// the system clock is the only one the generator sleeps on, reading it
+	// once per sequence makes sure the whole sequence fits in a millisecond
+	sf := snowflake.New(1, snowflake.WithWaitThresholds(0, 0), snowflake.WithClockCheckEvery(4096))
+
+	tolerance := 5 * time.Millisecond
+	for i := 0; i < 5; i++ {
+		id, returned := exhaust(sf)
+		tick := time.UnixMilli(snowflake.Parse(id).Timestamp)
+		if returned.Before(tick) {
+			t.Errorf("expected to wake up after the next tick %s, woke up at %s", tick, returned)
+		}
+		if late := returned.Sub(tick); late > tolerance {
+			t.Errorf("expected to wake up within %s of the next tick, was %s late", tolerance, late)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync/atomic"
+	"time"
+)
+
+// warmupPoll is how often a warming up generator with an injected Clock
+// reads it, since there's no telling when it moves. (internal-use only)
+const warmupPoll = 100 * time.Microsecond
+
+// closedReady is the Ready channel of generators without a warmup.
+// (internal-use only)
+var closedReady = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// WithWarmup makes the generator resume after the last millisecond saved
+// in store by a previous process with the same field (see WithStateStore),
+// so a restart within that millisecond can't reissue the sequence numbers
+// the old process raced ahead with. Until the clock is past it, Ready
+// isn't closed and NextID waits for the next millisecond as it does when a
+// sequence runs out; use WaitReady to bound the wait.
+//
+// A store holding no state, or the state of another field, needs no
+// warmup. A state that can't be loaded or decoded is reported to the
+// logger set with WithLogger and ignored.
+func WithWarmup(store StateStore) Option {
+	return func(g *generator) {
+		g.warmupStore = store
+	}
+}
+
+// Ready returns a channel closed once the generator is past the
+// millisecond of WithWarmup. It's closed from the start for generators
+// without a warmup.
+func (id *ID) Ready() <-chan struct{} { return id.readyChan() }
+
+// Ready is like (*ID).Ready.
+func (id *ID2) Ready() <-chan struct{} { return id.readyChan() }
+
+// WaitReady waits until the generator is ready, see Ready, or ctx is
+// done, returning ctx.Err() then.
+func (id *ID) WaitReady(ctx context.Context) error { return waitReady(ctx, id.readyChan()) }
+
+// WaitReady is like (*ID).WaitReady.
+func (id *ID2) WaitReady(ctx context.Context) error { return waitReady(ctx, id.readyChan()) }
+
+// readyChan returns the Ready channel of g. (internal-use only)
+func (g *generator) readyChan() <-chan struct{} {
+	if g.ready == nil {
+		return closedReady
+	}
+	return g.ready
+}
+
+// waitReady waits for ready or ctx. (internal-use only)
+func waitReady(ctx context.Context, ready <-chan struct{}) error {
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// warmup loads the state in g.warmupStore and, if it's for the field of g,
+// makes g resume after its millisecond, closing g.ready once the clock is
+// past it. Called by apply. (internal-use only)
+func (g *generator) warmup() {
+	data, err := g.warmupStore.Load()
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	var s state
+	if err == nil {
+		s, err = unmarshalState(data)
+	}
+	if err == nil && s.epochMillis != atomic.LoadInt64(&epochMillis) {
+		err = ErrEpochMismatch
+	}
+	if err != nil {
+		g.report(anomalyStateLoad, "snowflake: loading state for warmup failed", g.fieldSegment>>sequenceBits, "error", err)
+		return
+	}
+	if s.field != g.fieldSegment>>sequenceBits || s.elapsedTime < g.now() {
+		return
+	}
+
+	// IDs of the saved millisecond may have been issued after the save
+	g.elapsedTime = s.elapsedTime
+	g.sequence = maxSeqBits
+	g.ready = make(chan struct{})
+	go g.waitPast(s.elapsedTime)
+}
+
+// waitPast closes g.ready once the clock of g is past the millisecond
+// last. (internal-use only)
+func (g *generator) waitPast(last int64) {
+	for {
+		now := g.now()
+		if now > last {
+			close(g.ready)
+			return
+		}
+		if g.clock == nil {
+			time.Sleep(time.Duration(last-now+1) * time.Millisecond)
+		} else {
+			time.Sleep(warmupPoll)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package snowflake_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+)
+
+// memStore is a snowflake.StateStore in memory.
+type memStore struct {
+	mtx   sync.Mutex
+	state []byte
+}
+
+func (s *memStore) Save(state []byte) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.state = append([]byte(nil), state...)
+	return nil
+}
+
+func (s *memStore) Load() ([]byte, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.state == nil {
+		return nil, fs.ErrNotExist
+	}
+	return s.state, nil
+}
+
+// isReady reports whether ready gets closed within wait.
+func isReady(ready <-chan struct{}, wait time.Duration) bool {
+	select {
+	case <-ready:
+		return true
+	case <-time.After(wait):
+		return false
+	}
+}
+
+func TestWithWarmup(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	saved := start.Add(50 * time.Millisecond)
+
+	// the previous process issued IDs up to 50ms ahead of our clock
+	store := &memStore{}
+	var last uint64
+	old := snowflake.New(1, snowflake.WithClock(newFakeClock(saved)), snowflake.WithStateStore(store))
+	for i := 0; i < 10; i++ {
+		last = old.NextID()
+	}
+
+	clock := newFakeClock(start)
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithWarmup(store))
+	if isReady(sf.Ready(), 10*time.Millisecond) {
+		t.Fatal("expected the generator not to be ready before the saved millisecond")
+	}
+
+	clock.Set(saved)
+	if isReady(sf.Ready(), 10*time.Millisecond) {
+		t.Fatal("expected the generator not to be ready within the saved millisecond")
+	}
+
+	clock.Set(saved.Add(time.Millisecond))
+	if !isReady(sf.Ready(), time.Second) {
+		t.Fatal("expected the generator to be ready past the saved millisecond")
+	}
+
+	id := sf.NextID()
+	if ts, expected := snowflake.Parse(id).Timestamp, saved.Add(time.Millisecond).UnixMilli(); ts != expected {
+		t.Errorf("expected timestamp %d got %d", expected, ts)
+	}
+	if id <= last {
+		t.Errorf("expected an ID greater than %d got %d", last, id)
+	}
+}
+
+func TestWithWarmup_FieldFolding(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	store := &memStore{}
+	snowflake.New(5000, snowflake.WithFieldFolding(), snowflake.WithClock(newFakeClock(start)), snowflake.WithStateStore(store)).NextID()
+
+	// the saved field is the folded one
+	clock := newFakeClock(start)
+	sf := snowflake.New(5000, snowflake.WithFieldFolding(), snowflake.WithClock(clock), snowflake.WithWarmup(store))
+	defer clock.Set(start.Add(time.Second)) // let the warmup finish
+
+	if isReady(sf.Ready(), 10*time.Millisecond) {
+		t.Error("expected the folded generator not to be ready within the saved millisecond")
+	}
+}
+
+func TestWithWarmup_WaitReady(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	store := &memStore{}
+	snowflake.New(1, snowflake.WithClock(newFakeClock(start.Add(time.Second))), snowflake.WithStateStore(store)).NextID()
+
+	clock := newFakeClock(start)
+	sf := snowflake.New(1, snowflake.WithClock(clock), snowflake.WithWarmup(store))
+	defer clock.Set(start.Add(2 * time.Second)) // let the warmup finish
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := sf.WaitReady(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error %v got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestWithWarmup_NoWarmup(t *testing.T) {
+	start := snowflake.Epoch().Add(time.Hour)
+	saved := &memStore{}
+	snowflake.New(1, snowflake.WithClock(newFakeClock(start.Add(time.Second))), snowflake.WithStateStore(saved)).NextID()
+
+	tc := []struct {
+		name  string
+		field uint64
+		store snowflake.StateStore
+	}{
+		{"Should be ready without a warmup", 1, nil},
+		{"Should be ready with an empty store", 1, &memStore{}},
+		{"Should be ready with the state of another field", 2, saved},
+		{"Should be ready with a corrupted state", 1, &memStore{state: []byte("garbage")}},
+	}
+
+	for _, tt := range tc {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []snowflake.Option{snowflake.WithClock(newFakeClock(start))}
+			if tt.store != nil {
+				opts = append(opts, snowflake.WithWarmup(tt.store))
+			}
+			sf := snowflake.New(tt.field, opts...)
+
+			if err := sf.WaitReady(context.Background()); err != nil {
+				t.Errorf("expected no error got %v", err)
+			}
+			if ts := snowflake.Parse(sf.NextID()).Timestamp; ts != start.UnixMilli() {
+				t.Errorf("expected timestamp %d got %d", start.UnixMilli(), ts)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/HotPotatoC/snowflake/zkalloc
+
+go 1.22
+
+require (
+	github.com/HotPotatoC/snowflake v0.0.0-00010101000000-000000000000
+	github.com/go-zookeeper/zk v1.0.4
+)
+
+replace github.com/HotPotatoC/snowflake => ../
@@ -0,0 +1,260 @@
+// Package zkalloc assigns snowflake field values through ZooKeeper, the way
+// the original Twitter snowflake coordinated its worker IDs.
+//
+// Each process creates an ephemeral sequential znode under a parent path
+// and uses its sequence number modulo 1024 as its field. The znode lives
+// as long as the ZooKeeper session.
+//
+//	conn, _, err := zk.Connect([]string{"zk1:2181"}, 10*time.Second)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	lease, err := zkalloc.Acquire(conn, "/snowflake/workers")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer lease.Close()
+//
+//	sf := snowflake.New(lease.Field())
+//
+//	go func() {
+//		<-lease.Done() // the field may now be handed to someone else
+//		log.Fatal("lost snowflake field lease: ", lease.Err())
+//	}()
+package zkalloc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/go-zookeeper/zk"
+)
+
+// nodePrefix is the name of the znodes created under the parent path,
+// before ZooKeeper's 10 digit sequence number.
+const nodePrefix = "worker-"
+
+var (
+	// ErrNoFreeField is returned when 1024 workers are already registered.
+	// It wraps snowflake.ErrNoFreeField.
+	ErrNoFreeField = fmt.Errorf("zkalloc: %w", snowflake.ErrNoFreeField)
+	// ErrFieldCollision is returned when the sequence number wrapped around
+	// onto a field still held by an older worker. Acquiring again moves on
+	// to the next sequence number.
+	ErrFieldCollision = errors.New("zkalloc: field collision")
+	// ErrLeaseLost is reported by Lease.Err when the znode was deleted by
+	// someone else.
+	ErrLeaseLost = errors.New("zkalloc: lease lost")
+)
+
+// Conn is the subset of *zk.Conn used by Acquire.
+type Conn interface {
+	Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error)
+	Children(path string) ([]string, *zk.Stat, error)
+	ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error)
+	Delete(path string, version int32) error
+}
+
+// Lease is a field value held by an ephemeral znode. The znode is watched
+// until Close is called or it is lost.
+type Lease struct {
+	conn  Conn
+	node  string
+	field uint64
+
+	done      chan struct{}
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	mtx sync.Mutex
+	err error
+}
+
+// Acquire registers a worker under parent, which must already exist, and
+// derives its field from the sequence number of the created znode.
+// Returns ErrNoFreeField when 1024 workers are registered, or
+// ErrFieldCollision when the derived field is held by another worker.
+func Acquire(conn Conn, parent string) (*Lease, error) {
+	node, err := conn.Create(parent+"/"+nodePrefix, nil, zk.FlagEphemeral|zk.FlagSequence, zk.WorldACL(zk.PermAll))
+	if err != nil {
+		return nil, err
+	}
+
+	field, err := check(conn, parent, node)
+	if err != nil {
+		conn.Delete(node, -1)
+		return nil, err
+	}
+
+	exists, _, events, err := conn.ExistsW(node)
+	if err == nil && !exists {
+		err = ErrLeaseLost
+	}
+	if err != nil {
+		conn.Delete(node, -1)
+		return nil, err
+	}
+
+	l := &Lease{
+		conn:  conn,
+		node:  node,
+		field: field,
+		done:  make(chan struct{}),
+		stop:  make(chan struct{}),
+	}
+
+	l.wg.Add(1)
+	go l.watch(events)
+
+	return l, nil
+}
+
+// Allocator is a snowflake.FieldAllocator acquiring leases under Parent
+// with Acquire, for snowflake.NewLeased.
+//
+//	sf, err := snowflake.NewLeased(ctx, zkalloc.Allocator{Conn: conn, Parent: "/snowflake/workers"})
+type Allocator struct {
+	Conn   Conn
+	Parent string
+}
+
+var _ snowflake.FieldAllocator = Allocator{}
+
+// Acquire is like the package-level Acquire, returning early if ctx is
+// done.
+func (a Allocator) Acquire(ctx context.Context) (snowflake.Lease, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	l, err := Acquire(a.Conn, a.Parent)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Field returns the leased field value.
+func (l *Lease) Field() uint64 { return l.field }
+
+// Done is closed when the lease is lost, after which another process may
+// be handed the same field value. It is not closed by Close.
+func (l *Lease) Done() <-chan struct{} { return l.done }
+
+// Err returns why the lease was lost, or nil while it is held. An expired
+// ZooKeeper session is reported as zk.ErrSessionExpired.
+func (l *Lease) Err() error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.err
+}
+
+// Close stops watching the znode and deletes it.
+func (l *Lease) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.stop)
+		l.wg.Wait()
+
+		err = l.conn.Delete(l.node, -1)
+		if err == zk.ErrNoNode {
+			err = nil
+		}
+	})
+	return err
+}
+
+// watch waits for the znode to be deleted or the session to expire,
+// re-arming the watch on any other event. (internal-use only)
+func (l *Lease) watch(events <-chan zk.Event) {
+	defer l.wg.Done()
+
+	for {
+		var ev zk.Event
+		select {
+		case <-l.stop:
+			return
+		case ev = <-events:
+		}
+
+		switch ev.Type {
+		case zk.EventNodeDeleted:
+			l.lose(ErrLeaseLost)
+			return
+		case zk.EventNotWatching:
+			l.lose(ev.Err)
+			return
+		}
+
+		exists, _, next, err := l.conn.ExistsW(l.node)
+		if err == nil && !exists {
+			err = ErrLeaseLost
+		}
+		if err != nil {
+			l.lose(err)
+			return
+		}
+		events = next
+	}
+}
+
+// lose marks the lease as lost. (internal-use only)
+func (l *Lease) lose(err error) {
+	l.mtx.Lock()
+	l.err = err
+	l.mtx.Unlock()
+	close(l.done)
+}
+
+// check derives the field of node and makes sure no sibling maps to the
+// same field. (internal-use only)
+func check(conn Conn, parent, node string) (uint64, error) {
+	seq, ok := sequence(node[strings.LastIndex(node, "/")+1:])
+	if !ok {
+		return 0, errors.New("zkalloc: unexpected znode name " + node)
+	}
+	field := seq % (snowflake.MaxField() + 1)
+
+	children, _, err := conn.Children(parent)
+	if err != nil {
+		return 0, err
+	}
+
+	var siblings uint64
+	collision := false
+	for _, child := range children {
+		other, ok := sequence(child)
+		if !ok || other == seq {
+			continue
+		}
+		siblings++
+		if other%(snowflake.MaxField()+1) == field {
+			collision = true
+		}
+	}
+
+	switch {
+	case siblings > snowflake.MaxField():
+		return 0, ErrNoFreeField
+	case collision:
+		return 0, ErrFieldCollision
+	}
+
+	return field, nil
+}
+
+// sequence parses the sequence number of a worker znode name.
+// (internal-use only)
+func sequence(name string) (uint64, bool) {
+	if !strings.HasPrefix(name, nodePrefix) {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimPrefix(name, nodePrefix), 10, 64)
+	return seq, err == nil
+}
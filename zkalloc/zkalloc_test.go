@@ -0,0 +1,241 @@
+package zkalloc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HotPotatoC/snowflake"
+	"github.com/HotPotatoC/snowflake/zkalloc"
+	"github.com/go-zookeeper/zk"
+)
+
+// fakeConn is an in-memory ZooKeeper holding the children of one parent.
+type fakeConn struct {
+	mtx      sync.Mutex
+	seq      int
+	nodes    map[string]bool
+	watchers map[string][]chan zk.Event
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{
+		nodes:    make(map[string]bool),
+		watchers: make(map[string][]chan zk.Event),
+	}
+}
+
+func (c *fakeConn) Create(path string, data []byte, flags int32, acl []zk.ACL) (string, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	node := fmt.Sprintf("%s%010d", path, c.seq)
+	c.seq++
+	c.nodes[node] = true
+	return node, nil
+}
+
+func (c *fakeConn) Children(path string) ([]string, *zk.Stat, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var children []string
+	for node := range c.nodes {
+		children = append(children, strings.TrimPrefix(node, path+"/"))
+	}
+	return children, &zk.Stat{}, nil
+}
+
+func (c *fakeConn) ExistsW(path string) (bool, *zk.Stat, <-chan zk.Event, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	ch := make(chan zk.Event, 1)
+	c.watchers[path] = append(c.watchers[path], ch)
+	return c.nodes[path], &zk.Stat{}, ch, nil
+}
+
+func (c *fakeConn) Delete(path string, version int32) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if !c.nodes[path] {
+		return zk.ErrNoNode
+	}
+	delete(c.nodes, path)
+	c.fire(path, zk.Event{Type: zk.EventNodeDeleted, Path: path})
+	return nil
+}
+
+// expire ends the session: ephemeral znodes vanish and watches stop.
+func (c *fakeConn) expire() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for path := range c.watchers {
+		c.fire(path, zk.Event{Type: zk.EventNotWatching, Path: path, Err: zk.ErrSessionExpired})
+	}
+	c.nodes = make(map[string]bool)
+}
+
+func (c *fakeConn) fire(path string, ev zk.Event) {
+	for _, ch := range c.watchers[path] {
+		ch <- ev
+	}
+	delete(c.watchers, path)
+}
+
+func TestAcquire(t *testing.T) {
+	c := newFakeConn()
+
+	first, err := zkalloc.Acquire(c, "/workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := zkalloc.Acquire(c, "/workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Field() != 0 || second.Field() != 1 {
+		t.Errorf("expected fields 0 and 1 got %d and %d", first.Field(), second.Field())
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if c.nodes["/workers/worker-0000000000"] {
+		t.Error("expected Close to delete the znode")
+	}
+	select {
+	case <-first.Done():
+		t.Error("expected Done not to be closed by Close")
+	default:
+	}
+
+	second.Close()
+}
+
+func TestAcquire_Wraparound(t *testing.T) {
+	c := newFakeConn()
+
+	held, err := zkalloc.Acquire(c, "/workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Close()
+
+	// workers 1 to 1023 came and went
+	c.seq = 1024
+
+	_, err = zkalloc.Acquire(c, "/workers")
+	if err != zkalloc.ErrFieldCollision {
+		t.Errorf("expected error %v got %v", zkalloc.ErrFieldCollision, err)
+	}
+	if len(c.nodes) != 1 {
+		t.Errorf("expected the colliding znode to be deleted got %d znodes", len(c.nodes))
+	}
+
+	lease, err := zkalloc.Acquire(c, "/workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+	if lease.Field() != 1 {
+		t.Errorf("expected field 1 got %d", lease.Field())
+	}
+}
+
+func TestAcquire_NoFreeField(t *testing.T) {
+	c := newFakeConn()
+
+	for i := 0; i < 1024; i++ {
+		if _, err := zkalloc.Acquire(c, "/workers"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := zkalloc.Acquire(c, "/workers")
+	if err != zkalloc.ErrNoFreeField {
+		t.Errorf("expected error %v got %v", zkalloc.ErrNoFreeField, err)
+	}
+	if !errors.Is(err, snowflake.ErrNoFreeField) {
+		t.Errorf("expected error %v to wrap %v", err, snowflake.ErrNoFreeField)
+	}
+	if len(c.nodes) != 1024 {
+		t.Errorf("expected the extra znode to be deleted got %d znodes", len(c.nodes))
+	}
+}
+
+func TestLease_SessionExpired(t *testing.T) {
+	c := newFakeConn()
+
+	lease, err := zkalloc.Acquire(c, "/workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	c.expire()
+
+	select {
+	case <-lease.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the lease to be lost")
+	}
+
+	if lease.Err() != zk.ErrSessionExpired {
+		t.Errorf("expected error %v got %v", zk.ErrSessionExpired, lease.Err())
+	}
+}
+
+func TestLease_Deleted(t *testing.T) {
+	c := newFakeConn()
+
+	lease, err := zkalloc.Acquire(c, "/workers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Close()
+
+	c.Delete("/workers/worker-0000000000", -1)
+
+	select {
+	case <-lease.Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the lease to be lost")
+	}
+
+	if lease.Err() != zkalloc.ErrLeaseLost {
+		t.Errorf("expected error %v got %v", zkalloc.ErrLeaseLost, lease.Err())
+	}
+}
+
+func TestAllocator(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := newFakeConn()
+
+	sf, err := snowflake.NewLeased(ctx, zkalloc.Allocator{Conn: c, Parent: "/workers"}, snowflake.WithLeasePolicy(snowflake.ReacquireOnLeaseLoss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field := snowflake.Parse(sf.NextID()).MachineID; field != 0 {
+		t.Fatalf("expected field 0 got %d", field)
+	}
+
+	c.Delete("/workers/worker-0000000000", -1)
+
+	// the generator carries on with the next lease
+	deadline := time.Now().Add(5 * time.Second)
+	for snowflake.Parse(sf.NextID()).MachineID != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected field 1 to be leased once field 0 was lost")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}